@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/config"
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+)
+
+// uploadAssets uploads the asset directory under a prefix unique to image, so
+// objects can be cached as immutable, and returns the base URL of that prefix.
+func uploadAssets(ctx context.Context, project, region, image string, assets config.Assets) (string, error) {
+	dir := assets.Dir
+	if dir == "" {
+		dir = "dist"
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return "", errors.Errorf("assets dir %s not found. Run 'go do bundle' or set assets.dir in %s", dir, config.File)
+	}
+
+	apis := []string{"storage.googleapis.com"}
+	if assets.CDN {
+		apis = append(apis, "compute.googleapis.com")
+	}
+	if err := gcloud.EnsureAPIs(ctx, project, apis...); err != nil {
+		return "", err
+	}
+	if err := gcloud.EnsurePublicBucket(ctx, project, assets.Bucket, region); err != nil {
+		return "", err
+	}
+
+	prefix := assetsPrefix(image)
+	if err := gcloud.UploadDir(ctx, dir, fmt.Sprintf("gs://%s/%s", assets.Bucket, prefix), gcloud.ImmutableCacheControl); err != nil {
+		return "", err
+	}
+
+	base := "https://storage.googleapis.com/" + assets.Bucket
+	if assets.CDN {
+		ip, err := gcloud.EnsureBucketCDN(ctx, project, assets.Bucket)
+		if err != nil {
+			return "", err
+		}
+		base = "http://" + ip
+	}
+
+	url := base + "/" + prefix
+	fmt.Printf("Uploaded %s to %s\n", dir, url)
+	return url, nil
+}
+
+// assetsPrefix returns a short per-image prefix, like the first 12 characters of its digest.
+func assetsPrefix(image string) string {
+	ref := image
+	if _, digest, ok := strings.Cut(image, "@"); ok {
+		ref = strings.TrimPrefix(digest, "sha256:")
+	} else if i := strings.LastIndex(image, ":"); i >= 0 {
+		ref = image[i+1:]
+	}
+	return ref[:min(12, len(ref))]
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/svelte"
+	"github.com/pkg/errors"
+)
+
+// Values of --baseline besides the default, which skips the findings recorded in
+// baselineFile when it exists.
+const (
+	baselineIgnore = "ignore"
+	baselineWrite  = "write"
+)
+
+// baselineFile records the findings `do lint --baseline=write` accepted, relative to
+// the project root.
+var baselineFile = filepath.Join(".do", "lint-baseline.json")
+
+// baselineFinding identifies a finding by its file and the text of its line rather
+// than the line number, so findings survive edits elsewhere in the file.
+type baselineFinding struct {
+	Analyzer string `json:"analyzer"`
+	File     string `json:"file"`
+	Message  string `json:"message"`
+	Source   string `json:"source"`
+}
+
+type baseline struct {
+	Findings []baselineFinding `json:"findings"`
+}
+
+// baselineIndex counts the findings of a baseline not yet matched by a run.
+type baselineIndex struct {
+	counts map[baselineFinding]int
+	lines  map[string][]string
+	root   string
+	// Skipped is the number of findings matched.
+	Skipped int
+}
+
+// loadBaseline returns the baseline in root, or nil when there is none.
+func loadBaseline(root string) (*baselineIndex, error) {
+	data, err := os.ReadFile(filepath.Join(root, baselineFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", baselineFile)
+	}
+	idx := newBaselineIndex(root)
+	for _, f := range b.Findings {
+		idx.counts[f]++
+	}
+	return idx, nil
+}
+
+func newBaselineIndex(root string) *baselineIndex {
+	return &baselineIndex{counts: make(map[baselineFinding]int), lines: make(map[string][]string), root: root}
+}
+
+// finding returns the baseline entry for d.
+func (idx *baselineIndex) finding(d lintDiagnostic) baselineFinding {
+	file := d.Pos.Filename
+	if rel, err := filepath.Rel(idx.root, file); err == nil {
+		file = rel
+	}
+	f := baselineFinding{Analyzer: d.Analyzer, File: filepath.ToSlash(file), Message: d.Message}
+
+	lines, ok := idx.lines[d.Pos.Filename]
+	if !ok {
+		lines = readLines(d.Pos.Filename)
+		idx.lines[d.Pos.Filename] = lines
+	}
+	if d.Pos.Line > 0 && d.Pos.Line <= len(lines) {
+		f.Source = strings.TrimSpace(lines[d.Pos.Line-1])
+	}
+	return f
+}
+
+// skip reports whether d is in the baseline, using up one of its entries so the
+// baseline fails a run with more copies of a finding than it recorded.
+func (idx *baselineIndex) skip(d lintDiagnostic) bool {
+	if idx == nil {
+		return false
+	}
+	f := idx.finding(d)
+	if idx.counts[f] == 0 {
+		return false
+	}
+	idx.counts[f]--
+	idx.Skipped++
+	return true
+}
+
+// filterBaseline removes the analyzer, golangci-lint, and Svelte diagnostics in idx.
+func filterBaseline(idx *baselineIndex, analyzerDiags, golangciDiags []lintDiagnostic, svelteDiags []svelte.Diagnostic) ([]lintDiagnostic, []lintDiagnostic, []svelte.Diagnostic) {
+	if idx == nil {
+		return analyzerDiags, golangciDiags, svelteDiags
+	}
+	var keptAnalyzers []lintDiagnostic
+	for _, d := range analyzerDiags {
+		if !idx.skip(d) {
+			keptAnalyzers = append(keptAnalyzers, d)
+		}
+	}
+	var keptGolangci []lintDiagnostic
+	for _, d := range golangciDiags {
+		if !idx.skip(d) {
+			keptGolangci = append(keptGolangci, d)
+		}
+	}
+	var keptSvelte []svelte.Diagnostic
+	for i, d := range svelteLintDiagnostics(svelteDiags) {
+		if !idx.skip(d) {
+			keptSvelte = append(keptSvelte, svelteDiags[i])
+		}
+	}
+	return keptAnalyzers, keptGolangci, keptSvelte
+}
+
+// writeBaseline records diags as the baseline in root.
+func writeBaseline(root string, diags []lintDiagnostic) error {
+	idx := newBaselineIndex(root)
+	b := baseline{Findings: []baselineFinding{}}
+	for _, d := range diags {
+		b.Findings = append(b.Findings, idx.finding(d))
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		x, y := b.Findings[i], b.Findings[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Analyzer != y.Analyzer {
+			return x.Analyzer < y.Analyzer
+		}
+		if x.Source != y.Source {
+			return x.Source < y.Source
+		}
+		return x.Message < y.Message
+	})
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	path := filepath.Join(root, baselineFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, append(data, '\n'), 0644))
+}
+
+// readLines returns the lines of the file at path, or nil if it cannot be read.
+func readLines(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaselineGolangci(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	root := t.TempDir()
+	file := filepath.Join(root, "main.go")
+	r.NoError(os.WriteFile(file, []byte("package main\n\nfunc main() {\n\tf()\n\tg()\n}\n"), 0644))
+	unchecked := lintDiagnostic{Analyzer: "golangci-lint/errcheck", Message: "Error return value is not checked", Pos: token.Position{Filename: file, Line: 4}}
+	analyzer := lintDiagnostic{Analyzer: "pkgerrors", Message: "use github.com/pkg/errors", Pos: token.Position{Filename: file, Line: 5}}
+	r.NoError(writeBaseline(root, []lintDiagnostic{unchecked, analyzer}))
+
+	// The recorded findings moved down a line, and g's result is now unchecked too.
+	r.NoError(os.WriteFile(file, []byte("package main\n\nfunc main() {\n\n\tf()\n\tg()\n}\n"), 0644))
+	unchecked.Pos.Line, analyzer.Pos.Line = 5, 6
+	added := lintDiagnostic{Analyzer: "golangci-lint/errcheck", Message: "Error return value is not checked", Pos: token.Position{Filename: file, Line: 6}}
+	otherLinter := lintDiagnostic{Analyzer: "golangci-lint/staticcheck", Message: "Error return value is not checked", Pos: token.Position{Filename: file, Line: 5}}
+
+	idx, err := loadBaseline(root)
+	r.NoError(err)
+	analyzers, golangci, svelteDiags := filterBaseline(idx, []lintDiagnostic{analyzer}, []lintDiagnostic{unchecked, added, otherLinter}, nil)
+	a.Empty(analyzers)
+	a.Equal([]lintDiagnostic{added, otherLinter}, golangci)
+	a.Empty(svelteDiags)
+	a.Equal(2, idx.Skipped)
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Builders that can produce the deployed image.
+const (
+	builderDocker = "docker"
+	builderKo     = "ko"
+)
+
+// koModule is the module ko is released in. Remote builds run it at koVersion
+// unless go.mod declares it as a tool.
+const (
+	koModule  = "github.com/google/ko"
+	koVersion = "v0.18.0"
+)
+
+// checkBuilder validates a builder name, treating empty as ko.
+func checkBuilder(builder string) error {
+	switch builder {
+	case "", builderKo:
+		return nil
+	case builderDocker:
+		if _, err := os.Stat("Dockerfile"); err != nil {
+			return errors.New("--builder=docker needs a Dockerfile at the project root")
+		}
+		return nil
+	}
+	return errors.Errorf("--builder must be %q or %q", builderKo, builderDocker)
+}
+
+// dockerBuild builds the Dockerfile at the project root for linux/amd64, pushes
+// it to repo, and returns the pushed image reference by digest. It uses buildx
+// when available and falls back to docker build and docker push.
+func dockerBuild(w io.Writer, repo string) (string, error) {
+	tag := repo + ":" + imageTag()
+
+	if exec.Command("docker", "buildx", "version").Run() == nil {
+		meta, err := os.CreateTemp("", "do-buildx-*.json")
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		_ = meta.Close()
+		defer func() { _ = os.Remove(meta.Name()) }()
+
+		if err := runTo(w, "docker", "buildx", "build", "--platform", "linux/amd64", "--push", "--metadata-file", meta.Name(), "-t", tag, "."); err != nil {
+			return "", errors.Wrap(err, "docker buildx build failed")
+		}
+
+		data, err := os.ReadFile(meta.Name())
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		var metadata struct {
+			Digest string `json:"containerimage.digest"`
+		}
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return "", errors.WithStack(err)
+		}
+		if metadata.Digest == "" {
+			return "", errors.New("docker buildx build did not return an image digest")
+		}
+		return repo + "@" + metadata.Digest, nil
+	}
+
+	if err := runTo(w, "docker", "build", "--platform", "linux/amd64", "-t", tag, "."); err != nil {
+		return "", errors.Wrap(err, "docker build failed")
+	}
+	if err := runTo(w, "docker", "push", tag); err != nil {
+		return "", errors.Wrapf(err, "docker push to %s failed", repo)
+	}
+
+	out, err := exec.Command("docker", "inspect", "--format", "{{json .RepoDigests}}", tag).Output()
+	if err != nil {
+		return "", errors.Wrap(err, "docker inspect failed")
+	}
+	var digests []string
+	if err := json.Unmarshal(out, &digests); err != nil {
+		return "", errors.Wrap(err, "parse docker inspect output")
+	}
+	return repoDigest(repo, digests)
+}
+
+// repoDigest returns the entry of an image's RepoDigests in repo. An image also
+// tagged or pulled under other names has digests in those repositories too.
+func repoDigest(repo string, digests []string) (string, error) {
+	for _, d := range digests {
+		if strings.HasPrefix(d, repo+"@") {
+			return d, nil
+		}
+	}
+	return "", errors.Errorf("docker push did not return an image digest in %s", repo)
+}
+
+// remoteBuild submits the build to Cloud Build, streaming its logs, and returns
+// the pushed image reference by digest. The ko builder runs go generate and ko
+// in a Go container; the docker builder builds the Dockerfile.
+func remoteBuild(ctx context.Context, project, buildPath, builder, repo string) (string, error) {
+	version := imageTag()
+	tag := repo + ":" + version
+
+	var steps []gcloud.BuildStep
+	if builder != builderDocker {
+		root, err := findProjectRoot()
+		if err != nil {
+			return "", err
+		}
+		ko, err := koCommand(root)
+		if err != nil {
+			return "", err
+		}
+		steps = []gcloud.BuildStep{{
+			Name:       "golang",
+			Entrypoint: "sh",
+			Args:       []string{"-c", fmt.Sprintf("go generate ./... && %s build %s --bare --tags=%s", ko, buildPath, version)},
+			Env:        []string{"KO_DOCKER_REPO=" + repo},
+		}}
+	}
+
+	fmt.Printf("\nSubmitting build to Cloud Build...\n")
+	if err := gcloud.SubmitBuild(ctx, project, ".", tag, steps); err != nil {
+		return "", errors.Wrap(err, "cloud build failed")
+	}
+
+	digest, err := gcloud.ImageDigest(ctx, tag)
+	if err != nil {
+		return "", err
+	}
+	return repo + "@" + digest, nil
+}
+
+// koCommand returns how a remote build runs ko: go tool ko, at the version go.mod
+// pins, when the go.mod in root declares ko as a tool, and otherwise go run at
+// koVersion.
+func koCommand(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "parse go.mod")
+	}
+	if slices.ContainsFunc(f.Tool, func(t *modfile.Tool) bool { return t.Path == koModule }) {
+		return "go tool ko", nil
+	}
+	return "go run " + koModule + "@" + koVersion, nil
+}
+
+// imageTag returns a timestamp tag for images not tagged by ko.
+func imageTag() string {
+	return time.Now().UTC().Format("20060102-150405")
+}
+
+// runTo runs a command with stdout and stderr written to w.
+func runTo(w io.Writer, name string, args ...string) error {
+	c := exec.Command(name, args...)
+	c.Stdout = w
+	c.Stderr = w
+	return errors.WithStack(c.Run())
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKoCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		gomod string
+		want  string
+	}{
+		{"no tool", "module example.com/app\n\ngo 1.24\n", "go run github.com/google/ko@" + koVersion},
+		{"other tool", "module example.com/app\n\ngo 1.24\n\ntool golang.org/x/tools/cmd/stringer\n", "go run github.com/google/ko@" + koVersion},
+		{"ko tool", "module example.com/app\n\ngo 1.24\n\ntool github.com/google/ko\n\nrequire github.com/google/ko v0.17.1\n", "go tool ko"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			a := assert.New(t)
+
+			root := t.TempDir()
+			r.NoError(os.WriteFile(filepath.Join(root, "go.mod"), []byte(tt.gomod), 0644))
+			got, err := koCommand(root)
+			r.NoError(err)
+			a.Equal(tt.want, got)
+		})
+	}
+}
+
+func TestRepoDigest(t *testing.T) {
+	const repo = "us-docker.pkg.dev/my-project/app/app"
+	tests := []struct {
+		name    string
+		digests []string
+		want    string
+	}{
+		{"only", []string{repo + "@sha256:aaa"}, repo + "@sha256:aaa"},
+		{"other repository first", []string{"docker.io/library/app@sha256:bbb", repo + "@sha256:aaa"}, repo + "@sha256:aaa"},
+		{"repository prefix", []string{repo + "-staging@sha256:ccc", repo + "@sha256:aaa"}, repo + "@sha256:aaa"},
+		{"none in repository", []string{"docker.io/library/app@sha256:bbb"}, ""},
+		{"none", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+
+			got, err := repoDigest(repo, tt.digests)
+			if tt.want == "" {
+				a.EqualError(err, "docker push did not return an image digest in "+repo)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tt.want, got)
+		})
+	}
+}
@@ -1,148 +1,439 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"go/format"
 	"io/fs"
+	"maps"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
-	"github.com/evanw/esbuild/pkg/api"
+	"github.com/housecat-inc/do/pkg/config"
 	"github.com/housecat-inc/do/pkg/svelte"
+	"github.com/housecat-inc/do/pkg/tailwind"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
-var bundleVerbose bool
+var (
+	bundleBaseURL        string
+	bundleCustomElements bool
+	bundleDefines        []string
+	bundleDev            bool
+	bundleEmbed          bool
+	bundleEntries        []string
+	bundleExcludes       []string
+	bundleExtractCSS     bool
+	bundleIncludes       []string
+	bundleInstall        bool
+	bundleLegacy         bool
+	bundleManifest       bool
+	bundleOutdir         string
+	bundleOutfile        string
+	bundlePreview        bool
+	bundleRuntime        bool
+	bundleSourcemap      bool
+	bundleSplitting      bool
+	bundleTailwind       bool
+)
 
 var bundleCmd = &cobra.Command{
 	Use:   "bundle",
 	Short: "Bundle Svelte components into dist/app.min.js",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Find all .svelte files
-		var components []string
-		err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := svelte.UseVersion(cfg.Svelte.Version); err != nil {
+			return err
+		}
+		svelte.CacheDir = filepath.Join(".do", "cache", "svelte")
+		if bundleInstall {
+			if err := npmInstall("."); err != nil {
 				return err
 			}
-			name := d.Name()
-			if d.IsDir() {
-				if name == "node_modules" || name == "dist" || (name != "." && strings.HasPrefix(name, ".")) {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-			// Skip hidden files and non-svelte files
-			if strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".svelte") {
-				return nil
-			}
-			components = append(components, path)
-			return nil
-		})
-		if err != nil {
-			return errors.WithStack(err)
 		}
 
-		if len(components) == 0 {
-			fmt.Println("No .svelte files found")
-			return nil
+		entries := cfg.Svelte.Entries
+		if len(bundleEntries) > 0 || bundleOutfile != "" || len(entries) == 0 {
+			entries = []config.Entry{{Include: bundleEntries, Outfile: bundleOutfile}}
 		}
 
-		// Compile each component and build virtual filesystem
-		var imports []string
-		var exports []string
-		stdinContents := make(map[string]string)
+		define, err := bundleDefine(cfg, bundlePreview || os.Getenv(previewEnv) == "1", bundleDefines)
+		if err != nil {
+			return err
+		}
+		base := svelte.BuildOptions{
+			Aliases: cfg.Svelte.Aliases,
+			BaseURL: bundleBaseURL,
+			Define:  define,
+			Exclude: append(slices.Clone(cfg.Svelte.Exclude), bundleExcludes...),
+			Include: bundleIncludes,
+		}
 
-		for _, path := range components {
-			src, err := os.ReadFile(path)
+		var manifests []svelte.Manifest
+		embeds := make(map[string][]string)
+		for _, e := range entries {
+			manifest, err := bundleEntry(e, base)
 			if err != nil {
-				return errors.WithStack(err)
+				return err
 			}
+			manifests = append(manifests, manifest)
+			dir := bundleDir(e)
+			embeds[dir] = append(embeds[dir], manifest.Files...)
+		}
 
-			code, err := svelte.Compile(string(src))
-			if err != nil {
-				return errors.Errorf("compile %s: %v", path, err)
+		if cfg.Tailwind.Enabled || bundleTailwind {
+			if err := bundleTailwindCSS(cmd.Context(), cfg.Tailwind, embeds); err != nil {
+				return err
 			}
+		}
 
-			// Export key matches filesystem: src/animate/Foo.svelte -> src/animate/Foo
-			exportKey := strings.TrimSuffix(path, ".svelte")
+		if bundleEmbed {
+			for _, dir := range slices.Sorted(maps.Keys(embeds)) {
+				if err := writeEmbed(dir, embeds[dir]); err != nil {
+					return err
+				}
+			}
+		}
 
-			if bundleVerbose {
-				fmt.Printf("%s -> %s\n", path, exportKey)
+		if jsonOutput() {
+			if len(manifests) == 1 {
+				return printJSON(manifests[0])
 			}
+			return printJSON(manifests)
+		}
+		return nil
+	},
+}
+
+// bundleEntry builds and writes one bundle, with the project-wide options in base.
+func bundleEntry(e config.Entry, base svelte.BuildOptions) (svelte.Manifest, error) {
+	include := e.Include
+	if len(include) == 0 {
+		include = []string{"."}
+	}
+	outfile := bundleOutfilePath(e)
+	dir, name := bundleDir(e), filepath.Base(outfile)
+
+	opts := base
+	opts.Exclude = append(slices.Clone(base.Exclude), e.Exclude...)
+	opts.BundleRuntime = bundleRuntime
+	opts.CustomElements = bundleCustomElements
+	opts.Dev = bundleDev
+	opts.ExtractCSS = bundleExtractCSS
+	opts.Legacy = bundleLegacy
+	opts.Outfile = name
+	opts.Sourcemap = bundleSourcemap
+	opts.Splitting = bundleSplitting
+	opts.StripPrefix = e.StripPrefix
+	out, manifest, err := svelte.Build(include, opts)
+	if err != nil {
+		return manifest, err
+	}
+
+	if len(manifest.Components) == 0 && len(manifest.Scripts) == 0 {
+		if !jsonOutput() {
+			fmt.Printf("No .svelte files found for %s\n", outfile)
+		}
+		return manifest, nil
+	}
 
-			// Create safe identifier from path: src/forms/Button -> src_forms_Button
-			ident := strings.ReplaceAll(exportKey, "/", "_")
-			ident = strings.ReplaceAll(ident, "-", "_")
-			ident = strings.ReplaceAll(ident, ".", "_")
-
-			// Add to virtual filesystem
-			virtualPath := ident + ".js"
-			stdinContents[virtualPath] = code
-
-			imports = append(imports, fmt.Sprintf("import %s from './%s'", ident, virtualPath))
-			exports = append(exports, fmt.Sprintf("  '%s': %s", exportKey, ident))
-		}
-
-		// Create entry point
-		entry := fmt.Sprintf("%s\n\nexport default {\n%s\n}\n",
-			strings.Join(imports, "\n"),
-			strings.Join(exports, ",\n"))
-
-		// Bundle with esbuild
-		result := api.Build(api.BuildOptions{
-			Stdin: &api.StdinOptions{
-				Contents:   entry,
-				ResolveDir: ".",
-				Loader:     api.LoaderJS,
-			},
-			Bundle:            true,
-			MinifyWhitespace:  true,
-			MinifyIdentifiers: true,
-			MinifySyntax:      true,
-			Format:            api.FormatESModule,
-			External:          []string{"svelte", "svelte/*"},
-			Outfile:           "dist/app.min.js",
-			Write:             true,
-			Plugins: []api.Plugin{{
-				Name: "svelte-components",
-				Setup: func(build api.PluginBuild) {
-					build.OnResolve(api.OnResolveOptions{Filter: `^\.\/.*\.js$`},
-						func(args api.OnResolveArgs) (api.OnResolveResult, error) {
-							path := strings.TrimPrefix(args.Path, "./")
-							if _, ok := stdinContents[path]; ok {
-								return api.OnResolveResult{
-									Path:      path,
-									Namespace: "svelte-components",
-								}, nil
-							}
-							return api.OnResolveResult{}, nil
-						})
-					build.OnLoad(api.OnLoadOptions{Filter: `.*`, Namespace: "svelte-components"},
-						func(args api.OnLoadArgs) (api.OnLoadResult, error) {
-							contents := stdinContents[args.Path]
-							return api.OnLoadResult{
-								Contents: &contents,
-								Loader:   api.LoaderJS,
-							}, nil
-						})
-				},
-			}},
-		})
-
-		if len(result.Errors) > 0 {
-			for _, err := range result.Errors {
-				fmt.Fprintf(os.Stderr, "esbuild: %s\n", err.Text)
+	if verbose {
+		for _, s := range manifest.Scripts {
+			fmt.Printf("%s (script)\n", s)
+		}
+		for _, c := range manifest.Components {
+			if c.Chunk != "" {
+				fmt.Printf("%s -> %s (%s)\n", c.Path, c.Export, c.Chunk)
+				continue
 			}
-			return errors.New("esbuild bundling failed")
+			fmt.Printf("%s -> %s\n", c.Path, c.Export)
+		}
+	}
+
+	if err := writeDist(out, dir); err != nil {
+		return manifest, err
+	}
+	if bundleManifest {
+		path := bundleManifestPath(outfile)
+		if err := writeManifest(path, manifest); err != nil {
+			return manifest, err
 		}
+		manifest.Files = append(manifest.Files, filepath.Base(path))
+	}
+
+	if jsonOutput() {
+		return manifest, nil
+	}
+	if len(manifest.Scripts) > 0 {
+		fmt.Printf("Bundled %d components and %d scripts into %s\n", len(manifest.Components), len(manifest.Scripts), outfile)
+	} else {
+		fmt.Printf("Bundled %d components into %s\n", len(manifest.Components), outfile)
+	}
+	if bundleSplitting {
+		fmt.Printf("Split into %d files under %s\n", len(manifest.Files), dir)
+	}
+	if manifest.Legacy != "" {
+		fmt.Printf("Wrote legacy bundle to %s, loaded with:\n%s", filepath.Join(dir, manifest.Legacy), manifest.HTML)
+	}
+	if manifest.CSS != "" {
+		fmt.Printf("Wrote styles to %s (hash %s)\n", filepath.Join(dir, manifest.CSS), manifest.CSSHash)
+	}
+	return manifest, nil
+}
 
-		fmt.Printf("Bundled %d components into dist/app.min.js\n", len(components))
+// bundleManifestPath returns where --manifest writes the manifest of the bundle at
+// outfile, such as dist/app.min.manifest.json for dist/app.min.js.
+func bundleManifestPath(outfile string) string {
+	return strings.TrimSuffix(outfile, filepath.Ext(outfile)) + ".manifest.json"
+}
+
+// writeManifest writes manifest as indented JSON to path, for servers that load it
+// to render the bundle's tags and Link header.
+func writeManifest(path string, manifest svelte.Manifest) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, b.Bytes(), 0644))
+}
+
+// previewEnv is set to 1 by do deploy while generating code for a tagged deploy, so
+// bundles built by go:generate use the preview defines.
+const previewEnv = "DO_PREVIEW"
+
+// bundleDefine merges svelte.define, preview.define for preview builds, and
+// --define flags, expanding environment variables in the values from do.yaml.
+func bundleDefine(cfg *config.Config, preview bool, flags []string) (map[string]string, error) {
+	define := make(map[string]string)
+	for k, v := range cfg.Svelte.Define {
+		define[k] = os.ExpandEnv(v)
+	}
+	if preview {
+		for k, v := range cfg.Preview.Define {
+			define[k] = os.ExpandEnv(v)
+		}
+	}
+	for _, f := range flags {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, errors.Errorf("invalid --define %q: want NAME=value", f)
+		}
+		define[k] = v
+	}
+	return define, nil
+}
+
+// bundleTailwindCSS compiles Tailwind into tw.Output, adding it to the embedded
+// files of the bundle directory it is in.
+func bundleTailwindCSS(ctx context.Context, tw config.Tailwind, embeds map[string][]string) error {
+	output := tw.Output
+	if output == "" {
+		output = tailwind.DefaultOutput
+	}
+	for dir, files := range embeds {
+		rel, err := filepath.Rel(dir, output)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if slices.Contains(files, rel) {
+			return errors.Errorf("%s is written by both the bundle and Tailwind; set tailwind.output in %s", output, config.File)
+		}
+		embeds[dir] = append(files, rel)
+	}
+
+	if err := tailwind.Build(ctx, tailwind.Options{Input: tw.Input, Output: output, Version: tw.Version}); err != nil {
+		return err
+	}
+	if !jsonOutput() {
+		fmt.Printf("Wrote Tailwind styles to %s\n", output)
+	}
+	return nil
+}
+
+// bundleOutfilePath returns where the bundle for e is written.
+func bundleOutfilePath(e config.Entry) string {
+	if e.Outfile == "" {
+		return filepath.Join(bundleOutdir, svelte.DefaultOutfile)
+	}
+	return e.Outfile
+}
+
+// bundleDir returns the directory the bundle for e is written to.
+func bundleDir(e config.Entry) string {
+	return filepath.Dir(bundleOutfilePath(e))
+}
+
+// embedFile is the Go source written by --embed, given the package name and the
+// files to embed.
+const embedFile = `// Code generated by do bundle. DO NOT EDIT.
+
+// Package %[1]s embeds the Svelte bundle written by do bundle.
+package %[1]s
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed %[2]s
+var files embed.FS
+
+// FS returns the bundle's files.
+func FS() fs.FS {
+	return files
+}
+
+// Handler serves the bundle's files, for example with
+//
+//	mux.Handle("/%[1]s/", http.StripPrefix("/%[1]s/", %[1]s.Handler()))
+func Handler() http.Handler {
+	return http.FileServerFS(files)
+}
+`
+
+// goIdent matches names usable as a Go package name.
+var goIdent = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// writeEmbed writes dir/dist.go, a Go package embedding files, which are relative
+// to dir, so the app binary can serve the bundle without reading it from disk.
+// The package is named after dir.
+func writeEmbed(dir string, files []string) error {
+	if len(files) == 0 {
 		return nil
-	},
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	name := strings.ToLower(filepath.Base(abs))
+	if !goIdent.MatchString(name) {
+		name = "dist"
+	}
+
+	files = slices.Clone(files)
+	slices.Sort(files)
+	files = slices.Compact(files)
+	src, err := format.Source([]byte(fmt.Sprintf(embedFile, name, strings.Join(files, " "))))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	path := filepath.Join(dir, "dist.go")
+	if err := os.WriteFile(path, src, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	if !jsonOutput() {
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
+
+// npmInstall runs npm install in dir when its package.json lists dependencies
+// missing from node_modules, using npm ci when there is a lockfile.
+func npmInstall(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return errors.Wrap(err, "parse package.json")
+	}
+
+	missing := false
+	for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies} {
+		for name := range deps {
+			if _, err := os.Stat(filepath.Join(dir, "node_modules", filepath.FromSlash(name), "package.json")); err != nil {
+				missing = true
+			}
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	args := []string{"install"}
+	if _, err := os.Stat(filepath.Join(dir, "package-lock.json")); err == nil {
+		args = []string{"ci"}
+	}
+	if !jsonOutput() {
+		fmt.Println("npm " + strings.Join(args, " "))
+	}
+	install := exec.Command("npm", args...)
+	install.Dir = dir
+	install.Stdout = os.Stderr
+	install.Stderr = os.Stderr
+	if err := install.Run(); err != nil {
+		return errors.Wrapf(err, "npm %s", strings.Join(args, " "))
+	}
+	return nil
+}
+
+// writeDist copies every file in fsys into dir.
+func writeDist(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		dest := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	})
 }
 
 func init() {
-	bundleCmd.Flags().BoolVarP(&bundleVerbose, "verbose", "v", false, "show each file and its export path")
+	bundleCmd.Flags().StringVar(&bundleBaseURL, "base-url", "", "URL the bundle is served from, such as a CDN, prefixed to the files in the manifest's tags")
+	bundleCmd.Flags().BoolVar(&bundleRuntime, "bundle-runtime", false, "include the Svelte runtime in dist/app.min.js instead of importing it from esm.sh")
+	bundleCmd.Flags().BoolVar(&bundleCustomElements, "custom-elements", false, "compile components as custom elements, registering those that set <svelte:options customElement>")
+	bundleCmd.Flags().StringArrayVar(&bundleExcludes, "exclude", nil, "leave out components matching a pattern, such as *.stories.svelte or testdata/ (repeatable)")
+	bundleCmd.Flags().BoolVar(&bundleExtractCSS, "extract-css", false, "write component styles to dist/app.min.css instead of injecting them at runtime")
+	bundleCmd.Flags().StringArrayVar(&bundleIncludes, "include", nil, "bundle only components matching a pattern, such as forms/ (repeatable)")
+	bundleCmd.Flags().BoolVar(&bundleInstall, "install", false, "run npm install first if package.json lists dependencies missing from node_modules")
+	bundleCmd.Flags().StringArrayVar(&bundleDefines, "define", nil, "set import.meta.env.NAME to value, as NAME=value, over svelte.define (repeatable)")
+	bundleCmd.Flags().BoolVar(&bundleDev, "dev", false, "compile components in dev mode, unminified with an inline source map, for debugging")
+	bundleCmd.Flags().BoolVar(&bundleEmbed, "embed", false, "write dist/dist.go, a Go package embedding the bundle with FS and Handler accessors")
+	bundleCmd.Flags().StringArrayVar(&bundleEntries, "entry", nil, "bundle only these directories, .svelte, .js, or .ts files, or glob patterns instead of svelte.entries (repeatable)")
+	bundleCmd.Flags().BoolVar(&bundleLegacy, "legacy", false, "also write dist/app.legacy.min.js, a classic script setting window.SvelteComponents, for browsers without modules")
+	bundleCmd.Flags().BoolVar(&bundleManifest, "manifest", false, "write dist/app.min.manifest.json with integrity hashes, preload files, and the tags loading the bundle")
+	bundleCmd.Flags().StringVar(&bundleOutdir, "outdir", "dist", "directory to write the bundle to")
+	bundleCmd.Flags().StringVar(&bundleOutfile, "outfile", "", "path of the bundle, instead of dist/app.min.js or svelte.entries")
+	bundleCmd.Flags().BoolVar(&bundleTailwind, "tailwind", false, "compile Tailwind CSS into dist/app.min.css, as with tailwind.enabled")
+	bundleCmd.Flags().BoolVar(&bundlePreview, "preview", false, "use preview.define over svelte.define, as for tagged deploys")
+	bundleCmd.Flags().BoolVar(&bundleSplitting, "splitting", false, "put each component in its own chunk, loaded on demand from app.min.js")
+	bundleCmd.Flags().BoolVar(&bundleSourcemap, "sourcemap", true, "write dist/app.min.js.map mapping the bundle back to the .svelte sources")
 	rootCmd.AddCommand(bundleCmd)
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/iam"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -33,12 +35,15 @@ jobs:
           go-version-file: go.mod
 
       - name: Build and Test
-        run: go tool do
+        run: go tool do --output=github
 
   deploy:
     runs-on: ubuntu-latest
     needs: build
     if: github.event_name == 'pull_request' && vars.CLOUDSDK_CORE_PROJECT != ''
+    concurrency:
+      group: preview-${{ github.event.pull_request.head.repo.full_name }}-${{ github.event.pull_request.number }}
+      cancel-in-progress: true
     permissions:
       contents: read
       id-token: write
@@ -67,18 +72,7 @@ jobs:
           CLOUDSDK_RUN_REGION: ${{ vars.CLOUDSDK_RUN_REGION }}
           CLOUD_RUN_SERVICE: ${{ vars.CLOUD_RUN_SERVICE }}
           KO_DOCKER_REPO: gcr.io/${{ vars.CLOUDSDK_CORE_PROJECT }}/${{ vars.CLOUD_RUN_SERVICE }}
-        run: |
-          TAG="pr-${{ github.event.pull_request.number }}"
-          go tool do deploy --tag="$TAG"
-
-          # Get the preview URL
-          URL=$(gcloud run services describe $CLOUD_RUN_SERVICE \
-            --platform=managed \
-            --region=$CLOUDSDK_RUN_REGION \
-            --project=$CLOUDSDK_CORE_PROJECT \
-            --format="value(status.traffic.url)" \
-            | tr ';' '\n' | grep "$TAG" | head -1)
-          echo "url=$URL" >> $GITHUB_OUTPUT
+        run: go tool do deploy --preview
 
       - name: Comment on PR
         uses: actions/github-script@v7
@@ -150,15 +144,16 @@ var ciCmd = &cobra.Command{
 	Use:   "ci",
 	Short: "Create GitHub Actions CI workflow",
 	Long: `Creates a .github/workflows/ci.yml that:
-- Runs 'go tool do' on all pushes and PRs
+- Runs 'go tool do' on all pushes and PRs, annotating lint findings on the PR
 - Deploys preview environments for PRs (if GCP vars are configured)
 - Comments the preview URL on the PR
 - Deploys to production on merge to main
 
 Use --setup to configure GCP Workload Identity Federation for CI deploys.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		if ciSetup {
-			return runCISetup()
+			return runCISetup(ctx)
 		}
 
 		// Find project root
@@ -185,7 +180,7 @@ Use --setup to configure GCP Workload Identity Federation for CI deploys.`,
 	},
 }
 
-func runCISetup() error {
+func runCISetup(ctx context.Context) error {
 	// Get project from environment
 	project := os.Getenv("CLOUDSDK_CORE_PROJECT")
 	if project == "" {
@@ -222,13 +217,13 @@ func runCISetup() error {
 	if !gcloud.IsInstalled() {
 		return errors.New("gcloud is not installed")
 	}
-	if !gcloud.IsAuthenticated() {
+	if !gcloud.IsAuthenticated(ctx) {
 		return errors.New("gcloud is not authenticated. Run 'gcloud auth login'")
 	}
 
 	// Enable required APIs
 	fmt.Println("Enabling required APIs...")
-	if err := gcloud.Run("gcloud", "services", "enable",
+	if err := gcloud.Run(ctx, "gcloud", "services", "enable",
 		"iamcredentials.googleapis.com",
 		"run.googleapis.com",
 		"artifactregistry.googleapis.com",
@@ -236,68 +231,54 @@ func runCISetup() error {
 		return err
 	}
 
-	// Create workload identity pool (ignore error if exists)
-	fmt.Println("\nCreating workload identity pool...")
-	_ = gcloud.Run("gcloud", "iam", "workload-identity-pools", "create", "github",
-		"--project="+project,
-		"--location=global",
-		"--display-name=GitHub Actions")
-
-	// Create OIDC provider (ignore error if exists)
-	fmt.Println("\nCreating OIDC provider...")
-	_ = gcloud.Run("gcloud", "iam", "workload-identity-pools", "providers", "create-oidc", "github",
-		"--project="+project,
-		"--location=global",
-		"--workload-identity-pool=github",
-		"--display-name=GitHub",
-		"--attribute-mapping=google.subject=assertion.sub,attribute.actor=assertion.actor,attribute.repository=assertion.repository",
-		"--attribute-condition=assertion.repository=='"+repo+"'",
-		"--issuer-uri=https://token.actions.githubusercontent.com")
-
-	// Create service account (ignore error if exists)
-	fmt.Println("\nCreating service account...")
-	_ = gcloud.Run("gcloud", "iam", "service-accounts", "create", "github-actions",
-		"--project="+project,
-		"--display-name=GitHub Actions")
-
-	serviceAccount := fmt.Sprintf("github-actions@%s.iam.gserviceaccount.com", project)
-
-	// Grant roles
-	fmt.Println("\nGranting IAM roles...")
-	roles := []string{"roles/run.admin", "roles/storage.admin", "roles/artifactregistry.writer"}
-	for _, role := range roles {
-		if err := gcloud.Run("gcloud", "projects", "add-iam-policy-binding", project,
-			"--member=serviceAccount:"+serviceAccount,
-			"--role="+role); err != nil {
-			return err
-		}
+	fmt.Println("\nEnsuring workload identity pool...")
+	if err := iam.EnsureWorkloadIdentityPool(ctx, project, "github", "GitHub Actions"); err != nil {
+		return err
 	}
 
-	// Get project number for compute service account
-	var numOut bytes.Buffer
-	numCmd := exec.Command("gcloud", "projects", "describe", project, "--format=value(projectNumber)")
-	numCmd.Stdout = &numOut
-	if err := numCmd.Run(); err != nil {
-		return errors.WithStack(err)
+	provider := iam.OIDCProvider{
+		AttributeCondition: "assertion.repository=='" + repo + "'",
+		AttributeMapping:   "google.subject=assertion.sub,attribute.actor=assertion.actor,attribute.repository=assertion.repository",
+		DisplayName:        "GitHub",
+		ID:                 "github",
+		IssuerURI:          "https://token.actions.githubusercontent.com",
+		Pool:               "github",
+	}
+	fmt.Println("\nEnsuring OIDC provider...")
+	if err := iam.EnsureOIDCProvider(ctx, project, provider); err != nil {
+		return err
 	}
-	projectNumber := strings.TrimSpace(numOut.String())
 
-	// Allow github-actions to act as compute service account
-	computeSA := fmt.Sprintf("%s-compute@developer.gserviceaccount.com", projectNumber)
-	if err := gcloud.Run("gcloud", "iam", "service-accounts", "add-iam-policy-binding", computeSA,
-		"--member=serviceAccount:"+serviceAccount,
-		"--role=roles/iam.serviceAccountUser",
-		"--project="+project); err != nil {
+	serviceAccount := gcloud.ServiceAccountEmail(project, "github-actions")
+	fmt.Println("\nEnsuring service account...")
+	if err := iam.EnsureServiceAccount(ctx, project, serviceAccount, "GitHub Actions"); err != nil {
+		return err
+	}
+
+	fmt.Println("\nEnsuring IAM roles...")
+	member := gcloud.Member(serviceAccount)
+	if err := iam.EnsureRoleBinding(ctx, iam.Project(project), member,
+		"roles/run.admin", "roles/storage.admin", "roles/artifactregistry.writer"); err != nil {
+		return err
+	}
+
+	projectNumber, err := gcloud.ProjectNumber(ctx, project)
+	if err != nil {
+		return err
+	}
+
+	// Allow github-actions to act as the compute service account Cloud Run runs as
+	computeSA, err := gcloud.ComputeServiceAccount(ctx, project)
+	if err != nil {
+		return err
+	}
+	if err := iam.EnsureRoleBinding(ctx, iam.ServiceAccount(project, computeSA), member, "roles/iam.serviceAccountUser"); err != nil {
 		return err
 	}
 
-	// Allow workload identity to impersonate service account
-	member := fmt.Sprintf("principalSet://iam.googleapis.com/projects/%s/locations/global/workloadIdentityPools/github/attribute.repository/%s",
-		projectNumber, repo)
-	if err := gcloud.Run("gcloud", "iam", "service-accounts", "add-iam-policy-binding", serviceAccount,
-		"--project="+project,
-		"--role=roles/iam.workloadIdentityUser",
-		"--member="+member); err != nil {
+	// Allow workflows from this repo to impersonate github-actions
+	principals := iam.PoolPrincipalSet(projectNumber, provider.Pool, "repository", repo)
+	if err := iam.EnsureWorkloadIdentityBinding(ctx, project, serviceAccount, principals); err != nil {
 		return err
 	}
 
@@ -308,7 +289,7 @@ func runCISetup() error {
 	fmt.Printf("\nCLOUDSDK_CORE_PROJECT=%s\n", project)
 	fmt.Printf("CLOUDSDK_RUN_REGION=%s\n", region)
 	fmt.Printf("CLOUD_RUN_SERVICE=%s\n", service)
-	fmt.Printf("WORKLOAD_IDENTITY_PROVIDER=projects/%s/locations/global/workloadIdentityPools/github/providers/github\n", projectNumber)
+	fmt.Printf("WORKLOAD_IDENTITY_PROVIDER=%s\n", provider.Name(projectNumber))
 	fmt.Printf("SERVICE_ACCOUNT=%s\n", serviceAccount)
 
 	return nil
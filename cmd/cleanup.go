@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+)
+
+// previewTagPattern matches tags created by PreviewTag and captures the PR number.
+var previewTagPattern = regexp.MustCompile(`^pr-(\d+)(-|$)`)
+
+// cleanupPreviews removes pr-* traffic tags whose PR is closed or, when maxAge is set,
+// whose revision is older than maxAge. Revisions no longer serving traffic are deleted.
+func cleanupPreviews(ctx context.Context, maxAge time.Duration) error {
+	project, region, service, err := deployedService()
+	if err != nil {
+		return err
+	}
+
+	traffic, err := gcloud.Traffic(ctx, project, region, service)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := gcloud.ListRevisions(ctx, project, region, service, 0)
+	if err != nil {
+		return err
+	}
+	created := make(map[string]time.Time)
+	for _, r := range revisions {
+		created[r.Name] = r.Created
+	}
+
+	serving := make(map[string]bool)
+	for _, t := range traffic {
+		if t.Percent > 0 {
+			serving[t.Revision] = true
+		}
+	}
+
+	var removed int
+	for _, t := range traffic {
+		m := previewTagPattern.FindStringSubmatch(t.Tag)
+		if m == nil {
+			continue
+		}
+
+		reason := ""
+		if state := prState(m[1]); state == "CLOSED" || state == "MERGED" {
+			reason = "PR #" + m[1] + " is " + strings.ToLower(state)
+		} else if c, ok := created[t.Revision]; maxAge > 0 && ok && time.Since(c) > maxAge {
+			reason = fmt.Sprintf("older than %s", maxAge)
+		}
+		if reason == "" {
+			continue
+		}
+
+		fmt.Printf("\nRemoving preview '%s' (%s)...\n", t.Tag, reason)
+		if err := gcloud.RemoveTag(ctx, project, region, service, t.Tag); err != nil {
+			return err
+		}
+		removed++
+
+		if t.Revision == "" || serving[t.Revision] {
+			continue
+		}
+		if err := gcloud.DeleteRevision(ctx, project, region, t.Revision); err != nil {
+			fmt.Printf("Could not delete revision %s: %v\n", t.Revision, err)
+		}
+	}
+
+	fmt.Printf("\nRemoved %d stale preview(s)\n", removed)
+	return nil
+}
+
+// prState returns the GitHub PR state (OPEN, CLOSED, MERGED) using gh, or "" if unknown.
+func prState(number string) string {
+	out, err := exec.Command("gh", "pr", "view", number, "--json", "state", "--jq", ".state").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// parseAge parses a duration, also accepting a "d" suffix for days. "0" disables the age limit.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid age %q, use e.g. 72h or 7d", s)
+	}
+	return d, nil
+}
+
+// pruneImages deletes all but the keep most recent digests in the service's repository.
+// Digests used by revisions that serve traffic or carry a tag in any region are always kept.
+func pruneImages(ctx context.Context, project string, regions []string, service string, keep int) error {
+	repo := fmt.Sprintf("gcr.io/%s/%s", project, service)
+
+	images, err := gcloud.ListImages(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if len(images) <= keep {
+		return nil
+	}
+
+	inUse := make(map[string]bool)
+	for _, region := range regions {
+		used, err := imagesInUse(ctx, project, region, service)
+		if err != nil {
+			return err
+		}
+		for digest := range used {
+			inUse[digest] = true
+		}
+	}
+
+	fmt.Printf("\nPruning images in %s beyond the %d most recent...\n", repo, keep)
+	var deleted int
+	for _, img := range images[keep:] {
+		if inUse[img.Digest] {
+			continue
+		}
+		if err := gcloud.DeleteImage(ctx, repo, img.Digest); err != nil {
+			return err
+		}
+		deleted++
+	}
+
+	fmt.Printf("Deleted %d image(s)\n", deleted)
+	return nil
+}
+
+// imagesInUse returns the digests of images used by revisions with traffic or a tag.
+func imagesInUse(ctx context.Context, project, region, service string) (map[string]bool, error) {
+	traffic, err := gcloud.Traffic(ctx, project, region, service)
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[string]bool)
+	for _, t := range traffic {
+		if t.Percent > 0 || t.Tag != "" {
+			active[t.Revision] = true
+		}
+	}
+
+	revisions, err := gcloud.ListRevisions(ctx, project, region, service, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := make(map[string]bool)
+	for i, r := range revisions {
+		// The latest revision may be routed to with LATEST rather than by name
+		if !active[r.Name] && i > 0 {
+			continue
+		}
+		if _, digest, ok := strings.Cut(r.Image, "@"); ok {
+			inUse[digest] = true
+		}
+	}
+	return inUse, nil
+}
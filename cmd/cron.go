@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var cronName string
+var cronMethod string
+var cronTimeZone string
+var cronServiceAccount string
+
+// cronNameInvalid matches runs of characters not allowed in scheduler job names.
+var cronNameInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Call the Cloud Run service on a schedule with Cloud Scheduler",
+	Long: `Create Cloud Scheduler jobs that call a path on the deployed service with an
+OIDC token, so cron endpoints work on services that require authentication.
+
+  go do cron add "0 3 * * *" /tasks/cleanup
+  go do cron list
+  go do cron delete app-tasks-cleanup`,
+}
+
+var cronAddCmd = &cobra.Command{
+	Use:   "add <schedule> <path>",
+	Short: "Create or update a scheduled call to a service path",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		schedule, path := args[0], args[1]
+		if !strings.HasPrefix(path, "/") {
+			return errors.Errorf("path %q must start with /", path)
+		}
+
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		url := gcloud.ServiceURL(ctx, project, region, service)
+		if url == "" {
+			return errors.Errorf("no URL for service '%s'. Run 'go do deploy' first", service)
+		}
+
+		if err := gcloud.EnsureAPIs(ctx, project, "cloudscheduler.googleapis.com"); err != nil {
+			return err
+		}
+
+		account, err := grantServiceInvoker(ctx, project, region, service, cronServiceAccount)
+		if err != nil {
+			return err
+		}
+
+		name := cronName
+		if name == "" {
+			name = cronJobName(service, path)
+		}
+
+		err = gcloud.SaveSchedulerJob(ctx, project, region, name, gcloud.SchedulerOptions{
+			Audience:       url,
+			Method:         cronMethod,
+			Schedule:       schedule,
+			ServiceAccount: account,
+			TimeZone:       cronTimeZone,
+			URI:            url + path,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\nScheduled %s %s%s as '%s' (%s %s)\n", cronMethod, url, path, name, schedule, cronTimeZone)
+		return nil
+	},
+}
+
+var cronListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled calls to the service",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		jobs, err := gcloud.ListSchedulerJobs(ctx, project, region)
+		if err != nil {
+			return err
+		}
+
+		url := gcloud.ServiceURL(ctx, project, region, service)
+		var found bool
+		for _, j := range jobs {
+			if url == "" || !strings.HasPrefix(j.URI, url) {
+				continue
+			}
+			found = true
+			fmt.Printf("  %-30s %-15s %-6s %-20s %s\n", j.Name, j.Schedule, j.Method, strings.TrimPrefix(j.URI, url), strings.ToLower(j.State))
+		}
+		if !found {
+			fmt.Printf("No scheduled calls to '%s'\n", service)
+		}
+		return nil
+	},
+}
+
+var cronDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a scheduled call",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, region, _, err := deployedService()
+		if err != nil {
+			return err
+		}
+		return gcloud.DeleteSchedulerJob(ctx, project, region, args[0])
+	},
+}
+
+// grantServiceInvoker grants roles/run.invoker on the service to account, or to
+// the compute service account when account is empty, and returns the account.
+func grantServiceInvoker(ctx context.Context, project, region, service, account string) (string, error) {
+	if account == "" {
+		var err error
+		if account, err = gcloud.ComputeServiceAccount(ctx, project); err != nil {
+			return "", err
+		}
+	}
+	if err := gcloud.GrantInvoker(ctx, project, region, service, "serviceAccount:"+account); err != nil {
+		return "", err
+	}
+	return account, nil
+}
+
+// cronJobName derives a scheduler job name from the service and path,
+// e.g. app and /tasks/cleanup become app-tasks-cleanup.
+func cronJobName(service, path string) string {
+	slug := strings.Trim(cronNameInvalid.ReplaceAllString(strings.ToLower(path), "-"), "-")
+	if slug == "" {
+		return service
+	}
+	return service + "-" + slug
+}
+
+func init() {
+	cronAddCmd.Flags().StringVar(&cronName, "name", "", "scheduler job name (default <service>-<path>)")
+	cronAddCmd.Flags().StringVar(&cronMethod, "method", "GET", "HTTP method to call the path with")
+	cronAddCmd.Flags().StringVar(&cronTimeZone, "time-zone", "Etc/UTC", "time zone the schedule is interpreted in")
+	cronAddCmd.Flags().StringVar(&cronServiceAccount, "service-account", "", "service account whose OIDC token authenticates the call (default compute service account)")
+
+	cronCmd.AddCommand(cronAddCmd, cronListCmd, cronDeleteCmd)
+	rootCmd.AddCommand(cronCmd)
+}
@@ -1,20 +1,53 @@
 package cmd
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/housecat-inc/do/pkg/config"
 	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/iam"
+	"github.com/housecat-inc/do/pkg/github"
+	"github.com/housecat-inc/do/pkg/notify"
+	"github.com/housecat-inc/do/pkg/progress"
+	"github.com/housecat-inc/do/pkg/prompt"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var deployTag string
+var deployCanary int
+var deployEnv []string
+var deployMemory string
+var deployCPU string
+var deployConcurrency int
+var deployMinInstances int
+var deployMaxInstances int
+var deployPreview bool
+var deployParallel bool
+var skipPreflight bool
+var deployCloudSQL []string
+var deployAuth string
+var deployBuilder string
+var deployRemote bool
+var deployServiceAccount string
+var createServiceAccount bool
+var deployInvokers []string
 var deleteTag string
+var cleanupAge string
+var keepImages int
+var healthPath string
+var healthTimeout time.Duration
 
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
@@ -26,41 +59,113 @@ Use --tag for branch deploys which creates a separate URL without affecting prod
 
 This creates a URL like: https://feature-x---service-xxx.run.app
 
+Use --preview in a GitHub Actions pull request workflow to derive the tag from the PR.
+Forks and stacked PRs get distinct tags so they don't overwrite each other:
+  go do deploy --preview
+
+Use --canary to route only a percentage of traffic to the new revision, then
+adjust or finalize the split with 'go do traffic':
+  go do deploy --canary=10
+  go do traffic --finalize
+
 Use --delete-tag to remove a traffic tag:
-  go do deploy --delete-tag=feature-x`,
+  go do deploy --delete-tag=feature-x
+
+Use --cleanup-previews to remove pr-* tags whose PR is closed (checked with gh),
+optionally also removing previews older than an age:
+  go do deploy --cleanup-previews
+  go do deploy --cleanup-previews=7d`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		// Handle tag deletion
 		if deleteTag != "" {
-			return deleteTrafficTag(deleteTag)
+			return deleteTrafficTag(ctx, deleteTag)
+		}
+
+		if cmd.Flags().Changed("cleanup-previews") {
+			age, err := parseAge(cleanupAge)
+			if err != nil {
+				return err
+			}
+			return cleanupPreviews(ctx, age)
+		}
+
+		if deployPreview {
+			tag, err := previewTag()
+			if err != nil {
+				return err
+			}
+			deployTag = tag
+		}
+
+		if deployCanary != 0 && deployTag != "" {
+			return errors.New("--canary cannot be combined with a traffic tag")
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("cloudsql") {
+			cfg.CloudSQL = deployCloudSQL
+		}
+		if cmd.Flags().Changed("auth") {
+			cfg.Auth = deployAuth
+		}
+		if cmd.Flags().Changed("invoker") {
+			cfg.Invokers = deployInvokers
+		}
+		if cmd.Flags().Changed("builder") {
+			cfg.Builder = deployBuilder
+		}
+		if err := checkBuilder(cfg.Builder); err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("service-account") {
+			cfg.ServiceAccount = deployServiceAccount
+		}
+		if createServiceAccount && cfg.ServiceAccount == "" {
+			return errors.New("--create-service-account needs --service-account")
+		}
+		if cmd.Flags().Changed("health-path") {
+			cfg.HealthPath = healthPath
+		}
+
+		opts, err := deployOptions(cmd, cfg)
+		if err != nil {
+			return err
 		}
 
 		// Check required tools
-		if err := checkDeployTools(); err != nil {
+		if err := checkDeployTools(cfg.Builder, deployRemote); err != nil {
 			return err
 		}
 
 		// Ensure authenticated with gcloud
-		if !gcloud.IsAuthenticated() {
+		if !gcloud.IsAuthenticated(ctx) {
+			if prompt.NonInteractive || gcloud.AmbientCredentials() {
+				return errors.WithStack(gcloud.ErrNotAuthenticated)
+			}
 			fmt.Println("Not authenticated with Google Cloud. Starting login...")
-			if err := gcloud.Login(); err != nil {
+			if err := gcloud.Login(ctx); err != nil {
 				return err
 			}
 		}
 
 		// Get or select project
-		project, err := selectProject()
+		project, err := selectProject(ctx)
 		if err != nil {
 			return err
 		}
 
 		// Get or select region
-		region, err := selectRegion()
+		region, err := selectRegion(ctx, project)
 		if err != nil {
 			return err
 		}
 
 		// Get or create Cloud Run service
-		service, err := selectOrCreateService(project, region)
+		service, err := selectOrCreateService(ctx, project, region)
 		if err != nil {
 			return err
 		}
@@ -71,113 +176,304 @@ Use --delete-tag to remove a traffic tag:
 			return err
 		}
 
-		// Save settings to .envrc
-		if err := saveDeploySettings(project, region, service, buildPath); err != nil {
+		// Save settings to do.yaml
+		if err := saveDeploySettings(cfg, project, region, service, buildPath); err != nil {
 			return err
 		}
 
-		// Build and deploy with ko
-		if err := deployWithKo(project, region, service, buildPath, deployTag); err != nil {
+		opts.Auth = cfg.Auth
+		opts.CloudSQL = cloudSQLConnections(project, region, cfg.CloudSQL)
+		if cfg.ServiceAccount != "" {
+			opts.ServiceAccount = gcloud.ServiceAccountEmail(project, cfg.ServiceAccount)
+		}
+
+		if createServiceAccount {
+			roles := runtimeRoles
+			if len(opts.CloudSQL) > 0 {
+				roles = append(slices.Clone(roles), "roles/cloudsql.client")
+			}
+			fmt.Printf("\nEnsuring service account %s...\n", opts.ServiceAccount)
+			if err := iam.EnsureServiceAccount(ctx, project, opts.ServiceAccount, ""); err != nil {
+				return err
+			}
+			if err := iam.EnsureRoleBinding(ctx, iam.Project(project), gcloud.Member(opts.ServiceAccount), roles...); err != nil {
+				return err
+			}
+		}
+
+		regions := cfg.Regions
+		if len(regions) == 0 {
+			regions = []string{region}
+		}
+
+		if !skipPreflight {
+			if err := runPreflight(ctx, project, regions, buildPath, cfg.Builder); err != nil {
+				return err
+			}
+		}
+
+		verify := verifyOptions{
+			AuthRequired: cfg.Auth == gcloud.AuthRequired,
+			HealthPath:   cfg.HealthPath,
+			Timeout:      healthTimeout,
+		}
+
+		event := notify.Event{
+			Commit:   gitLabels()["commit-sha"],
+			Deployer: deployer(ctx),
+			Project:  project,
+			Service:  service,
+			Status:   notify.Started,
+			Tag:      opts.Tag,
+		}
+		notifyDeploy(cfg.Notifications.Webhook, event)
+
+		image, urls, err := deployImage(ctx, project, regions, service, buildPath, cfg.Builder, cfg.Assets, opts, verify)
+		if err != nil {
+			event.Status, event.Err = notify.Failed, err
+			notifyDeploy(cfg.Notifications.Webhook, event)
 			return err
 		}
+		event.Status, event.URLs = notify.Succeeded, urls
+		notifyDeploy(cfg.Notifications.Webhook, event)
+
+		for _, r := range regions {
+			for _, member := range cfg.Invokers {
+				if err := gcloud.GrantInvoker(ctx, project, r, service, member); err != nil {
+					return err
+				}
+			}
+		}
+
+		if keepImages > 0 {
+			if err := pruneImages(ctx, project, regions, service, keepImages); err != nil {
+				return err
+			}
+		}
 
+		if jsonOutput() {
+			type regionJSON struct {
+				Region string `json:"region"`
+				URL    string `json:"url"`
+			}
+			result := struct {
+				Canary   int             `json:"canary,omitempty"`
+				Commands []commandResult `json:"commands"`
+				Commit   string          `json:"commit,omitempty"`
+				Image    string          `json:"image"`
+				Project  string          `json:"project"`
+				Regions  []regionJSON    `json:"regions"`
+				Service  string          `json:"service"`
+				Tag      string          `json:"tag,omitempty"`
+			}{Canary: opts.Canary, Commands: commandsRun(), Commit: event.Commit, Image: image, Project: project, Service: service, Tag: opts.Tag}
+			for i, r := range regions {
+				result.Regions = append(result.Regions, regionJSON{Region: r, URL: urls[i]})
+			}
+			return printJSON(result)
+		}
 		return nil
 	},
 }
 
-func checkDeployTools() error {
+// checkDeployTools checks gcloud and the local builder are installed.
+// Remote builds run the builder on Cloud Build and only need gcloud.
+func checkDeployTools(builder string, remote bool) error {
 	var missing []string
 
-	if _, err := exec.LookPath("ko"); err != nil {
-		missing = append(missing, "ko")
+	tool := "ko"
+	if builder == builderDocker {
+		tool = "docker"
+	}
+	if _, err := exec.LookPath(tool); err != nil && !remote {
+		missing = append(missing, tool)
 	}
 	if !gcloud.IsInstalled() {
 		missing = append(missing, "gcloud")
 	}
 
 	if len(missing) > 0 {
-		return errors.Errorf("required tools not installed: %s\nInstall ko: go install github.com/ko-build/ko@latest\nInstall gcloud: https://cloud.google.com/sdk/docs/install", strings.Join(missing, ", "))
+		install := "Install ko: go install " + koModule + "@" + koVersion
+		if builder == builderDocker {
+			install = "Install docker: https://docs.docker.com/get-docker/"
+		}
+		return errors.Errorf("required tools not installed: %s\n%s\nInstall gcloud: https://cloud.google.com/sdk/docs/install", strings.Join(missing, ", "), install)
 	}
 
 	return nil
 }
 
-func selectProject() (string, error) {
+func selectProject(ctx context.Context) (string, error) {
 	// Check if already set in environment
 	if project := os.Getenv("CLOUDSDK_CORE_PROJECT"); project != "" {
 		return project, nil
 	}
 
 	// Check current gcloud config
-	if current := gcloud.CurrentProject(); current != "" {
+	if current := gcloud.CurrentProject(ctx); current != "" {
 		fmt.Printf("Current project: %s\n", current)
-		if confirm("Use this project?") {
+		ok, err := prompt.Confirm("Use this project?", true)
+		if err != nil {
+			return "", err
+		}
+		if ok {
 			return current, nil
 		}
 	}
 
 	// List available projects
 	fmt.Println("Fetching available projects...")
-	projects, err := gcloud.ListProjects()
+	projects, err := gcloud.ListProjects(ctx)
 	if err != nil {
 		return "", err
 	}
 
 	if len(projects) == 0 {
 		fmt.Println("No projects found. Creating a new project...")
-		return createProject()
+		return createProject(ctx)
 	}
 
-	fmt.Println("\nAvailable projects:")
+	options := make([]string, len(projects)+1)
 	for i, p := range projects {
-		fmt.Printf("  %d) %s (%s)\n", i+1, p.ID, p.Name)
+		options[i] = fmt.Sprintf("%s (%s)", p.ID, p.Name)
 	}
-	fmt.Printf("  %d) Create new project\n", len(projects)+1)
+	options[len(projects)] = "Create new project"
 
-	choice := promptInt("Select project", 1, len(projects)+1)
-	if choice == len(projects)+1 {
-		return createProject()
+	choice, err := prompt.Select("Select project", options, 0)
+	if err != nil {
+		return "", err
+	}
+	if choice == len(projects) {
+		return createProject(ctx)
 	}
 
-	return projects[choice-1].ID, nil
+	return projects[choice].ID, nil
 }
 
-func createProject() (string, error) {
-	projectID := prompt("Enter new project ID")
+func createProject(ctx context.Context) (string, error) {
+	projectID, err := prompt.Text("Enter new project ID", "")
+	if err != nil {
+		return "", err
+	}
 	if projectID == "" {
 		return "", errors.New("project ID cannot be empty")
 	}
 
+	parent, err := selectParent(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	fmt.Printf("Creating project %s...\n", projectID)
-	if err := gcloud.CreateProject(projectID); err != nil {
+	if err := gcloud.CreateProject(ctx, projectID, parent); err != nil {
 		return "", err
 	}
 
+	if err := linkBilling(ctx, projectID); err != nil {
+		return "", err
+	}
 	return projectID, nil
 }
 
-func selectRegion() (string, error) {
+// selectParent asks which organization and folder to create a project under.
+// Accounts without an organization create projects without a parent.
+func selectParent(ctx context.Context) (gcloud.Parent, error) {
+	orgs, err := gcloud.ListOrganizations(ctx)
+	if err != nil || len(orgs) == 0 {
+		return gcloud.Parent{}, nil
+	}
+
+	options := []string{"No organization"}
+	for _, o := range orgs {
+		options = append(options, fmt.Sprintf("%s (organization %s)", o.Name, o.ID))
+	}
+	choice, err := prompt.Select("Create project in", options, 1)
+	if err != nil || choice == 0 {
+		return gcloud.Parent{}, err
+	}
+	parent := orgs[choice-1]
+
+	// Walk down the folder tree until the user stops or there are no subfolders
+	for {
+		folders, err := gcloud.ListFolders(ctx, parent)
+		if err != nil || len(folders) == 0 {
+			return parent, nil
+		}
+		options := []string{fmt.Sprintf("Directly in %s", parent.Name)}
+		for _, f := range folders {
+			options = append(options, fmt.Sprintf("%s (folder %s)", f.Name, f.ID))
+		}
+		choice, err := prompt.Select("Select folder", options, 0)
+		if err != nil || choice == 0 {
+			return parent, err
+		}
+		parent = folders[choice-1]
+	}
+}
+
+// linkBilling links a new project to a billing account, without which Cloud Run
+// cannot be enabled.
+func linkBilling(ctx context.Context, project string) error {
+	accounts, err := gcloud.ListBillingAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(accounts) == 0 {
+		fmt.Printf("No open billing accounts found. Link one before deploying:\n  https://console.cloud.google.com/billing/linkedaccount?project=%s\n", project)
+		return nil
+	}
+
+	options := make([]string, len(accounts))
+	for i, a := range accounts {
+		options[i] = fmt.Sprintf("%s (%s)", a.Name, a.ID)
+	}
+	choice, err := prompt.Select("Select billing account", options, 0)
+	if err != nil {
+		return err
+	}
+	return gcloud.LinkBilling(ctx, project, accounts[choice].ID)
+}
+
+func selectRegion(ctx context.Context, project string) (string, error) {
 	// Check if already set in environment
 	if region := os.Getenv("CLOUDSDK_RUN_REGION"); region != "" {
 		return region, nil
 	}
 
-	// Common Cloud Run regions
-	regions := []string{
-		"us-central1",
-		"us-east1",
-		"us-west1",
-		"europe-west1",
-		"asia-east1",
+	available, err := gcloud.ListRegions(ctx, project)
+	if err != nil || len(available) == 0 {
+		// The Cloud Run API may not be enabled yet on a new project
+		available = []string{
+			"asia-east1",
+			"europe-west1",
+			"us-central1",
+			"us-east1",
+			"us-west1",
+		}
 	}
 
-	fmt.Println("\nAvailable regions:")
-	for i, r := range regions {
-		fmt.Printf("  %d) %s\n", i+1, r)
+	// Recently used regions first, then the rest
+	var regions []string
+	for _, r := range gcloud.RecentRegions() {
+		if slices.Contains(available, r) {
+			regions = append(regions, r)
+		}
+	}
+	def := 0
+	if len(regions) == 0 {
+		def = max(slices.Index(available, "us-central1"), 0)
+	}
+	for _, r := range available {
+		if !slices.Contains(regions, r) {
+			regions = append(regions, r)
+		}
 	}
 
-	choice := promptInt("Select region", 1, len(regions))
-	return regions[choice-1], nil
+	choice, err := prompt.Select("Select region", regions, def)
+	if err != nil {
+		return "", err
+	}
+	gcloud.UseRegion(regions[choice])
+	return regions[choice], nil
 }
 
 func selectBuildPath() (string, error) {
@@ -215,16 +511,15 @@ func selectBuildPath() (string, error) {
 		return candidates[0], nil
 	}
 
-	fmt.Println("\nFound multiple main packages:")
-	for i, c := range candidates {
-		fmt.Printf("  %d) %s\n", i+1, c)
+	fmt.Println("\nFound multiple main packages")
+	choice, err := prompt.Select("Select package to deploy", candidates, 0)
+	if err != nil {
+		return "", err
 	}
-
-	choice := promptInt("Select package to deploy", 1, len(candidates))
-	return candidates[choice-1], nil
+	return candidates[choice], nil
 }
 
-func selectOrCreateService(project, region string) (string, error) {
+func selectOrCreateService(ctx context.Context, project, region string) (string, error) {
 	// Check if already set in environment
 	if service := os.Getenv("CLOUD_RUN_SERVICE"); service != "" {
 		return service, nil
@@ -232,7 +527,7 @@ func selectOrCreateService(project, region string) (string, error) {
 
 	// List existing services
 	fmt.Println("Fetching existing Cloud Run services...")
-	services, err := gcloud.ListServices(project, region)
+	services, err := gcloud.ListServices(ctx, project, region)
 	if err != nil || len(services) == 0 {
 		if err != nil {
 			fmt.Println("No existing services found or API not enabled.")
@@ -240,18 +535,21 @@ func selectOrCreateService(project, region string) (string, error) {
 		return createServiceName()
 	}
 
-	fmt.Println("\nExisting services:")
+	options := make([]string, len(services)+1)
 	for i, s := range services {
-		fmt.Printf("  %d) %s\n", i+1, s.Name)
+		options[i] = s.Name
 	}
-	fmt.Printf("  %d) Create new service\n", len(services)+1)
+	options[len(services)] = "Create new service"
 
-	choice := promptInt("Select service", 1, len(services)+1)
-	if choice == len(services)+1 {
+	choice, err := prompt.Select("Select service", options, 0)
+	if err != nil {
+		return "", err
+	}
+	if choice == len(services) {
 		return createServiceName()
 	}
 
-	return services[choice-1].Name, nil
+	return services[choice].Name, nil
 }
 
 func createServiceName() (string, error) {
@@ -265,228 +563,565 @@ func createServiceName() (string, error) {
 		}
 	}
 
-	var promptMsg string
-	if defaultName != "" {
-		promptMsg = fmt.Sprintf("Enter service name [%s]", defaultName)
-	} else {
-		promptMsg = "Enter service name"
+	name, err := prompt.Text("Enter service name", defaultName)
+	if err != nil {
+		return "", err
 	}
-
-	name := prompt(promptMsg)
 	if name == "" {
-		if defaultName != "" {
-			return defaultName, nil
-		}
 		return "", errors.New("service name cannot be empty")
 	}
 	return name, nil
 }
 
-func saveDeploySettings(project, region, service, buildPath string) error {
-	entries := []string{
-		fmt.Sprintf("export CLOUDSDK_CORE_PROJECT=%s", project),
-		fmt.Sprintf("export CLOUDSDK_RUN_REGION=%s", region),
-		fmt.Sprintf("export CLOUD_RUN_SERVICE=%s", service),
-		fmt.Sprintf("export KO_DOCKER_REPO=gcr.io/%s/%s", project, service),
-		fmt.Sprintf("export KO_BUILD_PATH=%s", buildPath),
-	}
-
-	existing := make(map[string]bool)
-	existingKeys := make(map[string]string) // key -> full line
-
-	if file, err := os.Open(".envrc"); err == nil {
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			existing[line] = true
-			// Extract key from "export KEY=value"
-			if strings.HasPrefix(line, "export ") {
-				parts := strings.SplitN(strings.TrimPrefix(line, "export "), "=", 2)
-				if len(parts) == 2 {
-					existingKeys[parts[0]] = line
-				}
-			}
+func saveDeploySettings(cfg *config.Config, project, region, service, buildPath string) error {
+	cfg.Project = project
+	cfg.Region = region
+	cfg.Service = service
+	cfg.BuildPath = buildPath
+	return saveConfig(cfg)
+}
+
+// deployImage builds the image once, deploys it to each region, and returns the image and URLs.
+func deployImage(ctx context.Context, project string, regions []string, service, buildPath, builder string, assets config.Assets, opts gcloud.DeployOptions, verify verifyOptions) (string, []string, error) {
+	image, err := buildImage(ctx, project, service, buildPath, builder, deployRemote)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if assets.Bucket != "" {
+		assetsURL, err := uploadAssets(ctx, project, regions[0], image, assets)
+		if err != nil {
+			return "", nil, err
 		}
-		_ = file.Close()
+		env := maps.Clone(opts.Env)
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env["ASSETS_URL"] = assetsURL
+		opts.Env = env
 	}
 
-	var toAdd []string
-	var toUpdate []struct{ old, new string }
+	// Label the revision so it can be traced back to source
+	opts.Labels = gitLabels()
 
-	for _, entry := range entries {
-		if existing[entry] {
-			continue
+	if len(opts.CloudSQL) > 0 {
+		if err := gcloud.EnsureAPIs(ctx, project, "sqladmin.googleapis.com"); err != nil {
+			return "", nil, err
 		}
-		// Check if key exists with different value
-		parts := strings.SplitN(strings.TrimPrefix(entry, "export "), "=", 2)
-		if len(parts) == 2 {
-			if oldLine, found := existingKeys[parts[0]]; found {
-				toUpdate = append(toUpdate, struct{ old, new string }{oldLine, entry})
-				continue
-			}
+	}
+
+	urls, err := deployRegions(ctx, project, regions, service, image, opts, verify, deployParallel)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if opts.Tag != "" && urls[0] != "" {
+		if err := writeGitHubOutput(map[string]string{"tag": opts.Tag, "url": urls[0]}); err != nil {
+			return "", nil, err
 		}
-		toAdd = append(toAdd, entry)
 	}
 
-	// Update existing entries
-	if len(toUpdate) > 0 {
-		data, err := os.ReadFile(".envrc")
+	if len(regions) > 1 {
+		fmt.Println("\nURLs:")
+		for i, region := range regions {
+			fmt.Printf("  %-16s %s\n", region, urls[i])
+		}
+	}
+
+	if opts.Canary > 0 {
+		fmt.Println("\nRun 'go do traffic --finalize' to route all traffic to the new revision, or 'go do rollback' to undo.")
+	}
+	return image, urls, nil
+}
+
+// deployRegions deploys image to the service in each region, one after another or,
+// with parallel, at the same time, and returns the URL in each region. If a region
+// fails, the regions already deployed are rolled back, and the error lists each
+// region's outcome.
+func deployRegions(ctx context.Context, project string, regions []string, service, image string, opts gcloud.DeployOptions, verify verifyOptions, parallel bool) ([]string, error) {
+	var previous map[string]gcloud.Service
+	if opts.Tag == "" {
+		// A first deploy has no traffic to restore
+		previous, _ = gcloud.GetServices(ctx, project, regions, service)
+	}
+
+	urls := make([]string, len(regions))
+	deployed := make([]bool, len(regions))
+	deploy := func(ctx context.Context, region string) error {
+		i := slices.Index(regions, region)
+		url, err := deployRegion(ctx, project, region, service, image, previous[region].Traffic, opts, verify)
 		if err != nil {
-			return errors.WithStack(err)
+			return err
+		}
+		urls[i], deployed[i] = url, true
+		return nil
+	}
+
+	var err error
+	if parallel {
+		err = gcloud.ForEachRegion(ctx, regions, deploy)
+	} else {
+		for _, region := range regions {
+			if err = deploy(ctx, region); err != nil {
+				if len(regions) > 1 {
+					err = errors.WithStack(gcloud.RegionErrors{{Err: err, Region: region}})
+				}
+				break
+			}
 		}
-		content := string(data)
-		for _, u := range toUpdate {
-			content = strings.Replace(content, u.old, u.new, 1)
+	}
+	if err != nil && len(regions) > 1 {
+		return nil, undeployRegions(ctx, project, regions, service, previous, deployed, opts.Tag, err)
+	}
+	return urls, err
+}
+
+// undeployRegions routes traffic in the regions that deployed back to the revisions
+// that served it before, after another region failed, so every region keeps serving
+// the same version. Regions with nothing to restore, such as on a first or tagged
+// deploy, keep the new revision. The returned error wraps failure with what happened
+// in each region.
+func undeployRegions(ctx context.Context, project string, regions []string, service string, previous map[string]gcloud.Service, deployed []bool, tag string, failure error) error {
+	var failed gcloud.RegionErrors
+	errors.As(failure, &failed)
+
+	var summary []string
+	for i, region := range regions {
+		switch {
+		case slices.ContainsFunc(failed, func(e *gcloud.RegionError) bool { return e.Region == region }):
+			continue
+		case !deployed[i]:
+			summary = append(summary, region+" was not deployed")
+		case tag != "":
+			summary = append(summary, fmt.Sprintf("tag %s in %s points at the new revision", tag, region))
+		case len(previous[region].Traffic) == 0:
+			summary = append(summary, region+" is left on the new revision")
+		default:
+			// Restore traffic even if the deploy was interrupted with Ctrl-C.
+			if err := restoreTraffic(context.WithoutCancel(ctx), project, region, service, previous[region].Traffic); err != nil {
+				summary = append(summary, fmt.Sprintf("%s is left on the new revision, rollback failed: %v", region, err))
+				continue
+			}
+			summary = append(summary, region+" was rolled back")
 		}
-		if err := os.WriteFile(".envrc", []byte(content), 0644); err != nil {
-			return errors.WithStack(err)
+	}
+	if len(summary) == 0 {
+		return errors.Wrap(failure, "deploy failed in every region")
+	}
+	return errors.Wrapf(failure, "deploy failed (%s)", strings.Join(summary, "; "))
+}
+
+// deployRegion deploys image to the service in one region, verifies the new
+// revision, and returns its URL. If verification fails, traffic is routed back
+// to previous, the revisions that were serving before the deploy.
+func deployRegion(ctx context.Context, project, region, service, image string, previous []gcloud.TrafficTarget, opts gcloud.DeployOptions, verify verifyOptions) (string, error) {
+	switch {
+	case opts.Tag != "":
+		fmt.Printf("\nDeploying to Cloud Run service '%s' in %s with tag '%s'...\n", service, region, opts.Tag)
+	case opts.Canary > 0:
+		fmt.Printf("\nDeploying to Cloud Run service '%s' in %s with %d%% canary traffic...\n", service, region, opts.Canary)
+	default:
+		fmt.Printf("\nDeploying to Cloud Run service '%s' in %s...\n", service, region)
+	}
+
+	if err := gcloud.Deploy(ctx, project, region, service, image, opts); err != nil {
+		return "", err
+	}
+
+	var url string
+	if opts.Tag != "" {
+		url = gcloud.TagURL(ctx, project, region, service, opts.Tag)
+	} else {
+		url = gcloud.ServiceURL(ctx, project, region, service)
+	}
+
+	if err := verifyDeploy(ctx, project, region, service, url, verify); err != nil {
+		if len(previous) > 0 {
+			// Restore traffic even if the deploy was interrupted with Ctrl-C.
+			if rerr := restoreTraffic(context.WithoutCancel(ctx), project, region, service, previous); rerr != nil {
+				return "", errors.Wrapf(err, "rollback failed: %v", rerr)
+			}
 		}
-		for _, u := range toUpdate {
-			fmt.Printf("Updated .envrc: %s\n", u.new)
+		return "", err
+	}
+
+	if url != "" {
+		if opts.Tag != "" {
+			fmt.Printf("\nTagged deploy successful!\nURL: %s\n", url)
+		} else {
+			fmt.Printf("\nService deployed successfully!\nURL: %s\n", url)
 		}
 	}
+	return url, nil
+}
 
-	// Add new entries
-	if len(toAdd) > 0 {
-		file, err := os.OpenFile(".envrc", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			return errors.WithStack(err)
+// deployEnvVars merges do.yaml env, preview env for tagged deploys, and --set-env flags.
+// Env vars live on the service template, so untagged deploys remove preview-only
+// keys that an earlier tagged deploy left behind.
+func deployEnvVars(cfg *config.Config, flagEnv map[string]string, tagged bool) (map[string]string, []string) {
+	env := make(map[string]string)
+	for k, v := range cfg.Env {
+		env[k] = v
+	}
+	if tagged {
+		for k, v := range cfg.Preview.Env {
+			env[k] = v
 		}
-		defer func() { _ = file.Close() }()
+	}
+	for k, v := range flagEnv {
+		env[k] = v
+	}
 
-		for _, entry := range toAdd {
-			if _, err := file.WriteString(entry + "\n"); err != nil {
-				return errors.WithStack(err)
+	var remove []string
+	if !tagged {
+		for k := range cfg.Preview.Env {
+			if _, ok := env[k]; !ok {
+				remove = append(remove, k)
 			}
-			fmt.Printf("Added to .envrc: %s\n", entry)
 		}
+		sort.Strings(remove)
 	}
 
-	// Remind user to run direnv allow
-	if len(toAdd) > 0 || len(toUpdate) > 0 {
-		fmt.Println("\nRun 'direnv allow' to load the new environment variables.")
+	if len(env) == 0 {
+		env = nil
 	}
+	return env, remove
+}
 
-	return nil
+// cloudSQLConnections expands bare instance names to PROJECT:REGION:INSTANCE connection names.
+func cloudSQLConnections(project, region string, instances []string) []string {
+	conns := make([]string, len(instances))
+	for i, inst := range instances {
+		if strings.Contains(inst, ":") {
+			conns[i] = inst
+		} else {
+			conns[i] = fmt.Sprintf("%s:%s:%s", project, region, inst)
+		}
+	}
+	return conns
+}
+
+// parseEnvFlags parses repeated --set-env KEY=VALUE flags.
+func parseEnvFlags(kvs []string) (map[string]string, error) {
+	if len(kvs) == 0 {
+		return nil, nil
+	}
+
+	env := make(map[string]string)
+	for _, kv := range kvs {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, errors.Errorf("invalid --set-env %q, expected KEY=VALUE", kv)
+		}
+		env[k] = v
+	}
+	return env, nil
 }
 
-func deployWithKo(project, region, service, buildPath, tag string) error {
+// buildImage builds buildPath with ko, or the Dockerfile with the docker builder,
+// and pushes it to gcr.io/<project>/<name>. With remote, the build runs on Cloud Build.
+func buildImage(ctx context.Context, project, name, buildPath, builder string, remote bool) (string, error) {
+	koRepo := fmt.Sprintf("gcr.io/%s/%s", project, name)
+
+	if remote {
+		if err := gcloud.EnsureAPIs(ctx, project, append(deployAPIs, "cloudbuild.googleapis.com")...); err != nil {
+			return "", err
+		}
+		image, err := remoteBuild(ctx, project, buildPath, builder, koRepo)
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("Built image: %s\n", image)
+		return image, nil
+	}
+
 	// Enable required APIs if not already enabled
-	if err := gcloud.EnsureAPIs(project, "run.googleapis.com", "artifactregistry.googleapis.com"); err != nil {
-		return err
+	if err := gcloud.EnsureAPIs(ctx, project, deployAPIs...); err != nil {
+		return "", err
 	}
 
 	// Configure docker auth for GCR if not already configured
-	if err := gcloud.EnsureDockerAuth(); err != nil {
-		return err
+	if err := gcloud.EnsureDockerAuth(ctx); err != nil {
+		return "", err
 	}
 
 	// Set KO_DOCKER_REPO for ko
-	koRepo := fmt.Sprintf("gcr.io/%s/%s", project, service)
 	if err := os.Setenv("KO_DOCKER_REPO", koRepo); err != nil {
-		return errors.WithStack(err)
+		return "", errors.WithStack(err)
 	}
 
-	// Run go generate
-	fmt.Println("\nRunning go generate...")
-	generate := exec.Command("go", "generate", "./...")
-	generate.Stdout = os.Stdout
-	generate.Stderr = os.Stderr
-	if err := generate.Run(); err != nil {
-		return errors.WithStack(err)
+	err := progress.Step("go generate ./...", func(w io.Writer) error {
+		generate := exec.Command("go", "generate", "./...")
+		if deployTag != "" {
+			generate.Env = append(os.Environ(), previewEnv+"=1")
+		}
+		generate.Stdout = w
+		generate.Stderr = w
+		if err := generate.Run(); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	// Build and push with ko
-	fmt.Println("\nBuilding and pushing image with ko...")
-	fmt.Printf(" → ko build %s --bare\n", buildPath)
+	var image string
+	if builder == builderDocker {
+		err = progress.Step("docker build .", func(w io.Writer) error {
+			var err error
+			image, err = dockerBuild(w, koRepo)
+			return err
+		})
+	} else {
+		err = progress.Step(fmt.Sprintf("ko build %s --bare", buildPath), func(w io.Writer) error {
+			var err error
+			image, err = koBuild(w, buildPath, koRepo)
+			return err
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Built image: %s\n", image)
+	return image, nil
+}
 
-	var imageOut bytes.Buffer
+// gitLabels returns commit-sha, git-branch, and git-dirty labels for the working tree.
+// It returns nil outside a git repository.
+func gitLabels() map[string]string {
+	sha, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return nil
+	}
+	labels := map[string]string{"commit-sha": gcloud.LabelValue(strings.TrimSpace(string(sha)))}
+
+	// GitHub Actions checks out PRs on a detached HEAD
+	branch := os.Getenv("GITHUB_HEAD_REF")
+	if branch == "" {
+		if out, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+			branch = strings.TrimSpace(string(out))
+		}
+	}
+	if branch != "" && branch != "HEAD" {
+		labels["git-branch"] = gcloud.LabelValue(branch)
+	}
+
+	dirty := "false"
+	if out, err := exec.Command("git", "status", "--porcelain").Output(); err == nil && len(bytes.TrimSpace(out)) > 0 {
+		dirty = "true"
+	}
+	labels["git-dirty"] = dirty
+
+	return labels
+}
+
+// deployOptions builds gcloud deploy options from do.yaml and the deploy command flags.
+func deployOptions(cmd *cobra.Command, cfg *config.Config) (gcloud.DeployOptions, error) {
+	opts := gcloud.DeployOptions{
+		Canary:      deployCanary,
+		Concurrency: deployConcurrency,
+		CPU:         deployCPU,
+		Memory:      deployMemory,
+		Tag:         deployTag,
+	}
+
+	flagEnv, err := parseEnvFlags(deployEnv)
+	if err != nil {
+		return opts, err
+	}
+	opts.Env, opts.RemoveEnv = deployEnvVars(cfg, flagEnv, deployTag != "")
+
+	if cmd.Flags().Changed("auth") && deployAuth != gcloud.AuthPublic && deployAuth != gcloud.AuthRequired {
+		return opts, errors.Errorf("--auth must be %q or %q", gcloud.AuthRequired, gcloud.AuthPublic)
+	}
+
+	if cmd.Flags().Changed("min-instances") {
+		opts.MinInstances = &deployMinInstances
+	}
+	if cmd.Flags().Changed("max-instances") {
+		opts.MaxInstances = &deployMaxInstances
+	}
+	return opts, nil
+}
+
+// koBuild runs ko build, writing its progress to w, and returns the pushed image reference.
+func koBuild(w io.Writer, buildPath, koRepo string) (string, error) {
+	var imageOut, logOut bytes.Buffer
 	koCmd := exec.Command("ko", "build", buildPath, "--bare")
 	koCmd.Stdout = &imageOut
-	koCmd.Stderr = os.Stderr
+	koCmd.Stderr = io.MultiWriter(w, &logOut)
 
 	if err := koCmd.Run(); err != nil {
-		return errors.Wrap(err, "ko build failed")
+		return "", errors.Wrap(err, koFailure(koRepo, logOut.String()))
 	}
 
-	image := strings.TrimSpace(imageOut.String())
+	lines := strings.Split(strings.TrimSpace(imageOut.String()), "\n")
+	image := strings.TrimSpace(lines[len(lines)-1])
 	if image == "" {
-		return errors.New("ko build did not return image reference")
+		return "", errors.New("ko build did not return image reference")
 	}
-	fmt.Printf("Built image: %s\n", image)
+	return image, nil
+}
 
-	// Deploy to Cloud Run
-	if tag != "" {
-		fmt.Printf("\nDeploying to Cloud Run service '%s' with tag '%s'...\n", service, tag)
-		if err := gcloud.DeployWithTag(project, region, service, image, tag); err != nil {
-			return err
-		}
+// koUnauthorized and koDenied match the registry responses ko logs for push errors,
+// such as "401 Unauthorized" or "DENIED: Permission ... denied", and not digests,
+// sizes, or file names that happen to contain the same digits or words.
+var (
+	koUnauthorized = regexp.MustCompile(`\b401 Unauthorized\b|\bUNAUTHORIZED:`)
+	koDenied       = regexp.MustCompile(`\b403 Forbidden\b|\bDENIED:`)
+)
 
-		// Get the tagged URL
-		if url := gcloud.TagURL(project, region, service, tag); url != "" {
-			fmt.Printf("\nTagged deploy successful!\nURL: %s\n", url)
-		}
-	} else {
-		fmt.Printf("\nDeploying to Cloud Run service '%s'...\n", service)
-		if err := gcloud.Deploy(project, region, service, image); err != nil {
-			return err
-		}
+// koFailure describes a failed ko build, adding registry auth hints for push errors.
+func koFailure(koRepo, log string) string {
+	host := strings.SplitN(koRepo, "/", 2)[0]
+	lower := strings.ToLower(log)
+
+	switch {
+	case koUnauthorized.MatchString(log):
+		return fmt.Sprintf("ko build failed: not authenticated to %s\nRun 'gcloud auth configure-docker %s' and retry", host, host)
+	case koDenied.MatchString(log):
+		return fmt.Sprintf("ko build failed: permission denied pushing to %s\nCheck the account has Artifact Registry or Storage write access to %s", host, koRepo)
+	case strings.Contains(lower, "retrying") || strings.Contains(lower, "timeout") || strings.Contains(lower, "connection reset"):
+		return fmt.Sprintf("ko build failed: push to %s did not complete after retries", host)
+	}
+	return "ko build failed"
+}
 
-		// Get the service URL
-		if url := gcloud.ServiceURL(project, region, service); url != "" {
-			fmt.Printf("\nService deployed successfully!\nURL: %s\n", url)
-		}
+// previewTag derives a traffic tag from the GitHub Actions pull request event.
+func previewTag() (string, error) {
+	pr, err := github.CurrentPullRequest()
+	if err != nil {
+		return "", errors.Wrap(err, "--preview requires a GitHub Actions pull_request event")
 	}
 
-	return nil
+	p := gcloud.Preview{
+		BaseRepo: pr.BaseRepo,
+		HeadRepo: pr.HeadRepo,
+		Number:   pr.Number,
+	}
+	if pr.DefaultBranch != "" && pr.BaseRef != pr.DefaultBranch {
+		p.Parent = pr.BaseRef
+	}
+	return gcloud.PreviewTag(p), nil
 }
 
-func prompt(msg string) string {
-	fmt.Printf("%s: ", msg)
-	reader := bufio.NewReader(os.Stdin)
-	line, _ := reader.ReadString('\n')
-	return strings.TrimSpace(line)
-}
+// writeGitHubOutput appends step outputs to $GITHUB_OUTPUT when running in GitHub Actions.
+func writeGitHubOutput(outputs map[string]string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
 
-func promptInt(msg string, min, max int) int {
-	for {
-		fmt.Printf("%s (%d-%d): ", msg, min, max)
-		reader := bufio.NewReader(os.Stdin)
-		line, _ := reader.ReadString('\n')
-		line = strings.TrimSpace(line)
-
-		var n int
-		if _, err := fmt.Sscanf(line, "%d", &n); err != nil || n < min || n > max {
-			fmt.Printf("Please enter a number between %d and %d\n", min, max)
-			continue
-		}
-		return n
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStack(err)
 	}
-}
+	defer func() { _ = file.Close() }()
 
-func confirm(msg string) bool {
-	fmt.Printf("%s (y/n): ", msg)
-	reader := bufio.NewReader(os.Stdin)
-	line, _ := reader.ReadString('\n')
-	line = strings.ToLower(strings.TrimSpace(line))
-	return line == "y" || line == "yes"
+	keys := make([]string, 0, len(outputs))
+	for k := range outputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(file, "%s=%s\n", k, outputs[k]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
 }
 
-func deleteTrafficTag(tag string) error {
+// deployedService returns the project, region, and service saved by a previous deploy.
+func deployedService() (string, string, string, error) {
 	project := os.Getenv("CLOUDSDK_CORE_PROJECT")
 	region := os.Getenv("CLOUDSDK_RUN_REGION")
 	service := os.Getenv("CLOUD_RUN_SERVICE")
 
 	if project == "" || region == "" || service == "" {
-		return errors.New("no service deployed. Run 'go do deploy' first")
+		return "", "", "", errors.New("no service deployed. Run 'go do deploy' first")
+	}
+	return project, region, service, nil
+}
+
+// deployedRegions is deployedService with the regions listed in do.yaml after the
+// saved region, for commands that act on every region a deploy updates.
+func deployedRegions() (string, []string, string, error) {
+	project, region, service, err := deployedService()
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	regions := []string{region}
+	if _, err := findProjectRoot(); err != nil {
+		return project, regions, service, nil // No go.mod, so no do.yaml
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", nil, "", err
+	}
+	for _, r := range cfg.Regions {
+		if !slices.Contains(regions, r) {
+			regions = append(regions, r)
+		}
+	}
+	return project, regions, service, nil
+}
+
+// notifyDeploy posts a deploy event to webhook, if set. Failures are reported
+// but don't fail the deploy.
+func notifyDeploy(webhook string, e notify.Event) {
+	if webhook == "" {
+		return
+	}
+	if err := notify.Post(webhook, e); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// deployer identifies who is deploying: the GitHub actor in CI, else the gcloud account.
+func deployer(ctx context.Context) string {
+	if actor := os.Getenv("GITHUB_ACTOR"); actor != "" {
+		return actor
+	}
+	return gcloud.Account(ctx)
+}
+
+func deleteTrafficTag(ctx context.Context, tag string) error {
+	project, region, service, err := deployedService()
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("Removing tag '%s' from service '%s'...\n", tag, service)
-	return gcloud.RemoveTag(project, region, service, tag)
+	return gcloud.RemoveTag(ctx, project, region, service, tag)
 }
 
 func init() {
 	deployCmd.Flags().StringVarP(&deployTag, "tag", "t", "", "deploy with a traffic tag (for branch deploys)")
+	deployCmd.Flags().IntVar(&deployCanary, "canary", 0, "route only this percentage of traffic (1-99) to the new revision")
+	deployCmd.Flags().StringArrayVar(&deployEnv, "set-env", nil, "set a runtime env var as KEY=VALUE (repeatable)")
+	deployCmd.Flags().StringVar(&deployMemory, "memory", "", "memory limit per instance (e.g. 512Mi, 1Gi)")
+	deployCmd.Flags().StringVar(&deployCPU, "cpu", "", "CPU limit per instance (e.g. 1, 2)")
+	deployCmd.Flags().IntVar(&deployConcurrency, "concurrency", 0, "maximum concurrent requests per instance")
+	deployCmd.Flags().IntVar(&deployMinInstances, "min-instances", 0, "minimum number of instances")
+	deployCmd.Flags().IntVar(&deployMaxInstances, "max-instances", 0, "maximum number of instances")
+	deployCmd.Flags().StringSliceVar(&deployCloudSQL, "cloudsql", nil, "Cloud SQL instance to connect (name or PROJECT:REGION:INSTANCE, saved to do.yaml)")
+	deployCmd.Flags().StringVar(&deployAuth, "auth", "", "who can invoke the service: required or public (saved to do.yaml)")
+	deployCmd.Flags().StringArrayVar(&deployInvokers, "invoker", nil, "grant roles/run.invoker to a principal, e.g. user:x@example.com (repeatable, saved to do.yaml)")
+	deployCmd.Flags().BoolVar(&deployParallel, "parallel", false, "deploy to the regions in do.yaml at the same time instead of one after another")
+	deployCmd.Flags().BoolVar(&deployPreview, "preview", false, "derive the traffic tag from the GitHub pull request (for CI preview deploys)")
+	deployCmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "skip billing, IAM, API, and build checks before deploying")
+	deployCmd.Flags().StringVar(&deployBuilder, "builder", "", "how to build the image: ko (default) or docker, which builds the Dockerfile (saved to do.yaml)")
+	deployCmd.Flags().BoolVar(&deployRemote, "remote", false, "build on Google Cloud Build instead of locally")
+	deployCmd.Flags().StringVar(&deployServiceAccount, "service-account", "", "runtime service account name or email instead of the compute service account (saved to do.yaml)")
+	deployCmd.Flags().BoolVar(&createServiceAccount, "create-service-account", false, "create the --service-account if missing and grant it minimal runtime roles")
+	deployCmd.Flags().StringVar(&healthPath, "health-path", "", "path that must return 2xx after deploy, e.g. /readyz (saved to do.yaml)")
+	deployCmd.Flags().DurationVar(&healthTimeout, "health-timeout", 2*time.Minute, "how long to wait for the revision to become ready and healthy")
+	deployCmd.Flags().IntVar(&keepImages, "keep", 0, "after deploying, delete images beyond the N most recent (images in use are kept)")
 	deployCmd.Flags().StringVar(&deleteTag, "delete-tag", "", "remove a traffic tag")
+	deployCmd.Flags().StringVar(&cleanupAge, "cleanup-previews", "", "remove preview tags for closed PRs, or older than the given age (e.g. 7d)")
+	deployCmd.Flags().Lookup("cleanup-previews").NoOptDefVal = "0"
 	rootCmd.AddCommand(deployCmd)
 }
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKoFailure(t *testing.T) {
+	const repo = "us-docker.pkg.dev/my-project/app"
+	const unauthorized = "ko build failed: not authenticated to us-docker.pkg.dev\nRun 'gcloud auth configure-docker us-docker.pkg.dev' and retry"
+	const denied = "ko build failed: permission denied pushing to us-docker.pkg.dev\nCheck the account has Artifact Registry or Storage write access to " + repo
+
+	tests := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"401", "GET https://us-docker.pkg.dev/v2/token: unexpected status code 401 Unauthorized", unauthorized},
+		{"unauthorized code", "POST https://us-docker.pkg.dev/v2/my-project/app/blobs/uploads/: UNAUTHORIZED: authentication failed", unauthorized},
+		{"403", "HEAD https://gcr.io/v2/my-project/app/manifests/latest: unexpected status code 403 Forbidden (HEAD responses have no body)", denied},
+		{"denied code", `DENIED: Permission "artifactregistry.repositories.uploadArtifacts" denied on resource`, denied},
+		{"timeout", "Get https://us-docker.pkg.dev/v2/: net/http: TLS handshake timeout", "ko build failed: push to us-docker.pkg.dev did not complete after retries"},
+		{"digest with 401", "Published us-docker.pkg.dev/my-project/app@sha256:4014031d1c1f\nError: build: exit status 1", "ko build failed"},
+		{"size with 403", "pushed blob: sha256:9a1f (4030 bytes)\ncompile: main.go:3: undefined: x", "ko build failed"},
+		{"denied in file name", "open /src/denied.go: no such file or directory", "ko build failed"},
+		{"unauthorized in package", "package example.com/unauthorized is not in std", "ko build failed"},
+		{"permission denied locally", "mkdir /root/.cache: permission denied", "ko build failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+
+			a.Equal(tt.want, koFailure(repo, tt.log))
+		})
+	}
+}
+
+const (
+	describeApp   = "gcloud run services describe app --platform=managed --region=us-central1 --project=my-project"
+	revisionReady = `{"metadata": {"name": "app-00002-xyz"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}`
+)
+
+func TestDeployRegionTag(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{"metadata": {"name": "app"}, "status": {"traffic": [{"latestRevision": true, "percent": 100}, {"revisionName": "app-00002-xyz", "tag": "feature-x", "url": "https://feature-x---app-abc.a.run.app"}]}}`,
+		"gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=us-central1", "--project=my-project", "--format=json")
+	fake.Reply("app-00002-xyz\n", "gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=us-central1", "--project=my-project", "--format=value(status.latestCreatedRevisionName)")
+	fake.Reply(revisionReady, "gcloud", "run", "revisions", "describe", "app-00002-xyz")
+
+	url, err := deployRegion(t.Context(), "my-project", "us-central1", "app", "gcr.io/my-project/app@sha256:abc", nil, gcloud.DeployOptions{Tag: "feature-x"}, verifyOptions{})
+	r.NoError(err)
+	a.Equal("https://feature-x---app-abc.a.run.app", url)
+	a.Equal([]string{
+		"gcloud run deploy app --image=gcr.io/my-project/app@sha256:abc --platform=managed --region=us-central1 --project=my-project --tag=feature-x --no-traffic",
+		describeApp + " --format=json",
+		describeApp + " --format=value(status.latestCreatedRevisionName)",
+		"gcloud run revisions describe app-00002-xyz --platform=managed --region=us-central1 --project=my-project --format=json",
+	}, fake.Commands())
+}
+
+func TestDeployRegionRollback(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{"metadata": {"name": "app"}, "status": {"traffic": [{"revisionName": "app-00001-abc", "percent": 100}], "url": "https://app-abc.a.run.app"}}`,
+		"gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=us-central1", "--project=my-project", "--format=json")
+	fake.Reply("app-00002-xyz\n", "gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=us-central1", "--project=my-project", "--format=value(status.latestCreatedRevisionName)")
+	fake.Reply(`{"metadata": {"name": "app-00002-xyz"}, "status": {"conditions": [{"type": "Ready", "status": "False", "message": "container failed to start"}]}}`,
+		"gcloud", "run", "revisions", "describe", "app-00002-xyz")
+
+	previous := []gcloud.TrafficTarget{{Percent: 100, Revision: "app-00001-abc"}}
+	_, err := deployRegion(t.Context(), "my-project", "us-central1", "app", "gcr.io/my-project/app@sha256:abc", previous, gcloud.DeployOptions{}, verifyOptions{})
+	r.Error(err)
+	a.ErrorContains(err, "revision app-00002-xyz failed: container failed to start")
+
+	commands := fake.Commands()
+	r.NotEmpty(commands)
+	a.Contains(commands, "gcloud run services update-traffic app --platform=managed --region=us-central1 --project=my-project --to-latest")
+	a.Equal("gcloud run services update-traffic app --platform=managed --region=us-central1 --project=my-project --to-revisions=app-00001-abc=100", commands[len(commands)-1])
+}
+
+func TestDeleteTrafficTag(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	deployedEnv(t)
+	fake := gcloudtest.Install(t)
+
+	r.NoError(deleteTrafficTag(t.Context(), "pr-7"))
+	a.Equal([]string{
+		"gcloud run services update-traffic app --platform=managed --region=us-central1 --project=my-project --remove-tags=pr-7",
+	}, fake.Commands())
+}
+
+// replyRegion sets up a service in region serving previous, whose next revision
+// becomes ready or, with failed, fails to start.
+func replyRegion(fake *gcloudtest.Runner, region, previous string, failed bool) {
+	describe := []string{"gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=" + region, "--project=my-project"}
+	fake.Reply(`{"metadata": {"name": "app"}, "status": {"traffic": [{"revisionName": "`+previous+`", "percent": 100}], "url": "https://app-`+region+`.a.run.app"}}`,
+		append(describe, "--format=json")...)
+	fake.Reply("app-next-"+region+"\n", append(describe, "--format=value(status.latestCreatedRevisionName)")...)
+	status := `"True"`
+	if failed {
+		status = `"False", "message": "container failed to start"`
+	}
+	fake.Reply(`{"metadata": {"name": "app-next-`+region+`"}, "status": {"conditions": [{"type": "Ready", "status": `+status+`}]}}`,
+		"gcloud", "run", "revisions", "describe", "app-next-"+region)
+}
+
+func TestDeployRegionsParallelRollback(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	replyRegion(fake, "us-central1", "app-00001-us", false)
+	replyRegion(fake, "europe-west1", "app-00001-eu", true)
+	replyRegion(fake, "asia-east1", "app-00001-asia", false)
+
+	_, err := deployRegions(t.Context(), "my-project", []string{"us-central1", "europe-west1", "asia-east1"}, "app", "gcr.io/my-project/app@sha256:abc", gcloud.DeployOptions{}, verifyOptions{}, true)
+	r.Error(err)
+	a.EqualError(err, "deploy failed (us-central1 was rolled back; asia-east1 was rolled back): europe-west1: revision app-next-europe-west1 failed: container failed to start")
+
+	var failed gcloud.RegionErrors
+	r.True(errors.As(err, &failed))
+	a.Len(failed, 1)
+
+	commands := fake.Commands()
+	for _, region := range []string{"us-central1", "europe-west1", "asia-east1"} {
+		a.Contains(commands, "gcloud run deploy app --image=gcr.io/my-project/app@sha256:abc --platform=managed --region="+region+" --project=my-project")
+	}
+	a.Contains(commands, "gcloud run services update-traffic app --platform=managed --region=us-central1 --project=my-project --to-revisions=app-00001-us=100")
+	a.Contains(commands, "gcloud run services update-traffic app --platform=managed --region=europe-west1 --project=my-project --to-revisions=app-00001-eu=100")
+	a.Contains(commands, "gcloud run services update-traffic app --platform=managed --region=asia-east1 --project=my-project --to-revisions=app-00001-asia=100")
+}
+
+func TestDeployRegionsSequentialTag(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	replyRegion(fake, "us-central1", "app-00001-us", false)
+	replyRegion(fake, "europe-west1", "app-00001-eu", true)
+	replyRegion(fake, "asia-east1", "app-00001-asia", false)
+
+	_, err := deployRegions(t.Context(), "my-project", []string{"us-central1", "europe-west1", "asia-east1"}, "app", "gcr.io/my-project/app@sha256:abc", gcloud.DeployOptions{Tag: "feature-x"}, verifyOptions{}, false)
+	r.Error(err)
+	a.EqualError(err, "deploy failed (tag feature-x in us-central1 points at the new revision; asia-east1 was not deployed): europe-west1: revision app-next-europe-west1 failed: container failed to start")
+
+	for _, c := range fake.Commands() {
+		a.NotContains(c, "--region=asia-east1")
+		a.NotContains(c, "update-traffic")
+	}
+}
+
+func TestDeployRegionsSingle(t *testing.T) {
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	replyRegion(fake, "us-central1", "app-00001-us", true)
+
+	_, err := deployRegions(t.Context(), "my-project", []string{"us-central1"}, "app", "gcr.io/my-project/app@sha256:abc", gcloud.DeployOptions{}, verifyOptions{}, false)
+	a.EqualError(err, "revision app-next-us-central1 failed: container failed to start")
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"os"
 	"os/exec"
 
+	"github.com/housecat-inc/do/pkg/svelte"
+	"github.com/housecat-inc/do/pkg/tailwind"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+var devHMR bool
+
 var devCmd = &cobra.Command{
 	Use:   "dev",
 	Short: "Run development server with live reload",
@@ -21,6 +26,44 @@ var devCmd = &cobra.Command{
 			}
 		}
 
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if cfg.Tailwind.Enabled {
+			ctx, cancel := context.WithCancel(cmd.Context())
+			watch, err := tailwind.Command(ctx, tailwind.Options{
+				Input:   cfg.Tailwind.Input,
+				Output:  cfg.Tailwind.Output,
+				Version: cfg.Tailwind.Version,
+				Watch:   true,
+			})
+			if err != nil {
+				cancel()
+				return err
+			}
+			watch.Stdout = os.Stdout
+			watch.Stderr = os.Stderr
+			if err := watch.Start(); err != nil {
+				cancel()
+				return errors.WithStack(err)
+			}
+			// Stopped when air exits.
+			defer func() {
+				cancel()
+				_ = watch.Wait()
+			}()
+		}
+
+		// With HMR the app's svelte.Registry pushes component edits to the browser,
+		// so .svelte files no longer trigger a rebuild and reload.
+		includeExt := "css,go,html,svelte,templ"
+		env := append(os.Environ(), "PORT=8081")
+		if devHMR {
+			includeExt = "css,go,html,templ"
+			env = append(env, svelte.HMREnv+"=1")
+		}
+
 		air := exec.Command("air",
 			"--tmp_dir", "bin",
 			"--build.pre_cmd", "go generate ./...",
@@ -28,12 +71,12 @@ var devCmd = &cobra.Command{
 			"--build.bin", "bin/app",
 			"--build.exclude_dir", "node_modules,bin,vendor,.git,dist,build",
 			"--build.exclude_regex", `\.min\.js$|\.sql\.go$|_templ\.go$|_test\.go$|out\.css$|pkg/db/(db|models|querier)\.go$`,
-			"--build.include_ext", "css,go,html,svelte,templ",
+			"--build.include_ext", includeExt,
 			"--proxy.enabled", "true",
 			"--proxy.proxy_port", "8080",
 			"--proxy.app_port", "8081",
 		)
-		air.Env = append(os.Environ(), "PORT=8081")
+		air.Env = env
 		air.Stdout = os.Stdout
 		air.Stderr = os.Stderr
 		air.Stdin = os.Stdin
@@ -45,5 +88,6 @@ var devCmd = &cobra.Command{
 }
 
 func init() {
+	devCmd.Flags().BoolVar(&devHMR, "hmr", false, "update edited Svelte components served by svelte.Registry in place instead of rebuilding and reloading")
 	rootCmd.AddCommand(devCmd)
 }
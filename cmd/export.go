@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/terraform"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var exportFile string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the deployed service as infrastructure as code",
+	Long: `Writes the deployed service configuration to stdout, or to --file:
+  go do export terraform -f run.tf
+  go do export yaml -f service.yaml`,
+}
+
+var exportTerraformCmd = &cobra.Command{
+	Use:   "terraform",
+	Short: "Export the service, IAM bindings, and domain mappings as Terraform",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		cfg, err := gcloud.DescribeService(ctx, project, region, service)
+		if err != nil {
+			return err
+		}
+		bindings, err := gcloud.ServiceIAMBindings(ctx, project, region, service)
+		if err != nil {
+			return err
+		}
+		domains, err := gcloud.DomainMappings(ctx, project, region, service)
+		if err != nil {
+			return err
+		}
+
+		return writeExport(func(w io.Writer) error {
+			return terraform.Write(w, terraform.Service{
+				Config:  cfg,
+				Domains: domains,
+				IAM:     bindings,
+				Project: project,
+				Region:  region,
+			})
+		})
+	},
+}
+
+var exportYAMLCmd = &cobra.Command{
+	Use:   "yaml",
+	Short: "Export the service as YAML for 'gcloud run services replace'",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		return writeExport(func(w io.Writer) error {
+			out, err := gcloud.Output(cmd.Context(), "gcloud", "run", "services", "describe", service,
+				"--platform=managed",
+				"--region="+region,
+				"--project="+project,
+				"--format=export")
+			if err != nil {
+				return errors.Wrap(err, "failed to export service")
+			}
+			_, err = w.Write(out)
+			return errors.WithStack(err)
+		})
+	},
+}
+
+// writeExport runs write against --file, or stdout when unset.
+func writeExport(write func(w io.Writer) error) error {
+	if exportFile == "" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(exportFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return errors.WithStack(f.Close())
+}
+
+func init() {
+	exportCmd.PersistentFlags().StringVarP(&exportFile, "file", "f", "", "file to write instead of stdout")
+	exportCmd.AddCommand(exportTerraformCmd, exportYAMLCmd)
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/housecat-inc/do/pkg/config"
+	"github.com/housecat-inc/do/pkg/golangci"
+	"github.com/housecat-inc/do/pkg/progress"
+	"github.com/pkg/errors"
+)
+
+// ensureGolangci checks that go.mod pins golangci-lint at the version from
+// lint.golangci and that the project has a configuration. Only with sync does it
+// write them: it pins the version, writes the managed .golangci.yml when there is
+// none, and rewrites a managed configuration that is out of date. A configuration
+// without golangci.Header is the project's own and is never written.
+func ensureGolangci(cfg config.LintGolangci, sync bool) error {
+	root, err := findProjectRoot()
+	if err != nil {
+		return err
+	}
+	if err := pinGolangci(root, cfg.Version, sync); err != nil {
+		return err
+	}
+
+	want, err := golangci.Config(golangci.Options{Disable: cfg.Disable, Enable: cfg.Enable})
+	if err != nil {
+		return errors.Wrap(err, "lint.golangci")
+	}
+	path, err := golangci.FindConfig(root)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		path = filepath.Join(root, golangci.ConfigFiles[0])
+		if !sync {
+			return errors.Errorf("no golangci-lint configuration; run go do lint --sync-config to create %s", path)
+		}
+		if err := os.WriteFile(path, want, 0644); err != nil {
+			return errors.WithStack(err)
+		}
+		fmt.Printf("Created %s\n", path)
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	switch {
+	case !golangci.Managed(data):
+		if len(cfg.Enable) > 0 || len(cfg.Disable) > 0 {
+			fmt.Fprintf(os.Stderr, "lint.golangci.enable and disable are ignored: %s is not managed by do\n", path)
+		} else if sync {
+			fmt.Printf("%s is not managed by do; leaving it as is\n", path)
+		}
+	case bytes.Equal(data, want):
+	case sync:
+		if err := os.WriteFile(path, want, 0644); err != nil {
+			return errors.WithStack(err)
+		}
+		fmt.Printf("Updated %s\n", path)
+	default:
+		fmt.Fprintf(os.Stderr, "%s is out of date; run go do lint --sync-config to update it\n", path)
+	}
+	return nil
+}
+
+// pinGolangci checks that go.mod declares golangci-lint as a tool at version, or at
+// golangci.Version when version is empty and go.mod does not declare it yet. With
+// sync it runs go get -tool to pin it; otherwise it fails with that command.
+func pinGolangci(root, version string, sync bool) error {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	have, err := golangci.ModVersion(data)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		if have != "" {
+			return nil
+		}
+		version = golangci.Version
+	}
+	want, err := golangci.NormalizeVersion(version)
+	if err != nil {
+		return errors.Wrap(err, "lint.golangci.version")
+	}
+	if have == want {
+		return nil
+	}
+
+	command := "go get -tool " + golangci.Tool + "@" + want
+	if !sync {
+		return errors.Errorf("golangci-lint %s is not pinned in go.mod; run %s or go do lint --sync-config", want, command)
+	}
+	progress.Echo(command)
+	get := exec.Command("go", "get", "-tool", golangci.Tool+"@"+want)
+	get.Dir = root
+	get.Stdout = os.Stdout
+	get.Stderr = os.Stderr
+	if err := get.Run(); err != nil {
+		return errors.Wrap(err, "install golangci-lint")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/config"
+	"github.com/housecat-inc/do/pkg/golangci"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureGolangciNoSync(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	mod := []byte("module example.com/app\n\ngo 1.24\n")
+	r.NoError(os.WriteFile("go.mod", mod, 0644))
+
+	err := ensureGolangci(config.LintGolangci{}, false)
+	r.Error(err)
+	a.Contains(err.Error(), "go get -tool "+golangci.Tool+"@v"+golangci.Version)
+	data, err := os.ReadFile("go.mod")
+	r.NoError(err)
+	a.Equal(mod, data)
+
+	mod = append(mod, []byte("\ntool "+golangci.Tool+"\n\nrequire "+golangci.Module+" v"+golangci.Version+"\n")...)
+	r.NoError(os.WriteFile("go.mod", mod, 0644))
+	err = ensureGolangci(config.LintGolangci{}, false)
+	r.Error(err)
+	a.Contains(err.Error(), "--sync-config")
+	a.NoFileExists(golangci.ConfigFiles[0])
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var jobPath string
+var jobEnv []string
+var jobMemory string
+var jobCPU string
+var jobTasks int
+var jobMaxRetries int
+var jobRemote bool
+var jobWait bool
+var jobLogsTail bool
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Deploy and run Cloud Run jobs",
+	Long: `Deploy batch workers built in this repo as Cloud Run jobs.
+
+  go do jobs deploy worker --path=./cmd/worker
+  go do jobs run worker --wait
+  go do jobs logs worker`,
+}
+
+var jobsDeployCmd = &cobra.Command{
+	Use:   "deploy <job>",
+	Short: "Build and create or update a Cloud Run job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		job := args[0]
+
+		env, err := parseEnvFlags(jobEnv)
+		if err != nil {
+			return err
+		}
+
+		opts := gcloud.JobOptions{CPU: jobCPU, Env: env, Memory: jobMemory, Tasks: jobTasks}
+		if cmd.Flags().Changed("max-retries") {
+			opts.MaxRetries = &jobMaxRetries
+		}
+
+		buildPath := jobPath
+		if buildPath == "" {
+			buildPath = "./cmd/" + job
+			if _, err := os.Stat(buildPath); err != nil {
+				return errors.Errorf("no main package at %s. Use --path to set the package to build", buildPath)
+			}
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := checkBuilder(cfg.Builder); err != nil {
+			return err
+		}
+		if err := checkDeployTools(cfg.Builder, jobRemote); err != nil {
+			return err
+		}
+
+		project, region, err := jobTarget()
+		if err != nil {
+			return err
+		}
+
+		image, err := buildImage(ctx, project, job, buildPath, cfg.Builder, jobRemote)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\nDeploying Cloud Run job '%s'...\n", job)
+		if err := gcloud.DeployJob(ctx, project, region, job, image, opts); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nJob deployed. Run it with: go do jobs run %s\n", job)
+		return nil
+	},
+}
+
+var jobsRunCmd = &cobra.Command{
+	Use:   "run <job>",
+	Short: "Execute a Cloud Run job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, region, err := jobTarget()
+		if err != nil {
+			return err
+		}
+		return gcloud.RunJob(ctx, project, region, args[0], jobWait)
+	},
+}
+
+var jobsLogsCmd = &cobra.Command{
+	Use:   "logs <job>",
+	Short: "View logs from a Cloud Run job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, region, err := jobTarget()
+		if err != nil {
+			return err
+		}
+
+		action := "read"
+		if jobLogsTail {
+			action = "tail"
+		}
+
+		return gcloud.Attach(cmd.Context(), "gcloud", "beta", "run", "jobs", "logs", action, args[0],
+			"--project="+project,
+			"--region="+region)
+	},
+}
+
+// jobTarget returns the project and region jobs are deployed to.
+func jobTarget() (string, string, error) {
+	project := os.Getenv("CLOUDSDK_CORE_PROJECT")
+	region := os.Getenv("CLOUDSDK_RUN_REGION")
+
+	if project == "" || region == "" {
+		return "", "", errors.New("no project configured. Run 'go do deploy' first")
+	}
+	return project, region, nil
+}
+
+func init() {
+	jobsDeployCmd.Flags().StringVar(&jobPath, "path", "", "main package to build (default ./cmd/<job>)")
+	jobsDeployCmd.Flags().StringArrayVar(&jobEnv, "set-env", nil, "set a runtime env var as KEY=VALUE (repeatable)")
+	jobsDeployCmd.Flags().StringVar(&jobMemory, "memory", "", "memory limit per task (e.g. 512Mi, 1Gi)")
+	jobsDeployCmd.Flags().StringVar(&jobCPU, "cpu", "", "CPU limit per task (e.g. 1, 2)")
+	jobsDeployCmd.Flags().IntVar(&jobTasks, "tasks", 0, "number of tasks per execution")
+	jobsDeployCmd.Flags().IntVar(&jobMaxRetries, "max-retries", 0, "retries per failed task")
+	jobsDeployCmd.Flags().BoolVar(&jobRemote, "remote", false, "build on Google Cloud Build instead of locally")
+	jobsRunCmd.Flags().BoolVarP(&jobWait, "wait", "w", false, "wait for the execution to finish")
+	jobsLogsCmd.Flags().BoolVarP(&jobLogsTail, "tail", "t", false, "Tail logs in real-time")
+
+	jobsCmd.AddCommand(jobsDeployCmd, jobsRunCmd, jobsLogsCmd)
+	rootCmd.AddCommand(jobsCmd)
+}
@@ -1,58 +1,218 @@
 package cmd
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"go/ast"
+	"go/token"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/housecat-inc/do/pkg/analysis/httphandler"
+	"github.com/housecat-inc/do/pkg/analysis/imports"
 	"github.com/housecat-inc/do/pkg/analysis/nocomments"
+	"github.com/housecat-inc/do/pkg/analysis/nolint"
 	"github.com/housecat-inc/do/pkg/analysis/pkgerrors"
+	"github.com/housecat-inc/do/pkg/analysis/rules"
+	"github.com/housecat-inc/do/pkg/analysis/testhygiene"
+	"github.com/housecat-inc/do/pkg/analysis/todos"
+	"github.com/housecat-inc/do/pkg/config"
+	"github.com/housecat-inc/do/pkg/github"
+	"github.com/housecat-inc/do/pkg/sarif"
+	"github.com/housecat-inc/do/pkg/svelte"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
 	"golang.org/x/tools/go/packages"
 )
 
+var lintBase string
+var lintBaseline string
+var lintChanged bool
 var listAnalyzers bool
+var lintFix bool
+var lintReview bool
+var lintSARIF string
+var lintSyncConfig bool
+var lintTodos bool
 
 var lintCmd = &cobra.Command{
 	Use:   "lint",
 	Short: "Run linters on the project",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		analyzers := []*doanalysis.Analyzer{pkgerrors.Analyzer, nocomments.Analyzer}
-
 		if listAnalyzers {
-			for _, a := range analyzers {
-				fmt.Printf("%s: %s\n", a.Name, a.Doc)
+			var disabled []string
+			if cfg, err := loadConfig(); err == nil {
+				enabled, _ := lintAnalyzers(cfg.Lint)
+				for _, a := range allAnalyzers {
+					if !slices.Contains(enabled, a) {
+						disabled = append(disabled, a.Name)
+					}
+				}
+			}
+			for _, a := range allAnalyzers {
+				if slices.Contains(disabled, a.Name) {
+					fmt.Printf("%s (disabled): %s\n", a.Name, a.Doc)
+				} else {
+					fmt.Printf("%s: %s\n", a.Name, a.Doc)
+				}
 				for _, msg := range a.Messages {
-					fmt.Printf("  - %s\n", msg)
+					if a.Severity(string(msg)) == doanalysis.SeverityWarning {
+						fmt.Printf("  - %s (warning)\n", msg)
+					} else {
+						fmt.Printf("  - %s\n", msg)
+					}
 				}
+				a.Flags.VisitAll(func(f *flag.Flag) {
+					fmt.Printf("  option %s: %s\n", f.Name, f.Usage)
+				})
 			}
 			return nil
 		}
 
-		if err := ensureLintConfig(); err != nil {
+		cfg, err := loadConfig()
+		if err != nil {
 			return err
 		}
+		analyzers, err := lintAnalyzers(cfg.Lint)
+		if err != nil {
+			return err
+		}
+
+		if lintTodos {
+			return listTodos()
+		}
+
+		if err := ensureGolangci(cfg.Lint.Golangci, lintSyncConfig); err != nil {
+			return err
+		}
+		if lintSyncConfig {
+			return nil
+		}
 
 		var hasErrors bool
 
-		// Run golangci-lint via go tool (requires tool directive in go.mod)
-		golangci := exec.Command("go", "tool", "golangci-lint", "run", "./...")
-		golangci.Stdout = os.Stdout
-		golangci.Stderr = os.Stderr
-		if err := golangci.Run(); err != nil {
-			hasErrors = true
+		// A review posts only what the pull request introduces, so it lints the
+		// packages it changed, as --changed does
+		if lintReview {
+			lintBase = reviewBase(lintBase, cmd.Flags().Changed("base"))
+			lintChanged = true
 		}
 
-		// Run custom analyzers
-		if issues := runAnalyzers("./...", analyzers); issues > 0 {
+		patterns := []string{"./..."}
+		var changed map[string]bool
+		var since string
+		if lintChanged {
+			if since, err = mergeBase(lintBase); err != nil {
+				return err
+			}
+			if changed, patterns, err = changedPackages(since); err != nil {
+				return err
+			}
+			if len(patterns) == 0 {
+				fmt.Fprintf(os.Stderr, "No Go files changed since %s\n", lintBase)
+			}
+		}
+
+		// Run custom analyzers first, so golangci-lint sees the code they fix
+		var diags []lintDiagnostic
+		if len(patterns) > 0 {
+			if diags, err = collectDiagnostics(patterns, analyzers, cfg.Lint.Exclude); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				hasErrors = true
+			}
+		}
+		if len(patterns) > 0 && len(cfg.Lint.Vettools) > 0 {
+			vetDiags, err := runVettools(cfg.Lint.Vettools, patterns, cfg.Lint.Exclude)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				hasErrors = true
+			}
+			diags = append(diags, vetDiags...)
+		}
+
+		// Run golangci-lint via go tool, at the version pinned in go.mod, keeping
+		// its findings as JSON too for reports and the baseline
+		report := jsonOutput() || outputFormat == outputSARIF || outputFormat == outputGitHub || lintSARIF != "" || lintReview
+		baselined, err := usesBaseline()
+		if err != nil {
+			return err
+		}
+		var golangciDiags []lintDiagnostic
+		var golangciErr error
+		if len(patterns) > 0 {
+			golangciDiags, golangciErr = runGolangci(report, baselined, patterns, since)
+			if golangciErr != nil {
+				hasErrors = true
+			}
+		}
+
+		// Then check Svelte components
+		svelteDiags, err := checkSvelte()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			hasErrors = true
+		}
+		analyzerDiags := applySeverity(cfg.Lint.Severity, diags)
+		if changed != nil {
+			analyzerDiags, svelteDiags = filterChanged(changed, analyzerDiags, svelteDiags)
+		}
+		if analyzerDiags, golangciDiags, svelteDiags, err = applyBaseline(analyzerDiags, golangciDiags, svelteDiags); err != nil {
+			return err
+		}
+		diags = append(slices.Clone(analyzerDiags), svelteLintDiagnostics(svelteDiags)...)
+		if lintFailed(diags) || (baselined && len(golangciDiags) > 0) {
 			hasErrors = true
 		}
+		if baselined {
+			// golangci-lint printed nothing, so its findings left after the baseline
+			// are printed with the others
+			printLintDiagnostics(append(slices.Clone(diags), golangciDiags...))
+		} else {
+			printLintDiagnostics(diags)
+		}
+
+		if report {
+			r := lintReport{Analyzers: analyzerDiags, Golangci: golangciDiags, GolangciOK: golangciErr == nil, Svelte: svelteDiags}
+			if lintSARIF != "" {
+				if err := writeLintSARIF(lintSARIF, r); err != nil {
+					return err
+				}
+			}
+			switch {
+			case jsonOutput():
+				if err := printLintJSON(r); err != nil {
+					return err
+				}
+			case outputFormat == outputSARIF:
+				root, err := findProjectRoot()
+				if err != nil {
+					return err
+				}
+				if err := r.writeSARIF(jsonStdout, root); err != nil {
+					return err
+				}
+			case outputFormat == outputGitHub:
+				if err := r.writeAnnotations(os.Stdout); err != nil {
+					return err
+				}
+			}
+		}
+
+		if lintReview {
+			if err := postLintReview(since, append(slices.Clone(diags), golangciDiags...)); err != nil {
+				return errors.Wrap(err, "lint review")
+			}
+		}
 
 		if hasErrors {
 			os.Exit(1)
@@ -61,94 +221,711 @@ var lintCmd = &cobra.Command{
 	},
 }
 
-func runAnalyzers(pattern string, analyzers []*doanalysis.Analyzer) int {
-	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+// changedPackages returns the files changed since the merge base commit, committed
+// or not, as absolute paths, and the directories of the Go packages
+// under the working directory among them as package patterns.
+func changedPackages(mergeBase string) (map[string]bool, []string, error) {
+	root, err := gitRoot()
+	if err != nil {
+		return nil, nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if cwd, err = filepath.EvalSymlinks(cwd); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	files, err := changedFiles(mergeBase)
+	if err != nil {
+		return nil, nil, err
+	}
+	changed := make(map[string]bool, len(files))
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		changed[path] = true
+		if !strings.HasSuffix(path, ".go") || slices.Contains(strings.Split(f, "/"), "testdata") {
+			continue
+		}
+		rel, err := filepath.Rel(cwd, filepath.Dir(path))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			dirs["."] = true
+		} else {
+			dirs["./"+filepath.ToSlash(rel)] = true
+		}
+	}
+	return changed, slices.Sorted(maps.Keys(dirs)), nil
+}
+
+// filterChanged keeps the analyzer and Svelte diagnostics in changed files.
+func filterChanged(changed map[string]bool, analyzerDiags []lintDiagnostic, svelteDiags []svelte.Diagnostic) ([]lintDiagnostic, []svelte.Diagnostic) {
+	isChanged := func(path string) bool {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			path = real
+		}
+		abs, err := filepath.Abs(path)
+		return err == nil && changed[abs]
+	}
+	var keptAnalyzers []lintDiagnostic
+	for _, d := range analyzerDiags {
+		if isChanged(d.Pos.Filename) {
+			keptAnalyzers = append(keptAnalyzers, d)
+		}
+	}
+	var keptSvelte []svelte.Diagnostic
+	for _, d := range svelteDiags {
+		if isChanged(d.Filename) {
+			keptSvelte = append(keptSvelte, d)
+		}
+	}
+	return keptAnalyzers, keptSvelte
+}
+
+// usesBaseline reports whether --baseline writes the baseline or the run skips the
+// findings a baseline file records, in which case lint reads golangci-lint's findings
+// instead of letting it print them.
+func usesBaseline() (bool, error) {
+	switch lintBaseline {
+	case baselineIgnore:
+		return false, nil
+	case baselineWrite:
+		return true, nil
+	case "":
+	default:
+		return false, errors.Errorf("--baseline must be %q or %q", baselineWrite, baselineIgnore)
 	}
+	root, err := findProjectRoot()
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(filepath.Join(root, baselineFile)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
 
-	pkgs, err := packages.Load(cfg, pattern)
+// applyBaseline handles --baseline, already checked by usesBaseline: it records the
+// diagnostics with write, leaving none to report, and otherwise removes those the
+// baseline file records, if any.
+func applyBaseline(analyzerDiags, golangciDiags []lintDiagnostic, svelteDiags []svelte.Diagnostic) ([]lintDiagnostic, []lintDiagnostic, []svelte.Diagnostic, error) {
+	root, err := findProjectRoot()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to load packages: %v\n", err)
-		return 1
+		return nil, nil, nil, err
+	}
+	switch lintBaseline {
+	case baselineIgnore:
+		return analyzerDiags, golangciDiags, svelteDiags, nil
+	case baselineWrite:
+		diags := append(append(slices.Clone(analyzerDiags), golangciDiags...), svelteLintDiagnostics(svelteDiags)...)
+		if err := writeBaseline(root, diags); err != nil {
+			return nil, nil, nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Recorded %s in %s\n", plural(len(diags), "finding"), baselineFile)
+		return nil, nil, nil, nil
 	}
 
-	var issues int
-	for _, pkg := range pkgs {
-		files := filterGenerated(pkg.Syntax)
-		if len(files) == 0 {
-			continue
+	idx, err := loadBaseline(root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	analyzerDiags, golangciDiags, svelteDiags = filterBaseline(idx, analyzerDiags, golangciDiags, svelteDiags)
+	if idx != nil && idx.Skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %s recorded in %s\n", plural(idx.Skipped, "finding"), baselineFile)
+	}
+	return analyzerDiags, golangciDiags, svelteDiags, nil
+}
+
+// allAnalyzers are the custom analyzers do lint can run.
+var allAnalyzers = []*doanalysis.Analyzer{
+	pkgerrors.Analyzer,
+	nocomments.Analyzer,
+	httphandler.Analyzer,
+	testhygiene.Analyzer,
+	todos.Analyzer,
+	rules.Analyzer,
+	imports.Analyzer,
+	nolint.New(pkgerrors.Analyzer, nocomments.Analyzer, httphandler.Analyzer, testhygiene.Analyzer, todos.Analyzer, rules.Analyzer, imports.Analyzer),
+}
+
+// lintAnalyzers returns the analyzers enabled by the lint section of the config,
+// with its settings applied.
+func lintAnalyzers(lint config.Lint) ([]*doanalysis.Analyzer, error) {
+	analyzers, err := doanalysis.Select(allAnalyzers, lint.Enable, lint.Disable)
+	if err != nil {
+		return nil, errors.Wrap(err, "lint")
+	}
+	for _, name := range slices.Sorted(maps.Keys(lint.Settings)) {
+		i := slices.IndexFunc(allAnalyzers, func(a *doanalysis.Analyzer) bool { return a.Name == name })
+		if i < 0 {
+			return nil, errors.Errorf("lint.settings: unknown analyzer %q", name)
+		}
+		if err := allAnalyzers[i].Configure(lint.Settings[name]); err != nil {
+			return nil, errors.Wrap(err, "lint.settings")
+		}
+	}
+	for name, severity := range lint.Severity {
+		if severity != string(doanalysis.SeverityError) && severity != string(doanalysis.SeverityWarning) {
+			return nil, errors.Errorf("lint.severity: %s is %q, not error or warning", name, severity)
+		}
+	}
+	if len(lint.Imports) > 0 {
+		fs := make([]imports.Forbidden, len(lint.Imports))
+		for i, imp := range lint.Imports {
+			fs[i] = imports.Forbidden{Allow: imp.Allow, Message: imp.Message, Path: imp.Path}
+		}
+		if err := setJSONOption(imports.Analyzer, "forbid", fs); err != nil {
+			return nil, errors.Wrap(err, "lint.imports")
+		}
+	}
+	if len(lint.Rules) > 0 {
+		rs := make([]rules.Rule, len(lint.Rules))
+		for i, r := range lint.Rules {
+			rs[i] = rules.Rule{Call: r.Call, Message: r.Message}
 		}
+		if err := setJSONOption(rules.Analyzer, "rules", rs); err != nil {
+			return nil, errors.Wrap(err, "lint.rules")
+		}
+	}
+	return analyzers, nil
+}
 
-		for _, a := range analyzers {
-			pass := &analysis.Pass{
-				Analyzer:  a.Analyzer,
-				Fset:      pkg.Fset,
-				Files:     files,
-				Pkg:       pkg.Types,
-				TypesInfo: pkg.TypesInfo,
-				Report: func(d analysis.Diagnostic) {
-					pos := pkg.Fset.Position(d.Pos)
-					fmt.Fprintf(os.Stderr, "%s: %s (%s)\n", pos, d.Message, a.Name)
-					issues++
-				},
-			}
-			_, _ = a.Run(pass)
+// applySeverity sets the severity of diags from lint.severity, by analyzer, then by
+// the vettool of analyzers named <tool>/<analyzer>, then by *.
+func applySeverity(severity map[string]string, diags []lintDiagnostic) []lintDiagnostic {
+	if len(severity) == 0 {
+		return diags
+	}
+	out := make([]lintDiagnostic, len(diags))
+	for i, d := range diags {
+		s, ok := severity[d.Analyzer]
+		if tool, _, found := strings.Cut(d.Analyzer, "/"); !ok && found {
+			s, ok = severity[tool]
+		}
+		if !ok {
+			s, ok = severity["*"]
+		}
+		if ok {
+			d.Warning = s == string(doanalysis.SeverityWarning)
+		}
+		out[i] = d
+	}
+	return out
+}
+
+// setJSONOption sets an option of a holding structured config from do.yaml as JSON.
+func setJSONOption(a *doanalysis.Analyzer, option string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return a.Flags.Set(option, string(data))
+}
+
+// lintExcluded reports whether the file at path, relative to the project root, is
+// excluded from analyzer by the lint.exclude patterns.
+func lintExcluded(exclude map[string][]string, analyzer, path string) (bool, error) {
+	for _, pattern := range append(slices.Clone(exclude["*"]), exclude[analyzer]...) {
+		ok, err := svelte.MatchPath(pattern, path)
+		if err != nil {
+			return false, errors.Wrap(err, "lint.exclude")
+		}
+		if ok {
+			return true, nil
 		}
 	}
-	return issues
+	return false, nil
 }
 
-func filterGenerated(files []*ast.File) []*ast.File {
-	var result []*ast.File
+// lintDiagnostic is a custom analyzer or Svelte check finding.
+type lintDiagnostic struct {
+	Analyzer string
+	Message  string
+	Pos      token.Position
+	// Warning marks findings that are reported but do not fail the lint.
+	Warning bool
+}
+
+func (d lintDiagnostic) severity() string {
+	if d.Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// lintFailed reports whether any diagnostic is an error.
+func lintFailed(diags []lintDiagnostic) bool {
+	for _, d := range diags {
+		if !d.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// printLintDiagnostics writes each diagnostic to stderr, then a summary per file.
+func printLintDiagnostics(diags []lintDiagnostic) {
+	type counts struct{ errors, warnings int }
+	var files []string
+	perFile := make(map[string]*counts)
+	for _, d := range diags {
+		fmt.Fprintf(os.Stderr, "%s: %s: %s (%s)\n", d.Pos, d.severity(), d.Message, d.Analyzer)
+		c, ok := perFile[d.Pos.Filename]
+		if !ok {
+			c = &counts{}
+			perFile[d.Pos.Filename] = c
+			files = append(files, d.Pos.Filename)
+		}
+		if d.Warning {
+			c.warnings++
+		} else {
+			c.errors++
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	sort.Strings(files)
+	fmt.Fprintln(os.Stderr)
 	for _, f := range files {
-		if !isGenerated(f) {
-			result = append(result, f)
+		c := perFile[f]
+		fmt.Fprintf(os.Stderr, "%s: %s, %s\n", f, plural(c.errors, "error"), plural(c.warnings, "warning"))
+	}
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// checkSvelte checks the project's .svelte files with the ignore and error codes
+// from the svelte section of the config.
+func checkSvelte() ([]svelte.Diagnostic, error) {
+	root, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := svelte.UseVersion(cfg.Svelte.Version); err != nil {
+		return nil, err
+	}
+	svelte.CacheDir = filepath.Join(root, ".do", "cache", "svelte")
+
+	return svelte.CheckDir(root, svelte.CheckOptions{Errors: cfg.Svelte.Errors, Fix: lintFix, Ignore: cfg.Svelte.Ignore})
+}
+
+func svelteLintDiagnostics(found []svelte.Diagnostic) []lintDiagnostic {
+	diags := make([]lintDiagnostic, len(found))
+	for i, d := range found {
+		pos := token.Position{Filename: d.Filename}
+		if d.Start != nil {
+			// Svelte columns are 0-based; token.Position columns are 1-based.
+			pos.Line, pos.Column = d.Start.Line, d.Start.Column+1
+		}
+		diags[i] = lintDiagnostic{
+			Analyzer: "svelte/" + d.Code,
+			Message:  d.Message,
+			Pos:      pos,
+			Warning:  d.Type == "warning",
 		}
 	}
-	return result
+	return diags
 }
 
-func isGenerated(file *ast.File) bool {
-	for _, cg := range file.Comments {
-		for _, c := range cg.List {
-			if strings.Contains(c.Text, "Code generated") && strings.Contains(c.Text, "DO NOT EDIT") {
-				return true
+// lintReport is every finding of a lint run, for --output=json, --output=sarif,
+// --output=github, and --sarif.
+type lintReport struct {
+	Analyzers  []lintDiagnostic
+	Golangci   []lintDiagnostic
+	GolangciOK bool
+	Svelte     []svelte.Diagnostic
+}
+
+// writeSARIF writes r as a SARIF log with a run each for the custom analyzers,
+// golangci-lint, and Svelte, with file paths relative to root.
+func (r lintReport) writeSARIF(w io.Writer, root string) error {
+	newRun := func(name, uri string, diags []lintDiagnostic, rule func(string) string) sarif.Run {
+		run := sarif.NewRun(name, uri, "")
+		for _, d := range diags {
+			id := rule(d.Analyzer)
+			run.AddRule(sarif.Rule{ID: id})
+			var region *sarif.Region
+			if d.Pos.Line > 0 {
+				region = &sarif.Region{StartColumn: max(d.Pos.Column, 1), StartLine: d.Pos.Line}
 			}
+			run.Add(d.severity(), id, d.Message, d.Pos.Filename, root, region)
 		}
+		return run
 	}
-	return false
+	return sarif.Write(w,
+		newRun("do", "https://github.com/housecat-inc/do", r.Analyzers, func(id string) string { return id }),
+		newRun("golangci-lint", "https://golangci-lint.run", r.Golangci, func(id string) string { return strings.TrimPrefix(id, "golangci-lint/") }),
+		svelte.SARIFRun(r.Svelte, root),
+	)
+}
+
+// writeAnnotations writes r as GitHub workflow commands, which annotate the lines of
+// the findings on the run and its pull request, with file paths relative to the
+// repository root.
+func (r lintReport) writeAnnotations(w io.Writer) error {
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		return err
+	}
+	root, err := gitRoot()
+	if err != nil {
+		root = projectRoot
+	}
+	diags := append(append(slices.Clone(r.Analyzers), r.Golangci...), svelteLintDiagnostics(r.Svelte)...)
+	for _, d := range diags {
+		file := d.Pos.Filename
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(projectRoot, file)
+		}
+		if rel, err := filepath.Rel(root, file); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+		a := github.Annotation{Column: d.Pos.Column, File: file, Level: d.severity(), Line: d.Pos.Line, Message: d.Message, Title: d.Analyzer}
+		if err := github.WriteAnnotation(w, a); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func ensureLintConfig() error {
-	// Find project root (where go.mod is)
+// writeLintSARIF writes r to path as SARIF for GitHub code scanning, with file
+// paths relative to the project root.
+func writeLintSARIF(path string, r lintReport) error {
 	root, err := findProjectRoot()
 	if err != nil {
 		return err
 	}
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := r.writeSARIF(f, root); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return errors.WithStack(f.Close())
+}
 
-	configFile := filepath.Join(root, ".golangci.yml")
+// printLintJSON writes the analyzer, golangci-lint, and Svelte diagnostics and
+// whether golangci-lint passed.
+func printLintJSON(r lintReport) error {
+	type diagnosticJSON struct {
+		Analyzer string `json:"analyzer"`
+		Column   int    `json:"column"`
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+		Message  string `json:"message"`
+		Severity string `json:"severity"`
+	}
+	diags := append(append(slices.Clone(r.Analyzers), r.Golangci...), svelteLintDiagnostics(r.Svelte)...)
+	result := struct {
+		Diagnostics  []diagnosticJSON `json:"diagnostics"`
+		GolangciLint bool             `json:"golangci_lint_ok"`
+		OK           bool             `json:"ok"`
+	}{Diagnostics: []diagnosticJSON{}, GolangciLint: r.GolangciOK, OK: r.GolangciOK && !lintFailed(diags)}
+	for _, d := range diags {
+		result.Diagnostics = append(result.Diagnostics, diagnosticJSON{
+			Analyzer: d.Analyzer,
+			Column:   d.Pos.Column,
+			File:     d.Pos.Filename,
+			Line:     d.Pos.Line,
+			Message:  d.Message,
+			Severity: d.severity(),
+		})
+	}
+	return printJSON(result)
+}
 
-	if _, err := os.Stat(configFile); err == nil {
-		return nil
+// golangciIssuesFound is the status golangci-lint exits with when it reports issues.
+const golangciIssuesFound = 1
+
+// runGolangci runs golangci-lint on the packages matching patterns with its text
+// output on stdout. With report set, it also returns its findings, read from its
+// JSON output. With since set, it reports only issues in code changed since that
+// commit. With baselined set, it returns its findings without printing them, and
+// finding issues is not an error, since the caller reports what the baseline
+// leaves.
+func runGolangci(report, baselined bool, patterns []string, since string) ([]lintDiagnostic, error) {
+	args := []string{"tool", "golangci-lint", "run"}
+	if since != "" {
+		args = append(args, "--new-from-rev="+since)
+	}
+	var jsonPath string
+	if report || baselined {
+		f, err := os.CreateTemp("", "golangci-*.json")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		jsonPath = f.Name()
+		_ = f.Close()
+		defer func() { _ = os.Remove(jsonPath) }()
+		if !baselined {
+			args = append(args, "--output.text.path=stdout")
+		}
+		args = append(args, "--output.json.path="+jsonPath)
+	}
+	golangci := exec.Command("go", append(args, patterns...)...)
+	golangci.Stdout = os.Stdout
+	golangci.Stderr = os.Stderr
+	runErr := golangci.Run()
+	if jsonPath == "" {
+		return nil, runErr
 	}
 
-	const defaultConfig = `version: "2"
+	root, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	diags, err := golangciDiagnostics(jsonPath, root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "golangci-lint: %v\n", err)
+		return diags, runErr
+	}
+	var exit *exec.ExitError
+	if baselined && errors.As(runErr, &exit) && exit.ExitCode() == golangciIssuesFound {
+		return diags, nil
+	}
+	return diags, runErr
+}
 
-linters:
-  enable:
-    - errcheck
-    - govet
-    - ineffassign
-    - staticcheck
-    - unused
-`
+// golangciDiagnostics reads the issues golangci-lint wrote to path with
+// --output.json.path. Their file names are relative to root, where .golangci.yml is.
+func golangciDiagnostics(path, root string) ([]lintDiagnostic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil, errors.WithStack(err)
+	}
+	var out struct {
+		Issues []struct {
+			FromLinter string
+			Pos        struct {
+				Column   int
+				Filename string
+				Line     int
+			}
+			Severity string
+			Text     string
+		}
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, errors.Wrap(err, "parse JSON output")
+	}
+	var diags []lintDiagnostic
+	for _, issue := range out.Issues {
+		pos := token.Position{Column: issue.Pos.Column, Filename: issue.Pos.Filename, Line: issue.Pos.Line}
+		if !filepath.IsAbs(pos.Filename) {
+			pos.Filename = filepath.Join(root, pos.Filename)
+		}
+		diags = append(diags, lintDiagnostic{
+			Analyzer: "golangci-lint/" + issue.FromLinter,
+			Message:  issue.Text,
+			Pos:      pos,
+			Warning:  issue.Severity == "warning",
+		})
+	}
+	return diags, nil
+}
 
-	if err := os.WriteFile(configFile, []byte(defaultConfig), 0644); err != nil {
-		return errors.WithStack(err)
+// collectDiagnostics runs the analyzers on the packages matching patterns. With --fix,
+// it applies their suggested fixes and runs them again to report what remains.
+func collectDiagnostics(patterns []string, analyzers []*doanalysis.Analyzer, exclude map[string][]string) ([]lintDiagnostic, error) {
+	diags, fset, fixes, err := runAnalyzers(patterns, analyzers, exclude)
+	if err != nil || !lintFix || len(fixes) == 0 {
+		return diags, err
+	}
+	files, err := doanalysis.ApplyFixes(fset, fixes)
+	if err != nil {
+		return nil, err
 	}
+	if len(files) == 0 {
+		return diags, nil
+	}
+	for name, src := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := os.WriteFile(name, src, info.Mode().Perm()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	diags, _, _, err = runAnalyzers(patterns, analyzers, exclude)
+	return diags, err
+}
 
-	fmt.Printf("Created %s with default configuration\n", configFile)
-	return nil
+// runAnalyzers runs the analyzers on the packages matching patterns that changed
+// since lintCache last saw them, loading those packages once and running them in
+// parallel through the standard checker, which provides the results of required
+// analyzers, such as inspect.Analyzer, and facts from dependencies. Findings in
+// generated files, excluded by lint.exclude, or suppressed by comments are dropped.
+func runAnalyzers(patterns []string, analyzers []*doanalysis.Analyzer, exclude map[string][]string) ([]lintDiagnostic, *token.FileSet, []analysis.SuggestedFix, error) {
+	root, err := findProjectRoot()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	cache, roots, err := newLintCache(filepath.Join(root, ".do", "cache", "lint"), patterns, analyzers)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var found []lintDiagnostic
+	var misses []string
+	for _, pkg := range roots {
+		if entry, ok := cache.get(pkg); ok && !(lintFix && entry.Fixable) {
+			found = append(found, entry.Diagnostics...)
+			continue
+		}
+		// A package and its test variant share a path.
+		if !slices.Contains(misses, pkg.PkgPath) {
+			misses = append(misses, pkg.PkgPath)
+		}
+	}
+
+	var fset *token.FileSet
+	var fixes []analysis.SuggestedFix
+	if len(misses) > 0 {
+		var entries map[string]*lintCacheEntry
+		if entries, fset, fixes, err = analyzePackages(misses, analyzers); err != nil {
+			return nil, nil, nil, err
+		}
+		for id, entry := range entries {
+			if !entry.failed {
+				cache.put(id, *entry)
+			}
+			found = append(found, entry.Diagnostics...)
+		}
+	}
+
+	var diags []lintDiagnostic
+	seen := make(map[lintDiagnostic]bool)
+	for _, d := range found {
+		// Test variants of a package report the findings in its other files again.
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		rel := d.Pos.Filename
+		if r, err := filepath.Rel(root, rel); err == nil {
+			rel = r
+		}
+		excluded, err := lintExcluded(exclude, d.Analyzer, filepath.ToSlash(rel))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !excluded {
+			diags = append(diags, d)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		x, y := diags[i].Pos, diags[j].Pos
+		if x.Filename != y.Filename {
+			return x.Filename < y.Filename
+		}
+		if x.Offset != y.Offset {
+			return x.Offset < y.Offset
+		}
+		return diags[i].Analyzer < diags[j].Analyzer
+	})
+	return diags, fset, fixes, nil
+}
+
+// analyzePackages loads the packages matching patterns, with their tests, and runs
+// the analyzers on them, returning what they found in each package by ID.
+func analyzePackages(patterns []string, analyzers []*doanalysis.Analyzer) (map[string]*lintCacheEntry, *token.FileSet, []analysis.SuggestedFix, error) {
+	byAnalyzer := make(map[*analysis.Analyzer]*doanalysis.Analyzer, len(analyzers))
+	var all []*analysis.Analyzer
+	for _, a := range analyzers {
+		byAnalyzer[a.Analyzer] = a
+		all = append(all, a.Analyzer)
+	}
+	// Dependencies are type-checked from source rather than export data, which lets
+	// analyzers export facts from them and does not depend on the toolchain's format.
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadAllSyntax | packages.NeedModule, Tests: true}, patterns...)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to load packages")
+	}
+	pkgs = slices.DeleteFunc(pkgs, isTestMain)
+	if len(pkgs) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	graph, err := checker.Analyze(all, pkgs, nil)
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	generated := make(map[string]bool)
+	sups := make(map[*packages.Package][]*doanalysis.Suppression)
+	entries := make(map[string]*lintCacheEntry, len(pkgs))
+	for _, pkg := range pkgs {
+		var files []*ast.File
+		for _, f := range pkg.Syntax {
+			if isGenerated(f) {
+				generated[pkg.Fset.File(f.Pos()).Name()] = true
+			} else {
+				files = append(files, f)
+			}
+		}
+		sups[pkg] = doanalysis.Suppressions(pkg.Fset, files)
+		entries[pkg.ID] = &lintCacheEntry{}
+	}
+
+	var fixes []analysis.SuggestedFix
+	for _, act := range graph.Roots {
+		a := byAnalyzer[act.Analyzer]
+		name := a.Name
+		if act.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s: %v\n", act.Package.PkgPath, name, act.Err)
+			entries[act.Package.ID].failed = true
+			continue
+		}
+		entry := entries[act.Package.ID]
+		for _, d := range act.Diagnostics {
+			pos := act.Package.Fset.Position(d.Pos)
+			if generated[pos.Filename] || doanalysis.Suppressed(sups[act.Package], name, pos) {
+				continue
+			}
+			if len(d.SuggestedFixes) > 0 {
+				fixes = append(fixes, d.SuggestedFixes...)
+				entry.Fixable = true
+			}
+			entry.Diagnostics = append(entry.Diagnostics, lintDiagnostic{
+				Analyzer: name,
+				Message:  d.Message,
+				Pos:      pos,
+				Warning:  a.Severity(d.Message) == doanalysis.SeverityWarning,
+			})
+		}
+	}
+	return entries, pkgs[0].Fset, fixes, nil
+}
+
+func isGenerated(file *ast.File) bool {
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			if strings.Contains(c.Text, "Code generated") && strings.Contains(c.Text, "DO NOT EDIT") {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func findProjectRoot() (string, error) {
@@ -170,6 +947,14 @@ func findProjectRoot() (string, error) {
 }
 
 func init() {
+	lintCmd.Flags().StringVar(&lintBaseline, "baseline", "", "write to record current analyzer, golangci-lint, and Svelte findings in .do/lint-baseline.json, which later runs skip, or ignore to report them anyway")
+	lintCmd.Flags().StringVar(&lintBase, "base", "main", "branch --changed compares against")
+	lintCmd.Flags().BoolVar(&lintChanged, "changed", false, "lint only the packages with files changed since the branch left --base, reporting only findings in those files")
+	lintCmd.Flags().BoolVar(&lintFix, "fix", false, "apply suggested fixes: use github.com/pkg/errors, remove disallowed comments, and remove unused CSS selectors and redundant ARIA roles from .svelte files")
 	lintCmd.Flags().BoolVarP(&listAnalyzers, "list", "l", false, "list custom analyzers and their descriptions")
+	lintCmd.Flags().BoolVar(&lintReview, "review", false, "post issues on lines the PR changed as inline GitHub review comments, linting as --changed does against the PR's base branch (requires GITHUB_TOKEN in CI)")
+	lintCmd.Flags().BoolVar(&lintSyncConfig, "sync-config", false, "update the .golangci.yml do manages and the golangci-lint version in go.mod from do.yaml, then exit")
+	lintCmd.Flags().BoolVar(&lintTodos, "todos", false, "list the TODO and FIXME comments in Go files with their locations and issues instead of linting")
+	lintCmd.Flags().StringVar(&lintSARIF, "sarif", "", "also write the analyzer, golangci-lint, and Svelte diagnostics to this file as SARIF for GitHub code scanning")
 	rootCmd.AddCommand(lintCmd)
 }
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// lintCache keeps the diagnostics of each package under a key hashing the do binary,
+// the analyzers and their options, the package's files, and the keys of the packages
+// it imports, as go vet does, so only packages that changed or depend on a change
+// are analyzed again.
+type lintCache struct {
+	dir  string
+	keys map[string]string
+}
+
+// lintCacheEntry is what the analyzers found in a package, after dropping findings
+// in generated files and suppressed by comments; lint.exclude is applied on reading.
+type lintCacheEntry struct {
+	Diagnostics []lintDiagnostic `json:"diagnostics"`
+	// Fixable is set when a diagnostic suggested a fix, which --fix needs the
+	// analyzers to compute again.
+	Fixable bool `json:"fixable"`
+	// failed is set when an analyzer returned an error, so the entry is incomplete
+	// and not cached.
+	failed bool
+}
+
+// newLintCache loads the metadata of the packages matching patterns and their tests,
+// without parsing or type-checking them, and returns the cache in dir with their keys
+// and the packages themselves.
+func newLintCache(dir string, patterns []string, analyzers []*doanalysis.Analyzer) (*lintCache, []*packages.Package, error) {
+	mode := packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule
+	pkgs, err := packages.Load(&packages.Config{Mode: mode, Tests: true}, patterns...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load packages")
+	}
+	pkgs = slices.DeleteFunc(pkgs, isTestMain)
+
+	c := &lintCache{dir: dir, keys: make(map[string]string)}
+	base, err := analyzersKey(analyzers)
+	if err != nil {
+		// Without the binary's hash any entry could be stale, so skip caching.
+		c.dir = ""
+		return c, pkgs, nil
+	}
+	files := make(map[string]string)
+	var key func(pkg *packages.Package) string
+	key = func(pkg *packages.Package) string {
+		if k, ok := c.keys[pkg.ID]; ok {
+			return k
+		}
+		parts := []string{base, pkg.ID}
+		if m := pkg.Module; m != nil && m.Version != "" && m.Replace == nil {
+			// Module versions are immutable, so the version identifies the source.
+			parts = append(parts, m.Path+"@"+m.Version)
+		} else {
+			for _, name := range pkg.GoFiles {
+				sum, ok := files[name]
+				if !ok {
+					if s, err := hashFile(name); err == nil {
+						sum = s
+					}
+					files[name] = sum
+				}
+				parts = append(parts, name, sum)
+			}
+		}
+		paths := make([]string, 0, len(pkg.Imports))
+		for path := range pkg.Imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			parts = append(parts, path, key(pkg.Imports[path]))
+		}
+		k := hashParts(parts)
+		c.keys[pkg.ID] = k
+		return k
+	}
+	for _, pkg := range pkgs {
+		key(pkg)
+	}
+	return c, pkgs, nil
+}
+
+// get returns the entry for pkg. Packages go list could not load are never cached.
+func (c *lintCache) get(pkg *packages.Package) (lintCacheEntry, bool) {
+	var entry lintCacheEntry
+	if c.dir == "" || len(pkg.Errors) > 0 {
+		return entry, false
+	}
+	data, err := os.ReadFile(c.path(pkg.ID))
+	if err != nil || json.Unmarshal(data, &entry) != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// put stores the entry for the package with id, ignoring write failures.
+func (c *lintCache) put(id string, entry lintCacheEntry) {
+	if c.dir == "" || c.keys[id] == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	path := c.path(id)
+	if os.MkdirAll(filepath.Dir(path), 0o755) != nil {
+		return
+	}
+	// Through a temporary file, so concurrent runs never read a partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// isTestMain reports whether pkg is the main package go test generates for a test.
+func isTestMain(pkg *packages.Package) bool {
+	return strings.HasSuffix(pkg.ID, ".test")
+}
+
+func (c *lintCache) path(id string) string {
+	k := c.keys[id]
+	return filepath.Join(c.dir, k[:2], k+".json")
+}
+
+// analyzersKey hashes the running binary, which holds the analyzers' code, with
+// their names and option values.
+func analyzersKey(analyzers []*doanalysis.Analyzer) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	bin, err := hashFile(exe)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{bin}
+	for _, a := range analyzers {
+		parts = append(parts, a.Name)
+		a.Flags.VisitAll(func(f *flag.Flag) {
+			parts = append(parts, f.Name+"="+f.Value.String())
+		})
+	}
+	return hashParts(parts), nil
+}
+
+func hashParts(parts []string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"os"
-	"os/exec"
 
+	"github.com/housecat-inc/do/pkg/gcloud"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -14,6 +14,7 @@ var logsCmd = &cobra.Command{
 	Use:   "logs",
 	Short: "View logs from the deployed Cloud Run service",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		project := os.Getenv("CLOUDSDK_CORE_PROJECT")
 		region := os.Getenv("CLOUDSDK_RUN_REGION")
 		service := os.Getenv("CLOUD_RUN_SERVICE")
@@ -22,27 +23,14 @@ var logsCmd = &cobra.Command{
 			return errors.New("no service deployed. Run 'go do deploy' first")
 		}
 
-		var run *exec.Cmd
+		// Use gcloud beta run services logs tail for live streaming, or read for recent logs
+		action := "read"
 		if logsTail {
-			// Use gcloud beta run services logs tail for live streaming
-			run = exec.Command("gcloud", "beta", "run", "services", "logs", "tail", service,
-				"--project="+project,
-				"--region="+region)
-		} else {
-			// Use gcloud beta run services logs read for recent logs
-			run = exec.Command("gcloud", "beta", "run", "services", "logs", "read", service,
-				"--project="+project,
-				"--region="+region)
+			action = "tail"
 		}
-		run.Stdout = os.Stdout
-		run.Stderr = os.Stderr
-		run.Stdin = os.Stdin
-
-		if err := run.Run(); err != nil {
-			return errors.WithStack(err)
-		}
-
-		return nil
+		return gcloud.Attach(ctx, "gcloud", "beta", "run", "services", "logs", action, service,
+			"--project="+project,
+			"--region="+region)
 	},
 }
 
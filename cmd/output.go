@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/progress"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Output formats for --output.
+const (
+	outputGitHub = "github"
+	outputJSON   = "json"
+	outputSARIF  = "sarif"
+	outputText   = "text"
+)
+
+var outputFormat string
+
+// jsonStdout is the real stdout in JSON mode, where os.Stdout is pointed at
+// stderr so progress text and tool output can't corrupt the JSON.
+var jsonStdout io.Writer = os.Stdout
+
+// commandResult is a command run through pkg/gcloud, for JSON output.
+type commandResult struct {
+	Command    string `json:"command"`
+	DurationMS int64  `json:"duration_ms"`
+	OK         bool   `json:"ok"`
+}
+
+var (
+	commandsMu sync.Mutex
+	recorded   []commandResult
+)
+
+// recordCommand collects finished commands so JSON results can report them.
+func recordCommand(e gcloud.Event) {
+	if e.Type != gcloud.CommandFinished {
+		return
+	}
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+	recorded = append(recorded, commandResult{
+		Command:    strings.Join(append([]string{e.Name}, e.Args...), " "),
+		DurationMS: e.Duration.Milliseconds(),
+		OK:         e.Err == nil,
+	})
+}
+
+// commandsRun returns the commands recorded in JSON mode so far.
+func commandsRun() []commandResult {
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+	return append([]commandResult(nil), recorded...)
+}
+
+// setupOutput validates --output and, for JSON and SARIF, moves human-readable
+// output to stderr. Only lint writes SARIF, and only lint and the build pipeline,
+// which passes it to lint, write GitHub annotations.
+func setupOutput(cmd *cobra.Command) error {
+	switch outputFormat {
+	case outputText:
+		return nil
+	case outputGitHub:
+		if cmd != lintCmd && cmd.HasParent() {
+			return errors.Errorf("--output=%s is only supported by lint and the build pipeline", outputGitHub)
+		}
+		return nil
+	case outputSARIF:
+		if cmd != lintCmd {
+			return errors.Errorf("--output=%s is only supported by lint", outputSARIF)
+		}
+		jsonStdout = os.Stdout
+		os.Stdout = os.Stderr
+		progress.Output = os.Stderr
+		return nil
+	case outputJSON:
+		jsonStdout = os.Stdout
+		os.Stdout = os.Stderr
+		progress.Output = os.Stderr
+		gcloud.Events = recordCommand
+		return nil
+	}
+	return errors.Errorf("--output must be %q, %q, %q, or %q", outputText, outputJSON, outputSARIF, outputGitHub)
+}
+
+func jsonOutput() bool {
+	return outputFormat == outputJSON
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(jsonStdout)
+	enc.SetIndent("", "  ")
+	return errors.WithStack(enc.Encode(v))
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// deployAPIs are the APIs a deploy needs enabled.
+var deployAPIs = []string{"run.googleapis.com", "artifactregistry.googleapis.com"}
+
+// deployRoles grant enough access to deploy Cloud Run services.
+var deployRoles = []string{"roles/owner", "roles/editor", "roles/run.admin"}
+
+// runtimeRoles are the minimal roles for a service account Cloud Run runs as,
+// covering logs, metrics, and traces.
+var runtimeRoles = []string{"roles/logging.logWriter", "roles/monitoring.metricWriter", "roles/cloudtrace.agent"}
+
+// apiAdminRoles grant permission to enable APIs.
+var apiAdminRoles = []string{"roles/owner", "roles/editor", "roles/serviceusage.serviceUsageAdmin"}
+
+// preflightCheck validates one deploy prerequisite.
+// Warnings are reported but don't fail the preflight.
+type preflightCheck struct {
+	name string
+	run  func() (warning string, err error)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the project is ready to deploy",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := checkDeployTools(cfg.Builder, false); err != nil {
+			return err
+		}
+
+		project, region, _, err := deployedService()
+		if err != nil {
+			return err
+		}
+		regions := cfg.Regions
+		if len(regions) == 0 {
+			regions = []string{region}
+		}
+
+		buildPath := os.Getenv("KO_BUILD_PATH")
+		if buildPath == "" {
+			buildPath = "./cmd/app"
+		}
+		return runPreflight(ctx, project, regions, buildPath, cfg.Builder)
+	},
+}
+
+// runPreflight validates billing, APIs, IAM roles, quotas, and the build before
+// deploying, so failures surface early instead of halfway through ko or gcloud.
+func runPreflight(ctx context.Context, project string, regions []string, buildPath, builder string) error {
+	var roles []string
+	account := gcloud.Account(ctx)
+
+	checks := []preflightCheck{
+		{"billing enabled", func() (string, error) {
+			enabled, err := gcloud.BillingEnabled(ctx, project)
+			if err != nil {
+				return "could not check billing: " + err.Error(), nil
+			}
+			if !enabled {
+				return "", errors.Errorf("billing is not enabled on %s. Link a billing account: https://console.cloud.google.com/billing/linkedaccount?project=%s", project, project)
+			}
+			return "", nil
+		}},
+		{"account roles", func() (string, error) {
+			if account == "" {
+				return "", errors.New("no active gcloud account. Run 'gcloud auth login'")
+			}
+			var err error
+			roles, err = gcloud.ProjectRoles(ctx, project, gcloud.Member(account))
+			if err != nil {
+				return "could not read IAM policy: " + err.Error(), nil
+			}
+			if !hasAnyRole(roles, deployRoles) {
+				return fmt.Sprintf("%s has no direct %s grant on %s; deploy may fail unless it is inherited", account, strings.Join(deployRoles, " or "), project), nil
+			}
+			return "", nil
+		}},
+		{"required APIs", func() (string, error) {
+			enabled, err := gcloud.EnabledAPIs(ctx, project)
+			if err != nil {
+				return "could not list enabled APIs: " + err.Error(), nil
+			}
+			var missing []string
+			for _, api := range deployAPIs {
+				if !enabled[api] {
+					missing = append(missing, api)
+				}
+			}
+			if len(missing) == 0 {
+				return "", nil
+			}
+			if roles != nil && !hasAnyRole(roles, apiAdminRoles) {
+				return "", errors.Errorf("APIs not enabled and %s cannot enable them: %s", account, strings.Join(missing, ", "))
+			}
+			return "will enable " + strings.Join(missing, ", "), nil
+		}},
+	}
+	for _, region := range regions {
+		checks = append(checks, preflightCheck{"quotas in " + region, func() (string, error) {
+			return checkQuotas(ctx, project, region)
+		}})
+	}
+	checks = append(checks,
+		preflightCheck{"builds for linux/amd64", func() (string, error) {
+			if builder == builderDocker {
+				return "", checkBuilder(builder)
+			}
+			build := exec.Command("go", "build", "-o", os.DevNull, buildPath)
+			build.Env = append(os.Environ(), "GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0")
+			out, err := build.CombinedOutput()
+			if err != nil {
+				return "", errors.Errorf("go build %s failed for linux/amd64:\n%s", buildPath, strings.TrimSpace(string(out)))
+			}
+			return "", nil
+		}},
+	)
+
+	fmt.Println("\nRunning preflight checks...")
+	var failed []string
+	for _, c := range checks {
+		warning, err := c.run()
+		switch {
+		case err != nil:
+			fmt.Printf("  ✗ %s\n    %v\n", c.name, err)
+			failed = append(failed, c.name)
+		case warning != "":
+			fmt.Printf("  ! %s: %s\n", c.name, warning)
+		default:
+			fmt.Printf("  ✓ %s\n", c.name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("preflight failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// checkQuotas fails when a quota in region is used up and warns when one is near
+// its limit. Quotas that can't be read only warn, since usage data may be missing.
+func checkQuotas(ctx context.Context, project, region string) (string, error) {
+	quotas, err := gcloud.CheckQuotas(ctx, project, region)
+	var exceeded, near []string
+	for _, q := range quotas {
+		switch {
+		case q.Exceeded():
+			exceeded = append(exceeded, q.String())
+		case q.Near():
+			near = append(near, q.String())
+		}
+	}
+	if len(exceeded) > 0 {
+		return "", errors.Errorf("quota exhausted: %s. Request an increase: https://console.cloud.google.com/iam-admin/quotas?project=%s", strings.Join(exceeded, "; "), project)
+	}
+	var warnings []string
+	if len(near) > 0 {
+		warnings = append(warnings, "near limit: "+strings.Join(near, "; "))
+	}
+	if err != nil {
+		warnings = append(warnings, "could not read quota usage: "+err.Error())
+	}
+	return strings.Join(warnings, "; "), nil
+}
+
+func hasAnyRole(have, want []string) bool {
+	for _, r := range want {
+		if slices.Contains(have, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
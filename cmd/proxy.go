@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/spf13/cobra"
+)
+
+var proxyPort int
+var proxyTag string
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Proxy the deployed Cloud Run service to localhost with your credentials",
+	Long: `Runs a local proxy that adds an identity token for the active gcloud account,
+so services deployed with auth: required can be called from localhost:
+  go do proxy --port=9000
+  curl localhost:9000
+
+Use --tag to reach a tagged or preview revision:
+  go do proxy --tag=pr-42`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		proxyArgs := []string{"beta", "run", "services", "proxy", service,
+			"--project=" + project,
+			"--region=" + region,
+			fmt.Sprintf("--port=%d", proxyPort)}
+		if proxyTag != "" {
+			proxyArgs = append(proxyArgs, "--tag="+proxyTag)
+		}
+
+		return gcloud.Attach(cmd.Context(), "gcloud", proxyArgs...)
+	},
+}
+
+func init() {
+	proxyCmd.Flags().IntVarP(&proxyPort, "port", "p", 8080, "local port to listen on")
+	proxyCmd.Flags().StringVarP(&proxyTag, "tag", "t", "", "proxy to the revision with this traffic tag")
+	rootCmd.AddCommand(proxyCmd)
+}
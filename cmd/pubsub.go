@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var pubsubName string
+var pubsubServiceAccount string
+
+var pubsubCmd = &cobra.Command{
+	Use:   "pubsub",
+	Short: "Deliver Pub/Sub messages to the Cloud Run service",
+}
+
+var pubsubSubscribeCmd = &cobra.Command{
+	Use:   "subscribe <topic> <path>",
+	Short: "Push messages from a topic to a service path",
+	Long: `Creates the topic if missing and a push subscription that delivers its messages
+to a path on the deployed service with an OIDC token:
+  go do pubsub subscribe orders /events/orders`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		topic, path := args[0], args[1]
+		if !strings.HasPrefix(path, "/") {
+			return errors.Errorf("path %q must start with /", path)
+		}
+
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		url := gcloud.ServiceURL(ctx, project, region, service)
+		if url == "" {
+			return errors.Errorf("no URL for service '%s'. Run 'go do deploy' first", service)
+		}
+
+		if err := gcloud.EnsureAPIs(ctx, project, "pubsub.googleapis.com"); err != nil {
+			return err
+		}
+
+		account, err := grantServiceInvoker(ctx, project, region, service, pubsubServiceAccount)
+		if err != nil {
+			return err
+		}
+
+		if err := gcloud.EnsureTopic(ctx, project, topic); err != nil {
+			return err
+		}
+
+		name := pubsubName
+		if name == "" {
+			name = service + "-" + topic
+		}
+
+		err = gcloud.SavePushSubscription(ctx, project, name, topic, gcloud.PushOptions{
+			Audience:       url,
+			Endpoint:       url + path,
+			ServiceAccount: account,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\nSubscription '%s' pushes '%s' to %s%s\n", name, topic, url, path)
+		return nil
+	},
+}
+
+func init() {
+	pubsubSubscribeCmd.Flags().StringVar(&pubsubName, "name", "", "subscription name (default <service>-<topic>)")
+	pubsubSubscribeCmd.Flags().StringVar(&pubsubServiceAccount, "service-account", "", "service account whose OIDC token authenticates pushes (default compute service account)")
+
+	pubsubCmd.AddCommand(pubsubSubscribeCmd)
+	rootCmd.AddCommand(pubsubCmd)
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/github"
+	"github.com/housecat-inc/do/pkg/progress"
+	"github.com/pkg/errors"
+)
+
+// reviewMarker tags review comments posted by `do lint --review` so they can be resolved later.
+const reviewMarker = "<!-- go do lint -->"
+
+// postLintReview posts diagnostics on lines changed since the merge base commit as
+// inline PR review comments and resolves the threads of earlier comments whose issue
+// has been fixed, keeping any discussion on them. diags are already limited to
+// changed files and filtered by the baseline.
+func postLintReview(mergeBase string, diags []lintDiagnostic) error {
+	client, err := github.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	pr, err := github.CurrentPullRequest()
+	if err != nil {
+		return err
+	}
+
+	changed, err := changedLines(mergeBase)
+	if err != nil {
+		return err
+	}
+
+	root, err := gitRoot()
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string]github.ReviewComment)
+	for _, d := range diags {
+		path, err := filepath.Rel(root, d.Pos.Filename)
+		if err != nil {
+			continue
+		}
+		path = filepath.ToSlash(path)
+		if !changed[path][d.Pos.Line] {
+			continue
+		}
+		c := github.ReviewComment{
+			Body: fmt.Sprintf("**%s**: %s\n\n%s", d.Analyzer, d.Message, reviewMarker),
+			Line: d.Pos.Line,
+			Path: path,
+			Side: "RIGHT",
+		}
+		want[reviewKey(c)] = c
+	}
+
+	threads, err := client.ReviewThreads(pr.Number)
+	if err != nil {
+		return err
+	}
+
+	var resolved int
+	for _, t := range threads {
+		if !strings.Contains(t.Comment.Body, reviewMarker) {
+			continue
+		}
+		key := reviewKey(t.Comment)
+		if _, ok := want[key]; ok {
+			// Already posted, even if someone resolved it since
+			delete(want, key)
+			continue
+		}
+		if t.Resolved {
+			continue
+		}
+		if err := client.ResolveReviewThread(t.ID); err != nil {
+			return err
+		}
+		resolved++
+	}
+
+	var comments []github.ReviewComment
+	for _, c := range want {
+		comments = append(comments, c)
+	}
+
+	if len(comments) > 0 {
+		body := fmt.Sprintf("`go do lint` found %d new issue(s).", len(comments))
+		if err := client.CreateReview(pr.Number, pr.HeadSHA, body, comments); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Lint review: %d new, %d resolved\n", len(comments), resolved)
+	return nil
+}
+
+func reviewKey(c github.ReviewComment) string {
+	return fmt.Sprintf("%s:%d:%s", c.Path, c.Line, c.Body)
+}
+
+// reviewBase returns the branch --review compares against: --base when given, and
+// otherwise the pull request's base branch on origin, as actions/checkout fetches it.
+func reviewBase(base string, baseSet bool) string {
+	if ref := os.Getenv("GITHUB_BASE_REF"); ref != "" && !baseSet {
+		return "origin/" + ref
+	}
+	return base
+}
+
+// changedLines returns the added or modified line numbers per file, relative to the
+// repository root, since the merge base commit.
+func changedLines(mergeBase string) (map[string]map[int]bool, error) {
+	var out bytes.Buffer
+	// Explicit prefixes, since diff.noprefix or diff.mnemonicPrefix would change them
+	diff := exec.Command("git", "diff", "--unified=0", "--no-color", "--no-relative", "--src-prefix=a/", "--dst-prefix=b/", mergeBase)
+	diff.Stdout = &out
+	diff.Stderr = os.Stderr
+	if err := diff.Run(); err != nil {
+		return nil, errors.Wrapf(err, "git diff %s", mergeBase)
+	}
+	return parseDiffLines(out.String()), nil
+}
+
+// mergeBase returns the commit where the current branch left base. A shallow clone,
+// as actions/checkout makes by default, lacks it, so base is fetched with the rest
+// of the history first. When the two still share no commit, base itself is used.
+func mergeBase(base string) (string, error) {
+	if out, err := exec.Command("git", "merge-base", base, "HEAD").Output(); err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	// A local branch is fetched as origin's, leaving the local one alone.
+	ref := base
+	remotes, _ := exec.Command("git", "remote").Output()
+	remote, branch, ok := strings.Cut(base, "/")
+	if !ok || !slices.Contains(strings.Fields(string(remotes)), remote) {
+		remote, branch, ref = "origin", base, "origin/"+base
+	}
+	if slices.Contains(strings.Fields(string(remotes)), remote) {
+		args := []string{"fetch", "--no-tags"}
+		if shallow, _ := exec.Command("git", "rev-parse", "--is-shallow-repository").Output(); strings.TrimSpace(string(shallow)) == "true" {
+			args = append(args, "--unshallow")
+		}
+		args = append(args, remote, "+refs/heads/"+branch+":refs/remotes/"+remote+"/"+branch)
+		progress.Echo("git " + strings.Join(args, " "))
+		fetch := exec.Command("git", args...)
+		fetch.Stdout = os.Stderr
+		fetch.Stderr = os.Stderr
+		if err := fetch.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "fetch %s: %v\n", base, err)
+		} else if out, err := exec.Command("git", "merge-base", ref, "HEAD").Output(); err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+
+	for _, r := range []string{ref, base} {
+		if exec.Command("git", "rev-parse", "--verify", "--quiet", r+"^{commit}").Run() == nil {
+			fmt.Fprintf(os.Stderr, "No merge base with %s; comparing with it directly\n", r)
+			return r, nil
+		}
+	}
+	return "", errors.Errorf("%s is not a branch or commit", base)
+}
+
+// changedFiles returns the files, relative to the repository root, added or
+// modified since the merge base commit, including uncommitted and untracked files.
+func changedFiles(mergeBase string) ([]string, error) {
+	diff, err := exec.Command("git", "diff", "-z", "--name-only", "--diff-filter=d", "--no-relative", mergeBase).Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "git diff --name-only")
+	}
+	untracked, err := exec.Command("git", "ls-files", "-z", "--others", "--exclude-standard", "--full-name").Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "git ls-files --others")
+	}
+	var files []string
+	for _, f := range strings.Split(string(diff)+string(untracked), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// parseDiffLines parses unified diff hunks, with the b/ prefix changedLines asks
+// for on new files, into added line numbers per file.
+func parseDiffLines(diff string) map[string]map[int]bool {
+	lines := make(map[string]map[int]bool)
+	var file string
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if file == "/dev/null" {
+				file = ""
+			}
+		case strings.HasPrefix(line, "@@ ") && file != "":
+			// @@ -a,b +c,d @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			start, count := parseHunkRange(strings.TrimPrefix(fields[2], "+"))
+			if lines[file] == nil {
+				lines[file] = make(map[int]bool)
+			}
+			for i := start; i < start+count; i++ {
+				lines[file][i] = true
+			}
+		}
+	}
+	return lines
+}
+
+func parseHunkRange(r string) (int, int) {
+	startStr, countStr, found := strings.Cut(r, ",")
+	start, _ := strconv.Atoi(startStr)
+	count := 1
+	if found {
+		count, _ = strconv.Atoi(countStr)
+	}
+	return start, count
+}
+
+func gitRoot() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", errors.Wrap(err, "not in a git repository")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
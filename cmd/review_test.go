@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHunkRange(t *testing.T) {
+	tests := []struct {
+		in    string
+		start int
+		count int
+	}{
+		{"12", 12, 1},
+		{"12,3", 12, 3},
+		{"12,0", 12, 0},
+		{"1,100", 1, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+
+			start, count := parseHunkRange(tt.in)
+			a.Equal(tt.start, start)
+			a.Equal(tt.count, count)
+		})
+	}
+}
+
+func TestParseDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want map[string]map[int]bool
+	}{
+		{
+			name: "added and modified",
+			diff: `diff --git a/cmd/main.go b/cmd/main.go
+--- a/cmd/main.go
++++ b/cmd/main.go
+@@ -3 +3,2 @@ func main() {
+-	old()
++	a()
++	b()
+@@ -10,0 +12 @@
++	c()
+`,
+			want: map[string]map[int]bool{"cmd/main.go": {3: true, 4: true, 12: true}},
+		},
+		{
+			name: "deleted lines only",
+			diff: `--- a/main.go
++++ b/main.go
+@@ -3,2 +2,0 @@
+-	a()
+-	b()
+`,
+			want: map[string]map[int]bool{"main.go": {}},
+		},
+		{
+			name: "deleted file",
+			diff: `--- a/gone.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package gone
+`,
+			want: map[string]map[int]bool{},
+		},
+		{
+			name: "new file and second file",
+			diff: `--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package new
++
+--- a/web/App.svelte
++++ b/web/App.svelte
+@@ -5 +5 @@
+-<p>old</p>
++<p>new</p>
+`,
+			want: map[string]map[int]bool{"new.go": {1: true, 2: true}, "web/App.svelte": {5: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+
+			a.Equal(tt.want, parseDiffLines(tt.diff))
+		})
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), out)
+	return strings.TrimSpace(string(out))
+}
+
+func TestMergeBaseShallow(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	// A repository with main and a feature branch two commits ahead of it, cloned
+	// one commit deep on the feature branch, as actions/checkout does.
+	upstream := t.TempDir()
+	git(t, upstream, "init", "-q", "-b", "main")
+	for _, name := range []string{"a.go", "b.go"} {
+		r.NoError(os.WriteFile(filepath.Join(upstream, name), []byte("package a\n"), 0644))
+		git(t, upstream, "add", name)
+		git(t, upstream, "commit", "-qm", name)
+	}
+	want := git(t, upstream, "rev-parse", "HEAD")
+	git(t, upstream, "checkout", "-qb", "feature")
+	for _, name := range []string{"c.go", "d.go"} {
+		r.NoError(os.WriteFile(filepath.Join(upstream, name), []byte("package a\n"), 0644))
+		git(t, upstream, "add", name)
+		git(t, upstream, "commit", "-qm", name)
+	}
+
+	clone := t.TempDir()
+	git(t, clone, "clone", "-q", "--depth=1", "--branch=feature", "file://"+upstream, ".")
+	t.Chdir(clone)
+
+	got, err := mergeBase("origin/main")
+	r.NoError(err)
+	a.Equal(want, got)
+
+	files, err := changedFiles(got)
+	r.NoError(err)
+	a.ElementsMatch([]string{"c.go", "d.go"}, files)
+
+	// Prefixes are set on the command line, whatever the user's configuration.
+	git(t, clone, "config", "diff.mnemonicPrefix", "true")
+	lines, err := changedLines(got)
+	r.NoError(err)
+	a.Equal(map[string]map[int]bool{"c.go": {1: true}, "d.go": {1: true}}, lines)
+}
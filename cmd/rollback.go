@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/prompt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var rollbackLimit int
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [revision]",
+	Short: "Shift 100% of traffic back to a previous Cloud Run revision",
+	Long: `Lists recent revisions of the deployed service and routes all traffic to the selected one.
+
+Pass a revision name to skip the prompt:
+  go do rollback app-00042-xyz`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		revision := ""
+		if len(args) == 1 {
+			revision = args[0]
+		} else {
+			revision, err = selectRevision(ctx, project, region, service)
+			if err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("\nRouting 100%% of traffic on '%s' to %s...\n", service, revision)
+		if err := gcloud.UpdateTraffic(ctx, project, region, service, map[string]int{revision: 100}); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nRolled back to %s\n", revision)
+		return nil
+	},
+}
+
+func selectRevision(ctx context.Context, project, region, service string) (string, error) {
+	revisions, err := gcloud.ListRevisions(ctx, project, region, service, rollbackLimit)
+	if err != nil {
+		return "", err
+	}
+	if len(revisions) == 0 {
+		return "", errors.Errorf("no revisions found for service %s", service)
+	}
+
+	options := make([]string, len(revisions))
+	for i, r := range revisions {
+		status := ""
+		if r.Percent > 0 {
+			status = fmt.Sprintf(" [%d%% traffic]", r.Percent)
+		}
+		if !r.Ready {
+			status += " [not ready]"
+		}
+		options[i] = fmt.Sprintf("%s  %s%s", r.Name, r.Created.Local().Format("2006-01-02 15:04"), status)
+	}
+
+	// Default to the revision before the newest one
+	choice, err := prompt.Select("Select revision to roll back to", options, min(1, len(options)-1))
+	if err != nil {
+		return "", err
+	}
+	return revisions[choice].Name, nil
+}
+
+func init() {
+	rollbackCmd.Flags().IntVarP(&rollbackLimit, "limit", "n", 10, "number of recent revisions to list")
+	rootCmd.AddCommand(rollbackCmd)
+}
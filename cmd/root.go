@@ -2,24 +2,44 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
+	"reflect"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/housecat-inc/do/pkg/config"
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/progress"
+	"github.com/housecat-inc/do/pkg/prompt"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var verbose bool
+var profileName string
+var assumeYes bool
 
 var rootCmd = &cobra.Command{
 	Use:   "do",
 	Short: "A CLI tool for app init, build, test, deploy",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		prompt.NonInteractive = assumeYes
+		progress.Verbose = verbose
+		if err := setupOutput(cmd); err != nil {
+			return err
+		}
 		// Skip CI setup for certain commands
 		if cmd.Name() == "help" || cmd.Name() == "init" {
 			return nil
 		}
+		if err := loadProjectConfig(); err != nil {
+			return err
+		}
 		return ciSetupIfNeeded()
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -40,15 +60,31 @@ var rootCmd = &cobra.Command{
 			skipInCI   bool
 		}
 
+		lint := []string{"go", "tool", "do", "lint"}
+		if outputFormat == outputGitHub {
+			lint = append(lint, "--output="+outputGitHub)
+		}
+
 		commands := []command{
 			{[]string{"go", "generate", "./..."}, true, false},
 			{[]string{"go", "mod", "tidy"}, true, true},
 			{[]string{"go", "build", "-o", "/dev/null", "./..."}, true, false},
 			{[]string{"go", "vet", "./..."}, false, false},
-			{[]string{"go", "tool", "do", "lint"}, false, false},
+			{lint, false, false},
 			{[]string{"go", "test", "./..."}, true, false},
 		}
 
+		var steps []commandResult
+		report := func(err error) error {
+			if !jsonOutput() {
+				return err
+			}
+			if perr := printJSON(map[string]any{"ok": err == nil, "steps": steps}); perr != nil {
+				return perr
+			}
+			return err
+		}
+
 		isCI := os.Getenv("CI") == "true"
 		for _, c := range commands {
 			if c.skipInCI && isCI {
@@ -59,23 +95,96 @@ var rootCmd = &cobra.Command{
 				args = append(args[:2:2], append([]string{"-v"}, args[2:]...)...)
 			}
 
-			fmt.Printf(" →")
-			for _, arg := range args {
-				fmt.Printf(" %s", arg)
-			}
-			fmt.Println()
+			progress.Echo(strings.Join(args, " "))
 
+			start := time.Now()
 			run := exec.Command(args[0], args[1:]...)
 			run.Stdout = os.Stdout
 			run.Stderr = os.Stderr
-			if err := run.Run(); err != nil {
-				return err
+			err := run.Run()
+			steps = append(steps, commandResult{
+				Command:    strings.Join(args, " "),
+				DurationMS: time.Since(start).Milliseconds(),
+				OK:         err == nil,
+			})
+			if err != nil {
+				return report(err)
 			}
 		}
-		return nil
+		return report(nil)
 	},
 }
 
+// loadProjectConfig exports do.yaml settings as environment variables so that
+// subcommands and the tools they spawn (gcloud, ko) see them without direnv.
+// Values in do.yaml take precedence over the environment.
+func loadProjectConfig() error {
+	if _, err := findProjectRoot(); err != nil {
+		return nil // No go.mod, skip
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	for key, value := range cfg.Environ() {
+		if err := os.Setenv(key, value); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// loadConfig reads do.yaml from the project root with the --env profile applied.
+func loadConfig() (*config.Config, error) {
+	root, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Profile(profileName)
+}
+
+// saveConfig writes cfg to the project root if it differs from what is on disk.
+// With a --env profile, settings that differ from the top level are saved to the profile.
+func saveConfig(cfg *config.Config) error {
+	root, err := findProjectRoot()
+	if err != nil {
+		return err
+	}
+
+	existing, err := config.Load(root)
+	if err != nil {
+		return err
+	}
+
+	updated := cfg
+	if profileName != "" {
+		base := *existing
+		base.Profiles = make(map[string]config.Config)
+		for name, p := range existing.Profiles {
+			base.Profiles[name] = p
+		}
+		base.SetProfile(profileName, cfg)
+		updated = &base
+	}
+
+	if reflect.DeepEqual(existing, updated) {
+		return nil
+	}
+
+	if err := updated.Save(root); err != nil {
+		return err
+	}
+	fmt.Printf("Saved deploy settings to %s\n", config.Path(root))
+	return nil
+}
+
 // ciSetupIfNeeded runs CI-specific setup when CI=true
 func ciSetupIfNeeded() error {
 	if os.Getenv("CI") != "true" {
@@ -122,7 +231,7 @@ func dropLocalReplaces() error {
 	}
 
 	for _, mod := range replaces {
-		fmt.Printf(" → go mod edit -dropreplace %s\n", mod)
+		progress.Echo("go mod edit -dropreplace " + mod)
 		cmd := exec.Command("go", "mod", "edit", "-dropreplace", mod)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -133,7 +242,7 @@ func dropLocalReplaces() error {
 
 	// Run go mod download if we dropped any replaces
 	if len(replaces) > 0 {
-		fmt.Println(" → go mod download")
+		progress.Echo("go mod download")
 		cmd := exec.Command("go", "mod", "download")
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -147,7 +256,7 @@ func dropLocalReplaces() error {
 
 // installToolDeps installs tool dependencies from go.mod at their pinned versions
 func installToolDeps() error {
-	fmt.Println(" → go install tool")
+	progress.Echo("go install tool")
 	cmd := exec.Command("go", "install", "tool")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -159,11 +268,42 @@ func installToolDeps() error {
 }
 
 func init() {
-	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "stream each step's output as it runs, pass -v to go generate, build, and test, and list the files bundle exports")
+	rootCmd.PersistentFlags().StringVar(&profileName, "env", os.Getenv("DO_ENV"), "use a named profile from do.yaml (e.g. staging)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "answer yes to confirmations and fail instead of prompting for input")
+	rootCmd.PersistentFlags().BoolVar(&assumeYes, "non-interactive", false, "same as --yes")
+	rootCmd.PersistentFlags().BoolVarP(&progress.Quiet, "quiet", "q", false, "hide command echo lines and successful steps")
+	rootCmd.PersistentFlags().BoolVar(&gcloud.CacheRefresh, "refresh", false, "ignore cached gcloud lookups such as the project list and enabled APIs")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "output format: text or json (status, deploy, lint, bundle, and the build pipeline), sarif (lint), or github for workflow annotations (lint and the build pipeline)")
 }
 
+// Execute runs the root command. Ctrl-C cancels the command's context, which stops
+// running gcloud child processes instead of leaving them behind.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		if hint := remediation(err); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		stop()
 		os.Exit(1)
 	}
 }
+
+// remediation returns what to do next for a recognized gcloud failure.
+func remediation(err error) string {
+	switch {
+	case errors.Is(err, gcloud.ErrNotAuthenticated) && gcloud.AmbientCredentials():
+		return "Check the credentials this environment provides: GOOGLE_APPLICATION_CREDENTIALS, CLOUDSDK_AUTH_ACCESS_TOKEN, or workload identity (e.g. google-github-actions/auth in CI)."
+	case errors.Is(err, gcloud.ErrNotAuthenticated):
+		return "Run 'gcloud auth login' or set GOOGLE_APPLICATION_CREDENTIALS, and try again."
+	case errors.Is(err, gcloud.ErrAPIDisabled):
+		return "Run 'go do doctor' to enable the required APIs, then try again. Newly enabled APIs can take a few minutes to become available."
+	case errors.Is(err, gcloud.ErrPermissionDenied):
+		return "Run 'go do doctor' to check your account's roles. Deploying needs roles/run.admin and roles/iam.serviceAccountUser."
+	case errors.Is(err, gcloud.ErrServiceNotFound):
+		return "Run 'go do deploy' to create the service, or check project, region, and service in " + config.File + " and --env."
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var scaleMin int
+var scaleMax int
+var scaleConcurrency int
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "Change instance limits of the Cloud Run service without rebuilding",
+	Long: `Updates scaling settings on the deployed service. Only the flags passed are changed:
+  go do scale --min=1 --max=20
+  go do scale --concurrency=40`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		var opts gcloud.DeployOptions
+		var changes []string
+		if cmd.Flags().Changed("min") {
+			opts.MinInstances = &scaleMin
+			changes = append(changes, fmt.Sprintf("min instances %d", scaleMin))
+		}
+		if cmd.Flags().Changed("max") {
+			opts.MaxInstances = &scaleMax
+			changes = append(changes, fmt.Sprintf("max instances %d", scaleMax))
+		}
+		if cmd.Flags().Changed("concurrency") {
+			if scaleConcurrency < 1 {
+				return errors.New("--concurrency must be at least 1")
+			}
+			opts.Concurrency = scaleConcurrency
+			changes = append(changes, fmt.Sprintf("concurrency %d", scaleConcurrency))
+		}
+		if len(changes) == 0 {
+			return errors.New("pass at least one of --min, --max, or --concurrency")
+		}
+		if opts.MinInstances != nil && opts.MaxInstances != nil && scaleMin > scaleMax {
+			return errors.Errorf("--min %d is greater than --max %d", scaleMin, scaleMax)
+		}
+
+		project, region, service, err := deployedService()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Setting %s on '%s'...\n", strings.Join(changes, ", "), service)
+		return gcloud.UpdateService(ctx, project, region, service, opts)
+	},
+}
+
+func init() {
+	scaleCmd.Flags().IntVar(&scaleMin, "min", 0, "minimum number of instances")
+	scaleCmd.Flags().IntVar(&scaleMax, "max", 0, "maximum number of instances")
+	scaleCmd.Flags().IntVar(&scaleConcurrency, "concurrency", 0, "maximum concurrent requests per instance")
+	rootCmd.AddCommand(scaleCmd)
+}
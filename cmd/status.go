@@ -1,74 +1,271 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/housecat-inc/do/pkg/gcloud"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+var statusCost bool
+var statusRevisions int
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show deployment status and service info",
+	Long: `Shows the service URL, latest revision, and traffic tags. Services deployed to
+several regions also list the latest revision and URL in each region.
+
+Use --cost to estimate monthly cost from the last 30 days of Cloud Monitoring usage:
+  go do status --cost
+
+Use --revisions to list recent revisions with their traffic, tags, and image digests:
+  go do status --revisions`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		project := os.Getenv("CLOUDSDK_CORE_PROJECT")
-		region := os.Getenv("CLOUDSDK_RUN_REGION")
-		service := os.Getenv("CLOUD_RUN_SERVICE")
+		ctx := cmd.Context()
+		project, regions, service, err := deployedRegions()
+		if err != nil {
+			return err
+		}
+
+		services, err := gcloud.GetServices(ctx, project, regions, service)
+		if err != nil {
+			return errors.Wrap(err, "failed to get service status")
+		}
+		region := regions[0]
+		svc := services[region]
 
-		if project == "" || region == "" || service == "" {
-			return errors.New("no service deployed. Run 'go do deploy' first")
+		if jsonOutput() {
+			return printStatusJSON(ctx, project, regions, services)
 		}
 
 		fmt.Printf("Project: %s\n", project)
 		fmt.Printf("Region:  %s\n", region)
 		fmt.Printf("Service: %s\n", service)
+		if svc.URL != "" {
+			fmt.Printf("URL:     %s\n", svc.URL)
+		}
+		if svc.LatestReadyRevision != "" {
+			fmt.Printf("Latest:  %s\n", svc.LatestReadyRevision)
+		}
 
-		// Get service details
-		run := exec.Command("gcloud", "run", "services", "describe", service,
-			"--project="+project,
-			"--region="+region,
-			"--format=value(status.url,status.latestReadyRevisionName)")
-		out, err := run.Output()
-		if err != nil {
-			return errors.Wrap(err, "failed to get service status")
+		if tags := svc.Tags(); len(tags) > 0 {
+			fmt.Println("\nTraffic tags:")
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TAG\tPERCENT\tURL")
+			for _, t := range tags {
+				fmt.Fprintf(w, "%s\t%d\t%s\n", t.Tag, t.Percent, t.URL)
+			}
+			_ = w.Flush()
+		}
+
+		if len(regions) > 1 {
+			fmt.Println("\nRegions:")
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "REGION\tLATEST\tURL")
+			for _, r := range regions {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", r, services[r].LatestReadyRevision, services[r].URL)
+			}
+			_ = w.Flush()
 		}
 
-		parts := strings.Split(strings.TrimSpace(string(out)), "\n")
-		if len(parts) >= 1 && parts[0] != "" {
-			fmt.Printf("URL:     %s\n", parts[0])
-		}
-		if len(parts) >= 2 && parts[1] != "" {
-			fmt.Printf("Latest:  %s\n", parts[1])
-		}
-
-		// Get traffic tags
-		run = exec.Command("gcloud", "run", "services", "describe", service,
-			"--project="+project,
-			"--region="+region,
-			"--format=json(status.traffic)")
-		out, err = run.Output()
-		if err == nil {
-			outStr := string(out)
-			if strings.Contains(outStr, "tag") {
-				fmt.Println("\nTraffic tags:")
-				// Parse and show tags with URLs
-				run = exec.Command("gcloud", "run", "services", "describe", service,
-					"--project="+project,
-					"--region="+region,
-					"--format=table(status.traffic.tag,status.traffic.percent,status.traffic.url)")
-				run.Stdout = os.Stdout
-				run.Stderr = os.Stderr
-				_ = run.Run()
+		if statusRevisions > 0 {
+			if err := printRevisions(ctx, project, region, service); err != nil {
+				return err
 			}
 		}
 
+		if statusCost {
+			return printCost(ctx, project, region, svc)
+		}
 		return nil
 	},
 }
 
+// printRevisions prints the most recent revisions as a table, newest first.
+func printRevisions(ctx context.Context, project, region, service string) error {
+	revisions, err := gcloud.ListRevisions(ctx, project, region, service, statusRevisions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nRevisions:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tCREATED\tTRAFFIC\tTAGS\tCOMMIT\tDIGEST")
+	for _, r := range revisions {
+		traffic := "-"
+		if r.Percent > 0 {
+			traffic = fmt.Sprintf("%d%%", r.Percent)
+		}
+		if r.Failed {
+			traffic = "failed"
+		}
+		tags := strings.Join(r.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		commit := r.Labels["commit-sha"]
+		if commit == "" {
+			commit = "-"
+		}
+		digest := strings.TrimPrefix(r.Digest, "sha256:")
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.Created.Local().Format("2006-01-02 15:04"), traffic, tags, commit, digest)
+	}
+	return errors.WithStack(w.Flush())
+}
+
+func printStatusJSON(ctx context.Context, project string, regions []string, services map[string]gcloud.Service) error {
+	type regionJSON struct {
+		LatestRevision string `json:"latest_revision,omitempty"`
+		Region         string `json:"region"`
+		URL            string `json:"url,omitempty"`
+	}
+	type trafficJSON struct {
+		Latest   bool   `json:"latest,omitempty"`
+		Percent  int    `json:"percent"`
+		Revision string `json:"revision,omitempty"`
+		Tag      string `json:"tag,omitempty"`
+		URL      string `json:"url,omitempty"`
+	}
+	type costJSON struct {
+		BillableSeconds float64 `json:"billable_seconds"`
+		CPU             float64 `json:"cpu_usd"`
+		Memory          float64 `json:"memory_usd"`
+		Requests        float64 `json:"requests"`
+		RequestsCost    float64 `json:"requests_usd"`
+		Total           float64 `json:"total_usd"`
+	}
+	type revisionJSON struct {
+		Created time.Time         `json:"created"`
+		Digest  string            `json:"digest,omitempty"`
+		Image   string            `json:"image"`
+		Labels  map[string]string `json:"labels,omitempty"`
+		Name    string            `json:"name"`
+		Percent int               `json:"percent"`
+		Ready   bool              `json:"ready"`
+		Tags    []string          `json:"tags,omitempty"`
+	}
+	status := struct {
+		Cost           *costJSON      `json:"monthly_cost,omitempty"`
+		LatestRevision string         `json:"latest_revision,omitempty"`
+		Project        string         `json:"project"`
+		Region         string         `json:"region"`
+		Regions        []regionJSON   `json:"regions,omitempty"`
+		Revisions      []revisionJSON `json:"revisions,omitempty"`
+		Service        string         `json:"service"`
+		Traffic        []trafficJSON  `json:"traffic"`
+		URL            string         `json:"url,omitempty"`
+	}{Project: project, Region: regions[0], Traffic: []trafficJSON{}}
+
+	region, svc := regions[0], services[regions[0]]
+	status.LatestRevision, status.Service, status.URL = svc.LatestReadyRevision, svc.Name, svc.URL
+	if len(regions) > 1 {
+		for _, r := range regions {
+			status.Regions = append(status.Regions, regionJSON{LatestRevision: services[r].LatestReadyRevision, Region: r, URL: services[r].URL})
+		}
+	}
+
+	for _, t := range svc.Traffic {
+		status.Traffic = append(status.Traffic, trafficJSON(t))
+	}
+
+	if statusRevisions > 0 {
+		revisions, err := gcloud.ListRevisions(ctx, project, region, svc.Name, statusRevisions)
+		if err != nil {
+			return err
+		}
+		for _, r := range revisions {
+			status.Revisions = append(status.Revisions, revisionJSON{
+				Created: r.Created,
+				Digest:  r.Digest,
+				Image:   r.Image,
+				Labels:  r.Labels,
+				Name:    r.Name,
+				Percent: r.Percent,
+				Ready:   r.Ready,
+				Tags:    r.Tags,
+			})
+		}
+	}
+
+	if statusCost {
+		usage, c, err := estimateCost(ctx, project, region, svc)
+		if err != nil {
+			return err
+		}
+		status.Cost = &costJSON{
+			BillableSeconds: usage.BillableSeconds,
+			CPU:             c.CPU,
+			Memory:          c.Memory,
+			Requests:        usage.Requests,
+			RequestsCost:    c.Requests,
+			Total:           c.Total,
+		}
+	}
+	return printJSON(status)
+}
+
+// printCost prints the monthly cost estimate from estimateCost.
+func printCost(ctx context.Context, project, region string, svc gcloud.Service) error {
+	usage, c, err := estimateCost(ctx, project, region, svc)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nUsage (last 30 days):\n")
+	fmt.Printf("  Requests:       %.0f\n", usage.Requests)
+	fmt.Printf("  Billable time:  %.0f instance-seconds at %g vCPU, %g GiB\n", usage.BillableSeconds, usage.CPU, usage.MemoryGiB)
+	fmt.Printf("\nEstimated monthly cost (USD, list price, before free tier):\n")
+	fmt.Printf("  CPU:       $%.2f\n", c.CPU)
+	fmt.Printf("  Memory:    $%.2f\n", c.Memory)
+	fmt.Printf("  Requests:  $%.2f\n", c.Requests)
+	fmt.Printf("  Total:     $%.2f\n", c.Total)
+	return nil
+}
+
+// estimateCost estimates the service's monthly cost from its CPU and memory
+// allocation and the last 30 days of requests and billable instance time.
+func estimateCost(ctx context.Context, project, region string, svc gcloud.Service) (gcloud.Usage, gcloud.Cost, error) {
+	const period = 30 * 24 * time.Hour
+
+	// Cloud Run defaults when limits are unset
+	cpu, memory := svc.Config.CPU, svc.Config.Memory
+	if cpu == "" {
+		cpu = "1"
+	}
+	if memory == "" {
+		memory = "512Mi"
+	}
+
+	var err error
+	usage := gcloud.Usage{Period: period}
+	if usage.CPU, err = gcloud.ParseCPU(cpu); err != nil {
+		return usage, gcloud.Cost{}, err
+	}
+	if usage.MemoryGiB, err = gcloud.ParseMemoryGiB(memory); err != nil {
+		return usage, gcloud.Cost{}, err
+	}
+	if usage.Requests, err = gcloud.ServiceMetricSum(ctx, project, region, svc.Name, "run.googleapis.com/request_count", period); err != nil {
+		return usage, gcloud.Cost{}, err
+	}
+	if usage.BillableSeconds, err = gcloud.ServiceMetricSum(ctx, project, region, svc.Name, "run.googleapis.com/container/billable_instance_time", period); err != nil {
+		return usage, gcloud.Cost{}, err
+	}
+	return usage, gcloud.EstimateMonthlyCost(usage), nil
+}
+
 func init() {
+	statusCmd.Flags().BoolVar(&statusCost, "cost", false, "estimate monthly cost from the last 30 days of usage")
+	statusCmd.Flags().IntVar(&statusRevisions, "revisions", 0, "list this many recent revisions")
+	statusCmd.Flags().Lookup("revisions").NoOptDefVal = "10"
 	rootCmd.AddCommand(statusCmd)
 }
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureJSON switches to --output=json with printJSON writing to the returned
+// buffer until the test ends.
+func captureJSON(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	prevFormat, prevStdout := outputFormat, jsonStdout
+	outputFormat, jsonStdout = outputJSON, &buf
+	t.Cleanup(func() {
+		outputFormat, jsonStdout = prevFormat, prevStdout
+	})
+	return &buf
+}
+
+func deployedEnv(t *testing.T) {
+	t.Setenv("CLOUDSDK_CORE_PROJECT", "my-project")
+	t.Setenv("CLOUDSDK_RUN_REGION", "us-central1")
+	t.Setenv("CLOUD_RUN_SERVICE", "app")
+}
+
+// multiRegionProject changes to a project whose do.yaml also deploys to europe-west1.
+func multiRegionProject(t *testing.T) {
+	r := require.New(t)
+	t.Chdir(t.TempDir())
+	r.NoError(os.WriteFile("go.mod", []byte("module example.com/app\n"), 0644))
+	r.NoError(os.WriteFile("do.yaml", []byte("regions: [us-central1, europe-west1]\n"), 0644))
+}
+
+func TestStatusJSON(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	deployedEnv(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{
+		"metadata": {"name": "app"},
+		"status": {
+			"latestReadyRevisionName": "app-00002-xyz",
+			"traffic": [
+				{"latestRevision": true, "percent": 100},
+				{"revisionName": "app-00001-abc", "tag": "pr-7", "url": "https://pr-7---app-abc.a.run.app"}
+			],
+			"url": "https://app-abc.a.run.app"
+		}
+	}`, "gcloud", "run", "services", "describe", "app")
+	out := captureJSON(t)
+	statusCmd.SetContext(t.Context())
+
+	r.NoError(statusCmd.RunE(statusCmd, nil))
+
+	var got map[string]any
+	r.NoError(json.Unmarshal(out.Bytes(), &got))
+	a.Equal(map[string]any{
+		"latest_revision": "app-00002-xyz",
+		"project":         "my-project",
+		"region":          "us-central1",
+		"service":         "app",
+		"traffic": []any{
+			map[string]any{"latest": true, "percent": float64(100)},
+			map[string]any{"percent": float64(0), "revision": "app-00001-abc", "tag": "pr-7", "url": "https://pr-7---app-abc.a.run.app"},
+		},
+		"url": "https://app-abc.a.run.app",
+	}, got)
+	a.Equal([]string{
+		"gcloud run services describe app --platform=managed --region=us-central1 --project=my-project --format=json",
+	}, fake.Commands())
+}
+
+func TestStatusNotDeployed(t *testing.T) {
+	a := assert.New(t)
+	t.Setenv("CLOUD_RUN_SERVICE", "")
+	fake := gcloudtest.Install(t)
+	statusCmd.SetContext(t.Context())
+
+	a.ErrorContains(statusCmd.RunE(statusCmd, nil), "no service deployed")
+	a.Empty(fake.Calls())
+}
+
+func TestStatusServiceMissing(t *testing.T) {
+	a := assert.New(t)
+	deployedEnv(t)
+	fake := gcloudtest.Install(t)
+	fake.Fail("ERROR: (gcloud.run.services.describe) Cannot find service [app]", "gcloud", "run", "services", "describe", "app")
+	statusCmd.SetContext(t.Context())
+
+	a.ErrorContains(statusCmd.RunE(statusCmd, nil), "failed to get service status")
+}
+
+func TestStatusRegions(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	deployedEnv(t)
+	multiRegionProject(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{"metadata": {"name": "app"}, "status": {"latestReadyRevisionName": "app-00002-us", "url": "https://app-us.a.run.app"}}`,
+		"gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=us-central1")
+	fake.Reply(`{"metadata": {"name": "app"}, "status": {"latestReadyRevisionName": "app-00002-eu", "url": "https://app-eu.a.run.app"}}`,
+		"gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=europe-west1")
+	out := captureJSON(t)
+	statusCmd.SetContext(t.Context())
+
+	r.NoError(statusCmd.RunE(statusCmd, nil))
+
+	var got struct {
+		Region  string           `json:"region"`
+		Regions []map[string]any `json:"regions"`
+		URL     string           `json:"url"`
+	}
+	r.NoError(json.Unmarshal(out.Bytes(), &got))
+	a.Equal("us-central1", got.Region)
+	a.Equal("https://app-us.a.run.app", got.URL)
+	a.Equal([]map[string]any{
+		{"latest_revision": "app-00002-us", "region": "us-central1", "url": "https://app-us.a.run.app"},
+		{"latest_revision": "app-00002-eu", "region": "europe-west1", "url": "https://app-eu.a.run.app"},
+	}, got.Regions)
+}
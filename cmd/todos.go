@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/housecat-inc/do/pkg/analysis/todos"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// todoJSON is a TODO or FIXME comment in the JSON output of do lint --todos.
+type todoJSON struct {
+	Column  int    `json:"column"`
+	File    string `json:"file"`
+	Issue   string `json:"issue,omitempty"`
+	Keyword string `json:"keyword"`
+	Line    int    `json:"line"`
+	Text    string `json:"text"`
+}
+
+// listTodos prints the TODO and FIXME comments in the project's Go files, including
+// tests, using the keywords and issue pattern from the todos settings.
+func listTodos() error {
+	root, err := findProjectRoot()
+	if err != nil {
+		return err
+	}
+	mode := packages.NeedName | packages.NeedFiles | packages.NeedSyntax
+	pkgs, err := packages.Load(&packages.Config{Mode: mode, Tests: true}, "./...")
+	if err != nil {
+		return errors.Wrap(err, "failed to load packages")
+	}
+
+	seen := make(map[string]bool)
+	found := []todoJSON{}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			name := pkg.Fset.File(file.Pos()).Name()
+			if seen[name] || isGenerated(file) {
+				continue
+			}
+			seen[name] = true
+			rel := name
+			if r, err := filepath.Rel(root, name); err == nil {
+				rel = r
+			}
+			for _, todo := range todos.Find(file) {
+				pos := pkg.Fset.Position(todo.Pos)
+				found = append(found, todoJSON{
+					Column:  pos.Column,
+					File:    filepath.ToSlash(rel),
+					Issue:   todo.Issue,
+					Keyword: todo.Keyword,
+					Line:    pos.Line,
+					Text:    todo.Text,
+				})
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].File != found[j].File {
+			return found[i].File < found[j].File
+		}
+		return found[i].Line < found[j].Line
+	})
+
+	if jsonOutput() {
+		return printJSON(struct {
+			Todos []todoJSON `json:"todos"`
+		}{found})
+	}
+	missing := 0
+	for _, t := range found {
+		if t.Issue == "" {
+			missing++
+			fmt.Printf("%s:%d:%d: %s (no issue)\n", t.File, t.Line, t.Column, t.Text)
+		} else {
+			fmt.Printf("%s:%d:%d: %s\n", t.File, t.Line, t.Column, t.Text)
+		}
+	}
+	fmt.Printf("\n%s, %d without an issue\n", plural(len(found), "comment"), missing)
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var trafficFinalize bool
+
+var trafficCmd = &cobra.Command{
+	Use:   "traffic [revision=percent...]",
+	Short: "Show or adjust the traffic split of the Cloud Run service",
+	Long: `Shows the current traffic split when run without arguments.
+
+Pass revision=percent pairs to adjust the split. LATEST refers to the newest revision,
+and revisions not listed share the remainder:
+  go do traffic LATEST=50
+
+Use --finalize to route 100% of traffic to the latest revision:
+  go do traffic --finalize
+
+Services deployed to several regions are shown and updated in every region. Revision
+names differ between regions, so only LATEST can be split across them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, regions, service, err := deployedRegions()
+		if err != nil {
+			return err
+		}
+
+		if trafficFinalize {
+			if len(args) > 0 {
+				return errors.New("--finalize does not take revision arguments")
+			}
+			fmt.Printf("Routing 100%% of traffic on '%s' to the latest revision...\n", service)
+			return gcloud.ForEachRegion(ctx, regions, func(ctx context.Context, region string) error {
+				return gcloud.RouteToLatest(ctx, project, region, service)
+			})
+		}
+
+		if len(args) == 0 {
+			return printTraffic(ctx, project, regions, service)
+		}
+
+		split, err := parseTrafficSplit(args)
+		if err != nil {
+			return err
+		}
+		if len(regions) > 1 {
+			for rev := range split {
+				if rev != "LATEST" {
+					return errors.Errorf("revision %s is only in one region; splits across %s can only use LATEST", rev, strings.Join(regions, ", "))
+				}
+			}
+		}
+		return gcloud.UpdateTrafficRegions(ctx, project, regions, service, split)
+	},
+}
+
+// printTraffic prints the traffic split in each region. Regions that could not be
+// described are reported in the returned error after the others are printed.
+func printTraffic(ctx context.Context, project string, regions []string, service string) error {
+	services, err := gcloud.GetServices(ctx, project, regions, service)
+
+	for i, region := range regions {
+		svc, ok := services[region]
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		if len(regions) > 1 {
+			fmt.Printf("Traffic for %s in %s:\n", service, region)
+		} else {
+			fmt.Printf("Traffic for %s:\n", service)
+		}
+		for _, t := range svc.Traffic {
+			name := t.Revision
+			if t.Latest && name == "" {
+				name = "LATEST"
+			}
+			line := fmt.Sprintf("  %3d%%  %s", t.Percent, name)
+			if t.Tag != "" {
+				line += fmt.Sprintf("  (tag: %s)", t.Tag)
+			}
+			fmt.Println(line)
+		}
+	}
+	return errors.Wrap(err, "failed to get service traffic")
+}
+
+func parseTrafficSplit(args []string) (map[string]int, error) {
+	split := make(map[string]int)
+	for _, arg := range args {
+		rev, pct, ok := strings.Cut(arg, "=")
+		if !ok || rev == "" {
+			return nil, errors.Errorf("invalid traffic assignment %q, expected revision=percent", arg)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(pct, "%"))
+		if err != nil || n < 0 || n > 100 {
+			return nil, errors.Errorf("invalid traffic percent in %q", arg)
+		}
+		split[rev] = n
+	}
+	return split, nil
+}
+
+func init() {
+	trafficCmd.Flags().BoolVar(&trafficFinalize, "finalize", false, "route 100% of traffic to the latest revision")
+	rootCmd.AddCommand(trafficCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrafficRegions(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	deployedEnv(t)
+	multiRegionProject(t)
+	fake := gcloudtest.Install(t)
+	trafficCmd.SetContext(t.Context())
+
+	r.NoError(trafficCmd.RunE(trafficCmd, []string{"LATEST=10"}))
+	a.ElementsMatch([]string{
+		"gcloud run services update-traffic app --platform=managed --region=us-central1 --project=my-project --to-revisions=LATEST=10",
+		"gcloud run services update-traffic app --platform=managed --region=europe-west1 --project=my-project --to-revisions=LATEST=10",
+	}, fake.Commands())
+}
+
+func TestTrafficRegionsRevision(t *testing.T) {
+	a := assert.New(t)
+	deployedEnv(t)
+	multiRegionProject(t)
+	fake := gcloudtest.Install(t)
+	trafficCmd.SetContext(t.Context())
+
+	a.ErrorContains(trafficCmd.RunE(trafficCmd, []string{"app-00001-abc=100"}), "splits across us-central1, europe-west1 can only use LATEST")
+	a.Empty(fake.Calls())
+}
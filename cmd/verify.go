@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/progress"
+	"github.com/pkg/errors"
+)
+
+// verifyOptions configures post-deploy verification.
+type verifyOptions struct {
+	AuthRequired bool
+	HealthPath   string
+	Timeout      time.Duration
+}
+
+// verifyDeploy waits for the latest revision to become ready and, when a health
+// path is set, for url+path to respond with a 2xx status.
+func verifyDeploy(ctx context.Context, project, region, service, url string, opts verifyOptions) error {
+	deadline := time.Now().Add(opts.Timeout)
+
+	revision, err := gcloud.LatestRevision(ctx, project, region, service)
+	if err != nil {
+		return err
+	}
+
+	err = progress.Step(fmt.Sprintf("wait for %s to be ready", revision), func(w io.Writer) error {
+		for {
+			rev, err := gcloud.GetRevision(ctx, project, region, revision)
+			if err != nil {
+				return err
+			}
+			if rev.Ready {
+				return nil
+			}
+			if rev.Failed {
+				return errors.Errorf("revision %s failed: %s", revision, rev.Message)
+			}
+			if time.Now().After(deadline) {
+				return errors.Errorf("revision %s not ready after %s", revision, opts.Timeout)
+			}
+			if err := sleep(ctx, 2*time.Second); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.HealthPath == "" || url == "" {
+		return nil
+	}
+
+	target := strings.TrimSuffix(url, "/") + "/" + strings.TrimPrefix(opts.HealthPath, "/")
+	return progress.Step("GET "+target, func(w io.Writer) error {
+		var token string
+		if opts.AuthRequired {
+			if token, err = gcloud.IdentityToken(ctx); err != nil {
+				return err
+			}
+		}
+
+		client := &http.Client{Timeout: 10 * time.Second}
+		for {
+			status, err := healthCheck(ctx, client, target, token)
+			if err == nil && status >= 200 && status < 300 {
+				return nil
+			}
+			if err != nil {
+				fmt.Fprintf(w, "%v\n", err)
+			} else {
+				fmt.Fprintf(w, "%s: %d %s\n", target, status, http.StatusText(status))
+			}
+			if time.Now().After(deadline) {
+				return errors.Errorf("health check %s failed after %s", target, opts.Timeout)
+			}
+			if err := sleep(ctx, 2*time.Second); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	case <-t.C:
+		return nil
+	}
+}
+
+func healthCheck(ctx context.Context, client *http.Client, url, token string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// restoreTraffic routes traffic back to the split captured before a deploy.
+func restoreTraffic(ctx context.Context, project, region, service string, previous []gcloud.TrafficTarget) error {
+	split := make(map[string]int)
+	for _, t := range previous {
+		if t.Percent > 0 && t.Revision != "" {
+			split[t.Revision] += t.Percent
+		}
+	}
+	if len(split) == 0 {
+		return errors.New("no previous revision to roll back to")
+	}
+
+	fmt.Printf("\nRolling back traffic on '%s' in %s...\n", service, region)
+	return gcloud.UpdateTraffic(ctx, project, region, service, split)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// runVettools runs each of the lint.vettools binaries with go vet on the packages
+// matching patterns, building those given as Go packages first. Findings are
+// reported as from analyzer <tool>/<analyzer>, such as vet/printf.
+func runVettools(tools, patterns []string, exclude map[string][]string) ([]lintDiagnostic, error) {
+	root, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	var diags []lintDiagnostic
+	var failed []string
+	for _, tool := range tools {
+		name := strings.TrimSuffix(filepath.Base(tool), filepath.Ext(tool))
+		found, err := runVettool(root, name, tool, patterns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", tool, err)
+			failed = append(failed, tool)
+		}
+		for _, d := range found {
+			d.Analyzer = name + "/" + d.Analyzer
+			rel := d.Pos.Filename
+			if r, err := filepath.Rel(root, rel); err == nil {
+				rel = r
+			}
+			excluded, err := lintExcluded(exclude, d.Analyzer, filepath.ToSlash(rel))
+			if err != nil {
+				return nil, err
+			}
+			if !excluded {
+				diags = append(diags, d)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return diags, errors.Errorf("lint.vettools failed: %s", strings.Join(failed, ", "))
+	}
+	return diags, nil
+}
+
+func runVettool(root, name, tool string, patterns []string) ([]lintDiagnostic, error) {
+	bin := tool
+	if info, err := os.Stat(tool); err != nil || info.IsDir() {
+		bin = filepath.Join(root, ".do", "cache", "lint", "vettools", name)
+		build := exec.Command("go", "build", "-o", bin, tool)
+		build.Stdout = os.Stderr
+		build.Stderr = os.Stderr
+		if err := build.Run(); err != nil {
+			return nil, errors.Wrap(err, "build")
+		}
+	} else if bin, err = filepath.Abs(tool); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// With -json, go vet exits 0 unless a package fails. Its findings go to stderr
+	// before Go 1.27 and to stdout since.
+	var out bytes.Buffer
+	vet := exec.Command("go", append([]string{"vet", "-vettool=" + bin, "-json"}, patterns...)...)
+	vet.Stdout = &out
+	vet.Stderr = &out
+	runErr := vet.Run()
+	diags, err := vetDiagnostics(bytes.NewReader(out.Bytes()))
+	if runErr != nil {
+		fmt.Fprint(os.Stderr, out.String())
+		return diags, errors.WithStack(runErr)
+	}
+	return diags, err
+}
+
+// vetDiagnostics parses the output of go vet -json: a "# package" line before the
+// JSON object for each package, mapping its path to analyzers to their findings or,
+// when an analyzer failed, to an object with its error.
+func vetDiagnostics(r io.Reader) ([]lintDiagnostic, error) {
+	var objects bytes.Buffer
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), "#") {
+			objects.WriteString(scanner.Text() + "\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var diags []lintDiagnostic
+	dec := json.NewDecoder(&objects)
+	for dec.More() {
+		var out map[string]map[string]json.RawMessage
+		if err := dec.Decode(&out); err != nil {
+			return diags, errors.Wrap(err, "parse go vet -json output")
+		}
+		for _, analyzers := range out {
+			for analyzer, raw := range analyzers {
+				var findings []struct {
+					Message string `json:"message"`
+					Posn    string `json:"posn"`
+				}
+				if json.Unmarshal(raw, &findings) != nil {
+					var failure struct {
+						Error string `json:"error"`
+					}
+					if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+						fmt.Fprintf(os.Stderr, "%s: %s\n", analyzer, failure.Error)
+					}
+					continue
+				}
+				for _, f := range findings {
+					diags = append(diags, lintDiagnostic{Analyzer: analyzer, Message: f.Message, Pos: parsePosn(f.Posn)})
+				}
+			}
+		}
+	}
+	return diags, nil
+}
+
+// parsePosn parses a file:line:column or file:line position, as go vet prints them.
+func parsePosn(posn string) token.Position {
+	file := posn
+	var nums []int
+	for len(nums) < 2 {
+		i := strings.LastIndex(file, ":")
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(file[i+1:])
+		if err != nil {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		file = file[:i]
+	}
+	pos := token.Position{Filename: file}
+	if len(nums) > 0 {
+		pos.Line = nums[0]
+	}
+	if len(nums) > 1 {
+		pos.Column = nums[1]
+	}
+	return pos
+}
@@ -1,8 +1,12 @@
 package analysis
 
 import (
+	"flag"
 	"go/token"
+	"slices"
+	"strings"
 
+	"github.com/pkg/errors"
 	"golang.org/x/tools/go/analysis"
 )
 
@@ -12,7 +16,78 @@ func (m Message) Report(pass *analysis.Pass, pos token.Pos) {
 	pass.Reportf(pos, "%s", m)
 }
 
+// ReportFix reports m at pos with a fix that do lint --fix can apply.
+func (m Message) ReportFix(pass *analysis.Pass, pos token.Pos, fix analysis.SuggestedFix) {
+	pass.Report(analysis.Diagnostic{Pos: pos, Message: string(m), SuggestedFixes: []analysis.SuggestedFix{fix}})
+}
+
+// Severity is how a finding affects do lint: errors fail it, and warnings are printed
+// without failing it.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
 type Analyzer struct {
 	*analysis.Analyzer
 	Messages []Message
+	// Warnings are the Messages reported as warnings. The others are errors.
+	Warnings []Message
+}
+
+// Severity returns the severity of a diagnostic of a with message, which may add
+// details after one of Messages, as in "message (details)".
+func (a *Analyzer) Severity(message string) Severity {
+	for _, w := range a.Warnings {
+		if message == string(w) || strings.HasPrefix(message, string(w)+" ") {
+			return SeverityWarning
+		}
+	}
+	return SeverityError
+}
+
+// Configure sets the options of a from settings, by flag name.
+func (a *Analyzer) Configure(settings map[string]string) error {
+	for name, value := range settings {
+		if a.Flags.Lookup(name) == nil {
+			return errors.Errorf("%s has no option %q (have: %s)", a.Name, name, strings.Join(a.Options(), ", "))
+		}
+		if err := a.Flags.Set(name, value); err != nil {
+			return errors.Wrapf(err, "%s option %s", a.Name, name)
+		}
+	}
+	return nil
+}
+
+// Options returns the names of the options of a.
+func (a *Analyzer) Options() []string {
+	var names []string
+	a.Flags.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	return names
+}
+
+// Select returns the analyzers named in enable, or all of them when enable is empty,
+// except those named in disable.
+func Select(analyzers []*Analyzer, enable, disable []string) ([]*Analyzer, error) {
+	byName := make(map[string]bool, len(analyzers))
+	var names []string
+	for _, a := range analyzers {
+		byName[a.Name] = true
+		names = append(names, a.Name)
+	}
+	for _, name := range append(slices.Clone(enable), disable...) {
+		if !byName[name] {
+			return nil, errors.Errorf("unknown analyzer %q (have: %s)", name, strings.Join(names, ", "))
+		}
+	}
+
+	var selected []*Analyzer
+	for _, a := range analyzers {
+		if (len(enable) == 0 || slices.Contains(enable, a.Name)) && !slices.Contains(disable, a.Name) {
+			selected = append(selected, a)
+		}
+	}
+	return selected, nil
 }
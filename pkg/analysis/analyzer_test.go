@@ -0,0 +1,53 @@
+package analysis_test
+
+import (
+	"testing"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/housecat-inc/do/pkg/analysis/nocomments"
+	"github.com/housecat-inc/do/pkg/analysis/pkgerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelect(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	all := []*doanalysis.Analyzer{pkgerrors.Analyzer, nocomments.Analyzer}
+
+	selected, err := doanalysis.Select(all, nil, nil)
+	r.NoError(err)
+	a.Equal(all, selected)
+
+	selected, err = doanalysis.Select(all, nil, []string{"nocomments"})
+	r.NoError(err)
+	a.Equal([]*doanalysis.Analyzer{pkgerrors.Analyzer}, selected)
+
+	selected, err = doanalysis.Select(all, []string{"nocomments"}, nil)
+	r.NoError(err)
+	a.Equal([]*doanalysis.Analyzer{nocomments.Analyzer}, selected)
+
+	_, err = doanalysis.Select(all, nil, []string{"nocoments"})
+	a.ErrorContains(err, `unknown analyzer "nocoments" (have: pkgerrors, nocomments)`)
+}
+
+func TestConfigure(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal([]string{"allow"}, nocomments.Analyzer.Options())
+	a.ErrorContains(nocomments.Analyzer.Configure(map[string]string{"alow": "TODO"}), `nocomments has no option "alow" (have: allow)`)
+	a.NoError(nocomments.Analyzer.Configure(map[string]string{"allow": "TODO"}))
+	t.Cleanup(func() { _ = nocomments.Analyzer.Flags.Set("allow", "") })
+	a.Equal("TODO", nocomments.Analyzer.Flags.Lookup("allow").Value.String())
+}
+
+func TestSeverity(t *testing.T) {
+	a := assert.New(t)
+
+	an := &doanalysis.Analyzer{Messages: []doanalysis.Message{"hard", "soft"}, Warnings: []doanalysis.Message{"soft"}}
+	a.Equal(doanalysis.SeverityError, an.Severity("hard"))
+	a.Equal(doanalysis.SeverityWarning, an.Severity("soft"))
+	a.Equal(doanalysis.SeverityWarning, an.Severity("soft (details)"))
+	a.Equal(doanalysis.SeverityError, an.Severity("softer"))
+}
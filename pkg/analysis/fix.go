@@ -0,0 +1,111 @@
+package analysis
+
+import (
+	"bytes"
+	"go/format"
+	"go/token"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+)
+
+// edit is a TextEdit as byte offsets into its file.
+type edit struct {
+	end   int
+	start int
+	text  []byte
+}
+
+// ApplyFixes returns the formatted contents of each file changed by fixes, by name.
+// A fix is skipped if one of its edits overlaps an edit of a fix applied before it,
+// unless the edits are identical, as when fixes add the same import; running the
+// analyzers again finds what remains.
+func ApplyFixes(fset *token.FileSet, fixes []analysis.SuggestedFix) (map[string][]byte, error) {
+	edits := make(map[string][]edit)
+	for _, fix := range fixes {
+		pending := make(map[string][]edit)
+		ok := true
+		for _, te := range fix.TextEdits {
+			file := fset.File(te.Pos)
+			if file == nil {
+				ok = false
+				break
+			}
+			end := te.End
+			if !end.IsValid() {
+				end = te.Pos
+			}
+			e := edit{end: file.Offset(end), start: file.Offset(te.Pos), text: te.NewText}
+			if conflicts(append(edits[file.Name()], pending[file.Name()]...), e) {
+				ok = false
+				break
+			}
+			pending[file.Name()] = append(pending[file.Name()], e)
+		}
+		if !ok {
+			continue
+		}
+		for name, es := range pending {
+			edits[name] = append(edits[name], es...)
+		}
+	}
+
+	files := make(map[string][]byte, len(edits))
+	for name, es := range edits {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		out, err := applyEdits(src, es)
+		if err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+		if formatted, err := format.Source(out); err == nil {
+			out = formatted
+		}
+		if !bytes.Equal(out, src) {
+			files[name] = out
+		}
+	}
+	return files, nil
+}
+
+// conflicts reports whether e overlaps one of edits without being identical to it.
+// Insertions at the same offset conflict unless their text is the same.
+func conflicts(edits []edit, e edit) bool {
+	for _, o := range edits {
+		if o.start == e.start && o.end == e.end && bytes.Equal(o.text, e.text) {
+			continue
+		}
+		if e.start < o.end && o.start < e.end || e.start == o.start && (e.start == e.end || o.start == o.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEdits returns src with edits applied, dropping duplicates.
+func applyEdits(src []byte, edits []edit) ([]byte, error) {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end < edits[j].end
+	})
+	var out []byte
+	last := 0
+	for i, e := range edits {
+		if i > 0 && e.start == edits[i-1].start && e.end == edits[i-1].end && bytes.Equal(e.text, edits[i-1].text) {
+			continue
+		}
+		if e.start < last || e.end > len(src) || e.start > e.end {
+			return nil, errors.Errorf("invalid edit at offset %d", e.start)
+		}
+		out = append(out, src[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	return append(out, src[last:]...), nil
+}
@@ -0,0 +1,223 @@
+package analysis_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/housecat-inc/do/pkg/analysis/nocomments"
+	"github.com/housecat-inc/do/pkg/analysis/pkgerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// run runs a on src, written to a file of its own, and returns its diagnostics. The
+// analyzers in this repository require at most the inspector, which run provides.
+func run(t *testing.T, a *doanalysis.Analyzer, src string) (*token.FileSet, []analysis.Diagnostic) {
+	return runFile(t, a, "x.go", src)
+}
+
+// runFile is run with the file named name.
+func runFile(t *testing.T, a *doanalysis.Analyzer, name, src string) (*token.FileSet, []analysis.Diagnostic) {
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), name)
+	r.NoError(os.WriteFile(path, []byte(src), 0644))
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	r.NoError(err)
+	info := &types.Info{
+		Defs:  make(map[*ast.Ident]types.Object),
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("x", fset, []*ast.File{file}, info)
+	r.NoError(err)
+
+	var diags []analysis.Diagnostic
+	pass := &analysis.Pass{
+		Analyzer:  a.Analyzer,
+		Fset:      fset,
+		Files:     []*ast.File{file},
+		Pkg:       pkg,
+		ReadFile:  os.ReadFile,
+		Report:    func(d analysis.Diagnostic) { diags = append(diags, d) },
+		ResultOf:  map[*analysis.Analyzer]any{inspect.Analyzer: inspector.New([]*ast.File{file})},
+		TypesInfo: info,
+	}
+	_, err = a.Run(pass)
+	r.NoError(err)
+	return fset, diags
+}
+
+// fix runs a on src and returns it with the suggested fixes applied.
+func fix(t *testing.T, a *doanalysis.Analyzer, src string) string {
+	return fixFile(t, a, "x.go", src)
+}
+
+// fixFile is fix with the file named name.
+func fixFile(t *testing.T, a *doanalysis.Analyzer, name, src string) string {
+	r := require.New(t)
+
+	fset, diags := runFile(t, a, name, src)
+	var fixes []analysis.SuggestedFix
+	for _, d := range diags {
+		fixes = append(fixes, d.SuggestedFixes...)
+	}
+	files, err := doanalysis.ApplyFixes(fset, fixes)
+	r.NoError(err)
+	for _, out := range files {
+		return string(out)
+	}
+	return src
+}
+
+func TestFixPkgErrors(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(`package x
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func f(name string) error {
+	if _, err := os.Stat(name); err != nil {
+		return errors.Wrapf(err, "stat %s", name)
+	}
+	if name == "" {
+		return errors.Errorf("no name")
+	}
+	_, err := os.Open(name)
+	return errors.Wrap(err, "open")
+}
+
+func g(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("missing")
+	}
+	return errors.WithStack(err)
+}
+`, fix(t, pkgerrors.Analyzer, `package x
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+func f(name string) error {
+	if _, err := os.Stat(name); err != nil {
+		return fmt.Errorf("stat %s: %w", name, err)
+	}
+	if name == "" {
+		return fmt.Errorf("no name")
+	}
+	_, err := os.Open(name)
+	return fmt.Errorf("open: %w", err)
+}
+
+func g(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("missing")
+	}
+	return fmt.Errorf("%w", err)
+}
+`))
+
+	a.Equal(`package x
+
+import (
+	"errors"
+	"fmt"
+)
+
+func f(a, b error) error {
+	fmt.Println("joining")
+	return fmt.Errorf("joined: %w", errors.Join(a, b))
+}
+`, fix(t, pkgerrors.Analyzer, `package x
+
+import (
+	"errors"
+	"fmt"
+)
+
+func f(a, b error) error {
+	fmt.Println("joining")
+	return fmt.Errorf("joined: %w", errors.Join(a, b))
+}
+`), "files using errors.Join keep the standard package")
+
+	a.Equal(`package x
+
+import "github.com/pkg/errors"
+
+import "fmt"
+
+func f(a, b error, n int) (error, error) {
+	fmt.Println("wrapping")
+	return errors.Errorf("%d", n), fmt.Errorf("%w and %w", a, b)
+}
+`, fix(t, pkgerrors.Analyzer, `package x
+
+import "fmt"
+
+func f(a, b error, n int) (error, error) {
+	fmt.Println("wrapping")
+	return fmt.Errorf("%d", n), fmt.Errorf("%w and %w", a, b)
+}
+`), "errors wrapped before the end of the format are left alone")
+}
+
+func TestFixNoComments(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	a.Equal(`package x
+
+// F is documented.
+func F() int {
+	x := 1
+	//! important
+	return x
+}
+`, fix(t, nocomments.Analyzer, `package x
+
+// F is documented.
+func F() int {
+	// set x
+	x := 1 // one
+	//! important
+	return x /* done */
+}
+`))
+
+	r.NoError(nocomments.Analyzer.Configure(map[string]string{"allow": "TODO, FIXME"}))
+	t.Cleanup(func() { _ = nocomments.Analyzer.Flags.Set("allow", "") })
+	a.Equal(`package x
+
+func F() {
+	// TODO: something
+	/* FIXME */
+}
+`, fix(t, nocomments.Analyzer, `package x
+
+func F() {
+	// TODO: something
+	/* FIXME */
+	// other
+}
+`))
+}
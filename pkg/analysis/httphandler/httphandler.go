@@ -0,0 +1,376 @@
+package httphandler
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const (
+	MsgMissingReturn   doanalysis.Message = "return after http.Error or an error WriteHeader, or the handler goes on writing the response"
+	MsgHeaderAfterBody doanalysis.Message = "set headers and the status before writing the body, which sends them"
+	MsgRequestContext  doanalysis.Message = "use r.Context() so the work stops when the request is canceled"
+)
+
+var Analyzer = &doanalysis.Analyzer{
+	Analyzer: &analysis.Analyzer{
+		Name:     "httphandler",
+		Doc:      "checks http handlers for a missing return after an error, headers set after the body, and ignoring the request context",
+		Run:      run,
+		Requires: []*analysis.Analyzer{inspect.Analyzer},
+	},
+	Messages: []doanalysis.Message{MsgMissingReturn, MsgHeaderAfterBody, MsgRequestContext},
+}
+
+// handler is a function taking an http.ResponseWriter and an *http.Request.
+type handler struct {
+	pass *analysis.Pass
+	// w and r are the parameters, r nil when it is unnamed.
+	w, r types.Object
+	// results is set when the function returns values, so a bare return cannot be
+	// suggested.
+	results bool
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	for cur := range insp.Root().Preorder((*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)) {
+		var typ *ast.FuncType
+		var body *ast.BlockStmt
+		switch fn := cur.Node().(type) {
+		case *ast.FuncDecl:
+			typ, body = fn.Type, fn.Body
+		case *ast.FuncLit:
+			typ, body = fn.Type, fn.Body
+		}
+		h, ok := newHandler(pass, typ)
+		if !ok || body == nil {
+			continue
+		}
+		h.checkReturns(body.List, true)
+		h.checkHeaders(body.List, false)
+		h.checkContext(body)
+	}
+	return nil, nil
+}
+
+func newHandler(pass *analysis.Pass, typ *ast.FuncType) (*handler, bool) {
+	h := &handler{pass: pass, results: typ.Results != nil && len(typ.Results.List) > 0}
+	var hasW, hasR bool
+	for _, field := range typ.Params.List {
+		t := pass.TypesInfo.TypeOf(field.Type)
+		switch {
+		case isNamed(t, "ResponseWriter"):
+			hasW = true
+			if len(field.Names) == 1 {
+				h.w = pass.TypesInfo.Defs[field.Names[0]]
+			}
+		case isPointer(t, "Request"):
+			hasR = true
+			if len(field.Names) == 1 && field.Names[0].Name != "_" {
+				h.r = pass.TypesInfo.Defs[field.Names[0]]
+			}
+		}
+	}
+	return h, hasW && hasR && h.w != nil
+}
+
+func isNamed(t types.Type, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "net/http" && obj.Name() == name
+}
+
+func isPointer(t types.Type, name string) bool {
+	ptr, ok := t.(*types.Pointer)
+	return ok && isNamed(ptr.Elem(), name)
+}
+
+// checkReturns reports error responses in stmts that are not followed by a return
+// when more of the function runs after stmts. tail is set when nothing does.
+func (h *handler) checkReturns(stmts []ast.Stmt, tail bool) {
+	for i, stmt := range stmts {
+		last := tail && i == len(stmts)-1
+		h.checkNested(stmt, last)
+
+		expr, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		call, ok := expr.X.(*ast.CallExpr)
+		if !ok || !h.isErrorResponse(call) || tail || terminates(stmts[i+1:]) {
+			continue
+		}
+		if h.results {
+			MsgMissingReturn.Report(h.pass, call.Pos())
+			continue
+		}
+		MsgMissingReturn.ReportFix(h.pass, call.Pos(), analysis.SuggestedFix{
+			Message:   "return after the error response",
+			TextEdits: []analysis.TextEdit{{Pos: stmt.End(), End: stmt.End(), NewText: []byte("\nreturn")}},
+		})
+	}
+}
+
+// checkNested checks the statement lists inside stmt, where tail is set when nothing
+// runs after stmt. Something always runs after a loop body: the next iteration.
+func (h *handler) checkNested(stmt ast.Stmt, tail bool) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		h.checkReturns(s.List, tail)
+	case *ast.LabeledStmt:
+		h.checkNested(s.Stmt, tail)
+	case *ast.IfStmt:
+		h.checkReturns(s.Body.List, tail)
+		if s.Else != nil {
+			h.checkNested(s.Else, tail)
+		}
+	case *ast.SwitchStmt:
+		h.checkClauses(s.Body, tail)
+	case *ast.TypeSwitchStmt:
+		h.checkClauses(s.Body, tail)
+	case *ast.SelectStmt:
+		h.checkClauses(s.Body, tail)
+	case *ast.ForStmt:
+		h.checkReturns(s.Body.List, false)
+	case *ast.RangeStmt:
+		h.checkReturns(s.Body.List, false)
+	}
+}
+
+func (h *handler) checkClauses(body *ast.BlockStmt, tail bool) {
+	for _, clause := range body.List {
+		switch c := clause.(type) {
+		case *ast.CaseClause:
+			h.checkReturns(c.Body, tail)
+		case *ast.CommClause:
+			h.checkReturns(c.Body, tail)
+		}
+	}
+}
+
+// terminates reports whether stmts leave the current block with a return, a branch,
+// or a panic.
+func terminates(stmts []ast.Stmt) bool {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.ReturnStmt, *ast.BranchStmt:
+			return true
+		case *ast.ExprStmt:
+			if call, ok := s.X.(*ast.CallExpr); ok {
+				if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isErrorResponse reports whether call is http.Error, http.NotFound, http.Redirect,
+// or w.WriteHeader with a constant status of 300 or more.
+func (h *handler) isErrorResponse(call *ast.CallExpr) bool {
+	if fn := h.httpFunc(call); fn == "Error" || fn == "NotFound" || fn == "Redirect" {
+		return true
+	}
+	if h.method(call) != "WriteHeader" || len(call.Args) != 1 {
+		return false
+	}
+	tv := h.pass.TypesInfo.Types[call.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return false
+	}
+	status, ok := constant.Int64Val(tv.Value)
+	return ok && status >= 300
+}
+
+// checkHeaders reports header changes in stmts after the body is written, where
+// written is set when it already was before stmts run. Only writes at the top level
+// of a list or in the init of an if count, since writes in a branch may not happen.
+func (h *handler) checkHeaders(stmts []ast.Stmt, written bool) {
+	for _, stmt := range stmts {
+		if written {
+			h.inspect(stmt, func(n ast.Node) {
+				if call, ok := n.(*ast.CallExpr); ok && h.setsHeader(call) {
+					MsgHeaderAfterBody.Report(h.pass, call.Pos())
+				}
+			})
+			continue
+		}
+		h.checkNestedHeaders(stmt)
+		if h.writesBody(stmt) {
+			written = true
+		}
+		if s, ok := stmt.(*ast.IfStmt); ok && s.Init != nil && h.writesBody(s.Init) {
+			written = true
+		}
+	}
+}
+
+func (h *handler) checkNestedHeaders(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		h.checkHeaders(s.List, false)
+	case *ast.LabeledStmt:
+		h.checkNestedHeaders(s.Stmt)
+	case *ast.IfStmt:
+		h.checkHeaders(s.Body.List, false)
+		if s.Else != nil {
+			h.checkNestedHeaders(s.Else)
+		}
+	case *ast.ForStmt:
+		h.checkHeaders(s.Body.List, false)
+	case *ast.RangeStmt:
+		h.checkHeaders(s.Body.List, false)
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		ast.Inspect(s, func(n ast.Node) bool {
+			switch c := n.(type) {
+			case *ast.CaseClause:
+				h.checkHeaders(c.Body, false)
+				return false
+			case *ast.CommClause:
+				h.checkHeaders(c.Body, false)
+				return false
+			case *ast.FuncLit:
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// writesBody reports whether stmt is a call writing the response body.
+func (h *handler) writesBody(stmt ast.Stmt) bool {
+	var expr ast.Expr
+	switch s := stmt.(type) {
+	case *ast.ExprStmt:
+		expr = s.X
+	case *ast.AssignStmt:
+		if len(s.Rhs) != 1 {
+			return false
+		}
+		expr = s.Rhs[0]
+	default:
+		return false
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	if h.method(call) == "Write" {
+		return true
+	}
+	switch h.httpFunc(call) {
+	case "Error", "NotFound", "Redirect", "ServeContent", "ServeFile":
+		return true
+	}
+	if len(call.Args) == 0 || !h.isW(call.Args[0]) {
+		return h.encodes(call)
+	}
+	switch doanalysis.FuncName(h.pass.TypesInfo, call, "fmt") {
+	case "Fprint", "Fprintf", "Fprintln":
+		return true
+	}
+	switch doanalysis.FuncName(h.pass.TypesInfo, call, "io") {
+	case "Copy", "WriteString":
+		return true
+	}
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name == "Execute" || sel.Sel.Name == "ExecuteTemplate"
+	}
+	return false
+}
+
+// encodes reports whether call is json.NewEncoder(w).Encode.
+func (h *handler) encodes(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Encode" {
+		return false
+	}
+	inner, ok := sel.X.(*ast.CallExpr)
+	return ok && len(inner.Args) == 1 && h.isW(inner.Args[0]) && doanalysis.FuncName(h.pass.TypesInfo, inner, "encoding/json") == "NewEncoder"
+}
+
+// setsHeader reports whether call is w.WriteHeader or changes w.Header().
+func (h *handler) setsHeader(call *ast.CallExpr) bool {
+	if h.method(call) == "WriteHeader" {
+		return true
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	switch sel.Sel.Name {
+	case "Add", "Del", "Set":
+	default:
+		return false
+	}
+	inner, ok := sel.X.(*ast.CallExpr)
+	return ok && h.method(inner) == "Header"
+}
+
+// checkContext reports context.Background and context.TODO in body, suggesting the
+// request's context when r is named and not shadowed.
+func (h *handler) checkContext(body *ast.BlockStmt) {
+	h.inspect(body, func(n ast.Node) {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 0 {
+			return
+		}
+		if name := doanalysis.FuncName(h.pass.TypesInfo, call, "context"); name != "Background" && name != "TODO" {
+			return
+		}
+		if h.r == nil || doanalysis.Lookup(h.pass.Pkg, h.r.Name(), call.Pos()) != h.r {
+			MsgRequestContext.Report(h.pass, call.Pos())
+			return
+		}
+		MsgRequestContext.ReportFix(h.pass, call.Pos(), analysis.SuggestedFix{
+			Message:   "use the request's context",
+			TextEdits: []analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: []byte(h.r.Name() + ".Context()")}},
+		})
+	})
+}
+
+// inspect calls fn for the nodes in node outside function literals, which run at
+// another time and are checked as handlers of their own.
+func (h *handler) inspect(node ast.Node, fn func(ast.Node)) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		if n != nil {
+			fn(n)
+		}
+		return true
+	})
+}
+
+func (h *handler) isW(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && h.pass.TypesInfo.Uses[ident] == h.w
+}
+
+// method returns the name of the method call calls on w, or "".
+func (h *handler) method(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !h.isW(sel.X) {
+		return ""
+	}
+	return sel.Sel.Name
+}
+
+// httpFunc returns the name of the net/http function call calls with w, or "".
+func (h *handler) httpFunc(call *ast.CallExpr) string {
+	if len(call.Args) == 0 || !h.isW(call.Args[0]) {
+		return ""
+	}
+	return doanalysis.FuncName(h.pass.TypesInfo, call, "net/http")
+}
@@ -0,0 +1,106 @@
+package analysis_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/analysis/httphandler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	a := assert.New(t)
+
+	_, diags := run(t, httphandler.Analyzer, `package x
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func get(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "" {
+		http.Error(w, "missing", http.StatusBadRequest)
+	}
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+	w.Header().Set("X", "y")
+}
+
+func post(w http.ResponseWriter, _ *http.Request) {
+	if err := json.NewEncoder(w).Encode(context.Background()); err != nil {
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if r.URL.Path == "/new" {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_, _ = w.Write([]byte(r.URL.Path))
+	if r.URL.Path == "" {
+		http.NotFound(w, r)
+	}
+}
+
+func notHandler(w http.ResponseWriter) {
+	http.Error(w, "missing", http.StatusBadRequest)
+	w.WriteHeader(http.StatusOK)
+}
+`)
+	sort.Slice(diags, func(i, j int) bool { return diags[i].Pos < diags[j].Pos })
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Message)
+	}
+	a.Equal([]string{
+		string(httphandler.MsgMissingReturn),
+		string(httphandler.MsgHeaderAfterBody),
+		string(httphandler.MsgRequestContext),
+		string(httphandler.MsgHeaderAfterBody),
+	}, got, "create writes its status before the body and ends after NotFound")
+}
+
+func TestFixHTTPHandler(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(`package x
+
+import (
+	"context"
+	"net/http"
+)
+
+func get(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "" {
+		http.Error(w, "missing", http.StatusBadRequest)
+		return
+	}
+	do(r.Context())
+}
+
+func do(ctx context.Context) { _ = ctx }
+`, fix(t, httphandler.Analyzer, `package x
+
+import (
+	"context"
+	"net/http"
+)
+
+func get(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "" {
+		http.Error(w, "missing", http.StatusBadRequest)
+	}
+	do(context.Background())
+}
+
+func do(ctx context.Context) { _ = ctx }
+`))
+}
@@ -0,0 +1,141 @@
+package imports
+
+import (
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	MsgForbiddenImport doanalysis.Message = "import forbidden by lint.imports"
+)
+
+var Analyzer = &doanalysis.Analyzer{
+	Analyzer: &analysis.Analyzer{
+		Name: "imports",
+		Doc:  "reports imports of packages forbidden by the project's lint.imports, outside the packages allowed them",
+		Run:  run,
+		// Imports are syntax, so type errors in a package do not affect them.
+		RunDespiteErrors: true,
+	},
+	Messages: []doanalysis.Message{MsgForbiddenImport},
+}
+
+// Forbidden is a package that may not be imported.
+type Forbidden struct {
+	// Allow lists the packages that may import it, by path relative to the module,
+	// such as pkg/db, which also allows the packages below it. Patterns such as
+	// cmd/* match as path.Match does.
+	Allow []string `json:"allow,omitempty"`
+	// Message follows MsgForbiddenImport in reports, to say what to do instead.
+	Message string `json:"message,omitempty"`
+	// Path is the import path, which also forbids the packages below it.
+	Path string `json:"path"`
+}
+
+// forbidden are the packages checked, set with the forbid option.
+var forbidden forbidFlag
+
+func init() {
+	Analyzer.Flags.Var(&forbidden, "forbid", "JSON list of {path, allow, message} imports, set from lint.imports in do.yaml")
+}
+
+// forbidFlag is a flag.Value holding the forbidden imports as JSON, so the value
+// identifies them.
+type forbidFlag []Forbidden
+
+func (f *forbidFlag) String() string {
+	if len(*f) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(*f)
+	return string(data)
+}
+
+func (f *forbidFlag) Set(s string) error {
+	var fs []Forbidden
+	if s != "" {
+		if err := json.Unmarshal([]byte(s), &fs); err != nil {
+			return errors.Wrap(err, "parse imports")
+		}
+	}
+	for _, fb := range fs {
+		if fb.Path == "" {
+			return errors.New("forbidden import has no path")
+		}
+		for _, pattern := range fb.Allow {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Wrapf(err, "allow pattern %q", pattern)
+			}
+		}
+	}
+	*f = fs
+	return nil
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if len(forbidden) == 0 {
+		return nil, nil
+	}
+	pkg := relPath(pass)
+	for _, file := range pass.Files {
+		for _, imp := range file.Imports {
+			importPath, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			for _, fb := range forbidden {
+				if !within(importPath, fb.Path) || allowed(pkg, fb.Allow) {
+					continue
+				}
+				msg := string(MsgForbiddenImport) + " (" + fb.Path + ")"
+				if fb.Message != "" {
+					msg += ": " + fb.Message
+				}
+				pass.Reportf(imp.Pos(), "%s", msg)
+				break
+			}
+		}
+	}
+	return nil, nil
+}
+
+// relPath returns the path of the package relative to its module, "." for the
+// module's root package, or the import path when the module is unknown. Test
+// packages are named for the package they test.
+func relPath(pass *analysis.Pass) string {
+	pkg := strings.TrimSuffix(pass.Pkg.Path(), "_test")
+	if pass.Module == nil || pass.Module.Path == "" {
+		return pkg
+	}
+	if pkg == pass.Module.Path {
+		return "."
+	}
+	if rel, ok := strings.CutPrefix(pkg, pass.Module.Path+"/"); ok {
+		return rel
+	}
+	return pkg
+}
+
+// within reports whether p is the package at prefix or below it.
+func within(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+func allowed(pkg string, allow []string) bool {
+	for _, pattern := range allow {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if within(pkg, pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, pkg); ok {
+			return true
+		}
+	}
+	return false
+}
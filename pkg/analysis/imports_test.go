@@ -0,0 +1,39 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/analysis/imports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImports(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	src := `package x
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+)
+
+var _, _, _ = ioutil.Discard, httptest.NewRecorder, os.Stdout
+`
+	r.NoError(imports.Analyzer.Configure(map[string]string{"forbid": `[{"path": "io/ioutil", "message": "use io and os"}, {"path": "net/http"}, {"path": "os", "allow": ["x"]}]`}))
+	t.Cleanup(func() { _ = imports.Analyzer.Flags.Set("forbid", "") })
+	_, diags := run(t, imports.Analyzer, src)
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Message)
+	}
+	a.Equal([]string{
+		string(imports.MsgForbiddenImport) + " (io/ioutil): use io and os",
+		string(imports.MsgForbiddenImport) + " (net/http)",
+	}, got, "packages below a forbidden one are forbidden, and allowed packages may import it")
+
+	a.Error(imports.Analyzer.Configure(map[string]string{"forbid": `[{"allow": ["x"]}]`}))
+	a.Error(imports.Analyzer.Configure(map[string]string{"forbid": `[{"path": "os", "allow": ["["]}]`}))
+}
@@ -18,26 +18,79 @@ var Analyzer = &doanalysis.Analyzer{
 		Name: "nocomments",
 		Doc:  "disallows comments except godoc and //! for important notes",
 		Run:  run,
+		// Comments are syntax, so type errors in a package do not affect them.
+		RunDespiteErrors: true,
 	},
 	Messages: []doanalysis.Message{MsgNoComments},
 }
 
+// allow lists comment prefixes allowed besides //!, set with the allow option.
+var allow string
+
+func init() {
+	Analyzer.Flags.StringVar(&allow, "allow", "", "comma-separated prefixes of comments to allow, such as TODO,FIXME")
+}
+
 func run(pass *analysis.Pass) (any, error) {
 	for _, file := range pass.Files {
 		docPositions := collectDocPositions(file)
+		src := readFile(pass, file)
 
 		for _, cg := range file.Comments {
 			for _, c := range cg.List {
 				if isAllowed(c, docPositions) {
 					continue
 				}
-				MsgNoComments.Report(pass, c.Pos())
+				if src == nil {
+					MsgNoComments.Report(pass, c.Pos())
+					continue
+				}
+				MsgNoComments.ReportFix(pass, c.Pos(), analysis.SuggestedFix{
+					Message:   "remove the comment",
+					TextEdits: []analysis.TextEdit{removal(pass.Fset.File(c.Pos()), src, c)},
+				})
 			}
 		}
 	}
 	return nil, nil
 }
 
+// readFile returns the source of file, or nil if the pass cannot read it.
+func readFile(pass *analysis.Pass, file *ast.File) []byte {
+	if pass.ReadFile == nil {
+		return nil
+	}
+	src, err := pass.ReadFile(pass.Fset.File(file.Pos()).Name())
+	if err != nil {
+		return nil
+	}
+	return src
+}
+
+// removal returns the edit removing c, with its whole line when nothing else is on
+// it, or with the space before it when it trails code.
+func removal(tok *token.File, src []byte, c *ast.Comment) analysis.TextEdit {
+	start, end := tok.Offset(c.Pos()), tok.Offset(c.End())
+	before := start
+	for before > 0 && (src[before-1] == ' ' || src[before-1] == '\t') {
+		before--
+	}
+	after := end
+	for after < len(src) && (src[after] == ' ' || src[after] == '\t') {
+		after++
+	}
+	switch {
+	case (before == 0 || src[before-1] == '\n') && (after == len(src) || src[after] == '\n'):
+		if after < len(src) {
+			after++
+		}
+		return analysis.TextEdit{Pos: tok.Pos(before), End: tok.Pos(after)}
+	case after == len(src) || src[after] == '\n':
+		return analysis.TextEdit{Pos: tok.Pos(before), End: c.End()}
+	}
+	return analysis.TextEdit{Pos: c.Pos(), End: tok.Pos(after)}
+}
+
 func collectDocPositions(file *ast.File) map[token.Pos]bool {
 	docs := make(map[token.Pos]bool)
 
@@ -90,6 +143,12 @@ func isAllowed(c *ast.Comment, docPositions map[token.Pos]bool) bool {
 	if strings.HasPrefix(text, "//nolint") {
 		return true
 	}
+	body := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(text, "//"), "/*"))
+	for _, prefix := range strings.Split(allow, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" && strings.HasPrefix(body, prefix) {
+			return true
+		}
+	}
 
 	return false
 }
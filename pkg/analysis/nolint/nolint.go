@@ -0,0 +1,65 @@
+package nolint
+
+import (
+	"slices"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	MsgUnused doanalysis.Message = "remove the suppression; the analyzers it names report nothing on this line"
+)
+
+// New returns an analyzer that flags //nolint:<name> and //!ignore:<name> comments
+// naming one of analyzers that suppress nothing, by running those analyzers again.
+// It requires what those analyzers require, so their passes get the same results.
+func New(analyzers ...*doanalysis.Analyzer) *doanalysis.Analyzer {
+	var requires []*analysis.Analyzer
+	for _, a := range analyzers {
+		for _, req := range a.Requires {
+			if !slices.Contains(requires, req) {
+				requires = append(requires, req)
+			}
+		}
+	}
+	return &doanalysis.Analyzer{
+		Analyzer: &analysis.Analyzer{
+			Name: "nolint",
+			Doc:  "flags //nolint and //!ignore comments that suppress no diagnostic",
+			Run: func(pass *analysis.Pass) (any, error) {
+				return run(pass, analyzers)
+			},
+			RunDespiteErrors: true,
+			Requires:         requires,
+		},
+		Messages: []doanalysis.Message{MsgUnused},
+	}
+}
+
+func run(pass *analysis.Pass, analyzers []*doanalysis.Analyzer) (any, error) {
+	sups := doanalysis.Suppressions(pass.Fset, pass.Files)
+	if len(sups) == 0 {
+		return nil, nil
+	}
+
+	var names []string
+	for _, a := range analyzers {
+		names = append(names, a.Name)
+		sub := *pass
+		sub.Analyzer = a.Analyzer
+		sub.Report = func(d analysis.Diagnostic) {
+			doanalysis.Suppressed(sups, a.Name, pass.Fset.Position(d.Pos))
+		}
+		if _, err := a.Run(&sub); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range sups {
+		if len(s.Unused(names)) > 0 {
+			MsgUnused.Report(pass, s.Pos)
+		}
+	}
+	return nil, nil
+}
@@ -1,51 +1,308 @@
 package pkgerrors
 
 import (
+	"bytes"
 	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
 
 	doanalysis "github.com/housecat-inc/do/pkg/analysis"
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
 )
 
 const (
 	MsgFmtErrorf doanalysis.Message = "use github.com/pkg/errors errors.WithStack by default and errors.Wrap only if it will be unwrapped"
 )
 
+const pkgErrorsPath = "github.com/pkg/errors"
+
 var Analyzer = &doanalysis.Analyzer{
 	Analyzer: &analysis.Analyzer{
 		Name: "pkgerrors",
 		Doc:  "checks that github.com/pkg/errors is used instead of the standard errors package or fmt.Errorf",
 		Run:  run,
+		// Reports rely on syntax, so type errors elsewhere in a package do not hide them.
+		RunDespiteErrors: true,
+		Requires:         []*analysis.Analyzer{inspect.Analyzer},
 	},
 	Messages: []doanalysis.Message{MsgFmtErrorf},
 }
 
+// stdOnly are the functions of the standard errors package that github.com/pkg/errors
+// lacks, so files using them keep the standard import.
+var stdOnly = map[string]bool{"ErrUnsupported": true, "Join": true}
+
 func run(pass *analysis.Pass) (any, error) {
+	fixers := make(map[*ast.File]*fileFixer, len(pass.Files))
 	for _, file := range pass.Files {
+		f := newFileFixer(pass, file)
+		fixers[file] = f
+
 		for _, imp := range file.Imports {
-			if imp.Path.Value == `"errors"` {
-				MsgFmtErrorf.Report(pass, imp.Pos())
+			if imp.Path.Value != `"errors"` {
+				continue
 			}
+			if f.swapStd {
+				MsgFmtErrorf.ReportFix(pass, imp.Pos(), analysis.SuggestedFix{
+					Message:   "import github.com/pkg/errors",
+					TextEdits: append(f.deleteImport(imp), f.addImport()...),
+				})
+				continue
+			}
+			MsgFmtErrorf.Report(pass, imp.Pos())
 		}
+	}
 
-		ast.Inspect(file, func(n ast.Node) bool {
-			call, ok := n.(*ast.CallExpr)
-			if !ok {
-				return true
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	for fileCur := range insp.Root().Children() {
+		f := fixers[fileCur.Node().(*ast.File)]
+		for cur := range fileCur.Preorder((*ast.CallExpr)(nil)) {
+			call := cur.Node().(*ast.CallExpr)
+			if !isFmtErrorf(call) {
+				continue
 			}
-			sel, ok := call.Fun.(*ast.SelectorExpr)
-			if !ok {
-				return true
-			}
-			ident, ok := sel.X.(*ast.Ident)
-			if !ok {
-				return true
-			}
-			if ident.Name == "fmt" && sel.Sel.Name == "Errorf" {
+			if fix, ok := f.errorf(call); ok {
+				MsgFmtErrorf.ReportFix(pass, call.Pos(), fix)
+			} else {
 				MsgFmtErrorf.Report(pass, call.Pos())
 			}
-			return true
-		})
+		}
 	}
 	return nil, nil
 }
+
+// fileFixer builds the fixes for one file, which share the edits to its imports.
+type fileFixer struct {
+	file *ast.File
+	// name is what the file calls github.com/pkg/errors once fixed, or "" when it
+	// cannot use the package without a conflicting import.
+	name string
+	pass *analysis.Pass
+	// swapStd is set when the file imports the standard errors package and uses
+	// nothing github.com/pkg/errors lacks.
+	swapStd bool
+	// fmtImport is the fmt import, removed when every use of fmt is a fixed Errorf.
+	fmtImport *ast.ImportSpec
+	fmtUses   int
+	fixable   int
+}
+
+func newFileFixer(pass *analysis.Pass, file *ast.File) *fileFixer {
+	f := &fileFixer{file: file, pass: pass}
+	var std, pkg *ast.ImportSpec
+	for _, imp := range file.Imports {
+		switch imp.Path.Value {
+		case `"errors"`:
+			std = imp
+		case strconv.Quote(pkgErrorsPath):
+			pkg = imp
+		case `"fmt"`:
+			if imp.Name == nil {
+				f.fmtImport = imp
+			}
+		}
+	}
+
+	switch {
+	case pkg != nil && pkg.Name != nil:
+		f.name = pkg.Name.Name
+	case pkg != nil, std == nil, std.Name != nil:
+		f.name = "errors"
+	default:
+		f.swapStd = true
+		f.name = "errors"
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pkgName, ok := pass.TypesInfo.Uses[ident].(*types.PkgName)
+		if !ok {
+			return true
+		}
+		switch pkgName.Imported().Path() {
+		case "errors":
+			if stdOnly[sel.Sel.Name] {
+				f.swapStd = false
+			}
+		case "fmt":
+			f.fmtUses++
+		}
+		return true
+	})
+	if std != nil && std.Name == nil && pkg == nil && !f.swapStd {
+		f.name = ""
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && f.name != "" && isFmtErrorf(call) {
+			if _, ok := f.errorfText(call); ok {
+				f.fixable++
+			}
+		}
+		return true
+	})
+	return f
+}
+
+func isFmtErrorf(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && ident.Name == "fmt" && sel.Sel.Name == "Errorf"
+}
+
+// errorf returns a fix rewriting a fmt.Errorf call to github.com/pkg/errors.
+func (f *fileFixer) errorf(call *ast.CallExpr) (analysis.SuggestedFix, bool) {
+	if f.name == "" {
+		return analysis.SuggestedFix{}, false
+	}
+	text, ok := f.errorfText(call)
+	if !ok {
+		return analysis.SuggestedFix{}, false
+	}
+	edits := []analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: []byte(text)}}
+	if f.fmtImport != nil && f.fmtUses == f.fixable {
+		edits = append(edits, f.deleteImport(f.fmtImport)...)
+	}
+	edits = append(edits, f.addImport()...)
+	return analysis.SuggestedFix{Message: "use github.com/pkg/errors", TextEdits: edits}, true
+}
+
+// errorfText returns the github.com/pkg/errors call replacing a fmt.Errorf call:
+// errors.Errorf without %w, and errors.Wrap, errors.Wrapf, or errors.WithStack when
+// the format ends with %w. Errors wrapped elsewhere in the format are left alone.
+func (f *fileFixer) errorfText(call *ast.CallExpr) (string, bool) {
+	if len(call.Args) == 0 || call.Ellipsis.IsValid() {
+		return "", false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	layout, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+
+	var args []string
+	for _, arg := range call.Args[1:] {
+		src, ok := f.source(arg)
+		if !ok {
+			return "", false
+		}
+		args = append(args, src)
+	}
+
+	switch wraps(layout) {
+	case 0:
+		return f.name + ".Errorf(" + strings.Join(append([]string{lit.Value}, args...), ", ") + ")", true
+	case 1:
+		if !strings.HasSuffix(layout, "%w") || len(args) == 0 {
+			return "", false
+		}
+		prefix := strings.TrimRight(strings.TrimSuffix(layout, "%w"), ": ")
+		wrapped, rest := args[len(args)-1], args[:len(args)-1]
+		switch {
+		case prefix == "" && len(rest) == 0:
+			return f.name + ".WithStack(" + wrapped + ")", true
+		case len(rest) == 0 && !strings.Contains(prefix, "%"):
+			return f.name + ".Wrap(" + wrapped + ", " + strconv.Quote(prefix) + ")", true
+		default:
+			return f.name + ".Wrapf(" + strings.Join(append([]string{wrapped, strconv.Quote(prefix)}, rest...), ", ") + ")", true
+		}
+	}
+	return "", false
+}
+
+// wraps counts the %w verbs in a format string.
+func wraps(layout string) int {
+	n := 0
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(layout) && strings.IndexByte("+-# 0123456789.*[]", layout[i]) >= 0 {
+			i++
+		}
+		if i < len(layout) && layout[i] == 'w' {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fileFixer) source(node ast.Node) (string, bool) {
+	var b bytes.Buffer
+	if err := format.Node(&b, f.pass.Fset, node); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// addImport returns the edit adding github.com/pkg/errors to the file's imports,
+// in a group after the standard library ones, or nothing if it is already imported.
+func (f *fileFixer) addImport() []analysis.TextEdit {
+	for _, imp := range f.file.Imports {
+		if imp.Path.Value == strconv.Quote(pkgErrorsPath) {
+			return nil
+		}
+	}
+	spec := strconv.Quote(pkgErrorsPath)
+	for _, decl := range f.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT || !gen.Lparen.IsValid() || len(gen.Specs) == 0 {
+			continue
+		}
+		last := gen.Specs[len(gen.Specs)-1].(*ast.ImportSpec)
+		text := "\t" + spec + "\n"
+		if !strings.Contains(last.Path.Value, ".") {
+			text = "\n" + text
+		}
+		return []analysis.TextEdit{{Pos: gen.Rparen, End: gen.Rparen, NewText: []byte(text)}}
+	}
+	// Without an import block, a declaration of its own after the package clause.
+	pos := f.file.Name.End()
+	return []analysis.TextEdit{{Pos: pos, End: pos, NewText: []byte("\n\nimport " + spec)}}
+}
+
+// deleteImport returns the edit removing the line of imp from a parenthesized import
+// declaration, or the whole declaration when it imports nothing else.
+func (f *fileFixer) deleteImport(imp *ast.ImportSpec) []analysis.TextEdit {
+	for _, decl := range f.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if spec != imp {
+				continue
+			}
+			if !gen.Lparen.IsValid() {
+				return []analysis.TextEdit{{Pos: gen.Pos(), End: gen.End()}}
+			}
+			tok := f.pass.Fset.File(imp.Pos())
+			line := tok.Line(imp.Pos())
+			end := gen.Rparen
+			if line < tok.Line(gen.Rparen) {
+				end = tok.LineStart(line + 1)
+			}
+			return []analysis.TextEdit{{Pos: tok.LineStart(line), End: end}}
+		}
+	}
+	return nil
+}
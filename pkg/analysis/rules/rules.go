@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const (
+	MsgForbiddenCall doanalysis.Message = "call forbidden by lint.rules"
+)
+
+var Analyzer = &doanalysis.Analyzer{
+	Analyzer: &analysis.Analyzer{
+		Name:     "rules",
+		Doc:      "reports calls to functions forbidden by the project's lint.rules",
+		Run:      run,
+		Requires: []*analysis.Analyzer{inspect.Analyzer},
+	},
+	Messages: []doanalysis.Message{MsgForbiddenCall},
+}
+
+// Rule forbids calling a function or method.
+type Rule struct {
+	// Call is the import path and name of the function, such as log.Fatal, or of the
+	// method with its type, such as net/http.Client.Get.
+	Call string `json:"call"`
+	// Message follows MsgForbiddenCall in reports, to say what to do instead.
+	Message string `json:"message,omitempty"`
+}
+
+// rules are the rules checked, set with the rules option.
+var rules rulesFlag
+
+func init() {
+	Analyzer.Flags.Var(&rules, "rules", "JSON list of {call, message} rules, set from lint.rules in do.yaml")
+}
+
+// rulesFlag is a flag.Value holding rules as JSON, so the value identifies them.
+type rulesFlag []Rule
+
+func (f *rulesFlag) String() string {
+	if len(*f) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(*f)
+	return string(data)
+}
+
+func (f *rulesFlag) Set(s string) error {
+	var rs []Rule
+	if s != "" {
+		if err := json.Unmarshal([]byte(s), &rs); err != nil {
+			return errors.Wrap(err, "parse rules")
+		}
+	}
+	for _, r := range rs {
+		if _, _, ok := split(r.Call); !ok {
+			return errors.Errorf("rule call %q is not an import path and name, such as log.Fatal", r.Call)
+		}
+	}
+	*f = rs
+	return nil
+}
+
+// split returns the import path of call and the rest, the function name or the type
+// and method names.
+func split(call string) (string, string, bool) {
+	slash := strings.LastIndex(call, "/")
+	dot := strings.Index(call[slash+1:], ".")
+	if dot < 0 {
+		return "", "", false
+	}
+	path, name := call[:slash+1+dot], call[slash+2+dot:]
+	return path, name, path != "" && name != ""
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	for cur := range insp.Root().Preorder((*ast.CallExpr)(nil)) {
+		call := cur.Node().(*ast.CallExpr)
+		name := callee(pass.TypesInfo, call)
+		if name == "" {
+			continue
+		}
+		for _, r := range rules {
+			if r.Call != name {
+				continue
+			}
+			msg := string(MsgForbiddenCall) + " (" + r.Call + ")"
+			if r.Message != "" {
+				msg += ": " + r.Message
+			}
+			pass.Reportf(call.Pos(), "%s", msg)
+			break
+		}
+	}
+	return nil, nil
+}
+
+// callee returns the import path and name of the function or method call calls, as
+// in Rule.Call, or "".
+func callee(info *types.Info, call *ast.CallExpr) string {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return ""
+	}
+	fn, ok := info.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil {
+		return fn.Pkg().Path() + "." + fn.Name()
+	}
+	recv := sig.Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	if !ok {
+		return ""
+	}
+	return fn.Pkg().Path() + "." + named.Obj().Name() + "." + fn.Name()
+}
@@ -0,0 +1,46 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/analysis/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	src := `package x
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+func f(c *http.Client) {
+	_, _ = c.Get("https://example.com")
+	_, _ = http.Get("https://example.com")
+	log.Fatal(strings.ToUpper("x"))
+}
+`
+	_, diags := run(t, rules.Analyzer, src)
+	a.Empty(diags, "no rules, no findings")
+
+	r.NoError(rules.Analyzer.Configure(map[string]string{"rules": `[{"call": "net/http.Client.Get"}, {"call": "log.Fatal", "message": "return an error instead"}]`}))
+	t.Cleanup(func() { _ = rules.Analyzer.Flags.Set("rules", "") })
+	_, diags = run(t, rules.Analyzer, src)
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Message)
+	}
+	a.Equal([]string{
+		string(rules.MsgForbiddenCall) + " (net/http.Client.Get)",
+		string(rules.MsgForbiddenCall) + " (log.Fatal): return an error instead",
+	}, got)
+
+	a.Error(rules.Analyzer.Configure(map[string]string{"rules": `[{"call": "Fatal"}]`}))
+	a.Error(rules.Analyzer.Configure(map[string]string{"rules": `{`}))
+}
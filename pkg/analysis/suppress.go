@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"go/ast"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// Suppression is a //nolint:<analyzers> or //!ignore:<analyzers> comment, which
+// suppresses the diagnostics of those analyzers on its line. A bare //nolint
+// suppresses every analyzer, as it does in golangci-lint.
+type Suppression struct {
+	// Analyzers are the names listed, or nil for a bare //nolint.
+	Analyzers []string
+	Pos       token.Pos
+	Position  token.Position
+	used      map[string]bool
+}
+
+// Suppressions returns the suppression comments in files.
+func Suppressions(fset *token.FileSet, files []*ast.File) []*Suppression {
+	var sups []*Suppression
+	for _, file := range files {
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				if s, ok := parseSuppression(c.Text); ok {
+					s.Pos, s.Position = c.Pos(), fset.Position(c.Pos())
+					sups = append(sups, s)
+				}
+			}
+		}
+	}
+	return sups
+}
+
+// parseSuppression parses a comment holding //nolint or //!ignore:, possibly after
+// other text, as in "// reason //nolint:pkgerrors".
+func parseSuppression(text string) (*Suppression, bool) {
+	for _, prefix := range []string{"//nolint", "//!ignore:"} {
+		i := strings.Index(text, prefix)
+		if i < 0 {
+			continue
+		}
+		rest := text[i+len(prefix):]
+		if prefix == "//nolint" {
+			if rest == "" || rest[0] == ' ' || rest[0] == '\t' {
+				return &Suppression{used: make(map[string]bool)}, true
+			}
+			if rest[0] != ':' {
+				continue
+			}
+			rest = rest[1:]
+		}
+		if end := strings.IndexAny(rest, " \t"); end >= 0 {
+			rest = rest[:end]
+		}
+		var names []string
+		for _, name := range strings.Split(rest, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			return &Suppression{Analyzers: names, used: make(map[string]bool)}, true
+		}
+	}
+	return nil, false
+}
+
+// Suppressed reports whether one of sups suppresses the diagnostic of analyzer at
+// pos, recording that the suppression was used.
+func Suppressed(sups []*Suppression, analyzer string, pos token.Position) bool {
+	suppressed := false
+	for _, s := range sups {
+		if s.Position.Filename != pos.Filename || s.Position.Line != pos.Line {
+			continue
+		}
+		if s.Analyzers == nil || slices.Contains(s.Analyzers, analyzer) {
+			s.used[analyzer] = true
+			suppressed = true
+		}
+	}
+	return suppressed
+}
+
+// Unused returns the analyzers named by s, among those in known, that it did not
+// suppress a diagnostic of. Other names may belong to golangci-lint.
+func (s *Suppression) Unused(known []string) []string {
+	var unused []string
+	for _, name := range s.Analyzers {
+		if slices.Contains(known, name) && !s.used[name] {
+			unused = append(unused, name)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,67 @@
+package analysis_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/housecat-inc/do/pkg/analysis/nocomments"
+	"github.com/housecat-inc/do/pkg/analysis/nolint"
+	"github.com/housecat-inc/do/pkg/analysis/pkgerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuppressions(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "x.go", `package x
+
+var a = 1 //nolint:pkgerrors,nocomments // reason
+var b = 2 //!ignore:pkgerrors
+var c = 3 //nolint
+var d = 4 //nolintx
+`, parser.ParseComments)
+	r.NoError(err)
+
+	sups := doanalysis.Suppressions(fset, []*ast.File{file})
+	r.Len(sups, 3)
+	a.Equal([]string{"pkgerrors", "nocomments"}, sups[0].Analyzers)
+	a.Equal([]string{"pkgerrors"}, sups[1].Analyzers)
+	a.Nil(sups[2].Analyzers)
+
+	a.True(doanalysis.Suppressed(sups, "pkgerrors", token.Position{Filename: "x.go", Line: 3}))
+	a.False(doanalysis.Suppressed(sups, "pkgerrors", token.Position{Filename: "x.go", Line: 6}))
+	a.True(doanalysis.Suppressed(sups, "anything", token.Position{Filename: "x.go", Line: 5}))
+	a.Equal([]string{"nocomments"}, sups[0].Unused([]string{"pkgerrors", "nocomments"}))
+	a.Equal([]string{"pkgerrors"}, sups[1].Unused([]string{"pkgerrors", "nocomments"}))
+	a.Empty(sups[1].Unused([]string{"nocomments"}))
+}
+
+func TestNolint(t *testing.T) {
+	a := assert.New(t)
+
+	fset, diags := run(t, nolint.New(pkgerrors.Analyzer, nocomments.Analyzer), `package x
+
+import "fmt"
+
+func f() error {
+	return fmt.Errorf("used") //nolint:pkgerrors
+}
+
+func g() int {
+	return 1 //!ignore:pkgerrors
+}
+
+func h() error {
+	return nil //nolint:errcheck
+}
+`)
+	a.Len(diags, 1)
+	a.Equal(10, fset.Position(diags[0].Pos).Line)
+	a.Equal(string(nolint.MsgUnused), diags[0].Message)
+}
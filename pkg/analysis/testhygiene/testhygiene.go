@@ -0,0 +1,206 @@
+package testhygiene
+
+import (
+	"go/ast"
+	"go/types"
+	"go/version"
+	"strings"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const (
+	MsgParallel doanalysis.Message = "call t.Parallel() in subtests run from a table"
+	MsgContext  doanalysis.Message = "use t.Context(), which is canceled when the test ends"
+	MsgSleep    doanalysis.Message = "wait for the condition, such as with assert.Eventually or a channel, instead of time.Sleep"
+)
+
+var Analyzer = &doanalysis.Analyzer{
+	Analyzer: &analysis.Analyzer{
+		Name:     "testhygiene",
+		Doc:      "checks tests for table-driven subtests without t.Parallel(), context.Background() where t.Context() is available, and time.Sleep",
+		Run:      run,
+		Requires: []*analysis.Analyzer{inspect.Analyzer},
+	},
+	Messages: []doanalysis.Message{MsgParallel, MsgContext, MsgSleep},
+	// Some tests have no condition to wait for, such as those of timeouts.
+	Warnings: []doanalysis.Message{MsgSleep},
+}
+
+// parallel requires t.Parallel() in subtests run from a loop, set with the parallel
+// option. It is off by default, since not every project runs its table tests in
+// parallel.
+var parallel bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&parallel, "parallel", false, "require t.Parallel() in subtests run from a loop")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	for fileCur := range insp.Root().Children() {
+		file := fileCur.Node().(*ast.File)
+		if !strings.HasSuffix(pass.Fset.File(file.Pos()).Name(), "_test.go") {
+			continue
+		}
+		// t.Context was added in Go 1.24.
+		v := pass.TypesInfo.FileVersions[file]
+		hasContext := v == "" || version.Compare(v, "go1.24") >= 0
+
+		for cur := range fileCur.Preorder((*ast.CallExpr)(nil), (*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)) {
+			switch n := cur.Node().(type) {
+			case *ast.CallExpr:
+				if doanalysis.FuncName(pass.TypesInfo, n, "time") == "Sleep" {
+					MsgSleep.Report(pass, n.Pos())
+				}
+				if parallel && inLoop(cur) {
+					checkSubtest(pass, n)
+				}
+			case *ast.FuncDecl:
+				if hasContext && n.Body != nil {
+					checkContext(pass, n.Type, n.Body)
+				}
+			case *ast.FuncLit:
+				if hasContext {
+					checkContext(pass, n.Type, n.Body)
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// inLoop reports whether the node at cur runs in a loop of its function.
+func inLoop(cur inspector.Cursor) bool {
+	for c := range cur.Enclosing((*ast.ForStmt)(nil), (*ast.RangeStmt)(nil), (*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)) {
+		switch c.Node().(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// checkSubtest reports a t.Run call whose function does not call t.Parallel(), unless
+// it calls t.Setenv or t.Chdir, which panic in parallel tests.
+func checkSubtest(pass *analysis.Pass, call *ast.CallExpr) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Run" || len(call.Args) != 2 || !isTesting(pass.TypesInfo.TypeOf(sel.X)) {
+		return
+	}
+	lit, ok := call.Args[1].(*ast.FuncLit)
+	if !ok {
+		return
+	}
+	t := testingParam(pass, lit.Type)
+	if t == nil {
+		return
+	}
+	for _, stmt := range lit.Body.List {
+		if method(pass, stmt, t) == "Parallel" {
+			return
+		}
+	}
+	calls := false
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		if stmt, ok := n.(ast.Stmt); ok {
+			switch method(pass, stmt, t) {
+			case "Chdir", "Setenv":
+				calls = true
+			}
+		}
+		return !calls
+	})
+	if calls {
+		return
+	}
+	MsgParallel.ReportFix(pass, call.Pos(), analysis.SuggestedFix{
+		Message:   "run the subtest in parallel",
+		TextEdits: []analysis.TextEdit{{Pos: lit.Body.Lbrace + 1, End: lit.Body.Lbrace + 1, NewText: []byte("\n" + t.Name() + ".Parallel()")}},
+	})
+}
+
+// checkContext reports context.Background and context.TODO in body where the testing
+// parameter of the function with typ is in scope. Subtests are checked on their own.
+func checkContext(pass *analysis.Pass, typ *ast.FuncType, body *ast.BlockStmt) {
+	t := testingParam(pass, typ)
+	if t == nil {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok && testingParam(pass, lit.Type) != nil {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 0 {
+			return true
+		}
+		if name := doanalysis.FuncName(pass.TypesInfo, call, "context"); name != "Background" && name != "TODO" {
+			return true
+		}
+		if doanalysis.Lookup(pass.Pkg, t.Name(), call.Pos()) != t {
+			return true
+		}
+		MsgContext.ReportFix(pass, call.Pos(), analysis.SuggestedFix{
+			Message:   "use the test's context",
+			TextEdits: []analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: []byte(t.Name() + ".Context()")}},
+		})
+		return true
+	})
+}
+
+// testingParam returns the named *testing.T, *testing.B, *testing.F, or testing.TB
+// parameter of the function with typ, or nil.
+func testingParam(pass *analysis.Pass, typ *ast.FuncType) types.Object {
+	for _, field := range typ.Params.List {
+		if len(field.Names) != 1 || field.Names[0].Name == "_" || !isTesting(pass.TypesInfo.TypeOf(field.Type)) {
+			continue
+		}
+		return pass.TypesInfo.Defs[field.Names[0]]
+	}
+	return nil
+}
+
+func isTesting(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != "testing" {
+		return false
+	}
+	switch obj.Name() {
+	case "B", "F", "T", "TB":
+		return true
+	}
+	return false
+}
+
+// method returns the name of the method stmt calls on t, or "".
+func method(pass *analysis.Pass, stmt ast.Stmt, t types.Object) string {
+	expr, ok := stmt.(*ast.ExprStmt)
+	if !ok {
+		return ""
+	}
+	call, ok := expr.X.(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok || pass.TypesInfo.Uses[ident] != t {
+		return ""
+	}
+	return sel.Sel.Name
+}
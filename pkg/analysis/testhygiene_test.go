@@ -0,0 +1,101 @@
+package analysis_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/analysis/testhygiene"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tableTest = `package x
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTable(t *testing.T) {
+	for _, name := range []string{"a", "b"} {
+		t.Run(name, func(t *testing.T) {
+			_ = context.Background()
+		})
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("X", name)
+		})
+	}
+	t.Run("single", func(t *testing.T) {})
+	time.Sleep(time.Millisecond)
+	go func() { _ = context.TODO() }()
+}
+`
+
+// requireParallel turns on the parallel option until the test ends.
+func requireParallel(t *testing.T) {
+	require.NoError(t, testhygiene.Analyzer.Configure(map[string]string{"parallel": "true"}))
+	t.Cleanup(func() { _ = testhygiene.Analyzer.Flags.Set("parallel", "false") })
+}
+
+func TestTestHygiene(t *testing.T) {
+	a := assert.New(t)
+
+	_, diags := runFile(t, testhygiene.Analyzer, "x_test.go", tableTest)
+	a.Len(diags, 3, "subtests need no t.Parallel by default")
+
+	requireParallel(t)
+	_, diags = runFile(t, testhygiene.Analyzer, "x_test.go", tableTest)
+	sort.Slice(diags, func(i, j int) bool { return diags[i].Pos < diags[j].Pos })
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Message)
+	}
+	a.Equal([]string{
+		string(testhygiene.MsgParallel),
+		string(testhygiene.MsgContext),
+		string(testhygiene.MsgSleep),
+		string(testhygiene.MsgContext),
+	}, got, "subtests calling t.Setenv or not in a loop need no t.Parallel")
+
+	_, diags = run(t, testhygiene.Analyzer, tableTest)
+	a.Empty(diags, "only test files are checked")
+}
+
+func TestFixTestHygiene(t *testing.T) {
+	a := assert.New(t)
+	requireParallel(t)
+
+	a.Equal(`package x
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	for _, name := range []string{"a", "b"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_ = t.Context()
+		})
+	}
+	_ = context.Canceled
+}
+`, fixFile(t, testhygiene.Analyzer, "x_test.go", `package x
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	for _, name := range []string{"a", "b"} {
+		t.Run(name, func(t *testing.T) {
+			_ = context.Background()
+		})
+	}
+	_ = context.Canceled
+}
+`))
+}
@@ -0,0 +1,124 @@
+package todos
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+	"unicode"
+
+	doanalysis "github.com/housecat-inc/do/pkg/analysis"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	MsgNoIssue doanalysis.Message = "reference an issue in TODO and FIXME comments, such as TODO(#123), a URL, or a ticket ID like ABC-123"
+)
+
+var Analyzer = &doanalysis.Analyzer{
+	Analyzer: &analysis.Analyzer{
+		Name: "todos",
+		Doc:  "requires TODO and FIXME comments, including //! ones, to reference an issue URL or ticket ID",
+		Run:  run,
+		// Comments are syntax, so type errors in a package do not affect them.
+		RunDespiteErrors: true,
+	},
+	Messages: []doanalysis.Message{MsgNoIssue},
+}
+
+// defaultIssue matches URLs, GitHub references such as #123 and owner/repo#123, and
+// ticket IDs such as ABC-123.
+const defaultIssue = `https?://\S+|\b[\w.-]+/[\w.-]+#\d+|#\d+|\b[A-Z][A-Z0-9]+-\d+\b`
+
+// keywords lists the words that start a tracked comment, set with the keywords option.
+var keywords string
+
+// issue matches an issue reference, set with the issue option.
+var issue = regexpFlag{re: regexp.MustCompile(defaultIssue)}
+
+func init() {
+	Analyzer.Flags.StringVar(&keywords, "keywords", "TODO,FIXME", "comma-separated words that start a comment needing an issue")
+	Analyzer.Flags.Var(&issue, "issue", "regular expression matching an issue reference")
+}
+
+// regexpFlag is a flag.Value holding a compiled regular expression.
+type regexpFlag struct {
+	re *regexp.Regexp
+}
+
+func (f *regexpFlag) String() string {
+	if f.re == nil {
+		return ""
+	}
+	return f.re.String()
+}
+
+func (f *regexpFlag) Set(s string) error {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f.re = re
+	return nil
+}
+
+// Todo is a comment starting with one of the keywords.
+type Todo struct {
+	// Issue is the first issue reference in the comment, or "".
+	Issue   string
+	Keyword string
+	Pos     token.Pos
+	// Text is the comment without its markers, starting with the keyword.
+	Text string
+}
+
+// Find returns the comments in file starting with one of the keywords, after any
+// //! marking them important.
+func Find(file *ast.File) []Todo {
+	var todos []Todo
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			if todo, ok := parse(c.Text); ok {
+				todo.Pos = c.Pos()
+				todos = append(todos, todo)
+			}
+		}
+	}
+	return todos
+}
+
+func parse(text string) (Todo, bool) {
+	body := strings.TrimPrefix(text, "//")
+	if strings.HasPrefix(text, "/*") {
+		body = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	}
+	body = strings.TrimSpace(strings.TrimPrefix(body, "!"))
+	if line, _, ok := strings.Cut(body, "\n"); ok {
+		body = strings.TrimSpace(line)
+	}
+	for _, keyword := range strings.Split(keywords, ",") {
+		keyword = strings.TrimSpace(keyword)
+		rest, ok := strings.CutPrefix(body, keyword)
+		if keyword == "" || !ok {
+			continue
+		}
+		// The keyword must be a whole word, so TODOS does not count.
+		if rest != "" && (unicode.IsLetter(rune(rest[0])) || unicode.IsDigit(rune(rest[0]))) {
+			continue
+		}
+		return Todo{Issue: issue.re.FindString(rest), Keyword: keyword, Text: body}, true
+	}
+	return Todo{}, false
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		for _, todo := range Find(file) {
+			if todo.Issue == "" {
+				MsgNoIssue.Report(pass, todo.Pos)
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,58 @@
+package analysis_test
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/analysis/todos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const todoSrc = `package x
+
+// F is documented.
+func F() {
+	//! TODO(#12): keep
+	// TODO: see https://github.com/o/r/issues/3
+	/* FIXME ABC-42 */
+	// FIXME o/r#7 and more
+	// TODO handle this
+	// TODOS are not tracked
+	//! FIXME
+}
+`
+
+func TestTodos(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	file, err := parser.ParseFile(token.NewFileSet(), "x.go", todoSrc, parser.ParseComments)
+	r.NoError(err)
+	var issues, texts []string
+	for _, todo := range todos.Find(file) {
+		issues = append(issues, todo.Issue)
+		texts = append(texts, todo.Text)
+	}
+	a.Equal([]string{"#12", "https://github.com/o/r/issues/3", "ABC-42", "o/r#7", "", ""}, issues)
+	a.Equal([]string{"TODO(#12): keep", "TODO: see https://github.com/o/r/issues/3", "FIXME ABC-42", "FIXME o/r#7 and more", "TODO handle this", "FIXME"}, texts)
+
+	fset, diags := run(t, todos.Analyzer, todoSrc)
+	var lines []int
+	for _, d := range diags {
+		a.Equal(string(todos.MsgNoIssue), d.Message)
+		lines = append(lines, fset.Position(d.Pos).Line)
+	}
+	a.Equal([]int{9, 11}, lines)
+
+	r.NoError(todos.Analyzer.Configure(map[string]string{"issue": `JIRA-\d+`, "keywords": "HACK"}))
+	t.Cleanup(func() {
+		_ = todos.Analyzer.Flags.Set("issue", todos.Analyzer.Flags.Lookup("issue").DefValue)
+		_ = todos.Analyzer.Flags.Set("keywords", "TODO,FIXME")
+	})
+	_, diags = run(t, todos.Analyzer, "package x\n\n// HACK JIRA-1\n// HACK #1\n// TODO\n")
+	a.Len(diags, 1)
+
+	a.Error(todos.Analyzer.Configure(map[string]string{"issue": "("}))
+}
@@ -0,0 +1,36 @@
+package analysis
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// FuncName returns the name of the function of the package at path that call calls,
+// or "".
+func FuncName(info *types.Info, call *ast.CallExpr, path string) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	pkgName, ok := info.Uses[ident].(*types.PkgName)
+	if !ok || pkgName.Imported().Path() != path {
+		return ""
+	}
+	return sel.Sel.Name
+}
+
+// Lookup returns the object name refers to at pos in pkg, or nil, so fixes can check
+// that a name they insert is not shadowed.
+func Lookup(pkg *types.Package, name string, pos token.Pos) types.Object {
+	scope := pkg.Scope().Innermost(pos)
+	if scope == nil {
+		return nil
+	}
+	_, obj := scope.LookupParent(name, pos)
+	return obj
+}
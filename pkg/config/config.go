@@ -0,0 +1,336 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the project config file name, relative to the project root.
+const File = "do.yaml"
+
+// AltFile is checked when File does not exist.
+const AltFile = ".do/config.yaml"
+
+// Assets configures uploading a static asset directory to Cloud Storage on deploy.
+type Assets struct {
+	Bucket string `yaml:"bucket,omitempty"`
+	// CDN serves the bucket through Cloud CDN on an HTTP load balancer.
+	CDN bool `yaml:"cdn,omitempty"`
+	// Dir defaults to dist, the bundle output.
+	Dir string `yaml:"dir,omitempty"`
+}
+
+// Lint configures the custom analyzers run by do lint.
+type Lint struct {
+	// Disable lists analyzers not to run, such as nocomments.
+	Disable []string `yaml:"disable,omitempty"`
+	// Enable lists the only analyzers to run. Empty runs every analyzer not disabled.
+	Enable []string `yaml:"enable,omitempty"`
+	// Exclude maps an analyzer, or * for all of them, to patterns for files it does
+	// not check, such as *_test.go or internal/gen/, matched as svelte.exclude is.
+	Exclude map[string][]string `yaml:"exclude,omitempty"`
+	// Golangci pins golangci-lint and configures the .golangci.yml do manages.
+	Golangci LintGolangci `yaml:"golangci,omitempty"`
+	// Imports are packages the project may not import, checked by the imports
+	// analyzer.
+	Imports []LintImport `yaml:"imports,omitempty"`
+	// Rules are house rules forbidding calls, checked by the rules analyzer.
+	Rules []LintRule `yaml:"rules,omitempty"`
+	// Severity maps an analyzer to error or warning for all of its findings,
+	// overriding the severity of each. Keys may also be a vettool, for all of its
+	// analyzers, or * for every analyzer.
+	Severity map[string]string `yaml:"severity,omitempty"`
+	// Settings maps an analyzer to its options by name, such as allow: TODO for
+	// nocomments. do lint --list shows the options of each analyzer.
+	Settings map[string]map[string]string `yaml:"settings,omitempty"`
+	// Vettools are more analyzers, as binaries speaking the go vet -vettool protocol
+	// such as those built with unitchecker.Main. Each is a path to a binary or a Go
+	// package to build, such as ./tools/vet.
+	Vettools []string `yaml:"vettools,omitempty"`
+}
+
+// LintGolangci configures golangci-lint.
+type LintGolangci struct {
+	// Disable lists linters and formatters to remove from the managed
+	// .golangci.yml, such as misspell.
+	Disable []string `yaml:"disable,omitempty"`
+	// Enable lists linters and formatters to add to the managed .golangci.yml, such
+	// as gocritic or goimports. A .golangci.yml without do's header is the
+	// project's own and is used as is.
+	Enable []string `yaml:"enable,omitempty"`
+	// Version pins the golangci-lint release in go.mod, such as 2.6.2. Empty keeps
+	// the version go.mod has, or adds the one do was released with.
+	Version string `yaml:"version,omitempty"`
+}
+
+// LintImport forbids importing a package, and the packages below it, except from the
+// packages in Allow.
+type LintImport struct {
+	// Allow lists the packages that may import it, by path relative to the module,
+	// such as pkg/db, which also allows the packages below it.
+	Allow []string `yaml:"allow,omitempty"`
+	// Message says what to do instead.
+	Message string `yaml:"message,omitempty"`
+	// Path is the import path, such as io/ioutil.
+	Path string `yaml:"path"`
+}
+
+// LintRule forbids calling a function or method.
+type LintRule struct {
+	// Call is the import path and name of the function, such as log.Fatal, or of the
+	// method with its type, such as net/http.Client.Get.
+	Call string `yaml:"call"`
+	// Message says what to do instead.
+	Message string `yaml:"message,omitempty"`
+}
+
+// Notifications configures where deploy events are posted.
+type Notifications struct {
+	// Webhook is a Slack or Discord incoming webhook URL.
+	Webhook string `yaml:"webhook,omitempty"`
+}
+
+// Preview holds settings applied only to tagged preview deploys.
+type Preview struct {
+	// Define overrides svelte.define in bundles built for preview deploys.
+	Define map[string]string `yaml:"define,omitempty"`
+	Env    map[string]string `yaml:"env,omitempty"`
+}
+
+// Entry is one bundle written by do bundle.
+type Entry struct {
+	// Include lists the components and scripts to bundle as directories, .svelte,
+	// .js, or .ts files, or glob patterns relative to the project root. Empty
+	// includes every component.
+	Include []string `yaml:"include,omitempty"`
+	// Exclude adds patterns to svelte.exclude for this bundle.
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Outfile is where the bundle is written. Empty writes dist/app.min.js.
+	Outfile string `yaml:"outfile,omitempty"`
+	// StripPrefix is trimmed from export keys, so with admin/ the component
+	// admin/Users.svelte is exported as Users.
+	StripPrefix string `yaml:"strip_prefix,omitempty"`
+}
+
+// Svelte configures component compilation and checking.
+type Svelte struct {
+	// Aliases maps import prefixes to directories relative to the project root, such
+	// as $lib to src/lib, so components can import $lib/Button.svelte as in
+	// SvelteKit. A trailing /* on either side, as in tsconfig paths, is ignored.
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+	// Define sets compile-time constants read in components and scripts as
+	// import.meta.env.NAME, such as PUBLIC_API_URL. Values may reference
+	// environment variables as $VAR or ${VAR}.
+	Define map[string]string `yaml:"define,omitempty"`
+	// Entries splits do bundle into several bundles, such as one per frontend in a
+	// monorepo. Empty bundles every component into dist/app.min.js.
+	Entries []Entry `yaml:"entries,omitempty"`
+	// Exclude lists patterns for components do bundle leaves out, such as
+	// *.stories.svelte or testdata/. A pattern ending in / matches a directory
+	// anywhere, one without / a file name, and others a path from the project root.
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Errors lists warning codes, such as a11y_*, that fail do lint.
+	Errors []string `yaml:"errors,omitempty"`
+	// Ignore lists diagnostic codes do lint does not report, such as a11y_autofocus.
+	Ignore []string `yaml:"ignore,omitempty"`
+	// Version pins the Svelte release, such as 5.46.1. Empty uses the release
+	// embedded in do.
+	Version string `yaml:"version,omitempty"`
+}
+
+// Tailwind configures the Tailwind CSS step of do bundle and do dev.
+type Tailwind struct {
+	// Enabled runs Tailwind on do bundle and watches with it on do dev.
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Input is the stylesheet to compile. Empty compiles Tailwind with the classes
+	// used in .html, .svelte, and .templ files.
+	Input string `yaml:"input,omitempty"`
+	// Output defaults to dist/app.min.css.
+	Output string `yaml:"output,omitempty"`
+	// Version pins the Tailwind release of the standalone CLI, such as 4.1.13.
+	Version string `yaml:"version,omitempty"`
+}
+
+// Config holds project settings that were previously only stored in .envrc.
+type Config struct {
+	Assets         Assets            `yaml:"assets,omitempty"`
+	Auth           string            `yaml:"auth,omitempty"`
+	Builder        string            `yaml:"builder,omitempty"`
+	BuildPath      string            `yaml:"build_path,omitempty"`
+	CloudSQL       []string          `yaml:"cloudsql,omitempty"`
+	Env            map[string]string `yaml:"env,omitempty"`
+	HealthPath     string            `yaml:"health_path,omitempty"`
+	Invokers       []string          `yaml:"invokers,omitempty"`
+	Lint           Lint              `yaml:"lint,omitempty"`
+	Notifications  Notifications     `yaml:"notifications,omitempty"`
+	Preview        Preview           `yaml:"preview,omitempty"`
+	Profiles       map[string]Config `yaml:"profiles,omitempty"`
+	Project        string            `yaml:"project,omitempty"`
+	Region         string            `yaml:"region,omitempty"`
+	Regions        []string          `yaml:"regions,omitempty"`
+	Service        string            `yaml:"service,omitempty"`
+	ServiceAccount string            `yaml:"service_account,omitempty"`
+	Svelte         Svelte            `yaml:"svelte,omitempty"`
+	Tailwind       Tailwind          `yaml:"tailwind,omitempty"`
+}
+
+// Path returns the config file path in root, preferring File over AltFile.
+func Path(root string) string {
+	alt := filepath.Join(root, AltFile)
+	if _, err := os.Stat(filepath.Join(root, File)); err != nil {
+		if _, err := os.Stat(alt); err == nil {
+			return alt
+		}
+	}
+	return filepath.Join(root, File)
+}
+
+// Load reads the config in root. A missing file returns an empty Config.
+func Load(root string) (*Config, error) {
+	data, err := os.ReadFile(Path(root))
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", Path(root))
+	}
+	return &c, nil
+}
+
+// Save writes the config to root.
+func (c *Config) Save(root string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	path := Path(root)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// PrimaryRegion returns Region, or the first of Regions when Region is unset.
+func (c *Config) PrimaryRegion() string {
+	if c.Region == "" && len(c.Regions) > 0 {
+		return c.Regions[0]
+	}
+	return c.Region
+}
+
+// Environ returns the gcloud and ko environment variables the config sets, keyed by name.
+// Empty fields are omitted so the environment can fill them in.
+func (c *Config) Environ() map[string]string {
+	env := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			env[key] = value
+		}
+	}
+
+	set("CLOUDSDK_CORE_PROJECT", c.Project)
+	set("CLOUDSDK_RUN_REGION", c.PrimaryRegion())
+	set("CLOUD_RUN_SERVICE", c.Service)
+	set("KO_BUILD_PATH", c.BuildPath)
+	return env
+}
+
+// Profile returns c with the named profile layered on top. Fields set in the
+// profile replace the top-level value, except maps, which are merged by key.
+// An empty name returns c unchanged.
+func (c *Config) Profile(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, errors.Errorf("profile %q not found in %s (have: %s)", name, File, strings.Join(c.profileNames(), ", "))
+	}
+
+	merged := *c
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(profile)
+	for i := range dst.NumField() {
+		if dst.Type().Field(i).Name == "Profiles" {
+			continue
+		}
+		f := src.Field(i)
+		if f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Map && !dst.Field(i).IsNil() {
+			m := reflect.MakeMap(f.Type())
+			for _, k := range dst.Field(i).MapKeys() {
+				m.SetMapIndex(k, dst.Field(i).MapIndex(k))
+			}
+			for _, k := range f.MapKeys() {
+				m.SetMapIndex(k, f.MapIndex(k))
+			}
+			dst.Field(i).Set(m)
+			continue
+		}
+		dst.Field(i).Set(f)
+	}
+	return &merged, nil
+}
+
+// SetProfile stores the fields of merged that differ from the top-level settings
+// in the named profile, the inverse of Profile.
+func (c *Config) SetProfile(name string, merged *Config) {
+	var profile Config
+	dst := reflect.ValueOf(&profile).Elem()
+	base := reflect.ValueOf(*c)
+	src := reflect.ValueOf(*merged)
+	for i := range dst.NumField() {
+		if dst.Type().Field(i).Name == "Profiles" {
+			continue
+		}
+		f := src.Field(i)
+		if reflect.DeepEqual(f.Interface(), base.Field(i).Interface()) {
+			continue
+		}
+		if f.Kind() == reflect.Map {
+			m := reflect.MakeMap(f.Type())
+			for _, k := range f.MapKeys() {
+				if b := base.Field(i).MapIndex(k); !b.IsValid() || !reflect.DeepEqual(b.Interface(), f.MapIndex(k).Interface()) {
+					m.SetMapIndex(k, f.MapIndex(k))
+				}
+			}
+			if m.Len() > 0 {
+				dst.Field(i).Set(m)
+			}
+			continue
+		}
+		dst.Field(i).Set(f)
+	}
+
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Config)
+	}
+	c.Profiles[name] = profile
+}
+
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,86 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	dir := t.TempDir()
+
+	c, err := config.Load(dir)
+	r.NoError(err)
+	a.Equal(&config.Config{}, c)
+
+	c.Project = "my-project"
+	c.Region = "us-central1"
+	c.Service = "app"
+	r.NoError(c.Save(dir))
+
+	loaded, err := config.Load(dir)
+	r.NoError(err)
+	a.Equal(c, loaded)
+	a.Equal(map[string]string{
+		"CLOUDSDK_CORE_PROJECT": "my-project",
+		"CLOUDSDK_RUN_REGION":   "us-central1",
+		"CLOUD_RUN_SERVICE":     "app",
+	}, loaded.Environ())
+}
+
+func TestLoadAltFile(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	r.NoError(os.MkdirAll(filepath.Join(dir, ".do"), 0755))
+	r.NoError(os.WriteFile(filepath.Join(dir, config.AltFile), []byte("project: alt\n"), 0644))
+
+	c, err := config.Load(dir)
+	r.NoError(err)
+	a.Equal("alt", c.Project)
+	a.Equal(filepath.Join(dir, config.AltFile), config.Path(dir))
+}
+
+func TestProfile(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	base := &config.Config{
+		Env:     map[string]string{"DATABASE_URL": "prod", "LOG_LEVEL": "info"},
+		Project: "app-prod",
+		Region:  "us-central1",
+		Service: "app",
+		Profiles: map[string]config.Config{
+			"staging": {
+				Env:     map[string]string{"DATABASE_URL": "staging"},
+				Project: "app-staging",
+			},
+		},
+	}
+
+	staging, err := base.Profile("staging")
+	r.NoError(err)
+	a.Equal("app-staging", staging.Project)
+	a.Equal("us-central1", staging.Region)
+	a.Equal(map[string]string{"DATABASE_URL": "staging", "LOG_LEVEL": "info"}, staging.Env)
+	a.Equal("app-prod", base.Project)
+
+	staging.Service = "app-staging"
+	base.SetProfile("staging", staging)
+	a.Equal(config.Config{
+		Env:     map[string]string{"DATABASE_URL": "staging"},
+		Project: "app-staging",
+		Service: "app-staging",
+	}, base.Profiles["staging"])
+
+	_, err = base.Profile("prod")
+	a.Error(err)
+}
@@ -0,0 +1,131 @@
+package gcloud
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheDir holds cached results of slow lookups that rarely change, such as the
+// project list and enabled APIs. Empty disables the cache.
+var CacheDir = defaultCacheDir()
+
+// CacheRefresh skips cached results. Fresh results are still written to the cache.
+var CacheRefresh bool
+
+const (
+	apisTTL          = time.Hour
+	projectNumberTTL = 30 * 24 * time.Hour
+	projectsTTL      = time.Hour
+	regionsTTL       = 24 * time.Hour
+	servicesTTL      = 10 * time.Minute
+)
+
+type cacheEntry[T any] struct {
+	Created time.Time `json:"created"`
+	Value   T         `json:"value"`
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "do")
+}
+
+// cached returns the value stored under key if it is younger than ttl, and otherwise
+// calls fetch and stores its result. Cache read and write failures are ignored.
+func cached[T any](key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	path := cachePath(key)
+	if path != "" && !CacheRefresh {
+		if data, err := os.ReadFile(path); err == nil {
+			var e cacheEntry[T]
+			if json.Unmarshal(data, &e) == nil && time.Since(e.Created) < ttl {
+				return e.Value, nil
+			}
+		}
+	}
+
+	v, err := fetch()
+	if err != nil || path == "" {
+		return v, err
+	}
+	writeCache(path, cacheEntry[T]{Created: time.Now(), Value: v})
+	return v, nil
+}
+
+func writeCache(path string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0o700) == nil {
+		_ = os.WriteFile(path, data, 0o600)
+	}
+}
+
+// RecentRegions returns regions passed to UseRegion, most recent first.
+func RecentRegions() []string {
+	var recent []string
+	if path := cachePath(recentRegionsKey); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, &recent)
+		}
+	}
+	return recent
+}
+
+// UseRegion records region as the most recently used, keeping the last five.
+func UseRegion(region string) {
+	path := cachePath(recentRegionsKey)
+	if path == "" {
+		return
+	}
+	recent := []string{region}
+	for _, r := range RecentRegions() {
+		if r != region && len(recent) < 5 {
+			recent = append(recent, r)
+		}
+	}
+	writeCache(path, recent)
+}
+
+// invalidate removes cached values after a change that makes them stale.
+func invalidate(keys ...string) {
+	for _, key := range keys {
+		if path := cachePath(key); path != "" {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+func cachePath(key string) string {
+	if CacheDir == "" {
+		return ""
+	}
+	return filepath.Join(CacheDir, key+".json")
+}
+
+func apisKey(project string) string {
+	return "apis-" + project
+}
+
+func projectNumberKey(project string) string {
+	return "project-number-" + project
+}
+
+func servicesKey(project, region string) string {
+	return "services-" + project + "-" + region
+}
+
+func regionsKey(project string) string {
+	return "regions-" + project
+}
+
+const (
+	apisEnabledKey   = "apis-enabled"
+	projectsKey      = "projects"
+	recentRegionsKey = "recent-regions"
+)
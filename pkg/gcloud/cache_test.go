@@ -0,0 +1,74 @@
+package gcloud_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	ctx := t.Context()
+	fake := gcloudtest.Install(t)
+	fake.Reply("123\n", "gcloud", "projects", "describe", "p")
+
+	for range 2 {
+		number, err := gcloud.ProjectNumber(ctx, "p")
+		r.NoError(err)
+		a.Equal("123", number)
+	}
+	a.Len(fake.Calls(), 1)
+
+	gcloud.CacheRefresh = true
+	t.Cleanup(func() { gcloud.CacheRefresh = false })
+	_, err := gcloud.ProjectNumber(ctx, "p")
+	r.NoError(err)
+	a.Len(fake.Calls(), 2)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	ctx := t.Context()
+	fake := gcloudtest.Install(t)
+	fake.Reply(`[{"metadata": {"name": "app"}}]`, "gcloud", "run", "services", "list")
+
+	services, err := gcloud.ListServices(ctx, "p", "us-central1")
+	r.NoError(err)
+	a.Equal([]gcloud.Service{{Name: "app"}}, services)
+
+	r.NoError(gcloud.Deploy(ctx, "p", "us-central1", "api", "gcr.io/p/api:v1", gcloud.DeployOptions{}))
+	_, err = gcloud.ListServices(ctx, "p", "us-central1")
+	r.NoError(err)
+
+	a.Len(fake.Commands(), 4)
+	a.Equal("gcloud run services list --platform=managed --region=us-central1 --project=p --format=json", fake.Commands()[3])
+}
+
+func TestListRegions(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply("us-central1\nasia-northeast1\nsouthamerica-east1\n", "gcloud", "run", "regions", "list")
+
+	regions, err := gcloud.ListRegions(t.Context(), "p")
+	r.NoError(err)
+
+	a.Equal([]string{"asia-northeast1", "southamerica-east1", "us-central1"}, regions)
+}
+
+func TestRecentRegions(t *testing.T) {
+	a := assert.New(t)
+	gcloudtest.Install(t)
+
+	a.Empty(gcloud.RecentRegions())
+
+	for _, region := range []string{"us-central1", "asia-northeast1", "us-central1"} {
+		gcloud.UseRegion(region)
+	}
+	a.Equal([]string{"us-central1", "asia-northeast1"}, gcloud.RecentRegions())
+}
@@ -0,0 +1,80 @@
+package gcloud
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cloud Run list prices in USD for request-based billing in Tier 1 regions.
+const (
+	PricePerGiBSecond   = 0.0000025
+	PricePerMillionReqs = 0.40
+	PricePerVCPUSecond  = 0.000024
+)
+
+// Usage is a service's resource use over Period.
+type Usage struct {
+	// BillableSeconds is the total billable instance time across all instances.
+	BillableSeconds float64
+	CPU             float64
+	MemoryGiB       float64
+	Period          time.Duration
+	Requests        float64
+}
+
+// Cost is an estimated monthly cost in USD.
+type Cost struct {
+	CPU      float64
+	Memory   float64
+	Requests float64
+	Total    float64
+}
+
+// EstimateMonthlyCost scales usage over its period to 30 days and prices it with list prices.
+// It ignores the free tier, which is shared across a billing account.
+func EstimateMonthlyCost(u Usage) Cost {
+	if u.Period <= 0 {
+		return Cost{}
+	}
+	scale := float64(30*24*time.Hour) / float64(u.Period)
+
+	c := Cost{
+		CPU:      u.BillableSeconds * u.CPU * PricePerVCPUSecond * scale,
+		Memory:   u.BillableSeconds * u.MemoryGiB * PricePerGiBSecond * scale,
+		Requests: u.Requests / 1e6 * PricePerMillionReqs * scale,
+	}
+	c.Total = c.CPU + c.Memory + c.Requests
+	return c
+}
+
+// ParseCPU parses a Kubernetes CPU quantity such as "2" or "1000m" into vCPUs.
+func ParseCPU(s string) (float64, error) {
+	if m, ok := strings.CutSuffix(s, "m"); ok {
+		v, err := strconv.ParseFloat(m, 64)
+		return v / 1000, errors.Wrapf(err, "invalid cpu %q", s)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, errors.Wrapf(err, "invalid cpu %q", s)
+}
+
+// ParseMemoryGiB parses a Kubernetes memory quantity such as "512Mi" or "2G" into GiB.
+func ParseMemoryGiB(s string) (float64, error) {
+	units := []struct {
+		suffix string
+		bytes  float64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30},
+		{"k", 1e3}, {"M", 1e6}, {"G", 1e9},
+	}
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			return v * u.bytes / (1 << 30), errors.Wrapf(err, "invalid memory %q", s)
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v / (1 << 30), errors.Wrapf(err, "invalid memory %q", s)
+}
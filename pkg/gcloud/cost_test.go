@@ -0,0 +1,52 @@
+package gcloud_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateMonthlyCost(t *testing.T) {
+	a := assert.New(t)
+
+	c := gcloud.EstimateMonthlyCost(gcloud.Usage{
+		BillableSeconds: 1_000_000,
+		CPU:             1,
+		MemoryGiB:       0.5,
+		Period:          15 * 24 * time.Hour,
+		Requests:        5_000_000,
+	})
+	a.InDelta(48.0, c.CPU, 0.001)
+	a.InDelta(2.5, c.Memory, 0.001)
+	a.InDelta(4.0, c.Requests, 0.001)
+	a.InDelta(54.5, c.Total, 0.001)
+
+	a.Equal(gcloud.Cost{}, gcloud.EstimateMonthlyCost(gcloud.Usage{}))
+}
+
+func TestParseQuantities(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	cpu, err := gcloud.ParseCPU("1000m")
+	r.NoError(err)
+	a.Equal(1.0, cpu)
+
+	cpu, err = gcloud.ParseCPU("2")
+	r.NoError(err)
+	a.Equal(2.0, cpu)
+
+	mem, err := gcloud.ParseMemoryGiB("512Mi")
+	r.NoError(err)
+	a.Equal(0.5, mem)
+
+	mem, err = gcloud.ParseMemoryGiB("2Gi")
+	r.NoError(err)
+	a.Equal(2.0, mem)
+
+	_, err = gcloud.ParseMemoryGiB("lots")
+	a.Error(err)
+}
@@ -0,0 +1,86 @@
+package gcloud
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrAPIDisabled      = errors.New("API not enabled")
+	ErrNotAuthenticated = errors.New("not authenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrServiceNotFound  = errors.New("service not found")
+)
+
+// Error is a failed gcloud command. Kind is one of the Err* values when Classify
+// recognizes the failure, so callers can branch with errors.Is.
+type Error struct {
+	Err    error
+	Kind   error
+	Stderr string
+}
+
+// Error returns gcloud's last ERROR line, or the exec error when there is none.
+func (e *Error) Error() string {
+	lines := strings.Split(e.Stderr, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if msg, ok := strings.CutPrefix(strings.TrimSpace(lines[i]), "ERROR: "); ok {
+			return msg
+		}
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// statusCode matches a google.rpc status code, as gcloud prints it after the command
+// in an error, such as PERMISSION_DENIED: or the reason SERVICE_DISABLED.
+var statusCode = regexp.MustCompile(`\b(PERMISSION_DENIED|SERVICE_DISABLED|UNAUTHENTICATED)\b`)
+
+// Classify returns the Err* value matching the error gcloud printed to stderr, or nil
+// if the failure is not recognized. Only the error, from its "ERROR: (gcloud." prefix
+// on, is matched, so output before it, such as a container's logs, cannot be
+// mistaken for it.
+func Classify(stderr string) error {
+	i := strings.Index(stderr, "ERROR: (gcloud")
+	if i < 0 {
+		return nil
+	}
+	msg := stderr[i:]
+	codes := make(map[string]bool)
+	for _, code := range statusCode.FindAllString(msg, -1) {
+		codes[code] = true
+	}
+	s := strings.ToLower(msg)
+	switch {
+	case codes["UNAUTHENTICATED"],
+		strings.Contains(s, "you do not currently have an active account"),
+		strings.Contains(s, "reauthentication failed"),
+		strings.Contains(s, "problem refreshing your current auth tokens"):
+		return ErrNotAuthenticated
+	// Disabled APIs fail with PERMISSION_DENIED too, so they are checked first.
+	case codes["SERVICE_DISABLED"],
+		strings.Contains(s, "has not been used in project"):
+		return ErrAPIDisabled
+	case codes["PERMISSION_DENIED"],
+		strings.Contains(s, "httperror 403"),
+		strings.Contains(s, "does not have permission to"):
+		return ErrPermissionDenied
+	case strings.Contains(s, "cannot find service ["),
+		strings.Contains(s, "service [") && strings.Contains(s, "could not be found"):
+		return ErrServiceNotFound
+	}
+	return nil
+}
+
+func commandError(err error, stderr string) error {
+	stderr = strings.TrimSpace(stderr)
+	return errors.WithStack(&Error{Err: err, Kind: Classify(stderr), Stderr: stderr})
+}
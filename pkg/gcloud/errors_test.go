@@ -0,0 +1,61 @@
+package gcloud_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		want   error
+	}{
+		{"no account", "ERROR: (gcloud.projects.list) You do not currently have an active account selected.", gcloud.ErrNotAuthenticated},
+		{"reauthentication", "ERROR: (gcloud.auth.print-access-token) There was a problem refreshing your current auth tokens: Reauthentication failed.", gcloud.ErrNotAuthenticated},
+		{"unauthenticated status", "ERROR: (gcloud.run.services.list) UNAUTHENTICATED: Request had invalid authentication credentials.", gcloud.ErrNotAuthenticated},
+		{"api not used", "ERROR: (gcloud.run.deploy) PERMISSION_DENIED: Cloud Run Admin API has not been used in project 123 before or it is disabled.", gcloud.ErrAPIDisabled},
+		{"service disabled reason", "ERROR: (gcloud.run.deploy) PERMISSION_DENIED: Request blocked.\n- '@type': type.googleapis.com/google.rpc.ErrorInfo\n  reason: SERVICE_DISABLED", gcloud.ErrAPIDisabled},
+		{"permission denied status", "ERROR: (gcloud.run.deploy) PERMISSION_DENIED: Permission 'run.services.get' denied on resource", gcloud.ErrPermissionDenied},
+		{"no permission", "ERROR: (gcloud.run.deploy) User [me@example.com] does not have permission to access namespaces instance [app]", gcloud.ErrPermissionDenied},
+		{"http 403", "ERROR: (gcloud.storage.cp) HTTPError 403: Caller does not have storage.objects.create access", gcloud.ErrPermissionDenied},
+		{"cannot find service", "ERROR: (gcloud.run.services.describe) Cannot find service [app]", gcloud.ErrServiceNotFound},
+		{"service not found", "ERROR: (gcloud.run.services.update-traffic) Service [app] could not be found.", gcloud.ErrServiceNotFound},
+		{"revision not found", "ERROR: (gcloud.run.revisions.describe) Revision [app-1] could not be found.", nil},
+		{"empty", "", nil},
+
+		// Words in output before gcloud's error, or in its text, are not status codes.
+		{"container logs", "2024/01/02 GET /admin 403 forbidden\nunauthenticated request rejected\nERROR: (gcloud.run.deploy) Revision 'app-00002' is not ready and cannot serve traffic.", nil},
+		{"forbidden in description", "ERROR: (gcloud.run.deploy) Invalid value for description: \"forbidden words are permission denied\"", nil},
+		{"disabled in message", "ERROR: (gcloud.run.jobs.execute) The job failed because it is disabled by its configuration.", nil},
+		{"lowercase status", "ERROR: (gcloud.run.deploy) container exited: upstream returned permission_denied and unauthenticated", nil},
+		{"no gcloud error", "PERMISSION_DENIED: not from gcloud", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+
+			a.Equal(tt.want, gcloud.Classify(tt.stderr))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	a := assert.New(t)
+
+	err := errors.Wrap(&gcloud.Error{
+		Err:    errors.New("exit status 1"),
+		Kind:   gcloud.ErrServiceNotFound,
+		Stderr: "Deploying...\nERROR: (gcloud.run.services.describe) Cannot find service [app]",
+	}, "failed to describe service")
+
+	a.True(errors.Is(err, gcloud.ErrServiceNotFound))
+	a.False(errors.Is(err, gcloud.ErrPermissionDenied))
+	a.Equal("failed to describe service: (gcloud.run.services.describe) Cannot find service [app]", err.Error())
+
+	a.Equal("exit status 1", (&gcloud.Error{Err: errors.New("exit status 1")}).Error())
+}
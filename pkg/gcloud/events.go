@@ -0,0 +1,104 @@
+package gcloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	CommandFinished EventType = "finished"
+	CommandOutput   EventType = "output"
+	CommandStarted  EventType = "started"
+)
+
+// Event reports progress of a command run by this package.
+type Event struct {
+	Args []string
+	// Duration is how long the command ran, for CommandFinished.
+	Duration time.Duration
+	// Err is the command's failure, for CommandFinished.
+	Err error
+	// Line is one line of stdout or stderr without its newline, for CommandOutput.
+	// Output events are sent for Run, RunInteractive, and Attach but not for Output,
+	// whose stdout is returned to the caller.
+	Line string
+	Name string
+	Type EventType
+}
+
+// Events receives an Event for each command started, line of output, and command
+// finished. It may be called from multiple goroutines. Nil disables events.
+var Events func(Event)
+
+// run runs c with DefaultRunner, sending Events around it. With stream set, stdout and
+// stderr lines are sent as CommandOutput events too.
+func run(ctx context.Context, c Command, stream bool) error {
+	if Events == nil {
+		return DefaultRunner.Run(ctx, c)
+	}
+
+	Events(Event{Args: c.Args, Name: c.Name, Type: CommandStarted})
+	var lines *lineWriter
+	if stream {
+		lines = &lineWriter{event: Event{Args: c.Args, Name: c.Name, Type: CommandOutput}}
+		c.Stdout = tee(c.Stdout, lines)
+		c.Stderr = tee(c.Stderr, lines)
+	}
+
+	start := time.Now()
+	err := DefaultRunner.Run(ctx, c)
+	if lines != nil {
+		lines.flush()
+	}
+	Events(Event{Args: c.Args, Duration: time.Since(start), Err: err, Name: c.Name, Type: CommandFinished})
+	return err
+}
+
+func tee(w io.Writer, lines *lineWriter) io.Writer {
+	if w == nil {
+		return lines
+	}
+	return io.MultiWriter(w, lines)
+}
+
+// lineWriter sends a CommandOutput event for each complete line written to it.
+type lineWriter struct {
+	buf   []byte
+	event Event
+	mu    sync.Mutex
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.send(string(bytes.TrimSuffix(w.buf[:i], []byte("\r"))))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.send(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) send(line string) {
+	e := w.event
+	e.Line = line
+	Events(e)
+}
@@ -0,0 +1,43 @@
+package gcloud_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply("Enabling service\nDone", "gcloud", "services", "enable")
+	fake.Fail("ERROR: (gcloud.run.services.update-traffic) Service [app] could not be found.", "gcloud", "run", "services", "update-traffic")
+
+	var mu sync.Mutex
+	var events []gcloud.Event
+	gcloud.Events = func(e gcloud.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	t.Cleanup(func() { gcloud.Events = nil })
+
+	r.NoError(gcloud.Run(t.Context(), "gcloud", "services", "enable", "run.googleapis.com"))
+	r.Len(events, 4)
+	a.Equal(gcloud.CommandStarted, events[0].Type)
+	a.Equal([]string{"services", "enable", "run.googleapis.com"}, events[0].Args)
+	a.Equal(gcloud.Event{Args: events[0].Args, Line: "Enabling service", Name: "gcloud", Type: gcloud.CommandOutput}, events[1])
+	a.Equal("Done", events[2].Line)
+	a.Equal(gcloud.CommandFinished, events[3].Type)
+	a.NoError(events[3].Err)
+
+	events = nil
+	a.Error(gcloud.RouteToLatest(t.Context(), "p", "us-central1", "app"))
+	last := events[len(events)-1]
+	a.Equal(gcloud.CommandFinished, last.Type)
+	a.Error(last.Err)
+}
@@ -1,13 +1,20 @@
 package gcloud
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/housecat-inc/do/pkg/progress"
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
 // Project represents a GCP project.
@@ -16,9 +23,65 @@ type Project struct {
 	Name string
 }
 
-// Service represents a Cloud Run service.
+// Service represents a Cloud Run service. ListServices fills in only Name; GetService
+// fills in everything.
 type Service struct {
-	Name string
+	Config ServiceConfig
+	// LatestReadyRevision is the newest revision that passed its startup checks.
+	LatestReadyRevision string
+	Name                string
+	Traffic             []TrafficTarget
+	URL                 string
+}
+
+// Tags returns the traffic targets that have a tag, such as preview deploys.
+func (s Service) Tags() []TrafficTarget {
+	var tags []TrafficTarget
+	for _, t := range s.Traffic {
+		if t.Tag != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// Revision represents a Cloud Run revision.
+type Revision struct {
+	Created time.Time
+	// Digest is the sha256 digest the image resolved to when the revision was created.
+	Digest string
+	// Failed is true when the revision's Ready condition is False.
+	Failed  bool
+	Image   string
+	Labels  map[string]string
+	Message string
+	Name    string
+	// Percent is the share of traffic the revision serves. Only ListRevisions sets
+	// Percent and Tags.
+	Percent int
+	Ready   bool
+	Tags    []string
+}
+
+// Serving reports whether the revision receives traffic, either a share of production
+// traffic or through a tag.
+func (r Revision) Serving() bool {
+	return r.Percent > 0 || len(r.Tags) > 0
+}
+
+// Image is a container image digest in a registry repository.
+type Image struct {
+	Created time.Time
+	Digest  string
+}
+
+// TrafficTarget is a traffic assignment on a Cloud Run service.
+type TrafficTarget struct {
+	Latest   bool
+	Percent  int
+	Revision string
+	Tag      string
+	URL      string
 }
 
 // IsInstalled checks if gcloud CLI is installed.
@@ -27,29 +90,63 @@ func IsInstalled() bool {
 	return err == nil
 }
 
-// IsAuthenticated checks if gcloud is authenticated.
-// Returns true for user auth, service account, or workload identity (ADC).
-func IsAuthenticated() bool {
-	// Check for workload identity / ADC (set by google-github-actions/auth)
-	if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" ||
-		os.Getenv("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE") != "" ||
-		os.Getenv("GOOGLE_GHA_CREDS_PATH") != "" {
+// credentialEnv are variables that give gcloud credentials without a user login, as
+// set by google-github-actions/auth and similar CI integrations.
+var credentialEnv = []string{
+	"CLOUDSDK_AUTH_ACCESS_TOKEN",
+	"CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE",
+	"GOOGLE_APPLICATION_CREDENTIALS",
+	"GOOGLE_GHA_CREDS_PATH",
+}
+
+// ambientEnv are variables set by CI systems and Google Cloud runtimes, where
+// credentials come from workload identity or the metadata server.
+var ambientEnv = []string{
+	"BUILDER_OUTPUT",
+	"CLOUD_RUN_JOB",
+	"GITHUB_ACTIONS",
+	"K_SERVICE",
+	"KUBERNETES_SERVICE_HOST",
+}
+
+// AmbientCredentials reports whether credentials should come from the environment
+// rather than a user login: Application Default Credentials, an access token, or
+// workload identity in CI. An interactive 'gcloud auth login' can't complete there.
+func AmbientCredentials() bool {
+	if os.Getenv("CI") == "true" {
 		return true
 	}
+	for _, key := range append(credentialEnv, ambientEnv...) {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return false
+}
 
-	cmd := exec.Command("gcloud", "auth", "print-access-token")
-	return cmd.Run() == nil
+// IsAuthenticated checks that gcloud can get an access token, whether from a user
+// login, a service account, or workload identity (ADC).
+func IsAuthenticated(ctx context.Context) bool {
+	// gcloud ignores GOOGLE_APPLICATION_CREDENTIALS, so point it at the same file
+	// unless the environment already chose one.
+	if adc := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); adc != "" && os.Getenv("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE") == "" {
+		_ = os.Setenv("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE", adc)
+	}
+	return succeeds(ctx, "gcloud", "auth", "print-access-token")
 }
 
-// Login starts the gcloud login flow.
-func Login() error {
-	return Run("gcloud", "auth", "login")
+// Login starts the gcloud login flow. It fails without prompting where
+// AmbientCredentials reports that a browser login isn't applicable.
+func Login(ctx context.Context) error {
+	if AmbientCredentials() {
+		return errors.Wrap(ErrNotAuthenticated, "credentials from the environment don't work and login is not interactive here")
+	}
+	return RunInteractive(ctx, "gcloud", "auth", "login")
 }
 
 // CurrentProject returns the currently configured project, if any.
-func CurrentProject() string {
-	cmd := exec.Command("gcloud", "config", "get-value", "project")
-	out, err := cmd.Output()
+func CurrentProject(ctx context.Context) string {
+	out, err := Output(ctx, "gcloud", "config", "get-value", "project")
 	if err != nil {
 		return ""
 	}
@@ -60,10 +157,13 @@ func CurrentProject() string {
 	return project
 }
 
-// ListProjects returns all accessible GCP projects.
-func ListProjects() ([]Project, error) {
-	cmd := exec.Command("gcloud", "projects", "list", "--format=json")
-	out, err := cmd.Output()
+// ListProjects returns all accessible GCP projects. Results are cached for an hour.
+func ListProjects(ctx context.Context) ([]Project, error) {
+	return cached(projectsKey, projectsTTL, func() ([]Project, error) { return listProjects(ctx) })
+}
+
+func listProjects(ctx context.Context) ([]Project, error) {
+	out, err := Output(ctx, "gcloud", "projects", "list", "--format=json")
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list projects")
 	}
@@ -83,33 +183,29 @@ func ListProjects() ([]Project, error) {
 	return projects, nil
 }
 
-// CreateProject creates a new GCP project.
-func CreateProject(projectID string) error {
-	return Run("gcloud", "projects", "create", projectID)
+// CreateProject creates a new GCP project under parent, if set.
+func CreateProject(ctx context.Context, projectID string, parent Parent) error {
+	defer invalidate(projectsKey)
+	args := []string{"projects", "create", projectID}
+	if parent.ID != "" {
+		args = append(args, "--"+parent.Type+"="+parent.ID)
+	}
+	return Run(ctx, "gcloud", args...)
 }
 
 // EnsureAPIs enables the specified APIs if not already enabled.
-// Skips in CI (workload identity) since APIs should be pre-enabled and service account lacks permission.
-func EnsureAPIs(project string, apis ...string) error {
-	// Skip in CI - service account doesn't have permission to enable APIs
-	if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" ||
-		os.Getenv("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE") != "" ||
-		os.Getenv("GOOGLE_GHA_CREDS_PATH") != "" {
+// Skips where AmbientCredentials reports credentials from the environment, such as
+// workload identity in CI, since APIs should be pre-enabled and the service account
+// lacks permission.
+func EnsureAPIs(ctx context.Context, project string, apis ...string) error {
+	if AmbientCredentials() {
 		return nil
 	}
 
-	cmd := exec.Command("gcloud", "services", "list", "--enabled", "--format=value(config.name)", "--project", project)
-	out, err := cmd.Output()
+	enabled, err := EnabledAPIs(ctx, project)
 	if err != nil {
 		// Can't check, just try to enable all
-		args := append([]string{"services", "enable"}, apis...)
-		args = append(args, "--project", project)
-		return Run("gcloud", args...)
-	}
-
-	enabled := make(map[string]bool)
-	for _, line := range strings.Split(string(out), "\n") {
-		enabled[strings.TrimSpace(line)] = true
+		return enableAPIs(ctx, project, apis)
 	}
 
 	var toEnable []string
@@ -124,18 +220,130 @@ func EnsureAPIs(project string, apis ...string) error {
 	}
 
 	fmt.Printf("Enabling APIs: %s\n", strings.Join(toEnable, ", "))
-	args := append([]string{"services", "enable"}, toEnable...)
+	return enableAPIs(ctx, project, toEnable)
+}
+
+// enableAPIs enables apis, recording when so that commands failing while the change
+// propagates are retried.
+func enableAPIs(ctx context.Context, project string, apis []string) error {
+	defer invalidate(apisKey(project))
+	args := append([]string{"services", "enable"}, apis...)
 	args = append(args, "--project", project)
-	return Run("gcloud", args...)
+	if err := Run(ctx, "gcloud", args...); err != nil {
+		return err
+	}
+	markEnabled()
+	return nil
+}
+
+// EnabledAPIs returns the set of APIs enabled on a project. Results are cached for
+// an hour, and EnsureAPIs clears them when it enables an API.
+func EnabledAPIs(ctx context.Context, project string) (map[string]bool, error) {
+	return cached(apisKey(project), apisTTL, func() (map[string]bool, error) { return enabledAPIs(ctx, project) })
+}
+
+func enabledAPIs(ctx context.Context, project string) (map[string]bool, error) {
+	out, err := Output(ctx, "gcloud", "services", "list", "--enabled", "--format=value(config.name)", "--project", project)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list enabled APIs")
+	}
+
+	enabled := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			enabled[line] = true
+		}
+	}
+	return enabled, nil
+}
+
+// BillingEnabled reports whether a billing account is linked to the project.
+func BillingEnabled(ctx context.Context, project string) (bool, error) {
+	out, err := Output(ctx, "gcloud", "billing", "projects", "describe", project, "--format=value(billingEnabled)")
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get billing info")
+	}
+	return strings.EqualFold(strings.TrimSpace(string(out)), "true"), nil
+}
+
+// Account returns the active gcloud account, if any.
+func Account(ctx context.Context) string {
+	out, err := Output(ctx, "gcloud", "config", "get-value", "account")
+	if err != nil {
+		return ""
+	}
+	account := strings.TrimSpace(string(out))
+	if account == "(unset)" {
+		return ""
+	}
+	return account
+}
+
+// Member returns the IAM member string for an account email.
+func Member(account string) string {
+	if strings.HasSuffix(account, ".gserviceaccount.com") {
+		return "serviceAccount:" + account
+	}
+	return "user:" + account
+}
+
+// ProjectRoles returns the roles granted directly to member in the project IAM policy.
+// Roles inherited from folders, organizations, or groups are not included.
+func ProjectRoles(ctx context.Context, project, member string) ([]string, error) {
+	out, err := Output(ctx, "gcloud", "projects", "get-iam-policy", project,
+		"--flatten=bindings[].members",
+		"--filter=bindings.members:"+member,
+		"--format=value(bindings.role)")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get IAM policy")
+	}
+
+	var roles []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			roles = append(roles, line)
+		}
+	}
+	return roles, nil
+}
+
+// ProjectNumber returns the numeric ID of a project, which never changes once assigned.
+func ProjectNumber(ctx context.Context, project string) (string, error) {
+	return cached(projectNumberKey(project), projectNumberTTL, func() (string, error) { return projectNumber(ctx, project) })
+}
+
+func projectNumber(ctx context.Context, project string) (string, error) {
+	out, err := Output(ctx, "gcloud", "projects", "describe", project, "--format=value(projectNumber)")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe project %s", project)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ComputeServiceAccount returns the project's default compute service account,
+// which Cloud Run uses as the runtime identity unless another is set.
+func ComputeServiceAccount(ctx context.Context, project string) (string, error) {
+	number, err := ProjectNumber(ctx, project)
+	if err != nil {
+		return "", err
+	}
+	return number + "-compute@developer.gserviceaccount.com", nil
+}
+
+// ServiceAccountEmail expands a service account name to its email in project.
+// Values that are already emails are returned unchanged.
+func ServiceAccountEmail(project, name string) string {
+	if strings.Contains(name, "@") {
+		return name
+	}
+	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", name, project)
 }
 
 // EnsureDockerAuth configures docker authentication for gcr.io.
-// Skips in CI where workload identity handles auth.
-func EnsureDockerAuth() error {
-	// Skip in CI - workload identity handles auth
-	if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" ||
-		os.Getenv("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE") != "" ||
-		os.Getenv("GOOGLE_GHA_CREDS_PATH") != "" {
+// Skips where AmbientCredentials reports credentials from the environment, which
+// also authenticate ko and docker.
+func EnsureDockerAuth(ctx context.Context) error {
+	if AmbientCredentials() {
 		return nil
 	}
 
@@ -148,17 +356,35 @@ func EnsureDockerAuth() error {
 	}
 
 	fmt.Println("Configuring Docker authentication for gcr.io...")
-	return Run("gcloud", "auth", "configure-docker", "gcr.io", "--quiet")
+	return Run(ctx, "gcloud", "auth", "configure-docker", "gcr.io", "--quiet")
+}
+
+// ListRegions returns the regions where Cloud Run is available to the project,
+// sorted by name. Results are cached for a day.
+func ListRegions(ctx context.Context, project string) ([]string, error) {
+	return cached(regionsKey(project), regionsTTL, func() ([]string, error) {
+		out, err := Output(ctx, "gcloud", "run", "regions", "list", "--project="+project, "--format=value(locationId)")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list regions")
+		}
+		regions := strings.Fields(string(out))
+		sort.Strings(regions)
+		return regions, nil
+	})
 }
 
-// ListServices returns Cloud Run services in the specified project/region.
-func ListServices(project, region string) ([]Service, error) {
-	cmd := exec.Command("gcloud", "run", "services", "list",
+// ListServices returns Cloud Run services in the specified project/region. Results are
+// cached for ten minutes, and Deploy clears them.
+func ListServices(ctx context.Context, project, region string) ([]Service, error) {
+	return cached(servicesKey(project, region), servicesTTL, func() ([]Service, error) { return listServices(ctx, project, region) })
+}
+
+func listServices(ctx context.Context, project, region string) ([]Service, error) {
+	out, err := Output(ctx, "gcloud", "run", "services", "list",
 		"--platform=managed",
 		"--region="+region,
 		"--project="+project,
 		"--format=json")
-	out, err := cmd.Output()
 	if err != nil {
 		return nil, err
 	}
@@ -179,55 +405,237 @@ func ListServices(project, region string) ([]Service, error) {
 	return services, nil
 }
 
-// Deploy deploys an image to Cloud Run and routes 100% traffic to it.
-func Deploy(project, region, service, image string) error {
-	if err := Run("gcloud", "run", "deploy", service,
-		"--image="+image,
+const (
+	// AuthPublic allows unauthenticated invocations.
+	AuthPublic = "public"
+	// AuthRequired requires callers to have roles/run.invoker.
+	AuthRequired = "required"
+)
+
+// DeployOptions configures Deploy. Zero values leave the service setting unchanged.
+type DeployOptions struct {
+	// Auth is AuthPublic or AuthRequired. Empty leaves IAM unchanged, which makes new services private.
+	Auth string
+	// Canary routes only this percentage of traffic to the new revision when between 1 and 99.
+	Canary int
+	// CloudSQL lists PROJECT:REGION:INSTANCE connection names to attach.
+	CloudSQL    []string
+	Concurrency int
+	CPU         string
+	Env         map[string]string
+	// Labels are merged into the service and revision labels. Values must be valid label values.
+	Labels       map[string]string
+	MaxInstances *int
+	Memory       string
+	MinInstances *int
+	// RemoveEnv lists env vars to remove from the service.
+	RemoveEnv []string
+	// ServiceAccount is the email of the runtime identity. Empty keeps the current one,
+	// which is the compute service account for new services.
+	ServiceAccount string
+	// Tag deploys with a traffic tag and no production traffic.
+	Tag string
+}
+
+// Deploy deploys an image to Cloud Run and routes 100% traffic to it,
+// or opts.Canary percent when set. With opts.Tag it routes no production traffic.
+func Deploy(ctx context.Context, project, region, service, image string, opts DeployOptions) error {
+	if opts.Canary < 0 || opts.Canary >= 100 {
+		return errors.Errorf("canary percent must be between 1 and 99, got %d", opts.Canary)
+	}
+	if opts.Auth != "" && opts.Auth != AuthPublic && opts.Auth != AuthRequired {
+		return errors.Errorf("auth must be %q or %q, got %q", AuthRequired, AuthPublic, opts.Auth)
+	}
+
+	args := []string{"run", "deploy", service,
+		"--image=" + image,
 		"--platform=managed",
-		"--region="+region,
-		"--project="+project,
-		"--allow-unauthenticated"); err != nil {
+		"--region=" + region,
+		"--project=" + project}
+	args = append(args, opts.flags()...)
+	if opts.Tag != "" {
+		args = append(args, "--tag="+opts.Tag)
+	}
+	if opts.Canary > 0 || opts.Tag != "" {
+		args = append(args, "--no-traffic")
+	}
+	if err := Run(ctx, "gcloud", args...); err != nil {
 		return err
 	}
+	invalidate(servicesKey(project, region))
+
+	if opts.Tag != "" {
+		return nil
+	}
+
+	if opts.Canary > 0 {
+		return UpdateTraffic(ctx, project, region, service, map[string]int{"LATEST": opts.Canary})
+	}
 
 	// Ensure 100% traffic goes to latest revision
-	return Run("gcloud", "run", "services", "update-traffic", service,
+	return RouteToLatest(ctx, project, region, service)
+}
+
+// UpdateService changes a service's settings from opts without deploying a new
+// image. Traffic, Tag, and Canary options are ignored.
+func UpdateService(ctx context.Context, project, region, service string, opts DeployOptions) error {
+	args := []string{"run", "services", "update", service,
+		"--platform=managed",
+		"--region=" + region,
+		"--project=" + project}
+	return Run(ctx, "gcloud", append(args, opts.flags()...)...)
+}
+
+func (o DeployOptions) flags() []string {
+	var args []string
+	switch o.Auth {
+	case AuthPublic:
+		args = append(args, "--allow-unauthenticated")
+	case AuthRequired:
+		args = append(args, "--no-allow-unauthenticated")
+	}
+	if len(o.CloudSQL) > 0 {
+		args = append(args, "--set-cloudsql-instances="+strings.Join(o.CloudSQL, ","))
+	}
+	if o.Concurrency > 0 {
+		args = append(args, fmt.Sprintf("--concurrency=%d", o.Concurrency))
+	}
+	if o.CPU != "" {
+		args = append(args, "--cpu="+o.CPU)
+	}
+	if len(o.Env) > 0 {
+		args = append(args, "--update-env-vars="+EnvVarsArg(o.Env))
+	}
+	if len(o.Labels) > 0 {
+		args = append(args, "--update-labels="+EnvVarsArg(o.Labels))
+	}
+	if len(o.RemoveEnv) > 0 {
+		args = append(args, "--remove-env-vars="+strings.Join(o.RemoveEnv, ","))
+	}
+	if o.MaxInstances != nil {
+		args = append(args, fmt.Sprintf("--max-instances=%d", *o.MaxInstances))
+	}
+	if o.Memory != "" {
+		args = append(args, "--memory="+o.Memory)
+	}
+	if o.MinInstances != nil {
+		args = append(args, fmt.Sprintf("--min-instances=%d", *o.MinInstances))
+	}
+	if o.ServiceAccount != "" {
+		args = append(args, "--service-account="+o.ServiceAccount)
+	}
+	return args
+}
+
+// EnvVarsArg formats env vars for gcloud's --update-env-vars flag, sorted by key.
+// It switches to gcloud's alternate delimiter syntax when a value contains a comma.
+func EnvVarsArg(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	delim := ","
+	for k, v := range env {
+		keys = append(keys, k)
+		if strings.Contains(v, ",") {
+			delim = "@"
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + env[k]
+	}
+
+	if delim == "," {
+		return strings.Join(pairs, ",")
+	}
+	return "^@^" + strings.Join(pairs, "@")
+}
+
+// RouteToLatest sends 100% of traffic to the latest ready revision.
+func RouteToLatest(ctx context.Context, project, region, service string) error {
+	return Run(ctx, "gcloud", "run", "services", "update-traffic", service,
 		"--platform=managed",
 		"--region="+region,
 		"--project="+project,
 		"--to-latest")
 }
 
-// DeployWithTag deploys an image with a traffic tag (for branch deploys).
-// The tag gets its own URL without receiving production traffic.
-func DeployWithTag(project, region, service, image, tag string) error {
-	return Run("gcloud", "run", "deploy", service,
-		"--image="+image,
+// GrantInvoker grants roles/run.invoker on a service to member (e.g. user:x@example.com).
+func GrantInvoker(ctx context.Context, project, region, service, member string) error {
+	return Run(ctx, "gcloud", "run", "services", "add-iam-policy-binding", service,
 		"--platform=managed",
 		"--region="+region,
 		"--project="+project,
-		"--tag="+tag,
-		"--no-traffic",
-		"--allow-unauthenticated")
+		"--member="+member,
+		"--role=roles/run.invoker")
 }
 
-// ServiceURL returns the URL of a Cloud Run service.
-func ServiceURL(project, region, service string) string {
-	cmd := exec.Command("gcloud", "run", "services", "describe", service,
+// RevokeInvoker removes roles/run.invoker on a service from member.
+func RevokeInvoker(ctx context.Context, project, region, service, member string) error {
+	return Run(ctx, "gcloud", "run", "services", "remove-iam-policy-binding", service,
 		"--platform=managed",
 		"--region="+region,
 		"--project="+project,
-		"--format=value(status.url)")
-	out, err := cmd.Output()
+		"--member="+member,
+		"--role=roles/run.invoker")
+}
+
+// JobOptions configures DeployJob. Zero values leave the job setting unchanged.
+type JobOptions struct {
+	CPU        string
+	Env        map[string]string
+	MaxRetries *int
+	Memory     string
+	Tasks      int
+}
+
+// DeployJob creates or updates a Cloud Run job to run image.
+func DeployJob(ctx context.Context, project, region, job, image string, opts JobOptions) error {
+	args := []string{"run", "jobs", "deploy", job,
+		"--image=" + image,
+		"--region=" + region,
+		"--project=" + project}
+	if opts.CPU != "" {
+		args = append(args, "--cpu="+opts.CPU)
+	}
+	if len(opts.Env) > 0 {
+		args = append(args, "--update-env-vars="+EnvVarsArg(opts.Env))
+	}
+	if opts.MaxRetries != nil {
+		args = append(args, fmt.Sprintf("--max-retries=%d", *opts.MaxRetries))
+	}
+	if opts.Memory != "" {
+		args = append(args, "--memory="+opts.Memory)
+	}
+	if opts.Tasks > 0 {
+		args = append(args, fmt.Sprintf("--tasks=%d", opts.Tasks))
+	}
+	return Run(ctx, "gcloud", args...)
+}
+
+// RunJob starts an execution of a Cloud Run job, optionally waiting for it to finish.
+func RunJob(ctx context.Context, project, region, job string, wait bool) error {
+	args := []string{"run", "jobs", "execute", job,
+		"--region=" + region,
+		"--project=" + project}
+	if wait {
+		args = append(args, "--wait")
+	}
+	return Run(ctx, "gcloud", args...)
+}
+
+// ServiceURL returns the URL of a Cloud Run service.
+func ServiceURL(ctx context.Context, project, region, service string) string {
+	s, err := GetService(ctx, project, region, service)
 	if err != nil {
 		return ""
 	}
-	return strings.TrimSpace(string(out))
+	return s.URL
 }
 
 // RemoveTag removes a traffic tag from a Cloud Run service.
-func RemoveTag(project, region, service, tag string) error {
-	return Run("gcloud", "run", "services", "update-traffic", service,
+func RemoveTag(ctx context.Context, project, region, service, tag string) error {
+	return Run(ctx, "gcloud", "run", "services", "update-traffic", service,
 		"--platform=managed",
 		"--region="+region,
 		"--project="+project,
@@ -235,45 +643,296 @@ func RemoveTag(project, region, service, tag string) error {
 }
 
 // TagURL returns the URL for a specific traffic tag.
-func TagURL(project, region, service, tag string) string {
-	cmd := exec.Command("gcloud", "run", "services", "describe", service,
+func TagURL(ctx context.Context, project, region, service, tag string) string {
+	s, err := GetService(ctx, project, region, service)
+	if err != nil {
+		return ""
+	}
+	for _, t := range s.Traffic {
+		if t.Tag == tag {
+			return t.URL
+		}
+	}
+	return ""
+}
+
+// ListRevisions returns the most recent revisions of a service, newest first, with
+// the traffic each one serves. A limit of 0 lists every revision.
+func ListRevisions(ctx context.Context, project, region, service string, limit int) ([]Revision, error) {
+	limitArg := "--limit=unlimited"
+	if limit > 0 {
+		limitArg = fmt.Sprintf("--limit=%d", limit)
+	}
+	out, err := Output(ctx, "gcloud", "run", "revisions", "list",
 		"--platform=managed",
 		"--region="+region,
 		"--project="+project,
-		"--format=json(status.traffic)")
-	out, err := cmd.Output()
+		"--service="+service,
+		"--sort-by=~metadata.creationTimestamp",
+		limitArg,
+		"--format=json")
 	if err != nil {
-		return ""
+		return nil, errors.Wrap(err, "failed to list revisions")
 	}
 
-	var result struct {
-		Status struct {
-			Traffic []struct {
-				Tag string `json:"tag"`
-				URL string `json:"url"`
-			} `json:"traffic"`
-		} `json:"status"`
+	var raw []revisionJSON
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, errors.Wrap(err, "failed to parse revisions")
 	}
-	if err := json.Unmarshal(out, &result); err != nil {
-		return ""
+
+	traffic, err := Traffic(ctx, project, region, service)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, t := range result.Status.Traffic {
-		if t.Tag == tag {
-			return t.URL
+	revisions := make([]Revision, len(raw))
+	for i, r := range raw {
+		revisions[i] = r.revision()
+		for _, t := range traffic {
+			if t.Revision != revisions[i].Name {
+				continue
+			}
+			revisions[i].Percent += t.Percent
+			if t.Tag != "" {
+				revisions[i].Tags = append(revisions[i].Tags, t.Tag)
+			}
 		}
 	}
-	return ""
+	return revisions, nil
+}
+
+// GetRevision returns a single revision by name.
+func GetRevision(ctx context.Context, project, region, name string) (Revision, error) {
+	out, err := Output(ctx, "gcloud", "run", "revisions", "describe", name,
+		"--platform=managed",
+		"--region="+region,
+		"--project="+project,
+		"--format=json")
+	if err != nil {
+		return Revision{}, errors.Wrapf(err, "failed to describe revision %s", name)
+	}
+
+	var raw revisionJSON
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return Revision{}, errors.Wrap(err, "failed to parse revision")
+	}
+	return raw.revision(), nil
+}
+
+// LatestRevision returns the name of the most recently created revision of a service.
+func LatestRevision(ctx context.Context, project, region, service string) (string, error) {
+	out, err := Output(ctx, "gcloud", "run", "services", "describe", service,
+		"--platform=managed",
+		"--region="+region,
+		"--project="+project,
+		"--format=value(status.latestCreatedRevisionName)")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get latest revision")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// IdentityToken returns an ID token for the active account, for calling authenticated services.
+func IdentityToken(ctx context.Context) (string, error) {
+	out, err := Output(ctx, "gcloud", "auth", "print-identity-token")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get identity token")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+type revisionJSON struct {
+	Metadata struct {
+		CreationTimestamp time.Time         `json:"creationTimestamp"`
+		Labels            map[string]string `json:"labels"`
+		Name              string            `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []struct {
+			Image string `json:"image"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Type    string `json:"type"`
+		} `json:"conditions"`
+		ImageDigest string `json:"imageDigest"`
+	} `json:"status"`
+}
+
+func (r revisionJSON) revision() Revision {
+	rev := Revision{Created: r.Metadata.CreationTimestamp, Labels: r.Metadata.Labels, Name: r.Metadata.Name}
+	if _, digest, ok := strings.Cut(r.Status.ImageDigest, "@"); ok {
+		rev.Digest = digest
+	}
+	if len(r.Spec.Containers) > 0 {
+		rev.Image = r.Spec.Containers[0].Image
+	}
+	for _, c := range r.Status.Conditions {
+		if c.Type == "Ready" {
+			rev.Ready = c.Status == "True"
+			rev.Failed = c.Status == "False"
+			rev.Message = c.Message
+		}
+	}
+	return rev
+}
+
+// ListImages returns the image digests in a repository such as gcr.io/project/service, newest first.
+func ListImages(ctx context.Context, repo string) ([]Image, error) {
+	out, err := Output(ctx, "gcloud", "container", "images", "list-tags", repo,
+		"--sort-by=~timestamp",
+		"--limit=unlimited",
+		"--format=json")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list images")
+	}
+
+	var raw []struct {
+		Digest    string `json:"digest"`
+		Timestamp struct {
+			Datetime string `json:"datetime"`
+		} `json:"timestamp"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, errors.Wrap(err, "failed to parse images")
+	}
+
+	images := make([]Image, len(raw))
+	for i, r := range raw {
+		created, _ := time.Parse("2006-01-02 15:04:05-07:00", r.Timestamp.Datetime)
+		images[i] = Image{Created: created, Digest: r.Digest}
+	}
+	return images, nil
+}
+
+// DeleteImage deletes an image digest from a repository, including any tags on it.
+func DeleteImage(ctx context.Context, repo, digest string) error {
+	return Run(ctx, "gcloud", "container", "images", "delete", repo+"@"+digest,
+		"--force-delete-tags",
+		"--quiet")
+}
+
+// ImageDigest returns the sha256 digest of a pushed image reference such as gcr.io/project/service:tag.
+func ImageDigest(ctx context.Context, image string) (string, error) {
+	out, err := Output(ctx, "gcloud", "container", "images", "describe", image,
+		"--format=value(image_summary.digest)")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe image %s", image)
+	}
+	digest := strings.TrimSpace(string(out))
+	if digest == "" {
+		return "", errors.Errorf("no digest for image %s", image)
+	}
+	return digest, nil
 }
 
-// Run executes a gcloud command with output to stdout/stderr.
-func Run(name string, args ...string) error {
-	fmt.Printf(" → %s %s\n", name, strings.Join(args, " "))
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+// BuildStep is one step of a Cloud Build config.
+type BuildStep struct {
+	Args       []string `yaml:"args"`
+	Entrypoint string   `yaml:"entrypoint,omitempty"`
+	Env        []string `yaml:"env,omitempty"`
+	Name       string   `yaml:"name"`
+}
+
+// SubmitBuild uploads the source in dir to Cloud Build and streams the build logs.
+// Without steps, Cloud Build builds the Dockerfile in dir and pushes it as image.
+func SubmitBuild(ctx context.Context, project, dir, image string, steps []BuildStep) error {
+	args := []string{"builds", "submit", dir, "--project", project}
+	if len(steps) == 0 {
+		return RunInteractive(ctx, "gcloud", append(args, "--tag", image)...)
+	}
+
+	data, err := yaml.Marshal(struct {
+		Steps []BuildStep `yaml:"steps"`
+	}{steps})
+	if err != nil {
 		return errors.WithStack(err)
 	}
-	return nil
+
+	f, err := os.CreateTemp("", "do-cloudbuild-*.yaml")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return RunInteractive(ctx, "gcloud", append(args, "--config", f.Name())...)
+}
+
+// DeleteRevision deletes a Cloud Run revision. Revisions serving traffic cannot be deleted.
+func DeleteRevision(ctx context.Context, project, region, revision string) error {
+	return Run(ctx, "gcloud", "run", "revisions", "delete", revision,
+		"--platform=managed",
+		"--region="+region,
+		"--project="+project,
+		"--quiet")
+}
+
+// Traffic returns the current traffic assignments of a service.
+func Traffic(ctx context.Context, project, region, service string) ([]TrafficTarget, error) {
+	s, err := GetService(ctx, project, region, service)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get service traffic")
+	}
+	return s.Traffic, nil
+}
+
+// UpdateTraffic assigns traffic percentages to revisions. The revision name LATEST
+// refers to the newest revision. If the percentages total less than 100, revisions
+// not listed keep the remainder in proportion to their current share.
+func UpdateTraffic(ctx context.Context, project, region, service string, split map[string]int) error {
+	revisions := make([]string, 0, len(split))
+	for rev := range split {
+		revisions = append(revisions, rev)
+	}
+	sort.Strings(revisions)
+
+	var total int
+	pairs := make([]string, len(revisions))
+	for i, rev := range revisions {
+		pairs[i] = fmt.Sprintf("%s=%d", rev, split[rev])
+		total += split[rev]
+	}
+	if total > 100 {
+		return errors.Errorf("traffic split must total at most 100, got %d", total)
+	}
+
+	return Run(ctx, "gcloud", "run", "services", "update-traffic", service,
+		"--platform=managed",
+		"--region="+region,
+		"--project="+project,
+		"--to-revisions="+strings.Join(pairs, ","))
+}
+
+// Run executes a gcloud command as a progress step, retrying transient failures. Its
+// output is shown only if it fails.
+func Run(ctx context.Context, name string, args ...string) error {
+	return progress.Step(name+" "+strings.Join(args, " "), func(w io.Writer) error {
+		return Retry.do(ctx, func() error {
+			var stderr bytes.Buffer
+			err := run(ctx, Command{Args: args, Name: name, Stderr: io.MultiWriter(w, &stderr), Stdout: w}, true)
+			if err != nil {
+				return commandError(err, stderr.String())
+			}
+			return nil
+		}, func(err error, delay time.Duration) {
+			fmt.Fprintf(w, "retrying in %s: %v\n", delay, err)
+		})
+	})
+}
+
+// RunInteractive executes a gcloud command attached to the terminal, for commands that prompt
+// or stream long-running output.
+func RunInteractive(ctx context.Context, name string, args ...string) error {
+	progress.Echo(name + " " + strings.Join(args, " "))
+	return Attach(ctx, name, args...)
 }
@@ -0,0 +1,95 @@
+package gcloud_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvVarsArg(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("A=1,B=two", gcloud.EnvVarsArg(map[string]string{"B": "two", "A": "1"}))
+	a.Equal("^@^HOSTS=a,b@PORT=80", gcloud.EnvVarsArg(map[string]string{"HOSTS": "a,b", "PORT": "80"}))
+}
+
+func TestServiceAccountEmail(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("app@my-project.iam.gserviceaccount.com", gcloud.ServiceAccountEmail("my-project", "app"))
+	a.Equal("x@other.iam.gserviceaccount.com", gcloud.ServiceAccountEmail("my-project", "x@other.iam.gserviceaccount.com"))
+}
+
+// noAmbientCredentials clears the environment AmbientCredentials checks, as on a
+// developer's machine, so tests behave the same when run in CI.
+func noAmbientCredentials(t *testing.T) {
+	for _, key := range []string{"BUILDER_OUTPUT", "CI", "CLOUD_RUN_JOB", "CLOUDSDK_AUTH_ACCESS_TOKEN", "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE", "GITHUB_ACTIONS", "GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_GHA_CREDS_PATH", "K_SERVICE", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestAmbientCredentials(t *testing.T) {
+	a := assert.New(t)
+	noAmbientCredentials(t)
+
+	a.False(gcloud.AmbientCredentials())
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/tmp/key.json")
+	a.True(gcloud.AmbientCredentials())
+}
+
+func TestEnsureAmbient(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	noAmbientCredentials(t)
+	fake := gcloudtest.Install(t)
+	t.Setenv("HOME", t.TempDir())
+
+	// Neither an access token nor the metadata server on Cloud Run can enable APIs or
+	// configure docker like a user login.
+	t.Setenv("CLOUDSDK_AUTH_ACCESS_TOKEN", "token")
+	r.NoError(gcloud.EnsureAPIs(t.Context(), "my-project", "run.googleapis.com"))
+	r.NoError(gcloud.EnsureDockerAuth(t.Context()))
+	t.Setenv("CLOUDSDK_AUTH_ACCESS_TOKEN", "")
+	t.Setenv("K_SERVICE", "builder")
+	r.NoError(gcloud.EnsureAPIs(t.Context(), "my-project", "run.googleapis.com"))
+	r.NoError(gcloud.EnsureDockerAuth(t.Context()))
+	a.Empty(fake.Calls())
+
+	t.Setenv("K_SERVICE", "")
+	r.NoError(gcloud.EnsureAPIs(t.Context(), "my-project", "run.googleapis.com"))
+	r.NoError(gcloud.EnsureDockerAuth(t.Context()))
+	a.Equal([]string{
+		"gcloud services list --enabled --format=value(config.name) --project my-project",
+		"gcloud services enable run.googleapis.com --project my-project",
+		"gcloud auth configure-docker gcr.io --quiet",
+	}, fake.Commands())
+}
+
+func TestLoginAmbient(t *testing.T) {
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	t.Setenv("CI", "true")
+
+	err := gcloud.Login(t.Context())
+
+	a.True(errors.Is(err, gcloud.ErrNotAuthenticated))
+	a.Empty(fake.Calls())
+}
+
+func TestIsAuthenticatedADC(t *testing.T) {
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/tmp/key.json")
+	t.Setenv("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE", "")
+	fake.Fail("ERROR: (gcloud.auth.print-access-token) You do not currently have an active account selected.", "gcloud", "auth", "print-access-token")
+
+	a.False(gcloud.IsAuthenticated(t.Context()))
+	a.Equal("/tmp/key.json", os.Getenv("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE"))
+	a.Equal([]string{"gcloud auth print-access-token"}, fake.Commands())
+}
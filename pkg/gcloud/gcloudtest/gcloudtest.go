@@ -0,0 +1,123 @@
+// Package gcloudtest provides a fake gcloud.Runner for tests.
+package gcloudtest
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+)
+
+// Runner is a gcloud.Runner that records commands instead of running them. Commands
+// without a matching Reply or Fail succeed with no output.
+type Runner struct {
+	mu        sync.Mutex
+	calls     [][]string
+	responses []response
+}
+
+type response struct {
+	fail   bool
+	output string
+	prefix []string
+	// times is how many more commands this response matches: 0 for no limit, -1 once used up.
+	times int
+}
+
+// Install replaces gcloud.DefaultRunner with a new Runner until the test ends. The
+// on-disk cache is moved to a temporary directory so tests start empty.
+func Install(t testing.TB) *Runner {
+	r := &Runner{}
+	prevRunner, prevCache := gcloud.DefaultRunner, gcloud.CacheDir
+	gcloud.DefaultRunner = r
+	gcloud.CacheDir = t.TempDir()
+	t.Cleanup(func() {
+		gcloud.DefaultRunner = prevRunner
+		gcloud.CacheDir = prevCache
+	})
+	return r
+}
+
+// Reply makes commands starting with prefix (the command name, then arguments) write
+// stdout and succeed. Later replies take precedence over earlier ones.
+func (r *Runner) Reply(stdout string, prefix ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, response{output: stdout, prefix: prefix})
+}
+
+// Fail makes commands starting with prefix write stderr and exit with status 1.
+func (r *Runner) Fail(stderr string, prefix ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, response{fail: true, output: stderr, prefix: prefix})
+}
+
+// FailTimes is like Fail for the next n matching commands only.
+func (r *Runner) FailTimes(n int, stderr string, prefix ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, response{fail: true, output: stderr, prefix: prefix, times: n})
+}
+
+// Calls returns each command run so far as its name followed by its arguments.
+func (r *Runner) Calls() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+// Commands returns each command run so far joined with spaces.
+func (r *Runner) Commands() []string {
+	var commands []string
+	for _, c := range r.Calls() {
+		commands = append(commands, strings.Join(c, " "))
+	}
+	return commands
+}
+
+func (r *Runner) Run(ctx context.Context, c gcloud.Command) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	argv := append([]string{c.Name}, c.Args...)
+	r.mu.Lock()
+	r.calls = append(r.calls, argv)
+	var match response
+	found := false
+	for i := len(r.responses) - 1; i >= 0; i-- {
+		res := &r.responses[i]
+		if res.times < 0 || len(res.prefix) > len(argv) || !slices.Equal(res.prefix, argv[:len(res.prefix)]) {
+			continue
+		}
+		if res.times > 0 {
+			res.times--
+			if res.times == 0 {
+				res.times = -1
+			}
+		}
+		match, found = *res, true
+		break
+	}
+	r.mu.Unlock()
+
+	if !found {
+		return nil
+	}
+	if match.fail {
+		if c.Stderr != nil {
+			_, _ = io.WriteString(c.Stderr, match.output)
+		}
+		return errors.New("exit status 1")
+	}
+	if c.Stdout != nil {
+		_, _ = io.WriteString(c.Stdout, match.output)
+	}
+	return nil
+}
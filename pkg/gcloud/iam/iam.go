@@ -0,0 +1,177 @@
+// Package iam creates service accounts, role bindings, and workload identity
+// federation idempotently. Each Ensure function reads what exists first and only
+// changes what is missing, so setup can be re-run safely and real failures are
+// returned instead of ignored.
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+)
+
+// WorkloadIdentityUser lets a federated identity impersonate a service account.
+const WorkloadIdentityUser = "roles/iam.workloadIdentityUser"
+
+// Resource is something with an IAM policy: a project or a service account.
+type Resource struct {
+	// command is the gcloud command group, such as projects or iam service-accounts.
+	command []string
+	flags   []string
+	name    string
+}
+
+// Project returns the IAM resource for a project.
+func Project(project string) Resource {
+	return Resource{command: []string{"projects"}, name: project}
+}
+
+// ServiceAccount returns the IAM resource for a service account in project, for
+// granting roles on the account itself such as roles/iam.serviceAccountUser.
+func ServiceAccount(project, email string) Resource {
+	return Resource{command: []string{"iam", "service-accounts"}, flags: []string{"--project=" + project}, name: email}
+}
+
+func (r Resource) args(verb string, flags ...string) []string {
+	args := append(append([]string{}, r.command...), verb, r.name)
+	args = append(args, r.flags...)
+	return append(args, flags...)
+}
+
+type policyJSON struct {
+	Bindings []struct {
+		Condition *json.RawMessage `json:"condition"`
+		Members   []string         `json:"members"`
+		Role      string           `json:"role"`
+	} `json:"bindings"`
+}
+
+// Roles returns the roles granted unconditionally to member on r. Roles inherited
+// from folders, organizations, or groups are not included.
+func Roles(ctx context.Context, r Resource, member string) (map[string]bool, error) {
+	out, err := gcloud.Output(ctx, "gcloud", r.args("get-iam-policy", "--format=json")...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get IAM policy of %s", r.name)
+	}
+	var policy policyJSON
+	if err := json.Unmarshal(out, &policy); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse IAM policy of %s", r.name)
+	}
+
+	roles := make(map[string]bool)
+	for _, b := range policy.Bindings {
+		if b.Condition != nil {
+			continue
+		}
+		for _, m := range b.Members {
+			if m == member {
+				roles[b.Role] = true
+			}
+		}
+	}
+	return roles, nil
+}
+
+// EnsureRoleBinding grants member each of roles on r, skipping roles it already has.
+// member is an IAM principal such as serviceAccount:x@p.iam.gserviceaccount.com.
+func EnsureRoleBinding(ctx context.Context, r Resource, member string, roles ...string) error {
+	have, err := Roles(ctx, r, member)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if have[role] {
+			continue
+		}
+		if err := gcloud.Run(ctx, "gcloud", r.args("add-iam-policy-binding",
+			"--member="+member,
+			"--role="+role,
+			"--condition=None")...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureServiceAccount creates the service account with email in project unless it
+// exists. displayName defaults to the account name.
+func EnsureServiceAccount(ctx context.Context, project, email, displayName string) error {
+	if exists(ctx, "iam", "service-accounts", "describe", email, "--project="+project) {
+		return nil
+	}
+	name, _, _ := strings.Cut(email, "@")
+	if displayName == "" {
+		displayName = name
+	}
+	return gcloud.Run(ctx, "gcloud", "iam", "service-accounts", "create", name,
+		"--project="+project,
+		"--display-name="+displayName)
+}
+
+// OIDCProvider is a workload identity pool provider trusting an OIDC issuer such as
+// GitHub Actions.
+type OIDCProvider struct {
+	// AttributeCondition limits which tokens are accepted, such as
+	// assertion.repository=='owner/repo'.
+	AttributeCondition string
+	AttributeMapping   string
+	DisplayName        string
+	ID                 string
+	IssuerURI          string
+	Pool               string
+}
+
+// Name returns the provider's full resource name, as GitHub Actions and other
+// clients expect it.
+func (p OIDCProvider) Name(projectNumber string) string {
+	return "projects/" + projectNumber + "/locations/global/workloadIdentityPools/" + p.Pool + "/providers/" + p.ID
+}
+
+// EnsureWorkloadIdentityPool creates a global workload identity pool unless it exists.
+func EnsureWorkloadIdentityPool(ctx context.Context, project, pool, displayName string) error {
+	if exists(ctx, "iam", "workload-identity-pools", "describe", pool, "--project="+project, "--location=global") {
+		return nil
+	}
+	return gcloud.Run(ctx, "gcloud", "iam", "workload-identity-pools", "create", pool,
+		"--project="+project,
+		"--location=global",
+		"--display-name="+displayName)
+}
+
+// EnsureOIDCProvider creates the provider in its pool, or updates an existing one so
+// its attribute mapping and condition match p.
+func EnsureOIDCProvider(ctx context.Context, project string, p OIDCProvider) error {
+	verb := "create-oidc"
+	if exists(ctx, "iam", "workload-identity-pools", "providers", "describe", p.ID,
+		"--project="+project, "--location=global", "--workload-identity-pool="+p.Pool) {
+		verb = "update-oidc"
+	}
+	return gcloud.Run(ctx, "gcloud", "iam", "workload-identity-pools", "providers", verb, p.ID,
+		"--project="+project,
+		"--location=global",
+		"--workload-identity-pool="+p.Pool,
+		"--display-name="+p.DisplayName,
+		"--attribute-mapping="+p.AttributeMapping,
+		"--attribute-condition="+p.AttributeCondition,
+		"--issuer-uri="+p.IssuerURI)
+}
+
+// EnsureWorkloadIdentityBinding lets member, usually a principalSet from a workload
+// identity pool, impersonate serviceAccount.
+func EnsureWorkloadIdentityBinding(ctx context.Context, project, serviceAccount, member string) error {
+	return EnsureRoleBinding(ctx, ServiceAccount(project, serviceAccount), member, WorkloadIdentityUser)
+}
+
+// PoolPrincipalSet returns the member matching every identity in a workload identity
+// pool with attribute set to value, such as repository and owner/repo.
+func PoolPrincipalSet(projectNumber, pool, attribute, value string) string {
+	return "principalSet://iam.googleapis.com/projects/" + projectNumber + "/locations/global/workloadIdentityPools/" + pool + "/attribute." + attribute + "/" + value
+}
+
+func exists(ctx context.Context, args ...string) bool {
+	_, err := gcloud.Output(ctx, "gcloud", args...)
+	return err == nil
+}
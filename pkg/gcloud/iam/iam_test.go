@@ -0,0 +1,64 @@
+package iam_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/housecat-inc/do/pkg/gcloud/iam"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureRoleBinding(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{"bindings": [
+  {"role": "roles/run.admin", "members": ["serviceAccount:ci@p.iam.gserviceaccount.com"]},
+  {"role": "roles/storage.admin", "members": ["serviceAccount:ci@p.iam.gserviceaccount.com"], "condition": {"title": "temporary"}}
+]}`, "gcloud", "projects", "get-iam-policy", "p")
+
+	err := iam.EnsureRoleBinding(t.Context(), iam.Project("p"), "serviceAccount:ci@p.iam.gserviceaccount.com", "roles/run.admin", "roles/storage.admin")
+	r.NoError(err)
+
+	a.Equal([]string{
+		"gcloud projects get-iam-policy p --format=json",
+		"gcloud projects add-iam-policy-binding p --member=serviceAccount:ci@p.iam.gserviceaccount.com --role=roles/storage.admin --condition=None",
+	}, fake.Commands())
+}
+
+func TestEnsureWorkloadIdentityBinding(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	member := iam.PoolPrincipalSet("123", "github", "repository", "owner/repo")
+	fake.Reply(`{"bindings": [{"role": "roles/iam.workloadIdentityUser", "members": ["`+member+`"]}]}`,
+		"gcloud", "iam", "service-accounts", "get-iam-policy")
+
+	r.NoError(iam.EnsureWorkloadIdentityBinding(t.Context(), "p", "ci@p.iam.gserviceaccount.com", member))
+
+	a.Equal([]string{"gcloud iam service-accounts get-iam-policy ci@p.iam.gserviceaccount.com --project=p --format=json"}, fake.Commands())
+}
+
+func TestEnsureServiceAccount(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Fail("ERROR: (gcloud.iam.service-accounts.describe) NOT_FOUND: Unknown service account", "gcloud", "iam", "service-accounts", "describe")
+
+	r.NoError(iam.EnsureServiceAccount(t.Context(), "p", "app@p.iam.gserviceaccount.com", ""))
+
+	a.Equal("gcloud iam service-accounts create app --project=p --display-name=app", fake.Commands()[1])
+}
+
+func TestEnsureOIDCProviderUpdates(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+
+	p := iam.OIDCProvider{ID: "github", Pool: "github"}
+	r.NoError(iam.EnsureOIDCProvider(t.Context(), "p", p))
+
+	a.Contains(fake.Commands()[1], "providers update-oidc github")
+	a.Equal("projects/123/locations/global/workloadIdentityPools/github/providers/github", p.Name("123"))
+}
@@ -0,0 +1,113 @@
+package gcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AccessToken returns an OAuth access token for the active account, for calling Google APIs.
+func AccessToken(ctx context.Context) (string, error) {
+	out, err := Output(ctx, "gcloud", "auth", "print-access-token")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get access token")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ServiceMetricSum returns the sum of a Cloud Run metric such as
+// run.googleapis.com/request_count for a service over the last period.
+func ServiceMetricSum(ctx context.Context, project, region, service, metric string, period time.Duration) (float64, error) {
+	end := time.Now().UTC()
+	seconds := int64(period.Seconds())
+	series, err := timeSeries(ctx, project, metric, url.Values{
+		"filter": {fmt.Sprintf(`metric.type = %q AND resource.type = "cloud_run_revision" AND resource.labels.service_name = %q AND resource.labels.location = %q`,
+			metric, service, region)},
+		"interval.startTime":             {end.Add(-period).Format(time.RFC3339)},
+		"interval.endTime":               {end.Format(time.RFC3339)},
+		"aggregation.alignmentPeriod":    {fmt.Sprintf("%ds", seconds)},
+		"aggregation.perSeriesAligner":   {"ALIGN_SUM"},
+		"aggregation.crossSeriesReducer": {"REDUCE_SUM"},
+		"aggregation.groupByFields":      {"resource.labels.service_name"},
+		"view":                           {"FULL"},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	for _, ts := range series {
+		for _, p := range ts.Points {
+			sum += p.Value.float()
+		}
+	}
+	return sum, nil
+}
+
+type timeSeriesJSON struct {
+	Metric struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"metric"`
+	Points []struct {
+		Value pointValue `json:"value"`
+	} `json:"points"`
+	Resource struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"resource"`
+}
+
+type pointValue struct {
+	DoubleValue *float64 `json:"doubleValue"`
+	Int64Value  *string  `json:"int64Value"`
+}
+
+func (v pointValue) float() float64 {
+	switch {
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.Int64Value != nil:
+		f, _ := strconv.ParseFloat(*v.Int64Value, 64)
+		return f
+	}
+	return 0
+}
+
+// timeSeries queries the Cloud Monitoring timeSeries.list API. metric names the
+// query in errors.
+func timeSeries(ctx context.Context, project, metric string, q url.Values) ([]timeSeriesJSON, error) {
+	token, err := AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("https://monitoring.googleapis.com/v3/projects/%s/timeSeries?%s", project, q.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query %s", metric)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to query %s: %s", metric, resp.Status)
+	}
+
+	var body struct {
+		TimeSeries []timeSeriesJSON `json:"timeSeries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", metric)
+	}
+	return body.TimeSeries, nil
+}
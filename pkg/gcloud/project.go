@@ -0,0 +1,98 @@
+package gcloud
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BillingAccount is a Cloud Billing account the active account can link projects to.
+type BillingAccount struct {
+	ID   string
+	Name string
+}
+
+// Parent is the organization or folder a project is created under. The zero value
+// creates the project without a parent.
+type Parent struct {
+	ID   string
+	Name string
+	// Type is "organization" or "folder".
+	Type string
+}
+
+type resourceJSON struct {
+	DisplayName string `json:"displayName"`
+	Name        string `json:"name"`
+	Open        *bool  `json:"open"`
+}
+
+// id strips the collection prefix from a resource name such as folders/123.
+func (r resourceJSON) id() string {
+	_, id, ok := strings.Cut(r.Name, "/")
+	if !ok {
+		return r.Name
+	}
+	return id
+}
+
+func listResources(ctx context.Context, what string, args ...string) ([]resourceJSON, error) {
+	out, err := Output(ctx, "gcloud", append(args, "--format=json")...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list %s", what)
+	}
+	var raw []resourceJSON
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", what)
+	}
+	return raw, nil
+}
+
+// ListBillingAccounts returns the open billing accounts visible to the active account.
+func ListBillingAccounts(ctx context.Context) ([]BillingAccount, error) {
+	raw, err := listResources(ctx, "billing accounts", "billing", "accounts", "list")
+	if err != nil {
+		return nil, err
+	}
+	var accounts []BillingAccount
+	for _, r := range raw {
+		if r.Open != nil && !*r.Open {
+			continue
+		}
+		accounts = append(accounts, BillingAccount{ID: r.id(), Name: r.DisplayName})
+	}
+	return accounts, nil
+}
+
+// LinkBilling links a project to a billing account, which Cloud Run requires.
+func LinkBilling(ctx context.Context, project, account string) error {
+	return Run(ctx, "gcloud", "billing", "projects", "link", project, "--billing-account="+account)
+}
+
+// ListOrganizations returns the organizations visible to the active account.
+func ListOrganizations(ctx context.Context) ([]Parent, error) {
+	raw, err := listResources(ctx, "organizations", "organizations", "list")
+	if err != nil {
+		return nil, err
+	}
+	parents := make([]Parent, len(raw))
+	for i, r := range raw {
+		parents[i] = Parent{ID: r.id(), Name: r.DisplayName, Type: "organization"}
+	}
+	return parents, nil
+}
+
+// ListFolders returns the folders directly under an organization or folder.
+func ListFolders(ctx context.Context, parent Parent) ([]Parent, error) {
+	raw, err := listResources(ctx, "folders", "resource-manager", "folders", "list", "--"+parent.Type+"="+parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	parents := make([]Parent, len(raw))
+	for i, r := range raw {
+		parents[i] = Parent{ID: r.id(), Name: r.DisplayName, Type: "folder"}
+	}
+	return parents, nil
+}
@@ -0,0 +1,46 @@
+package gcloud_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListBillingAccounts(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`[
+  {"name": "billingAccounts/0000-AAAA", "displayName": "Main", "open": true},
+  {"name": "billingAccounts/1111-BBBB", "displayName": "Old", "open": false}
+]`, "gcloud", "billing", "accounts", "list")
+
+	accounts, err := gcloud.ListBillingAccounts(t.Context())
+	r.NoError(err)
+
+	a.Equal([]gcloud.BillingAccount{{ID: "0000-AAAA", Name: "Main"}}, accounts)
+}
+
+func TestCreateProjectInFolder(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	ctx := t.Context()
+	fake := gcloudtest.Install(t)
+	fake.Reply(`[{"name": "folders/42", "displayName": "Apps"}]`, "gcloud", "resource-manager", "folders", "list")
+
+	folders, err := gcloud.ListFolders(ctx, gcloud.Parent{ID: "7", Type: "organization"})
+	r.NoError(err)
+	r.Equal([]gcloud.Parent{{ID: "42", Name: "Apps", Type: "folder"}}, folders)
+
+	r.NoError(gcloud.CreateProject(ctx, "new-app", folders[0]))
+	r.NoError(gcloud.LinkBilling(ctx, "new-app", "0000-AAAA"))
+
+	a.Equal([]string{
+		"gcloud resource-manager folders list --organization=7 --format=json",
+		"gcloud projects create new-app --folder=42",
+		"gcloud billing projects link new-app --billing-account=0000-AAAA",
+	}, fake.Commands())
+}
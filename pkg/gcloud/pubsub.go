@@ -0,0 +1,37 @@
+package gcloud
+
+import (
+	"context"
+)
+
+// PushOptions configures a Pub/Sub push subscription that delivers to a Cloud
+// Run service with an OIDC token for ServiceAccount.
+type PushOptions struct {
+	Audience       string
+	Endpoint       string
+	ServiceAccount string
+}
+
+// EnsureTopic creates a Pub/Sub topic if it does not exist.
+func EnsureTopic(ctx context.Context, project, topic string) error {
+	if succeeds(ctx, "gcloud", "pubsub", "topics", "describe", topic, "--project", project) {
+		return nil
+	}
+	return Run(ctx, "gcloud", "pubsub", "topics", "create", topic, "--project", project)
+}
+
+// SavePushSubscription creates a push subscription on topic, or updates the
+// endpoint and auth of an existing one.
+func SavePushSubscription(ctx context.Context, project, name, topic string, opts PushOptions) error {
+	flags := []string{
+		"--project", project,
+		"--push-endpoint", opts.Endpoint,
+		"--push-auth-service-account", opts.ServiceAccount,
+		"--push-auth-token-audience", opts.Audience,
+	}
+
+	if succeeds(ctx, "gcloud", "pubsub", "subscriptions", "describe", name, "--project", project) {
+		return Run(ctx, "gcloud", append([]string{"pubsub", "subscriptions", "update", name}, flags...)...)
+	}
+	return Run(ctx, "gcloud", append([]string{"pubsub", "subscriptions", "create", name, "--topic", topic}, flags...)...)
+}
@@ -0,0 +1,123 @@
+package gcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxServices is Cloud Run's limit on services per region in a project.
+const MaxServices = 1000
+
+// quotaWarning is the fraction of a limit at which Quota.Near reports true.
+const quotaWarning = 0.8
+
+// quotaServices are the APIs whose allocation quotas a deploy uses: CPU and memory
+// for Cloud Run, and storage for Artifact Registry.
+var quotaServices = []string{"artifactregistry.googleapis.com", "run.googleapis.com"}
+
+// Quota is a project's usage of a limit in one location.
+type Quota struct {
+	Limit    float64
+	Location string
+	// Metric is the quota metric, such as run.googleapis.com/cpu_allocation.
+	Metric string
+	Usage  float64
+}
+
+// Near reports whether usage has reached 80% of the limit.
+func (q Quota) Near() bool {
+	return q.Limit > 0 && q.Usage >= quotaWarning*q.Limit
+}
+
+// Exceeded reports whether usage has reached the limit.
+func (q Quota) Exceeded() bool {
+	return q.Limit > 0 && q.Usage >= q.Limit
+}
+
+func (q Quota) String() string {
+	return fmt.Sprintf("%s in %s: %g of %g", q.Metric, q.Location, q.Usage, q.Limit)
+}
+
+type quotaKey struct {
+	location string
+	metric   string
+}
+
+// CheckQuotas returns the quotas a deploy to region can run into: the number of
+// Cloud Run services, and the Cloud Run and Artifact Registry allocation quotas
+// (such as CPU allocation and repository storage) last reported to Cloud
+// Monitoring for the region or globally.
+func CheckQuotas(ctx context.Context, project, region string) ([]Quota, error) {
+	services, err := ListServices(ctx, project, region)
+	if err != nil {
+		return nil, err
+	}
+	quotas := []Quota{{Limit: MaxServices, Location: region, Metric: "run.googleapis.com/services", Usage: float64(len(services))}}
+
+	usage, err := quotaSeries(ctx, project, region, "serviceruntime.googleapis.com/quota/allocation/usage", "REDUCE_SUM")
+	if err != nil {
+		return quotas, err
+	}
+	limits, err := quotaSeries(ctx, project, region, "serviceruntime.googleapis.com/quota/limit", "REDUCE_MIN")
+	if err != nil {
+		return quotas, err
+	}
+	for key, limit := range limits {
+		if used, ok := usage[key]; ok {
+			quotas = append(quotas, Quota{Limit: limit, Location: key.location, Metric: key.metric, Usage: used})
+		}
+	}
+	sort.Slice(quotas[1:], func(i, j int) bool {
+		a, b := quotas[i+1], quotas[j+1]
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		return a.Location < b.Location
+	})
+	return quotas, nil
+}
+
+// quotaSeries returns the latest value of a serviceruntime quota metric for each
+// quota metric and location, combining series with reducer. Quota limits are sampled
+// daily, so it looks back two days.
+func quotaSeries(ctx context.Context, project, region, metric, reducer string) (map[quotaKey]float64, error) {
+	end := time.Now().UTC()
+	period := 48 * time.Hour
+	series, err := timeSeries(ctx, project, metric, url.Values{
+		"filter": {fmt.Sprintf(`metric.type = %q AND resource.type = "consumer_quota" AND resource.labels.service = %s AND resource.labels.location = %s`,
+			metric, oneOf(quotaServices...), oneOf(region, "global"))},
+		"interval.startTime":             {end.Add(-period).Format(time.RFC3339)},
+		"interval.endTime":               {end.Format(time.RFC3339)},
+		"aggregation.alignmentPeriod":    {fmt.Sprintf("%ds", int64(period.Seconds()))},
+		"aggregation.perSeriesAligner":   {"ALIGN_NEXT_OLDER"},
+		"aggregation.crossSeriesReducer": {reducer},
+		"aggregation.groupByFields":      {"metric.labels.quota_metric", "resource.labels.location"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[quotaKey]float64)
+	for _, ts := range series {
+		if len(ts.Points) == 0 {
+			continue
+		}
+		key := quotaKey{location: ts.Resource.Labels["location"], metric: ts.Metric.Labels["quota_metric"]}
+		values[key] = ts.Points[0].Value.float()
+	}
+	return values, nil
+}
+
+// oneOf formats values as a Cloud Monitoring filter one_of(...) expression.
+func oneOf(values ...string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return "one_of(" + strings.Join(quoted, ", ") + ")"
+}
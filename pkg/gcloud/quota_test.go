@@ -0,0 +1,26 @@
+package gcloud_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuota(t *testing.T) {
+	a := assert.New(t)
+
+	q := gcloud.Quota{Limit: 1000, Location: "us-central1", Metric: "run.googleapis.com/services", Usage: 790}
+	a.False(q.Near())
+	a.False(q.Exceeded())
+
+	q.Usage = 800
+	a.True(q.Near())
+	a.False(q.Exceeded())
+	a.Equal("run.googleapis.com/services in us-central1: 800 of 1000", q.String())
+
+	q.Usage = 1000
+	a.True(q.Exceeded())
+
+	a.False(gcloud.Quota{Usage: 5}.Near())
+}
@@ -0,0 +1,106 @@
+package gcloud
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Parallelism bounds how many regions ForEachRegion works on at once.
+var Parallelism = 4
+
+// RegionError is a failure in one region.
+type RegionError struct {
+	Err    error
+	Region string
+}
+
+func (e *RegionError) Error() string {
+	return e.Region + ": " + e.Err.Error()
+}
+
+func (e *RegionError) Unwrap() error {
+	return e.Err
+}
+
+// RegionErrors collects the failures from ForEachRegion, in region order.
+type RegionErrors []*RegionError
+
+func (e RegionErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e RegionErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ForEachRegion calls fn for every region, at most Parallelism at a time, and waits
+// for all of them. Failures are returned together as RegionErrors, so one region
+// failing does not stop the others.
+func ForEachRegion(ctx context.Context, regions []string, fn func(ctx context.Context, region string) error) error {
+	sem := make(chan struct{}, max(Parallelism, 1))
+	errs := make([]error, len(regions))
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = errors.WithStack(ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+			errs[i] = fn(ctx, region)
+		}()
+	}
+	wg.Wait()
+
+	var failed RegionErrors
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, &RegionError{Err: err, Region: regions[i]})
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.WithStack(failed)
+}
+
+// GetServices describes a service in each region concurrently. Regions that fail are
+// missing from the map and reported in the error.
+func GetServices(ctx context.Context, project string, regions []string, service string) (map[string]Service, error) {
+	var mu sync.Mutex
+	services := make(map[string]Service)
+	err := ForEachRegion(ctx, regions, func(ctx context.Context, region string) error {
+		s, err := GetService(ctx, project, region, service)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		services[region] = s
+		return nil
+	})
+	return services, err
+}
+
+// UpdateTrafficRegions applies the same traffic split to a service in each region
+// concurrently. See UpdateTraffic.
+func UpdateTrafficRegions(ctx context.Context, project string, regions []string, service string, split map[string]int) error {
+	return ForEachRegion(ctx, regions, func(ctx context.Context, region string) error {
+		return UpdateTraffic(ctx, project, region, service, split)
+	})
+}
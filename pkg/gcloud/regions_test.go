@@ -0,0 +1,79 @@
+package gcloud_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachRegion(t *testing.T) {
+	a := assert.New(t)
+
+	prev := gcloud.Parallelism
+	gcloud.Parallelism = 2
+	t.Cleanup(func() { gcloud.Parallelism = prev })
+
+	// The first two regions hold their slots until both are running, so a third
+	// starting early would be seen.
+	var running, peak atomic.Int32
+	full, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	errBoom := errors.New("boom")
+	done := make(chan error)
+	go func() {
+		done <- gcloud.ForEachRegion(t.Context(), []string{"r1", "r2", "r3", "r4", "r5"}, func(ctx context.Context, region string) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			if n == 2 {
+				once.Do(func() { close(full) })
+			}
+			<-release
+			if region == "r2" || region == "r4" {
+				return errBoom
+			}
+			return nil
+		})
+	}()
+	<-full
+	close(release)
+	err := <-done
+
+	a.Equal(int32(2), peak.Load())
+	a.True(errors.Is(err, errBoom))
+	a.EqualError(err, "r2: boom; r4: boom")
+
+	var failed gcloud.RegionErrors
+	a.True(errors.As(err, &failed))
+	a.Len(failed, 2)
+
+	a.NoError(gcloud.ForEachRegion(t.Context(), []string{"r1"}, func(ctx context.Context, region string) error { return nil }))
+}
+
+func TestGetServices(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{"metadata": {"name": "app"}, "status": {"url": "https://app-us.a.run.app"}}`, "gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=us-central1")
+	fake.Fail("ERROR: (gcloud.run.services.describe) Cannot find service [app]", "gcloud", "run", "services", "describe", "app", "--platform=managed", "--region=europe-west1")
+
+	services, err := gcloud.GetServices(t.Context(), "p", []string{"us-central1", "europe-west1"}, "app")
+
+	r.Error(err)
+	a.True(errors.Is(err, gcloud.ErrServiceNotFound))
+	a.Contains(err.Error(), "europe-west1: ")
+	a.Equal("https://app-us.a.run.app", services["us-central1"].URL)
+	a.NotContains(services, "europe-west1")
+}
@@ -0,0 +1,113 @@
+package gcloud
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Backoff controls how Output and Run retry transient failures: rate limits, 5xx
+// errors, and APIs that EnsureAPIs enabled moments ago and have not propagated yet.
+// Delay doubles after each attempt up to MaxDelay. Attempts of 1 disables retries.
+type Backoff struct {
+	Attempts int
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// Retry is the Backoff used for every command in this package.
+var Retry = Backoff{Attempts: 5, Delay: 2 * time.Second, MaxDelay: 30 * time.Second}
+
+var transientStatus = regexp.MustCompile(`(httperror |status=\[|code=|"code": |error )(429|50[0234])\b`)
+
+// apiPropagation is how long after EnsureAPIs enables an API that failures saying
+// an API is disabled are retried, while the change propagates.
+const apiPropagation = 5 * time.Minute
+
+var (
+	enabledMu sync.Mutex
+	// enabledAt is when EnsureAPIs last enabled an API, kept here when there is no
+	// CacheDir to record it in for later runs.
+	enabledAt time.Time
+)
+
+// markEnabled records that APIs were just enabled.
+func markEnabled() {
+	now := time.Now()
+	if path := cachePath(apisEnabledKey); path != "" {
+		writeCache(path, now)
+		return
+	}
+	enabledMu.Lock()
+	defer enabledMu.Unlock()
+	enabledAt = now
+}
+
+// recentlyEnabled reports whether APIs were enabled within apiPropagation, by this
+// run or one before it.
+func recentlyEnabled() bool {
+	var at time.Time
+	if path := cachePath(apisEnabledKey); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, &at)
+		}
+	} else {
+		enabledMu.Lock()
+		at = enabledAt
+		enabledMu.Unlock()
+	}
+	return !at.IsZero() && time.Since(at) < apiPropagation
+}
+
+// Transient reports whether err is a gcloud failure that may succeed if retried.
+func Transient(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	s := strings.ToLower(e.Stderr)
+	// An API that is really disabled fails the same way as one still propagating, so
+	// only retry when one was just enabled.
+	if e.Kind == ErrAPIDisabled {
+		return recentlyEnabled() || strings.Contains(s, "not yet activated")
+	}
+	return transientStatus.MatchString(s) ||
+		strings.Contains(s, "resource_exhausted") ||
+		strings.Contains(s, "rate limit") ||
+		strings.Contains(s, "service unavailable") ||
+		strings.Contains(s, "try again later")
+}
+
+// do calls run until it succeeds, fails with an error that is not Transient, or runs
+// out of attempts. wait is called before each retry.
+func (b Backoff) do(ctx context.Context, run func() error, wait func(err error, delay time.Duration)) error {
+	delay := b.Delay
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || attempt >= b.Attempts || !Transient(err) {
+			return err
+		}
+
+		if wait != nil {
+			wait(err, delay)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		delay *= 2
+		if b.MaxDelay > 0 && delay > b.MaxDelay {
+			delay = b.MaxDelay
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package gcloud_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fastRetry(t *testing.T) {
+	prev := gcloud.Retry
+	gcloud.Retry = gcloud.Backoff{Attempts: 3, Delay: time.Millisecond}
+	t.Cleanup(func() { gcloud.Retry = prev })
+}
+
+func TestRetryTransient(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fastRetry(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply("my-project\n", "gcloud", "config", "get-value", "project")
+	fake.FailTimes(2, "ERROR: (gcloud.config.get-value) HTTPError 503: Service Unavailable", "gcloud", "config")
+
+	out, err := gcloud.Output(t.Context(), "gcloud", "config", "get-value", "project")
+	r.NoError(err)
+
+	a.Equal("my-project\n", string(out))
+	a.Len(fake.Calls(), 3)
+}
+
+const apiDisabled = "ERROR: (gcloud.run.deploy) PERMISSION_DENIED: Cloud Run Admin API has not been used in project 123 before or it is disabled."
+
+func TestRetryGivesUp(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fastRetry(t)
+	noAmbientCredentials(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply("", "gcloud", "services", "list")
+	fake.Fail(apiDisabled, "gcloud", "run", "deploy")
+
+	// The API was just enabled, so its disabled errors may be propagation delay.
+	r.NoError(gcloud.EnsureAPIs(t.Context(), "my-project", "run.googleapis.com"))
+	err := gcloud.Run(t.Context(), "gcloud", "run", "deploy", "app")
+
+	a.True(errors.Is(err, gcloud.ErrAPIDisabled))
+	a.Equal([]string{
+		"gcloud services list --enabled --format=value(config.name) --project my-project",
+		"gcloud services enable run.googleapis.com --project my-project",
+		"gcloud run deploy app",
+		"gcloud run deploy app",
+		"gcloud run deploy app",
+	}, fake.Commands())
+}
+
+func TestRetryAPIDisabled(t *testing.T) {
+	a := assert.New(t)
+	fastRetry(t)
+	fake := gcloudtest.Install(t)
+	fake.Fail(apiDisabled, "gcloud", "run", "deploy")
+
+	err := gcloud.Run(t.Context(), "gcloud", "run", "deploy", "app")
+
+	a.True(errors.Is(err, gcloud.ErrAPIDisabled))
+	a.Len(fake.Calls(), 1)
+}
+
+func TestRetryPermanent(t *testing.T) {
+	a := assert.New(t)
+	fastRetry(t)
+	fake := gcloudtest.Install(t)
+	fake.Fail("ERROR: (gcloud.run.deploy) PERMISSION_DENIED: Permission 'run.services.create' denied", "gcloud")
+
+	err := gcloud.Run(t.Context(), "gcloud", "run", "deploy", "app")
+
+	a.True(errors.Is(err, gcloud.ErrPermissionDenied))
+	a.Len(fake.Calls(), 1)
+}
+
+func TestTransient(t *testing.T) {
+	a := assert.New(t)
+
+	transient := func(stderr string) bool {
+		return gcloud.Transient(&gcloud.Error{Err: errors.New("exit status 1"), Kind: gcloud.Classify(stderr), Stderr: stderr})
+	}
+	a.True(transient("ERROR: (gcloud.run.deploy) HTTPError 429: Too Many Requests"))
+	a.True(transient("ERROR: (gcloud.run.services.describe) ResponseError: status=[502], code=[Bad Gateway]"))
+	a.True(transient("ERROR: (gcloud.projects.list) RESOURCE_EXHAUSTED: Quota exceeded"))
+	a.False(transient("ERROR: (gcloud.projects.describe) HTTPError 404: Not found"))
+	a.False(transient("ERROR: (gcloud.run.deploy) --memory=500Mi is invalid"))
+	a.False(gcloud.Transient(errors.New("exit status 1")))
+}
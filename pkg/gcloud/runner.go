@@ -0,0 +1,82 @@
+package gcloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// Command is an external command for a Runner. Nil Stdin, Stdout, or Stderr are
+// connected to the null device.
+type Command struct {
+	Args   []string
+	Name   string
+	Stderr io.Writer
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+// Runner executes the gcloud (and other) commands this package shells out to.
+type Runner interface {
+	Run(ctx context.Context, c Command) error
+}
+
+// ExecRunner runs commands with os/exec.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, c Command) error {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
+	return errors.WithStack(cmd.Run())
+}
+
+// DefaultRunner runs every command in this package. Tests replace it with a
+// gcloudtest.Runner to record commands and fake their output.
+var DefaultRunner Runner = ExecRunner{}
+
+// Output runs a command and returns its stdout, retrying transient failures. A failure
+// is returned as an *Error built from the command's stderr.
+func Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	err := Retry.do(ctx, func() error {
+		var stderr bytes.Buffer
+		stdout.Reset()
+		if err := run(ctx, Command{Args: args, Name: name, Stderr: &stderr, Stdout: &stdout}, false); err != nil {
+			return commandError(err, stderr.String())
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// Attach runs a command connected to the terminal without echoing it, for output the
+// user asked for such as logs.
+func Attach(ctx context.Context, name string, args ...string) error {
+	var stderr bytes.Buffer
+	err := run(ctx, Command{
+		Args:   args,
+		Name:   name,
+		Stderr: io.MultiWriter(os.Stderr, &stderr),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+	}, true)
+	if err != nil {
+		return commandError(err, stderr.String())
+	}
+	return nil
+}
+
+// succeeds reports whether a command exits successfully, for existence checks.
+func succeeds(ctx context.Context, name string, args ...string) bool {
+	_, err := Output(ctx, name, args...)
+	return err == nil
+}
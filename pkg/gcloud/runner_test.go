@@ -0,0 +1,131 @@
+package gcloud_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/gcloud/gcloudtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployCanary(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+
+	err := gcloud.Deploy(t.Context(), "p", "us-central1", "app", "gcr.io/p/app:v1", gcloud.DeployOptions{Canary: 10})
+	r.NoError(err)
+
+	a.Equal([]string{
+		"gcloud run deploy app --image=gcr.io/p/app:v1 --platform=managed --region=us-central1 --project=p --no-traffic",
+		"gcloud run services update-traffic app --platform=managed --region=us-central1 --project=p --to-revisions=LATEST=10",
+	}, fake.Commands())
+}
+
+func TestDescribeService(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{
+  "metadata": {"name": "app", "labels": {"commit-sha": "abc", "cloud.googleapis.com/location": "us-central1"}},
+  "spec": {"template": {
+    "metadata": {"annotations": {"autoscaling.knative.dev/maxScale": "5"}},
+    "spec": {"containers": [{"image": "gcr.io/p/app:v1", "env": [{"name": "A", "value": "1"}]}]}
+  }}
+}`, "gcloud", "run", "services", "describe", "app")
+
+	cfg, err := gcloud.DescribeService(t.Context(), "p", "us-central1", "app")
+	r.NoError(err)
+
+	a.Equal("gcr.io/p/app:v1", cfg.Image)
+	a.Equal(5, cfg.MaxInstances)
+	a.Equal(map[string]string{"commit-sha": "abc"}, cfg.Labels)
+	a.Equal([]gcloud.EnvVar{{Name: "A", Value: "1"}}, cfg.Env)
+}
+
+func TestRunFailure(t *testing.T) {
+	a := assert.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Fail("ERROR: (gcloud.run.services.update-traffic) Service [app] could not be found.", "gcloud", "run", "services", "update-traffic")
+
+	err := gcloud.RouteToLatest(t.Context(), "p", "us-central1", "app")
+
+	a.True(errors.Is(err, gcloud.ErrServiceNotFound))
+	a.Equal("(gcloud.run.services.update-traffic) Service [app] could not be found.", err.Error())
+}
+
+func TestOutputCanceled(t *testing.T) {
+	a := assert.New(t)
+	gcloudtest.Install(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err := gcloud.Output(ctx, "gcloud", "config", "get-value", "project")
+
+	a.True(errors.Is(err, context.Canceled))
+}
+
+func TestGetService(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`{
+  "metadata": {"name": "app"},
+  "spec": {"template": {"spec": {"containers": [{"image": "gcr.io/p/app:v2", "resources": {"limits": {"cpu": "1", "memory": "512Mi"}}}]}}},
+  "status": {
+    "url": "https://app-xyz.a.run.app",
+    "latestReadyRevisionName": "app-00002",
+    "traffic": [
+      {"revisionName": "app-00002", "percent": 100, "latestRevision": true},
+      {"revisionName": "app-00001", "percent": 0, "tag": "pr-7", "url": "https://pr-7---app-xyz.a.run.app"}
+    ]
+  }
+}`, "gcloud", "run", "services", "describe", "app")
+
+	svc, err := gcloud.GetService(t.Context(), "p", "us-central1", "app")
+	r.NoError(err)
+
+	a.Equal("app", svc.Name)
+	a.Equal("https://app-xyz.a.run.app", svc.URL)
+	a.Equal("app-00002", svc.LatestReadyRevision)
+	a.Equal("512Mi", svc.Config.Memory)
+	a.Len(svc.Traffic, 2)
+	a.Equal([]gcloud.TrafficTarget{{Percent: 0, Revision: "app-00001", Tag: "pr-7", URL: "https://pr-7---app-xyz.a.run.app"}}, svc.Tags())
+	a.Len(fake.Calls(), 1)
+}
+
+func TestListRevisions(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fake := gcloudtest.Install(t)
+	fake.Reply(`[
+  {"metadata": {"name": "app-00002", "creationTimestamp": "2026-01-02T00:00:00Z", "labels": {"commit-sha": "def"}},
+   "spec": {"containers": [{"image": "gcr.io/p/app:v2"}]},
+   "status": {"imageDigest": "gcr.io/p/app@sha256:bbb", "conditions": [{"type": "Ready", "status": "True"}]}},
+  {"metadata": {"name": "app-00001", "creationTimestamp": "2026-01-01T00:00:00Z"},
+   "spec": {"containers": [{"image": "gcr.io/p/app:v1"}]},
+   "status": {"conditions": [{"type": "Ready", "status": "False", "message": "crashed"}]}}
+]`, "gcloud", "run", "revisions", "list")
+	fake.Reply(`{"status": {"traffic": [
+  {"revisionName": "app-00002", "percent": 90},
+  {"revisionName": "app-00001", "percent": 10},
+  {"revisionName": "app-00001", "percent": 0, "tag": "pr-3"}
+]}}`, "gcloud", "run", "services", "describe")
+
+	revisions, err := gcloud.ListRevisions(t.Context(), "p", "us-central1", "app", 0)
+	r.NoError(err)
+	r.Len(revisions, 2)
+
+	a.Equal("sha256:bbb", revisions[0].Digest)
+	a.Equal(map[string]string{"commit-sha": "def"}, revisions[0].Labels)
+	a.Equal(90, revisions[0].Percent)
+	a.True(revisions[0].Ready)
+	a.Equal(10, revisions[1].Percent)
+	a.Equal([]string{"pr-3"}, revisions[1].Tags)
+	a.True(revisions[1].Failed)
+	a.True(revisions[1].Serving())
+	a.Contains(fake.Commands()[0], "--limit=unlimited")
+}
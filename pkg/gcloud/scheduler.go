@@ -0,0 +1,100 @@
+package gcloud
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// SchedulerJob is a Cloud Scheduler HTTP job.
+type SchedulerJob struct {
+	Method   string
+	Name     string
+	Schedule string
+	State    string
+	TimeZone string
+	URI      string
+}
+
+// SchedulerOptions configures a Cloud Scheduler HTTP job that calls a Cloud Run
+// service with an OIDC token for ServiceAccount.
+type SchedulerOptions struct {
+	Audience       string
+	Method         string
+	Schedule       string
+	ServiceAccount string
+	TimeZone       string
+	URI            string
+}
+
+// ListSchedulerJobs returns the Cloud Scheduler jobs in a project/location.
+func ListSchedulerJobs(ctx context.Context, project, location string) ([]SchedulerJob, error) {
+	var raw []struct {
+		HTTPTarget struct {
+			HTTPMethod string `json:"httpMethod"`
+			URI        string `json:"uri"`
+		} `json:"httpTarget"`
+		Name     string `json:"name"`
+		Schedule string `json:"schedule"`
+		State    string `json:"state"`
+		TimeZone string `json:"timeZone"`
+	}
+	out, err := Output(ctx, "gcloud", "scheduler", "jobs", "list",
+		"--project", project,
+		"--location", location,
+		"--format=json")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list scheduler jobs")
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, errors.Wrap(err, "failed to parse scheduler jobs")
+	}
+
+	jobs := make([]SchedulerJob, len(raw))
+	for i, r := range raw {
+		jobs[i] = SchedulerJob{
+			Method:   r.HTTPTarget.HTTPMethod,
+			Name:     path.Base(r.Name),
+			Schedule: r.Schedule,
+			State:    r.State,
+			TimeZone: r.TimeZone,
+			URI:      r.HTTPTarget.URI,
+		}
+	}
+	return jobs, nil
+}
+
+// SaveSchedulerJob creates the Cloud Scheduler job, or updates it if it exists.
+func SaveSchedulerJob(ctx context.Context, project, location, name string, opts SchedulerOptions) error {
+	jobs, err := ListSchedulerJobs(ctx, project, location)
+	if err != nil {
+		return err
+	}
+
+	action := "create"
+	for _, j := range jobs {
+		if j.Name == name {
+			action = "update"
+		}
+	}
+
+	return Run(ctx, "gcloud", "scheduler", "jobs", action, "http", name,
+		"--project", project,
+		"--location", location,
+		"--schedule", opts.Schedule,
+		"--time-zone", opts.TimeZone,
+		"--uri", opts.URI,
+		"--http-method", opts.Method,
+		"--oidc-service-account-email", opts.ServiceAccount,
+		"--oidc-token-audience", opts.Audience)
+}
+
+// DeleteSchedulerJob deletes a Cloud Scheduler job.
+func DeleteSchedulerJob(ctx context.Context, project, location, name string) error {
+	return Run(ctx, "gcloud", "scheduler", "jobs", "delete", name,
+		"--project", project,
+		"--location", location,
+		"--quiet")
+}
@@ -0,0 +1,216 @@
+package gcloud
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ServiceConfig is the deployed configuration of a Cloud Run service.
+type ServiceConfig struct {
+	CloudSQL       []string
+	Concurrency    int
+	CPU            string
+	Env            []EnvVar
+	Image          string
+	Labels         map[string]string
+	MaxInstances   int
+	Memory         string
+	MinInstances   int
+	Name           string
+	ServiceAccount string
+}
+
+// EnvVar is a service env var set either to Value or to a Secret Manager secret version.
+type EnvVar struct {
+	Name          string
+	Secret        string
+	SecretVersion string
+	Value         string
+}
+
+// IAMBinding grants Role to Member.
+type IAMBinding struct {
+	Member string
+	Role   string
+}
+
+// GetService returns a deployed service's configuration, URLs, and traffic from a
+// single describe call.
+func GetService(ctx context.Context, project, region, service string) (Service, error) {
+	out, err := Output(ctx, "gcloud", "run", "services", "describe", service,
+		"--platform=managed",
+		"--region="+region,
+		"--project="+project,
+		"--format=json")
+	if err != nil {
+		return Service{}, errors.Wrapf(err, "failed to describe service %s", service)
+	}
+
+	var raw serviceJSON
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return Service{}, errors.Wrap(err, "failed to parse service")
+	}
+	return raw.service(), nil
+}
+
+// DescribeService returns the configuration of a deployed service.
+func DescribeService(ctx context.Context, project, region, service string) (ServiceConfig, error) {
+	s, err := GetService(ctx, project, region, service)
+	return s.Config, err
+}
+
+// ServiceIAMBindings returns the members of each role on a service's IAM policy.
+func ServiceIAMBindings(ctx context.Context, project, region, service string) ([]IAMBinding, error) {
+	out, err := Output(ctx, "gcloud", "run", "services", "get-iam-policy", service,
+		"--platform=managed",
+		"--region="+region,
+		"--project="+project,
+		"--format=json")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get IAM policy")
+	}
+
+	var policy struct {
+		Bindings []struct {
+			Members []string `json:"members"`
+			Role    string   `json:"role"`
+		} `json:"bindings"`
+	}
+	if err := json.Unmarshal(out, &policy); err != nil {
+		return nil, errors.Wrap(err, "failed to parse IAM policy")
+	}
+
+	var bindings []IAMBinding
+	for _, b := range policy.Bindings {
+		for _, m := range b.Members {
+			bindings = append(bindings, IAMBinding{Member: m, Role: b.Role})
+		}
+	}
+	return bindings, nil
+}
+
+// DomainMappings returns the custom domains mapped to a service.
+func DomainMappings(ctx context.Context, project, region, service string) ([]string, error) {
+	out, err := Output(ctx, "gcloud", "beta", "run", "domain-mappings", "list",
+		"--platform=managed",
+		"--region="+region,
+		"--project="+project,
+		"--filter=spec.routeName="+service,
+		"--format=value(metadata.name)")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list domain mappings")
+	}
+	return strings.Fields(string(out)), nil
+}
+
+type serviceJSON struct {
+	Metadata struct {
+		Labels map[string]string `json:"labels"`
+		Name   string            `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Template struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+			Spec struct {
+				ContainerConcurrency int `json:"containerConcurrency"`
+				Containers           []struct {
+					Env []struct {
+						Name      string `json:"name"`
+						Value     string `json:"value"`
+						ValueFrom *struct {
+							SecretKeyRef struct {
+								Key  string `json:"key"`
+								Name string `json:"name"`
+							} `json:"secretKeyRef"`
+						} `json:"valueFrom"`
+					} `json:"env"`
+					Image     string `json:"image"`
+					Resources struct {
+						Limits map[string]string `json:"limits"`
+					} `json:"resources"`
+				} `json:"containers"`
+				ServiceAccountName string `json:"serviceAccountName"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		LatestReadyRevisionName string `json:"latestReadyRevisionName"`
+		Traffic                 []struct {
+			LatestRevision bool   `json:"latestRevision"`
+			Percent        int    `json:"percent"`
+			RevisionName   string `json:"revisionName"`
+			Tag            string `json:"tag"`
+			URL            string `json:"url"`
+		} `json:"traffic"`
+		URL string `json:"url"`
+	} `json:"status"`
+}
+
+func (s serviceJSON) service() Service {
+	svc := Service{
+		Config:              s.config(),
+		LatestReadyRevision: s.Status.LatestReadyRevisionName,
+		Name:                s.Metadata.Name,
+		URL:                 s.Status.URL,
+	}
+	for _, t := range s.Status.Traffic {
+		svc.Traffic = append(svc.Traffic, TrafficTarget{
+			Latest:   t.LatestRevision,
+			Percent:  t.Percent,
+			Revision: t.RevisionName,
+			Tag:      t.Tag,
+			URL:      t.URL,
+		})
+	}
+	return svc
+}
+
+func (s serviceJSON) config() ServiceConfig {
+	tmpl := s.Spec.Template
+	annotations := tmpl.Metadata.Annotations
+	cfg := ServiceConfig{
+		Concurrency:    tmpl.Spec.ContainerConcurrency,
+		Name:           s.Metadata.Name,
+		ServiceAccount: tmpl.Spec.ServiceAccountName,
+	}
+
+	// Labels managed by Cloud Run itself are not part of the service config
+	for k, v := range s.Metadata.Labels {
+		if strings.Contains(k, "googleapis.com/") {
+			continue
+		}
+		if cfg.Labels == nil {
+			cfg.Labels = make(map[string]string)
+		}
+		cfg.Labels[k] = v
+	}
+
+	cfg.MinInstances, _ = strconv.Atoi(annotations["autoscaling.knative.dev/minScale"])
+	cfg.MaxInstances, _ = strconv.Atoi(annotations["autoscaling.knative.dev/maxScale"])
+	if sql := annotations["run.googleapis.com/cloudsql-instances"]; sql != "" {
+		cfg.CloudSQL = strings.Split(sql, ",")
+	}
+
+	if len(tmpl.Spec.Containers) == 0 {
+		return cfg
+	}
+	c := tmpl.Spec.Containers[0]
+	cfg.Image = c.Image
+	cfg.CPU = c.Resources.Limits["cpu"]
+	cfg.Memory = c.Resources.Limits["memory"]
+	for _, e := range c.Env {
+		v := EnvVar{Name: e.Name, Value: e.Value}
+		if e.ValueFrom != nil {
+			v.Secret = e.ValueFrom.SecretKeyRef.Name
+			v.SecretVersion = e.ValueFrom.SecretKeyRef.Key
+		}
+		cfg.Env = append(cfg.Env, v)
+	}
+	return cfg
+}
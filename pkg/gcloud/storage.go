@@ -0,0 +1,71 @@
+package gcloud
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImmutableCacheControl caches objects for a year. Use it only for content that
+// never changes at its URL, such as assets under a per-deploy prefix.
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+// EnsurePublicBucket creates a bucket in location if it does not exist and
+// grants allUsers read access to its objects.
+func EnsurePublicBucket(ctx context.Context, project, bucket, location string) error {
+	url := "gs://" + bucket
+	if !succeeds(ctx, "gcloud", "storage", "buckets", "describe", url, "--project", project) {
+		if err := Run(ctx, "gcloud", "storage", "buckets", "create", url,
+			"--project", project,
+			"--location", location,
+			"--uniform-bucket-level-access"); err != nil {
+			return err
+		}
+	}
+	return Run(ctx, "gcloud", "storage", "buckets", "add-iam-policy-binding", url,
+		"--member=allUsers",
+		"--role=roles/storage.objectViewer")
+}
+
+// UploadDir copies the files in dir to dest, a gs:// URL, with a Cache-Control header.
+func UploadDir(ctx context.Context, dir, dest, cacheControl string) error {
+	return Run(ctx, "gcloud", "storage", "rsync", dir, dest,
+		"--recursive",
+		"--cache-control="+cacheControl)
+}
+
+// EnsureBucketCDN serves a bucket through Cloud CDN on a global HTTP load
+// balancer named after the bucket and returns the load balancer IP address.
+// Existing resources are reused.
+func EnsureBucketCDN(ctx context.Context, project, bucket string) (string, error) {
+	name := strings.ReplaceAll(bucket, ".", "-") + "-cdn"
+	steps := [][]string{
+		{"backend-buckets", "create", name, "--gcs-bucket-name=" + bucket, "--enable-cdn"},
+		{"url-maps", "create", name, "--default-backend-bucket=" + name},
+		{"target-http-proxies", "create", name, "--url-map=" + name},
+		{"addresses", "create", name, "--global"},
+		{"forwarding-rules", "create", name, "--global", "--address=" + name, "--target-http-proxy=" + name, "--ports=80"},
+	}
+	for _, step := range steps {
+		describe := []string{"compute", step[0], "describe", step[2], "--project", project}
+		if step[0] == "addresses" || step[0] == "forwarding-rules" {
+			describe = append(describe, "--global")
+		}
+		if succeeds(ctx, "gcloud", describe...) {
+			continue
+		}
+		if err := Run(ctx, "gcloud", append(append([]string{"compute"}, step...), "--project", project)...); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := Output(ctx, "gcloud", "compute", "addresses", "describe", name,
+		"--global",
+		"--project", project,
+		"--format=value(address)")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get CDN address")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,86 @@
+package gcloud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// MaxTagLength keeps "<tag>---<service>" within a 63 character DNS label for typical service names.
+const MaxTagLength = 46
+
+// Preview identifies a pull request preview deploy.
+type Preview struct {
+	BaseRepo string
+	HeadRepo string
+	Number   int
+	Parent   string
+}
+
+// PreviewTag returns a traffic tag that is unique per pull request.
+// Same-repo PRs get "pr-<n>". PRs from forks add the fork owner so a fork can't
+// overwrite a same-numbered preview, and stacked PRs add the parent branch.
+func PreviewTag(p Preview) string {
+	parts := []string{fmt.Sprintf("pr-%d", p.Number)}
+
+	if p.HeadRepo != "" && !strings.EqualFold(p.HeadRepo, p.BaseRepo) {
+		owner, _, _ := strings.Cut(p.HeadRepo, "/")
+		parts = append(parts, owner)
+	}
+
+	if p.Parent != "" {
+		parts = append(parts, "on", p.Parent)
+	}
+
+	return SanitizeTag(strings.Join(parts, "-"))
+}
+
+// LabelValue converts s into a valid label value: at most 63 lowercase letters,
+// digits, underscores, and dashes.
+func LabelValue(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	v := b.String()
+	if len(v) > 63 {
+		v = v[:63]
+	}
+	return v
+}
+
+// SanitizeTag converts s into a valid Cloud Run traffic tag: lowercase letters,
+// digits, and dashes, starting with a letter, at most MaxTagLength characters.
+// Long tags are truncated with a hash suffix so they stay unique.
+func SanitizeTag(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	tag := strings.Trim(b.String(), "-")
+	if tag == "" || tag[0] < 'a' || tag[0] > 'z' {
+		tag = "t-" + tag
+	}
+
+	if len(tag) > MaxTagLength {
+		sum := sha256.Sum256([]byte(tag))
+		hash := hex.EncodeToString(sum[:])[:7]
+		tag = strings.TrimRight(tag[:MaxTagLength-len(hash)-1], "-") + "-" + hash
+	}
+	return tag
+}
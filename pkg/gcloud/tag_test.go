@@ -0,0 +1,58 @@
+package gcloud_test
+
+import (
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreviewTag(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		name    string
+		preview gcloud.Preview
+		want    string
+	}{
+		{
+			name:    "same_repo",
+			preview: gcloud.Preview{BaseRepo: "acme/app", HeadRepo: "acme/app", Number: 12},
+			want:    "pr-12",
+		},
+		{
+			name:    "fork",
+			preview: gcloud.Preview{BaseRepo: "acme/app", HeadRepo: "Jane_Doe/app", Number: 12},
+			want:    "pr-12-jane-doe",
+		},
+		{
+			name:    "stacked",
+			preview: gcloud.Preview{BaseRepo: "acme/app", HeadRepo: "acme/app", Number: 13, Parent: "feature/login"},
+			want:    "pr-13-on-feature-login",
+		},
+	}
+
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			a.Equal(ts.want, gcloud.PreviewTag(ts.preview))
+		})
+	}
+}
+
+func TestSanitizeTag(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("t-123", gcloud.SanitizeTag("123"))
+	a.Equal("feature-x", gcloud.SanitizeTag("--Feature_X--"))
+
+	long := gcloud.SanitizeTag("pr-1-on-a-very-long-branch-name-that-keeps-going-and-going")
+	a.LessOrEqual(len(long), gcloud.MaxTagLength)
+	a.NotEqual(long, gcloud.SanitizeTag("pr-1-on-a-very-long-branch-name-that-keeps-going-and-gone"))
+}
+
+func TestLabelValue(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("feature-login_v2", gcloud.LabelValue("Feature/Login_v2"))
+	a.Len(gcloud.LabelValue(string(make([]byte, 100))), 63)
+}
@@ -0,0 +1,47 @@
+package github
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Annotation is an error or warning a workflow step reports on a line of a file,
+// which GitHub shows on the run and inline on pull requests.
+type Annotation struct {
+	Column int
+	// File is relative to the repository root.
+	File string
+	// Level is error, warning, or notice.
+	Level   string
+	Line    int
+	Message string
+	Title   string
+}
+
+// WriteAnnotation writes a as a workflow command, such as
+// ::error file=main.go,line=3::message, which the runner reads from stdout.
+func WriteAnnotation(w io.Writer, a Annotation) error {
+	var props []string
+	add := func(name, value string) {
+		if value != "" && value != "0" {
+			props = append(props, name+"="+escapeProperty(value))
+		}
+	}
+	add("file", a.File)
+	add("line", fmt.Sprint(a.Line))
+	add("col", fmt.Sprint(a.Column))
+	add("title", a.Title)
+	_, err := fmt.Fprintf(w, "::%s %s::%s\n", a.Level, strings.Join(props, ","), escapeData(a.Message))
+	return errors.WithStack(err)
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,33 @@
+package github_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAnnotation(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	var buf bytes.Buffer
+	r.NoError(github.WriteAnnotation(&buf, github.Annotation{
+		Column:  5,
+		File:    "cmd/main.go",
+		Level:   "error",
+		Line:    12,
+		Message: "100% wrong\nsee docs",
+		Title:   "pkgerrors",
+	}))
+	r.NoError(github.WriteAnnotation(&buf, github.Annotation{
+		File:    "web/App.svelte",
+		Level:   "warning",
+		Message: "unused",
+		Title:   "svelte/css_unused_selector, a11y",
+	}))
+	a.Equal("::error file=cmd/main.go,line=12,col=5,title=pkgerrors::100%25 wrong%0Asee docs\n"+
+		"::warning file=web/App.svelte,title=svelte/css_unused_selector%2C a11y::unused\n", buf.String())
+}
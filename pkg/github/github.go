@@ -0,0 +1,285 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultAPI is the GitHub REST API base URL.
+const DefaultAPI = "https://api.github.com"
+
+// DefaultGraphQL is the GitHub GraphQL API URL.
+const DefaultGraphQL = "https://api.github.com/graphql"
+
+// Client calls the GitHub REST and GraphQL APIs for a single repository.
+type Client struct {
+	API     string
+	GraphQL string
+	HTTP    *http.Client
+	Repo    string
+	Token   string
+}
+
+// PullRequest identifies the pull request a workflow run was triggered by.
+type PullRequest struct {
+	BaseRef       string
+	BaseRepo      string
+	DefaultBranch string
+	HeadRepo      string
+	HeadSHA       string
+	Number        int
+}
+
+// ReviewComment is an inline pull request review comment.
+type ReviewComment struct {
+	Body string `json:"body"`
+	Line int    `json:"line"`
+	Path string `json:"path"`
+	Side string `json:"side,omitempty"`
+}
+
+// ReviewThread is the conversation started by an inline review comment. Comment is
+// its first comment, with the line it is on, or 0 once the line is outdated.
+type ReviewThread struct {
+	Comment  ReviewComment
+	ID       string
+	Resolved bool
+}
+
+// FromEnv returns a client configured from GITHUB_TOKEN, GITHUB_REPOSITORY,
+// GITHUB_API_URL, and GITHUB_GRAPHQL_URL.
+func FromEnv() (*Client, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, errors.New("GITHUB_TOKEN not set")
+	}
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if repo == "" {
+		return nil, errors.New("GITHUB_REPOSITORY not set")
+	}
+	api := os.Getenv("GITHUB_API_URL")
+	if api == "" {
+		api = DefaultAPI
+	}
+	graphql := os.Getenv("GITHUB_GRAPHQL_URL")
+	if graphql == "" {
+		graphql = DefaultGraphQL
+	}
+	return &Client{API: api, GraphQL: graphql, HTTP: http.DefaultClient, Repo: repo, Token: token}, nil
+}
+
+// CurrentPullRequest reads the pull request from the GITHUB_EVENT_PATH payload.
+func CurrentPullRequest() (PullRequest, error) {
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return PullRequest{}, errors.New("GITHUB_EVENT_PATH not set")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PullRequest{}, errors.WithStack(err)
+	}
+
+	var event struct {
+		PullRequest struct {
+			Base struct {
+				Ref  string `json:"ref"`
+				Repo struct {
+					FullName string `json:"full_name"`
+				} `json:"repo"`
+			} `json:"base"`
+			Head struct {
+				Repo struct {
+					FullName string `json:"full_name"`
+				} `json:"repo"`
+				SHA string `json:"sha"`
+			} `json:"head"`
+			Number int `json:"number"`
+		} `json:"pull_request"`
+		Repository struct {
+			DefaultBranch string `json:"default_branch"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return PullRequest{}, errors.WithStack(err)
+	}
+	if event.PullRequest.Number == 0 {
+		return PullRequest{}, errors.New("workflow was not triggered by a pull request")
+	}
+
+	return PullRequest{
+		BaseRef:       event.PullRequest.Base.Ref,
+		BaseRepo:      event.PullRequest.Base.Repo.FullName,
+		DefaultBranch: event.Repository.DefaultBranch,
+		HeadRepo:      event.PullRequest.Head.Repo.FullName,
+		HeadSHA:       event.PullRequest.Head.SHA,
+		Number:        event.PullRequest.Number,
+	}, nil
+}
+
+const reviewThreadsQuery = `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        nodes {
+          id
+          isResolved
+          line
+          path
+          comments(first: 1) { nodes { body } }
+        }
+        pageInfo { endCursor hasNextPage }
+      }
+    }
+  }
+}`
+
+// ReviewThreads returns all inline review threads on a pull request.
+func (c *Client) ReviewThreads(pr int) ([]ReviewThread, error) {
+	owner, name, ok := strings.Cut(c.Repo, "/")
+	if !ok {
+		return nil, errors.Errorf("github: repository %q is not owner/name", c.Repo)
+	}
+
+	var all []ReviewThread
+	var after *string
+	for {
+		var out struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							Comments struct {
+								Nodes []struct {
+									Body string `json:"body"`
+								} `json:"nodes"`
+							} `json:"comments"`
+							ID         string `json:"id"`
+							IsResolved bool   `json:"isResolved"`
+							Line       int    `json:"line"`
+							Path       string `json:"path"`
+						} `json:"nodes"`
+						PageInfo struct {
+							EndCursor   string `json:"endCursor"`
+							HasNextPage bool   `json:"hasNextPage"`
+						} `json:"pageInfo"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		vars := map[string]any{"owner": owner, "name": name, "number": pr, "after": after}
+		if err := c.graphql(reviewThreadsQuery, vars, &out); err != nil {
+			return nil, err
+		}
+		threads := out.Repository.PullRequest.ReviewThreads
+		for _, n := range threads.Nodes {
+			t := ReviewThread{Comment: ReviewComment{Line: n.Line, Path: n.Path}, ID: n.ID, Resolved: n.IsResolved}
+			if len(n.Comments.Nodes) > 0 {
+				t.Comment.Body = n.Comments.Nodes[0].Body
+			}
+			all = append(all, t)
+		}
+		if !threads.PageInfo.HasNextPage {
+			return all, nil
+		}
+		after = &threads.PageInfo.EndCursor
+	}
+}
+
+// CreateReview posts a review with inline comments on commit sha.
+func (c *Client) CreateReview(pr int, sha, body string, comments []ReviewComment) error {
+	req := struct {
+		Body     string          `json:"body,omitempty"`
+		CommitID string          `json:"commit_id"`
+		Comments []ReviewComment `json:"comments"`
+		Event    string          `json:"event"`
+	}{body, sha, comments, "COMMENT"}
+	return c.do(http.MethodPost, fmt.Sprintf("/repos/%s/pulls/%d/reviews", c.Repo, pr), req, nil)
+}
+
+// ResolveReviewThread marks a review thread resolved, collapsing it while keeping
+// its comments.
+func (c *Client) ResolveReviewThread(id string) error {
+	const mutation = `mutation($id: ID!) { resolveReviewThread(input: {threadId: $id}) { thread { id } } }`
+	return c.graphql(mutation, map[string]any{"id": id}, nil)
+}
+
+// graphql runs a GraphQL query or mutation with vars, decoding its data into out.
+func (c *Client) graphql(query string, vars map[string]any, out any) error {
+	req := struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}{query, vars}
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.send(http.MethodPost, c.GraphQL, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		msgs := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			msgs[i] = e.Message
+		}
+		return errors.Errorf("github: graphql: %s", strings.Join(msgs, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.WithStack(json.Unmarshal(resp.Data, out))
+}
+
+func (c *Client) do(method, path string, in, out any) error {
+	return c.send(method, strings.TrimSuffix(c.API, "/")+path, in, out)
+}
+
+func (c *Client) send(method, url string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.Errorf("github: %s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package github_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request is what the fake API received.
+type request struct {
+	Auth   string
+	Body   map[string]any
+	Method string
+	Path   string
+}
+
+// newClient returns a client of a fake API that records requests and answers the
+// nth of them with status and replies[n], if given.
+func newClient(t *testing.T, status int, requests *[]request, replies ...string) *github.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got := request{Auth: req.Header.Get("Authorization"), Method: req.Method, Path: req.URL.RequestURI()}
+		if req.ContentLength > 0 {
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&got.Body))
+		}
+		n := len(*requests)
+		*requests = append(*requests, got)
+		w.WriteHeader(status)
+		switch {
+		case n < len(replies):
+			_, _ = w.Write([]byte(replies[n]))
+		case status >= 300:
+			_, _ = w.Write([]byte(`{"message":"Validation Failed"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &github.Client{API: srv.URL + "/", GraphQL: srv.URL + "/graphql", HTTP: srv.Client(), Repo: "acme/app", Token: "secret"}
+}
+
+func TestCreateReview(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	var requests []request
+	c := newClient(t, http.StatusOK, &requests)
+	r.NoError(c.CreateReview(7, "abc123", "1 new issue", []github.ReviewComment{{Body: "fix", Line: 3, Path: "main.go", Side: "RIGHT"}}))
+
+	r.Len(requests, 1)
+	got := requests[0]
+	a.Equal(http.MethodPost, got.Method)
+	a.Equal("/repos/acme/app/pulls/7/reviews", got.Path)
+	a.Equal("Bearer secret", got.Auth)
+	a.Equal(map[string]any{
+		"body":      "1 new issue",
+		"commit_id": "abc123",
+		"comments":  []any{map[string]any{"body": "fix", "line": float64(3), "path": "main.go", "side": "RIGHT"}},
+		"event":     "COMMENT",
+	}, got.Body)
+}
+
+func TestCreateReviewError(t *testing.T) {
+	a := assert.New(t)
+
+	var requests []request
+	c := newClient(t, http.StatusUnprocessableEntity, &requests)
+	err := c.CreateReview(7, "abc123", "", []github.ReviewComment{{Body: "fix", Line: 3, Path: "main.go"}})
+	a.ErrorContains(err, "422")
+	a.ErrorContains(err, "Validation Failed")
+}
+
+func TestReviewThreads(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	var requests []request
+	c := newClient(t, http.StatusOK, &requests,
+		`{"data": {"repository": {"pullRequest": {"reviewThreads": {
+			"nodes": [{"id": "T1", "isResolved": false, "line": 3, "path": "main.go", "comments": {"nodes": [{"body": "fix"}]}}],
+			"pageInfo": {"endCursor": "c1", "hasNextPage": true}}}}}}`,
+		`{"data": {"repository": {"pullRequest": {"reviewThreads": {
+			"nodes": [{"id": "T2", "isResolved": true, "line": null, "path": "web/App.svelte", "comments": {"nodes": [{"body": "old"}]}}],
+			"pageInfo": {"endCursor": "c2", "hasNextPage": false}}}}}}`)
+	threads, err := c.ReviewThreads(7)
+	r.NoError(err)
+
+	a.Equal([]github.ReviewThread{
+		{Comment: github.ReviewComment{Body: "fix", Line: 3, Path: "main.go"}, ID: "T1"},
+		{Comment: github.ReviewComment{Body: "old", Path: "web/App.svelte"}, ID: "T2", Resolved: true},
+	}, threads)
+	r.Len(requests, 2)
+	a.Equal(http.MethodPost, requests[0].Method)
+	a.Equal("/graphql", requests[0].Path)
+	a.Equal("Bearer secret", requests[0].Auth)
+	a.Equal(map[string]any{"after": nil, "name": "app", "number": float64(7), "owner": "acme"}, requests[0].Body["variables"])
+	a.Equal("c1", requests[1].Body["variables"].(map[string]any)["after"])
+}
+
+func TestResolveReviewThread(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	var requests []request
+	c := newClient(t, http.StatusOK, &requests, `{"data": {"resolveReviewThread": {"thread": {"id": "T1"}}}}`)
+	r.NoError(c.ResolveReviewThread("T1"))
+
+	r.Len(requests, 1)
+	a.Contains(requests[0].Body["query"], "resolveReviewThread")
+	a.Equal(map[string]any{"id": "T1"}, requests[0].Body["variables"])
+}
+
+func TestGraphQLError(t *testing.T) {
+	a := assert.New(t)
+
+	var requests []request
+	c := newClient(t, http.StatusOK, &requests, `{"data": null, "errors": [{"message": "Resource not accessible by integration"}]}`)
+	a.EqualError(c.ResolveReviewThread("T1"), "github: graphql: Resource not accessible by integration")
+}
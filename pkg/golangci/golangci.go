@@ -0,0 +1,168 @@
+// Package golangci pins the golangci-lint release a project lints with and renders
+// the .golangci.yml do manages, so every checkout and CI run uses the same linters
+// at the same version.
+package golangci
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the golangci-lint release used when Options.Version is empty.
+const Version = "2.6.2"
+
+// Module is the module golangci-lint is released in.
+const Module = "github.com/golangci/golangci-lint/v2"
+
+// Tool is the package go.mod declares as a tool, run as go tool golangci-lint.
+const Tool = Module + "/cmd/golangci-lint"
+
+// Header starts the configuration files do manages. Files without it belong to the
+// project and are never written.
+const Header = "# Managed by do. Run go do lint --sync-config to update it, or delete this line to maintain it yourself.\n"
+
+// ConfigFiles are the names golangci-lint reads its configuration from, in the
+// order it looks for them.
+var ConfigFiles = []string{".golangci.yml", ".golangci.yaml", ".golangci.toml", ".golangci.json"}
+
+// Linters are enabled in the managed configuration, on top of none of the defaults.
+var Linters = []string{"bodyclose", "errcheck", "govet", "ineffassign", "misspell", "nilerr", "staticcheck", "unconvert", "unused", "usestdlibvars"}
+
+// Formatters are enabled in the managed configuration, reporting unformatted files.
+var Formatters = []string{"gofmt"}
+
+// formatterNames are the names golangci-lint enables under formatters, not linters.
+var formatterNames = []string{"gci", "gofmt", "gofumpt", "goimports", "golines", "swaggo"}
+
+// versionPattern accepts release versions such as 2.6.2 or v2.6.2.
+var versionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`)
+
+// Options configures the managed configuration.
+type Options struct {
+	// Disable removes linters and formatters from Linters and Formatters.
+	Disable []string
+	// Enable adds linters and formatters, such as gocritic or goimports.
+	Enable []string
+}
+
+type config struct {
+	Version    string     `yaml:"version"`
+	Linters    linters    `yaml:"linters"`
+	Formatters formatters `yaml:"formatters"`
+}
+
+type linters struct {
+	Default    string     `yaml:"default"`
+	Enable     []string   `yaml:"enable,omitempty"`
+	Exclusions exclusions `yaml:"exclusions"`
+}
+
+type formatters struct {
+	Enable     []string   `yaml:"enable,omitempty"`
+	Exclusions exclusions `yaml:"exclusions"`
+}
+
+type exclusions struct {
+	Generated string   `yaml:"generated"`
+	Presets   []string `yaml:"presets,omitempty"`
+}
+
+// Config returns the managed configuration, starting with Header.
+func Config(opts Options) ([]byte, error) {
+	for _, name := range opts.Enable {
+		if slices.Contains(opts.Disable, name) {
+			return nil, errors.Errorf("%s is both enabled and disabled", name)
+		}
+	}
+	c := config{
+		Version: "2",
+		Linters: linters{
+			Default:    "none",
+			Enable:     enabled(Linters, opts, false),
+			Exclusions: exclusions{Generated: "lax", Presets: []string{"comments", "std-error-handling"}},
+		},
+		Formatters: formatters{
+			Enable:     enabled(Formatters, opts, true),
+			Exclusions: exclusions{Generated: "lax"},
+		},
+	}
+	var buf bytes.Buffer
+	buf.WriteString(Header)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// enabled returns the sorted names of defaults and opts.Enable that are formatters,
+// or not, without those in opts.Disable.
+func enabled(defaults []string, opts Options, formatter bool) []string {
+	var names []string
+	for _, name := range append(slices.Clone(defaults), opts.Enable...) {
+		if slices.Contains(formatterNames, name) != formatter || slices.Contains(opts.Disable, name) || slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Managed reports whether the configuration in data starts with Header, so do may
+// rewrite it.
+func Managed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("# Managed by do."))
+}
+
+// FindConfig returns the path of the configuration file in dir, or "" when it has
+// none.
+func FindConfig(dir string) (string, error) {
+	for _, name := range ConfigFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", errors.WithStack(err)
+		}
+	}
+	return "", nil
+}
+
+// NormalizeVersion returns version with the v prefix go get expects, such as v2.6.2.
+func NormalizeVersion(version string) (string, error) {
+	if !versionPattern.MatchString(version) {
+		return "", errors.Errorf("golangci-lint version %q is not a release such as %s", version, Version)
+	}
+	return "v" + strings.TrimPrefix(version, "v"), nil
+}
+
+// ModVersion returns the version of golangci-lint required by the go.mod in data,
+// or "" when it does not declare Tool.
+func ModVersion(data []byte) (string, error) {
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "parse go.mod")
+	}
+	if !slices.ContainsFunc(f.Tool, func(t *modfile.Tool) bool { return t.Path == Tool }) {
+		return "", nil
+	}
+	for _, req := range f.Require {
+		if req.Mod.Path == Module {
+			return req.Mod.Version, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,104 @@
+package golangci_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/golangci"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfig(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	data, err := golangci.Config(golangci.Options{Disable: []string{"misspell", "gofmt"}, Enable: []string{"gocritic", "goimports", "errcheck"}})
+	r.NoError(err)
+	a.True(golangci.Managed(data))
+
+	var c struct {
+		Version string
+		Linters struct {
+			Default string
+			Enable  []string
+		}
+		Formatters struct {
+			Enable []string
+		}
+	}
+	r.NoError(yaml.Unmarshal(data, &c))
+	a.Equal("2", c.Version)
+	a.Equal("none", c.Linters.Default)
+	a.Equal([]string{"bodyclose", "errcheck", "gocritic", "govet", "ineffassign", "nilerr", "staticcheck", "unconvert", "unused", "usestdlibvars"}, c.Linters.Enable)
+	a.Equal([]string{"goimports"}, c.Formatters.Enable)
+
+	_, err = golangci.Config(golangci.Options{Disable: []string{"gocritic"}, Enable: []string{"gocritic"}})
+	a.Error(err)
+}
+
+func TestManaged(t *testing.T) {
+	a := assert.New(t)
+
+	a.False(golangci.Managed([]byte("version: \"2\"\n")))
+	a.False(golangci.Managed([]byte("version: \"2\"\n" + golangci.Header)))
+	a.True(golangci.Managed([]byte(golangci.Header + "version: \"2\"\n")))
+}
+
+func TestFindConfig(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	path, err := golangci.FindConfig(dir)
+	r.NoError(err)
+	a.Empty(path)
+
+	r.NoError(os.WriteFile(filepath.Join(dir, ".golangci.toml"), []byte("version = \"2\"\n"), 0644))
+	path, err = golangci.FindConfig(dir)
+	r.NoError(err)
+	a.Equal(filepath.Join(dir, ".golangci.toml"), path)
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	v, err := golangci.NormalizeVersion("2.6.2")
+	r.NoError(err)
+	a.Equal("v2.6.2", v)
+
+	v, err = golangci.NormalizeVersion("v2.7.0-rc.1")
+	r.NoError(err)
+	a.Equal("v2.7.0-rc.1", v)
+
+	_, err = golangci.NormalizeVersion("latest")
+	a.Error(err)
+}
+
+func TestModVersion(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	v, err := golangci.ModVersion([]byte(`module example.com/app
+
+go 1.25
+
+require github.com/golangci/golangci-lint/v2 v2.5.0
+
+tool github.com/golangci/golangci-lint/v2/cmd/golangci-lint
+`))
+	r.NoError(err)
+	a.Equal("v2.5.0", v)
+
+	v, err = golangci.ModVersion([]byte(`module example.com/app
+
+go 1.25
+
+require github.com/golangci/golangci-lint/v2 v2.5.0 // indirect
+`))
+	r.NoError(err)
+	a.Empty(v)
+}
@@ -0,0 +1,104 @@
+// Package notify posts deploy events to Slack or Discord incoming webhooks.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Status is the stage of a deploy an Event reports.
+type Status string
+
+// Deploy stages.
+const (
+	Failed    Status = "failed"
+	Started   Status = "started"
+	Succeeded Status = "succeeded"
+)
+
+// Event describes a deploy.
+type Event struct {
+	Commit   string
+	Deployer string
+	Err      error
+	Project  string
+	Service  string
+	Status   Status
+	Tag      string
+	URLs     []string
+}
+
+// Text formats the event as a one-line summary followed by its URLs or error.
+func (e Event) Text() string {
+	target := e.Service
+	if e.Tag != "" {
+		target += " (tag " + e.Tag + ")"
+	}
+
+	var b strings.Builder
+	switch e.Status {
+	case Started:
+		b.WriteString("🚀 Deploying ")
+	case Succeeded:
+		b.WriteString("✅ Deployed ")
+	case Failed:
+		b.WriteString("❌ Deploy failed for ")
+	}
+	fmt.Fprintf(&b, "%s in %s", target, e.Project)
+
+	var details []string
+	if e.Commit != "" {
+		details = append(details, "commit "+e.Commit[:min(7, len(e.Commit))])
+	}
+	if e.Deployer != "" {
+		details = append(details, "by "+e.Deployer)
+	}
+	if len(details) > 0 {
+		b.WriteString(" (" + strings.Join(details, ", ") + ")")
+	}
+
+	for _, u := range e.URLs {
+		b.WriteString("\n" + u)
+	}
+	if e.Err != nil {
+		b.WriteString("\n" + e.Err.Error())
+	}
+	return b.String()
+}
+
+// Payload returns the JSON body for webhook: Discord webhooks take content,
+// and Slack and compatible webhooks take text.
+func Payload(webhook string, e Event) ([]byte, error) {
+	key := "text"
+	if u, err := url.Parse(webhook); err == nil && (u.Host == "discord.com" || u.Host == "discordapp.com") {
+		key = "content"
+	}
+	data, err := json.Marshal(map[string]string{key: e.Text()})
+	return data, errors.WithStack(err)
+}
+
+// Post sends the event to webhook.
+func Post(webhook string, e Event) error {
+	body, err := Payload(webhook, e)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to post notification")
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("notification webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/notify"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestText(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("🚀 Deploying app in my-project (commit 0123456, by dev@example.com)", notify.Event{
+		Commit:   "0123456789abcdef",
+		Deployer: "dev@example.com",
+		Project:  "my-project",
+		Service:  "app",
+		Status:   notify.Started,
+	}.Text())
+
+	a.Equal("✅ Deployed app (tag pr-1) in p\nhttps://pr-1---app.run.app", notify.Event{
+		Project: "p",
+		Service: "app",
+		Status:  notify.Succeeded,
+		Tag:     "pr-1",
+		URLs:    []string{"https://pr-1---app.run.app"},
+	}.Text())
+
+	a.Equal("❌ Deploy failed for app in p\nboom", notify.Event{
+		Err:     errors.New("boom"),
+		Project: "p",
+		Service: "app",
+		Status:  notify.Failed,
+	}.Text())
+}
+
+func TestPayload(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	e := notify.Event{Project: "p", Service: "app", Status: notify.Succeeded}
+
+	data, err := notify.Payload("https://hooks.slack.com/services/x", e)
+	r.NoError(err)
+	a.JSONEq(`{"text": "✅ Deployed app in p"}`, string(data))
+
+	data, err = notify.Payload("https://discord.com/api/webhooks/1/x", e)
+	r.NoError(err)
+	a.JSONEq(`{"content": "✅ Deployed app in p"}`, string(data))
+}
+
+func TestPost(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	r.NoError(notify.Post(srv.URL, notify.Event{Project: "p", Service: "app", Status: notify.Started}))
+	a.Equal("🚀 Deploying app in p", got["text"])
+}
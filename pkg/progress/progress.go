@@ -0,0 +1,161 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Output is where steps are reported.
+var Output io.Writer = os.Stdout
+
+// Verbose streams step output as it is written instead of collapsing it.
+var Verbose bool
+
+// Quiet hides command echo lines, spinners, and successful steps. Failed steps
+// are still reported with their output.
+var Quiet bool
+
+var frames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// mu serializes writes to Output so steps can run concurrently. Only one step at a
+// time shows a spinner; the others print a line when they start.
+var (
+	mu       sync.Mutex
+	spinning bool
+)
+
+// Step runs fn as a named step. Output written to the writer passed to fn is
+// hidden while a spinner shows elapsed time, then dropped on success or
+// printed in full on failure. On non-terminals it prints one line per step.
+// Step is safe to call from multiple goroutines.
+func Step(title string, fn func(w io.Writer) error) error {
+	if Verbose {
+		Echo(title)
+		start := time.Now()
+		err := fn(Output)
+		finish(title, start, err)
+		return err
+	}
+
+	var buf syncBuffer
+	start := time.Now()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	switch {
+	case Quiet:
+	case isTerminal(Output) && claimSpinner():
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			spin(title, start, done)
+		}()
+	default:
+		Echo(title)
+	}
+
+	err := fn(&buf)
+	close(done)
+	wg.Wait()
+
+	finish(title, start, err)
+	if err != nil {
+		printf("%s", buf.Bytes())
+	}
+	return err
+}
+
+// Echo prints a " → command" line for a command about to run, unless Quiet.
+func Echo(command string) {
+	if !Quiet {
+		printf(" → %s\n", command)
+	}
+}
+
+// printf writes to Output, first clearing the spinner so it is redrawn below.
+func printf(format string, args ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	if spinning {
+		fmt.Fprint(Output, "\r\033[K")
+	}
+	fmt.Fprintf(Output, format, args...)
+}
+
+func claimSpinner() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if spinning {
+		return false
+	}
+	spinning = true
+	return true
+}
+
+func spin(title string, start time.Time, done chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 0; ; i++ {
+		mu.Lock()
+		fmt.Fprintf(Output, "\r\033[K%s %s (%s)", frames[i%len(frames)], title, elapsed(start))
+		mu.Unlock()
+		select {
+		case <-done:
+			mu.Lock()
+			fmt.Fprint(Output, "\r\033[K")
+			spinning = false
+			mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func finish(title string, start time.Time, err error) {
+	if Quiet && err == nil {
+		return
+	}
+	mark := "✓"
+	if err != nil {
+		mark = "✗"
+	}
+	printf(" %s %s (%s)\n", mark, title, elapsed(start))
+}
+
+func elapsed(start time.Time) string {
+	return time.Since(start).Round(100 * time.Millisecond).String()
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
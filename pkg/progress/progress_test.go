@@ -0,0 +1,84 @@
+package progress_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/progress"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStep(t *testing.T) {
+	a := assert.New(t)
+
+	var out bytes.Buffer
+	prev := progress.Output
+	progress.Output = &out
+	t.Cleanup(func() { progress.Output = prev })
+
+	err := progress.Step("build", func(w io.Writer) error {
+		fmt.Fprintln(w, "compiling")
+		return nil
+	})
+	a.NoError(err)
+	a.Contains(out.String(), "✓ build")
+	a.NotContains(out.String(), "compiling")
+
+	out.Reset()
+	err = progress.Step("push", func(w io.Writer) error {
+		fmt.Fprintln(w, "401 unauthorized")
+		return errors.New("push failed")
+	})
+	a.Error(err)
+	a.Contains(out.String(), "✗ push")
+	a.Contains(out.String(), "401 unauthorized")
+}
+
+func TestStepQuiet(t *testing.T) {
+	a := assert.New(t)
+
+	var out bytes.Buffer
+	prev := progress.Output
+	progress.Output, progress.Quiet = &out, true
+	t.Cleanup(func() { progress.Output, progress.Quiet = prev, false })
+
+	a.NoError(progress.Step("build", func(w io.Writer) error { return nil }))
+	progress.Echo("go test ./...")
+	a.Empty(out.String())
+
+	a.Error(progress.Step("push", func(w io.Writer) error {
+		fmt.Fprintln(w, "401 unauthorized")
+		return errors.New("push failed")
+	}))
+	a.Contains(out.String(), "✗ push")
+	a.Contains(out.String(), "401 unauthorized")
+}
+
+func TestStepConcurrent(t *testing.T) {
+	a := assert.New(t)
+
+	var out bytes.Buffer
+	prev := progress.Output
+	progress.Output = &out
+	t.Cleanup(func() { progress.Output = prev })
+
+	var wg sync.WaitGroup
+	for _, region := range []string{"us-central1", "europe-west1", "asia-east1"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = progress.Step("deploy "+region, func(w io.Writer) error { return nil })
+		}()
+	}
+	wg.Wait()
+
+	a.Contains(out.String(), "✓ deploy us-central1")
+	a.Contains(out.String(), "✓ deploy europe-west1")
+	a.Contains(out.String(), "✓ deploy asia-east1")
+	a.Equal(6, strings.Count(out.String(), "\n"))
+}
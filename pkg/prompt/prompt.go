@@ -0,0 +1,177 @@
+// Package prompt asks the user for input on the terminal: filterable selection
+// lists with arrow keys, text with defaults, and yes/no confirmations. When
+// stdin is not a terminal it falls back to reading numbered lines.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCanceled is returned when the user presses ESC or Ctrl-C, or input ends.
+var ErrCanceled = errors.New("canceled")
+
+// NonInteractive answers confirmations with yes and text prompts with their
+// defaults, and makes prompts that need a choice fail instead of waiting.
+var NonInteractive bool
+
+// Input and Output are the prompt streams. Interactive selection requires Input
+// to be a terminal.
+var (
+	Input  io.Reader = os.Stdin
+	Output io.Writer = os.Stdout
+)
+
+// maxRows is how many options a selection list shows at once.
+const maxRows = 10
+
+var lines *bufio.Reader
+var linesFrom io.Reader
+
+// Select asks the user to pick one of options and returns its index. def is
+// the initially highlighted option. On a terminal, typing filters the list,
+// arrow keys move, and enter picks.
+func Select(label string, options []string, def int) (int, error) {
+	if len(options) == 0 {
+		return 0, errors.New("no options to select from")
+	}
+	def = max(0, min(def, len(options)-1))
+	if NonInteractive {
+		return 0, errors.Errorf("%s: input required, but running non-interactively", label)
+	}
+
+	if f, ok := Input.(*os.File); ok && isTerminal(f) {
+		if restore, err := makeRaw(int(f.Fd())); err == nil {
+			defer restore()
+			return selectRaw(f, label, options, def)
+		}
+	}
+	return selectLines(label, options, def)
+}
+
+// Text asks for a line of text, returning def when the answer is empty.
+func Text(label, def string) (string, error) {
+	if NonInteractive {
+		if def == "" {
+			return "", errors.Errorf("%s: input required, but running non-interactively", label)
+		}
+		return def, nil
+	}
+	if def != "" {
+		fmt.Fprintf(Output, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(Output, "%s: ", label)
+	}
+	line, err := readLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// Confirm asks a yes/no question, returning def when the answer is empty.
+func Confirm(label string, def bool) (bool, error) {
+	if NonInteractive {
+		return true, nil
+	}
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		fmt.Fprintf(Output, "%s [%s]: ", label, hint)
+		line, err := readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(line) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+func selectLines(label string, options []string, def int) (int, error) {
+	for i, o := range options {
+		fmt.Fprintf(Output, "  %d) %s\n", i+1, o)
+	}
+	for {
+		fmt.Fprintf(Output, "%s (1-%d) [%d]: ", label, len(options), def+1)
+		line, err := readLine()
+		if err != nil {
+			return 0, err
+		}
+		if line == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(line)
+		if err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		fmt.Fprintf(Output, "Please enter a number between 1 and %d\n", len(options))
+	}
+}
+
+func selectRaw(in io.Reader, label string, options []string, def int) (int, error) {
+	s := newSelector(options, def)
+	fmt.Fprint(Output, "\x1b[?25l")
+	defer fmt.Fprint(Output, "\x1b[?25h")
+
+	drawn := 0
+	buf := make([]byte, 64)
+	for {
+		drawn = s.render(Output, label, drawn)
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return 0, ErrCanceled
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			done, err := s.handle(k)
+			if err != nil {
+				s.clear(Output, drawn)
+				return 0, err
+			}
+			if done {
+				s.clear(Output, drawn)
+				fmt.Fprintf(Output, "%s: %s\n", label, options[s.selected()])
+				return s.selected(), nil
+			}
+		}
+	}
+}
+
+// readLine reads a trimmed line from Input, reusing one buffered reader so
+// typed-ahead input is not lost between prompts.
+func readLine() (string, error) {
+	if lines == nil || linesFrom != Input {
+		lines = bufio.NewReader(Input)
+		linesFrom = Input
+	}
+	line, err := lines.ReadString('\n')
+	if err != nil && line == "" {
+		return "", ErrCanceled
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,94 @@
+package prompt_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withInput(t *testing.T, input string) *bytes.Buffer {
+	var out bytes.Buffer
+	in, oldOut := prompt.Input, prompt.Output
+	prompt.Input, prompt.Output = strings.NewReader(input), &out
+	t.Cleanup(func() { prompt.Input, prompt.Output = in, oldOut })
+	return &out
+}
+
+func TestSelectLines(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	out := withInput(t, "9\n2\n\n")
+
+	i, err := prompt.Select("Select region", []string{"us-central1", "europe-west1"}, 0)
+	r.NoError(err)
+	a.Equal(1, i)
+	a.Contains(out.String(), "  1) us-central1\n  2) europe-west1\n")
+	a.Contains(out.String(), "Please enter a number between 1 and 2")
+
+	i, err = prompt.Select("Select region", []string{"us-central1", "europe-west1"}, 1)
+	r.NoError(err)
+	a.Equal(1, i)
+
+	_, err = prompt.Select("Select region", []string{"us-central1"}, 0)
+	a.ErrorIs(err, prompt.ErrCanceled)
+}
+
+func TestText(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	out := withInput(t, "\nmy-app\n")
+
+	s, err := prompt.Text("Enter service name", "app")
+	r.NoError(err)
+	a.Equal("app", s)
+	a.Equal("Enter service name [app]: ", out.String())
+
+	s, err = prompt.Text("Enter service name", "app")
+	r.NoError(err)
+	a.Equal("my-app", s)
+}
+
+func TestConfirm(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	withInput(t, "maybe\nY\n\n")
+
+	ok, err := prompt.Confirm("Use this project?", false)
+	r.NoError(err)
+	a.True(ok)
+
+	ok, err = prompt.Confirm("Use this project?", false)
+	r.NoError(err)
+	a.False(ok)
+}
+
+func TestNonInteractive(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	out := withInput(t, "")
+	prompt.NonInteractive = true
+	t.Cleanup(func() { prompt.NonInteractive = false })
+
+	ok, err := prompt.Confirm("Use this project?", false)
+	r.NoError(err)
+	a.True(ok)
+
+	s, err := prompt.Text("Enter service name", "app")
+	r.NoError(err)
+	a.Equal("app", s)
+
+	_, err = prompt.Text("Enter new project ID", "")
+	a.ErrorContains(err, "Enter new project ID: input required")
+
+	_, err = prompt.Select("Select region", []string{"us-central1"}, 0)
+	a.Error(err)
+	a.Empty(out.String())
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package prompt
+
+import "github.com/pkg/errors"
+
+// makeRaw is unsupported here, so prompts fall back to line input.
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
@@ -0,0 +1,164 @@
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyBackspace
+	keyCancel
+)
+
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// parseKeys decodes terminal input. A lone ESC cancels; escape sequences for
+// unknown keys are dropped.
+func parseKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b && len(b) == 1:
+			keys = append(keys, key{kind: keyCancel})
+			b = b[1:]
+		case b[0] == 0x1b && len(b) >= 3 && (b[1] == '[' || b[1] == 'O'):
+			switch b[2] {
+			case 'A':
+				keys = append(keys, key{kind: keyUp})
+			case 'B':
+				keys = append(keys, key{kind: keyDown})
+			}
+			b = b[3:]
+		case b[0] == 0x1b:
+			b = b[1:]
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, key{kind: keyEnter})
+			b = b[1:]
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, key{kind: keyBackspace})
+			b = b[1:]
+		case b[0] == 0x03 || b[0] == 0x04:
+			keys = append(keys, key{kind: keyCancel})
+			b = b[1:]
+		case b[0] == 0x10:
+			keys = append(keys, key{kind: keyUp})
+			b = b[1:]
+		case b[0] == 0x0e:
+			keys = append(keys, key{kind: keyDown})
+			b = b[1:]
+		case b[0] < 0x20:
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, key{kind: keyRune, r: r})
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+// selector is the state of a filterable selection list.
+type selector struct {
+	cursor  int
+	matches []int
+	options []string
+	query   string
+}
+
+func newSelector(options []string, def int) *selector {
+	s := &selector{options: options}
+	s.filter()
+	s.cursor = def
+	return s
+}
+
+// filter keeps the options containing the query, ignoring case.
+func (s *selector) filter() {
+	q := strings.ToLower(s.query)
+	s.matches = s.matches[:0]
+	for i, o := range s.options {
+		if strings.Contains(strings.ToLower(o), q) {
+			s.matches = append(s.matches, i)
+		}
+	}
+	s.cursor = 0
+}
+
+// selected returns the index into options of the highlighted match.
+func (s *selector) selected() int {
+	return s.matches[s.cursor]
+}
+
+// handle applies a key, reporting done when an option is picked.
+func (s *selector) handle(k key) (bool, error) {
+	switch k.kind {
+	case keyCancel:
+		return false, ErrCanceled
+	case keyEnter:
+		return len(s.matches) > 0, nil
+	case keyUp:
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case keyDown:
+		if s.cursor < len(s.matches)-1 {
+			s.cursor++
+		}
+	case keyBackspace:
+		if s.query != "" {
+			_, size := utf8.DecodeLastRuneInString(s.query)
+			s.query = s.query[:len(s.query)-size]
+			s.filter()
+		}
+	case keyRune:
+		s.query += string(k.r)
+		s.filter()
+	}
+	return false, nil
+}
+
+// render redraws the list over the previous drawn lines and returns the number of lines drawn.
+func (s *selector) render(w io.Writer, label string, drawn int) int {
+	s.clear(w, drawn)
+
+	fmt.Fprintf(w, "%s: %s\n", label, s.query)
+	lines := 1
+	if len(s.matches) == 0 {
+		fmt.Fprintln(w, "  (no matches)")
+		return lines + 1
+	}
+
+	start := max(0, min(s.cursor-maxRows/2, len(s.matches)-maxRows))
+	end := min(len(s.matches), start+maxRows)
+	for i := start; i < end; i++ {
+		marker := "  "
+		if i == s.cursor {
+			marker = "> "
+		}
+		fmt.Fprintf(w, "%s%s\n", marker, s.options[s.matches[i]])
+		lines++
+	}
+	if end-start < len(s.matches) {
+		fmt.Fprintf(w, "  (%d of %d, type to filter)\n", end-start, len(s.matches))
+		lines++
+	}
+	return lines
+}
+
+// clear erases the previously drawn lines.
+func (s *selector) clear(w io.Writer, drawn int) {
+	if drawn > 0 {
+		fmt.Fprintf(w, "\x1b[%dA\r\x1b[J", drawn)
+	}
+}
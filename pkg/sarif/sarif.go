@@ -0,0 +1,114 @@
+// Package sarif writes SARIF 2.1.0 logs, the format GitHub code scanning uploads
+// with github/codeql-action/upload-sarif.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Schema is the schema of the logs Write writes.
+const Schema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+	Version string `json:"version"`
+}
+
+// Run is the results of one tool.
+type Run struct {
+	Results []Result `json:"results"`
+	Tool    Tool     `json:"tool"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	InformationURI string `json:"informationUri,omitempty"`
+	Name           string `json:"name"`
+	Rules          []Rule `json:"rules"`
+	Version        string `json:"version,omitempty"`
+}
+
+type Rule struct {
+	HelpURI string `json:"helpUri,omitempty"`
+	ID      string `json:"id"`
+}
+
+type Result struct {
+	// Level is error, warning, or note.
+	Level     string     `json:"level"`
+	Locations []Location `json:"locations"`
+	Message   Message    `json:"message"`
+	RuleID    string     `json:"ruleId"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is where a result is in its file. Lines and columns are 1-based.
+type Region struct {
+	EndColumn   int `json:"endColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	StartColumn int `json:"startColumn"`
+	StartLine   int `json:"startLine"`
+}
+
+// NewRun returns a run of the tool called name with no results.
+func NewRun(name, informationURI, version string) Run {
+	return Run{Results: []Result{}, Tool: Tool{Driver: Driver{InformationURI: informationURI, Name: name, Rules: []Rule{}, Version: version}}}
+}
+
+// Add appends a result for the file at path, written relative to root with forward
+// slashes. A nil region refers to the whole file.
+func (r *Run) Add(level, ruleID, message, path, root string, region *Region) {
+	uri := path
+	if rel, err := filepath.Rel(root, path); err == nil {
+		uri = rel
+	}
+	res := Result{Level: level, Message: Message{Text: message}, RuleID: ruleID}
+	res.Locations = []Location{{PhysicalLocation: PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(uri)}, Region: region}}}
+	r.Results = append(r.Results, res)
+}
+
+// AddRule adds a rule to the driver unless one with its ID is there.
+func (r *Run) AddRule(rule Rule) {
+	for _, existing := range r.Tool.Driver.Rules {
+		if existing.ID == rule.ID {
+			return
+		}
+	}
+	r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, rule)
+}
+
+// Write writes runs as an indented SARIF log, with the rules of each run sorted.
+func Write(w io.Writer, runs ...Run) error {
+	for _, run := range runs {
+		rules := run.Tool.Driver.Rules
+		sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.WithStack(enc.Encode(Log{Schema: Schema, Runs: runs, Version: "2.1.0"}))
+}
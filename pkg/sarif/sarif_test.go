@@ -0,0 +1,41 @@
+package sarif_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/sarif"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	lint := sarif.NewRun("do", "", "")
+	lint.AddRule(sarif.Rule{ID: "pkgerrors"})
+	lint.AddRule(sarif.Rule{ID: "nocomments"})
+	lint.AddRule(sarif.Rule{ID: "pkgerrors"})
+	lint.Add("error", "pkgerrors", "use github.com/pkg/errors", "/repo/cmd/main.go", "/repo", &sarif.Region{StartColumn: 2, StartLine: 5})
+	lint.Add("warning", "nocomments", "no comments", "pkg/x.go", "/repo", nil)
+
+	var buf strings.Builder
+	r.NoError(sarif.Write(&buf, lint, sarif.NewRun("golangci-lint", "https://golangci-lint.run", "")))
+
+	var log sarif.Log
+	r.NoError(json.Unmarshal([]byte(buf.String()), &log))
+	a.Equal(sarif.Schema, log.Schema)
+	a.Equal("2.1.0", log.Version)
+	r.Len(log.Runs, 2)
+	a.Equal([]sarif.Rule{{ID: "nocomments"}, {ID: "pkgerrors"}}, log.Runs[0].Tool.Driver.Rules)
+	r.Len(log.Runs[0].Results, 2)
+	a.Equal("cmd/main.go", log.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	a.Equal(&sarif.Region{StartColumn: 2, StartLine: 5}, log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region)
+	a.Equal("pkg/x.go", log.Runs[0].Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	a.Nil(log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region)
+	a.Equal("golangci-lint", log.Runs[1].Tool.Driver.Name)
+	a.Empty(log.Runs[1].Results)
+	a.NotContains(buf.String(), `"version": ""`)
+}
@@ -0,0 +1,116 @@
+package serve
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPort is used when PORT is unset, matching the Cloud Run default.
+	DefaultPort = "8080"
+
+	// HealthPath always responds 200 while the process is serving.
+	HealthPath = "/healthz"
+
+	// ReadyPath responds 200 once the readiness check passes and 503 while shutting down.
+	ReadyPath = "/readyz"
+
+	// ShutdownTimeout is the time allowed for in-flight requests to finish after SIGTERM.
+	// Cloud Run sends SIGKILL 10 seconds after SIGTERM.
+	ShutdownTimeout = 8 * time.Second
+)
+
+// Options configures ListenAndServe.
+type Options struct {
+	Ready           func(ctx context.Context) error
+	ShutdownTimeout time.Duration
+}
+
+// Port returns the PORT env var or DefaultPort.
+func Port() string {
+	if port := os.Getenv("PORT"); port != "" {
+		return port
+	}
+	return DefaultPort
+}
+
+// Addr returns the listen address for Port.
+func Addr() string {
+	return net.JoinHostPort("", Port())
+}
+
+// Handler wraps h with HealthPath and ReadyPath endpoints.
+// The returned function marks the server as draining so ReadyPath starts failing.
+func Handler(h http.Handler, opts Options) (http.Handler, func()) {
+	var draining atomic.Bool
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc(ReadyPath, func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		if opts.Ready != nil {
+			if err := opts.Ready(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.Handle("/", h)
+
+	return mux, func() { draining.Store(true) }
+}
+
+// ListenAndServe serves h on Addr with health and readiness endpoints.
+// It shuts down gracefully on SIGTERM or SIGINT, or when ctx is done.
+func ListenAndServe(ctx context.Context, h http.Handler, opts Options) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	handler, drain := Handler(h, opts)
+	srv := &http.Server{
+		Addr:              Addr(),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return errors.WithStack(err)
+	case <-ctx.Done():
+	}
+
+	drain()
+
+	timeout := opts.ShutdownTimeout
+	if timeout == 0 {
+		timeout = ShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package serve_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/serve"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPort(t *testing.T) {
+	a := assert.New(t)
+
+	t.Setenv("PORT", "")
+	a.Equal("8080", serve.Port())
+
+	t.Setenv("PORT", "9090")
+	a.Equal("9090", serve.Port())
+	a.Equal(":9090", serve.Addr())
+}
+
+func TestHandler(t *testing.T) {
+	ctx := t.Context()
+	_ = ctx
+	a := assert.New(t)
+
+	var ready error
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h, drain := serve.Handler(app, serve.Options{
+		Ready: func(ctx context.Context) error { return ready },
+	})
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	a.Equal(http.StatusTeapot, get("/"))
+	a.Equal(http.StatusOK, get(serve.HealthPath))
+	a.Equal(http.StatusOK, get(serve.ReadyPath))
+
+	ready = errors.New("db not connected")
+	a.Equal(http.StatusServiceUnavailable, get(serve.ReadyPath))
+
+	ready = nil
+	drain()
+	a.Equal(http.StatusServiceUnavailable, get(serve.ReadyPath))
+	a.Equal(http.StatusOK, get(serve.HealthPath))
+}
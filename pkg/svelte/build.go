@@ -0,0 +1,751 @@
+package svelte
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing/fstest"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// DefaultOutfile is the bundle file name used when BuildOptions.Outfile is empty.
+const DefaultOutfile = "app.min.js"
+
+// DefaultGlobalName is the global the legacy bundle assigns its components to when
+// BuildOptions.GlobalName is empty.
+const DefaultGlobalName = "SvelteComponents"
+
+// BuildOptions configures Build.
+type BuildOptions struct {
+	Outfile string
+	// Define replaces import.meta.env.NAME with the string value of each NAME, so
+	// code can read constants such as API URLs that differ between deploys.
+	Define map[string]string
+	// Aliases maps import prefixes, such as $lib, to directories relative to the
+	// working directory, so $lib/Button.svelte imports src/lib/Button.svelte.
+	Aliases map[string]string
+	// BundleRuntime includes the Svelte runtime in Outfile, fetched from RuntimeURL
+	// at build time, so the app needs no CDN or import map in the browser.
+	BundleRuntime bool
+	// Dev makes a bundle for debugging: components compile in Svelte's dev mode, with
+	// runtime warnings, and the output is unminified with an inline source map, so
+	// Sourcemap has no effect.
+	Dev bool
+	// CustomElements compiles components as custom elements, so those declaring
+	// <svelte:options customElement="my-widget" /> register themselves when the
+	// bundle loads. Their styles stay in each element's shadow root, so ExtractCSS
+	// has no effect.
+	CustomElements bool
+	// BaseURL is where the bundle directory is served from, such as /dist/ or a CDN
+	// URL, for the tags in Manifest.HTML. Empty makes them relative.
+	BaseURL string
+	// Legacy also writes a classic script, such as app.legacy.min.js, for browsers
+	// without ES modules and third-party pages embedding the app with a plain
+	// <script> tag. It includes the Svelte runtime and assigns the component map to
+	// the global GlobalName. Styles are only extracted once, next to Outfile.
+	Legacy bool
+	// GlobalName defaults to DefaultGlobalName.
+	GlobalName string
+	// ExtractCSS writes component styles to a stylesheet next to Outfile, such as
+	// app.min.css, instead of injecting them at runtime.
+	ExtractCSS bool
+	// Sourcemap writes Outfile.map, mapping the bundle back to the .svelte sources.
+	Sourcemap bool
+	// Exclude drops components matching any of these patterns, such as
+	// *.stories.svelte or testdata/. See MatchPath.
+	Exclude []string
+	// Include keeps only components matching one of these patterns, when set.
+	Include []string
+	// StripPrefix is trimmed from export keys, so with src/ the component
+	// src/forms/Button.svelte is exported as forms/Button.
+	StripPrefix string
+	// Splitting puts each component in its own chunk under chunks/, with code they
+	// share factored into further chunks, so pages load only the components they
+	// use. Outfile's default export then maps each path to a function importing the
+	// component's module.
+	Splitting bool
+}
+
+// Component is a compiled .svelte file in a bundle.
+type Component struct {
+	// Chunk is the file holding the component when BuildOptions.Splitting is set.
+	Chunk  string `json:"chunk,omitempty"`
+	Export string `json:"export"`
+	Path   string `json:"path"`
+}
+
+// Manifest describes the output of Build.
+type Manifest struct {
+	Components []Component `json:"components"`
+	// CSS is the extracted stylesheet, if any, and CSSHash a hash of its contents
+	// for cache busting, as in app.min.css?v=CSSHash.
+	CSS     string `json:"css,omitempty"`
+	CSSHash string `json:"css_hash,omitempty"`
+	// BaseURL is BuildOptions.BaseURL, which Link and HTML prefix files with.
+	BaseURL string   `json:"base_url,omitempty"`
+	Files   []string `json:"files"`
+	// HTML is the tags loading the bundle with integrity checks: the stylesheet,
+	// modulepreload links for Preload, and the module, with a nomodule fallback to
+	// Legacy.
+	HTML string `json:"html"`
+	// Integrity is the subresource integrity hash of each file, as in
+	// <script integrity="sha384-...">.
+	Integrity map[string]string `json:"integrity"`
+	// Legacy is the classic script written with BuildOptions.Legacy.
+	Legacy string `json:"legacy,omitempty"`
+	// Preload is the module and the chunks it imports statically, which pages can
+	// preload to avoid a request waterfall.
+	Preload []string `json:"preload,omitempty"`
+	// Scripts are the .js and .ts entry points bundled alongside the components.
+	Scripts []string `json:"scripts,omitempty"`
+}
+
+// Build compiles every .svelte file under roots, which may also be .svelte files or
+// glob patterns, and bundles them into a single
+// ES module whose default export maps each component path (without .svelte) to its component.
+// Components may import other .svelte files and JavaScript by relative path.
+// Roots naming .js or .ts files, such as src/main.ts, are bundled as scripts that run
+// when the module loads, so app glue code can import and mount components.
+// The output is returned as an in-memory filesystem so nothing is written to disk.
+// With CacheDir set, the output is kept there and returned again by a later Build
+// of the same roots and options while none of the files it was built from change.
+func Build(roots []string, opts BuildOptions) (fs.FS, Manifest, error) {
+	outfile := opts.Outfile
+	if outfile == "" {
+		outfile = DefaultOutfile
+	}
+
+	var manifest Manifest
+	paths, scripts, err := expandRoots(roots)
+	if err != nil {
+		return nil, manifest, err
+	}
+
+	if paths, err = filterPaths(paths, opts.Include, opts.Exclude); err != nil {
+		return nil, manifest, err
+	}
+
+	if len(paths) == 0 && len(scripts) == 0 {
+		return fstest.MapFS{}, manifest, nil
+	}
+
+	var scriptImports []string
+	for _, path := range scripts {
+		manifest.Scripts = append(manifest.Scripts, path)
+		scriptImports = append(scriptImports, fmt.Sprintf("import './%s'", filepath.ToSlash(path)))
+	}
+	imports := slices.Clone(scriptImports)
+	var exports, lazyExports []string
+
+	for _, path := range paths {
+		// Export key matches filesystem: src/animate/Foo.svelte -> src/animate/Foo
+		exportKey := filepath.ToSlash(strings.TrimSuffix(path, ".svelte"))
+		exportKey = strings.TrimPrefix(exportKey, opts.StripPrefix)
+		manifest.Components = append(manifest.Components, Component{Export: exportKey, Path: path})
+
+		// Create safe identifier from path: src/forms/Button -> src_forms_Button
+		ident := strings.NewReplacer("/", "_", "-", "_", ".", "_").Replace(exportKey)
+
+		lazyExports = append(lazyExports, fmt.Sprintf("  '%s': () => import('./%s')", exportKey, filepath.ToSlash(path)))
+		imports = append(imports, fmt.Sprintf("import %s from './%s'", ident, filepath.ToSlash(path)))
+		exports = append(exports, fmt.Sprintf("  '%s': %s", exportKey, ident))
+	}
+
+	// The legacy bundle cannot load chunks, so it always imports every component.
+	staticEntry := entryModule(imports, exports)
+	entry := staticEntry
+	if opts.Splitting {
+		entry = entryModule(scriptImports, lazyExports)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, manifest, errors.WithStack(err)
+	}
+
+	sourcemap := api.SourceMapNone
+	if opts.Sourcemap {
+		sourcemap = api.SourceMapLinked
+	}
+	// Component libraries point the svelte condition at their .svelte sources.
+	conditions := []string{"svelte", "module"}
+	if opts.Dev {
+		sourcemap = api.SourceMapInline
+		opts.Sourcemap = true
+		conditions = append(conditions, "development")
+	}
+
+	cache := buildPath(entry, cwd, opts)
+	if out, prev, ok := loadBuild(cache); ok {
+		return out, prev, nil
+	}
+	// Preprocessors may read files esbuild does not see, such as Sass partials, so
+	// builds that preprocess are not kept.
+	var preprocessed atomic.Bool
+
+	define, err := defines(opts.Define)
+	if err != nil {
+		return nil, manifest, err
+	}
+
+	external := []string{"svelte", "svelte/*"}
+	plugins := []api.Plugin{componentsPlugin(cwd, opts, &preprocessed)}
+	if len(opts.Aliases) > 0 {
+		plugins = append([]api.Plugin{aliasPlugin(opts.Aliases, cwd)}, plugins...)
+	}
+	if opts.BundleRuntime {
+		external = nil
+		plugins = append(plugins, runtimePlugin(opts.Dev))
+	}
+
+	buildOpts := api.BuildOptions{
+		AbsWorkingDir: cwd,
+		Stdin: &api.StdinOptions{
+			Contents:   entry,
+			ResolveDir: cwd,
+			Loader:     api.LoaderJS,
+		},
+		Bundle:            true,
+		MinifyWhitespace:  !opts.Dev,
+		MinifyIdentifiers: !opts.Dev,
+		MinifySyntax:      !opts.Dev,
+		Format:            api.FormatESModule,
+		Conditions:        conditions,
+		Define:            define,
+		External:          external,
+		Outfile:           outfile,
+		Write:             false,
+		Plugins:           plugins,
+		Sourcemap:         sourcemap,
+		Metafile:          true,
+	}
+	base := buildOpts
+	if opts.Splitting {
+		// Splitting needs an output directory, and naming stdin's output needs a
+		// real entry point, so the entry is served by a plugin instead.
+		buildOpts.Stdin = nil
+		buildOpts.Outfile = ""
+		buildOpts.Outdir = cwd
+		buildOpts.Splitting = true
+		buildOpts.ChunkNames = "chunks/[name]-[hash]"
+		buildOpts.EntryPointsAdvanced = []api.EntryPoint{{InputPath: "svelte-entry", OutputPath: strings.TrimSuffix(outfile, ".js")}}
+		buildOpts.Plugins = append(buildOpts.Plugins, entryPlugin(entry, cwd))
+	}
+
+	result := api.Build(buildOpts)
+	if len(result.Errors) > 0 {
+		return nil, manifest, esbuildError(result.Errors)
+	}
+	outputs := result.OutputFiles
+
+	if opts.Legacy {
+		legacy, err := buildLegacy(base, staticEntry, opts)
+		if err != nil {
+			return nil, manifest, err
+		}
+		outputs = append(outputs, legacy...)
+		manifest.Legacy = LegacyOutfile(outfile)
+	}
+
+	out := fstest.MapFS{}
+	for _, f := range outputs {
+		name, err := filepath.Rel(cwd, f.Path)
+		if err != nil {
+			return nil, manifest, errors.WithStack(err)
+		}
+		name = filepath.ToSlash(name)
+		out[name] = &fstest.MapFile{Data: f.Contents, Mode: 0644}
+		manifest.Files = append(manifest.Files, name)
+		if strings.HasSuffix(name, ".css") && (!opts.Splitting || name == strings.TrimSuffix(outfile, ".js")+".css") {
+			sum := sha256.Sum256(f.Contents)
+			manifest.CSS = name
+			manifest.CSSHash = hex.EncodeToString(sum[:])[:12]
+		}
+	}
+
+	if opts.Splitting {
+		if err := setChunks(&manifest, result.Metafile, cwd); err != nil {
+			return nil, manifest, err
+		}
+	}
+	manifest.BaseURL = opts.BaseURL
+	if err := setIntegrity(&manifest, out, outfile, result.Metafile); err != nil {
+		return nil, manifest, err
+	}
+
+	if !preprocessed.Load() {
+		if err := saveBuild(cache, result.Metafile, cwd, out, manifest); err != nil {
+			return nil, manifest, err
+		}
+	}
+	return out, manifest, nil
+}
+
+// defineName matches names BuildOptions.Define accepts.
+var defineName = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// defines returns esbuild defines replacing import.meta.env.NAME with each value.
+func defines(values map[string]string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	define := make(map[string]string, len(values))
+	for name, value := range values {
+		if !defineName.MatchString(name) {
+			return nil, errors.Errorf("invalid define %q: it must be a JavaScript identifier", name)
+		}
+		literal, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		define["import.meta.env."+name] = string(literal)
+	}
+	return define, nil
+}
+
+// entryModule returns an entry point with imports whose default export is an object
+// with the properties in exports.
+func entryModule(imports, exports []string) string {
+	return fmt.Sprintf("%s\n\nexport default {\n%s\n}\n",
+		strings.Join(imports, "\n"),
+		strings.Join(exports, ",\n"))
+}
+
+// LegacyOutfile returns the name of the legacy bundle for outfile, inserting legacy
+// before .min.js or .js, as in app.legacy.min.js.
+func LegacyOutfile(outfile string) string {
+	for _, ext := range []string{".min.js", ".js"} {
+		if stem, ok := strings.CutSuffix(outfile, ext); ok {
+			return stem + ".legacy" + ext
+		}
+	}
+	return outfile + ".legacy.js"
+}
+
+// buildLegacy bundles entry as a classic script with the options of the module
+// build, base, returning its output except styles, which the module build writes.
+func buildLegacy(base api.BuildOptions, entry string, opts BuildOptions) ([]api.OutputFile, error) {
+	global := opts.GlobalName
+	if global == "" {
+		global = DefaultGlobalName
+	}
+	legacy := base
+	legacy.Stdin = &api.StdinOptions{Contents: entry, ResolveDir: base.AbsWorkingDir, Loader: api.LoaderJS}
+	legacy.Format = api.FormatIIFE
+	legacy.GlobalName = global
+	// The IIFE's value is the entry's namespace; expose its default export instead.
+	legacy.Footer = map[string]string{"js": global + "=" + global + ".default;"}
+	legacy.Target = api.ES2017
+	legacy.Outfile = LegacyOutfile(base.Outfile)
+	legacy.Metafile = false
+	if !opts.BundleRuntime {
+		legacy.External = nil
+		legacy.Plugins = append(slices.Clone(base.Plugins), runtimePlugin(opts.Dev))
+	}
+
+	result := api.Build(legacy)
+	if len(result.Errors) > 0 {
+		return nil, esbuildError(result.Errors)
+	}
+	var files []api.OutputFile
+	for _, f := range result.OutputFiles {
+		if !strings.HasSuffix(f.Path, ".css") && !strings.HasSuffix(f.Path, ".css.map") {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// esbuildError joins esbuild's errors, with the file each is in and a hint for
+// packages that are not installed.
+func esbuildError(errs []api.Message) error {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Text
+		if pkg, ok := missingPackage(e.Text); ok {
+			msgs[i] += ": install it with npm install " + pkg
+		}
+		if e.Location != nil {
+			msgs[i] = e.Location.File + ": " + msgs[i]
+		}
+	}
+	return errors.Errorf("esbuild: %s", strings.Join(msgs, "; "))
+}
+
+// entryPlugin serves the generated entry module, which imports every component.
+func entryPlugin(entry, cwd string) api.Plugin {
+	return api.Plugin{
+		Name: "svelte-entry",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: `^svelte-entry$`},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					return api.OnResolveResult{Path: "entry", Namespace: "svelte-entry"}, nil
+				})
+			build.OnLoad(api.OnLoadOptions{Filter: `.*`, Namespace: "svelte-entry"},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					return api.OnLoadResult{Contents: &entry, Loader: api.LoaderJS, ResolveDir: cwd}, nil
+				})
+		},
+	}
+}
+
+// missingPackage returns the npm package esbuild could not resolve, if text reports
+// a bare import that is not installed.
+func missingPackage(text string) (string, bool) {
+	spec, ok := strings.CutPrefix(text, "Could not resolve ")
+	if !ok {
+		return "", false
+	}
+	spec, err := strconv.Unquote(spec)
+	if err != nil || strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "/") {
+		return "", false
+	}
+	parts := strings.SplitN(spec, "/", 3)
+	if strings.HasPrefix(spec, "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1], true
+	}
+	return parts[0], true
+}
+
+// setChunks records the chunk esbuild emitted for each component, from the
+// metafile's outputs whose entry point is the component.
+func setChunks(manifest *Manifest, metafile, cwd string) error {
+	var meta struct {
+		Outputs map[string]struct {
+			EntryPoint string `json:"entryPoint"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(metafile), &meta); err != nil {
+		return errors.WithStack(err)
+	}
+	chunks := make(map[string]string)
+	for output, o := range meta.Outputs {
+		if path, ok := strings.CutPrefix(o.EntryPoint, "svelte-components:"); ok && strings.HasSuffix(output, ".js") {
+			chunks[path] = output
+		}
+	}
+	for i, c := range manifest.Components {
+		abs := c.Path
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(cwd, abs)
+		}
+		manifest.Components[i].Chunk = filepath.ToSlash(chunks[abs])
+	}
+	return nil
+}
+
+// expandRoots returns the components and scripts in roots, which may be directories,
+// .svelte, .js, or .ts files, or glob patterns, without duplicates. Directories only
+// contribute components.
+func expandRoots(roots []string) (paths, scripts []string, err error) {
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		matches, err := filepath.Glob(root)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "bad pattern %q", root)
+		}
+		if len(matches) == 0 {
+			return nil, nil, errors.Errorf("no components match %s", root)
+		}
+		for _, match := range matches {
+			found := []string{match}
+			if info, err := os.Stat(match); err != nil {
+				return nil, nil, errors.WithStack(err)
+			} else if info.IsDir() {
+				if found, err = findComponents(match); err != nil {
+					return nil, nil, err
+				}
+			} else if isScript(match) {
+				if !seen[match] {
+					seen[match] = true
+					scripts = append(scripts, match)
+				}
+				continue
+			} else if !strings.HasSuffix(match, ".svelte") {
+				continue
+			}
+			for _, path := range found {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	return paths, scripts, nil
+}
+
+// MatchPath reports whether the slash-separated path p matches pattern, which uses
+// path.Match syntax. A pattern ending in / matches files under any directory it
+// matches, as testdata/ does src/testdata/Fixture.svelte; a pattern without / matches
+// the file name, as *.stories.svelte does; and other patterns match the whole path.
+func MatchPath(pattern, p string) (bool, error) {
+	if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+		elems := strings.Split(p, "/")
+		for _, elem := range elems[:len(elems)-1] {
+			if ok, err := path.Match(dir, elem); ok || err != nil {
+				return ok, errors.Wrapf(err, "bad pattern %q", pattern)
+			}
+		}
+		return false, nil
+	}
+	if !strings.Contains(pattern, "/") {
+		p = path.Base(p)
+	}
+	ok, err := path.Match(pattern, p)
+	return ok, errors.Wrapf(err, "bad pattern %q", pattern)
+}
+
+// filterPaths returns the components in paths that match one of include, if any, and
+// none of exclude, matching paths relative to the working directory.
+func filterPaths(paths, include, exclude []string) ([]string, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return paths, nil
+	}
+	matchAny := func(patterns []string, p string) (bool, error) {
+		for _, pattern := range patterns {
+			if ok, err := MatchPath(pattern, p); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	var kept []string
+	for _, p := range paths {
+		rel := p
+		if filepath.IsAbs(p) {
+			if cwd, err := os.Getwd(); err == nil {
+				if r, err := filepath.Rel(cwd, p); err == nil {
+					rel = r
+				}
+			}
+		}
+		rel = filepath.ToSlash(rel)
+		if len(include) > 0 {
+			ok, err := matchAny(include, rel)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		excluded, err := matchAny(exclude, rel)
+		if err != nil {
+			return nil, err
+		}
+		if !excluded {
+			kept = append(kept, p)
+		}
+	}
+	return kept, nil
+}
+
+// isScript reports whether path is a JavaScript or TypeScript file esbuild can
+// bundle as an entry point, other than a declaration file.
+func isScript(path string) bool {
+	if strings.HasSuffix(path, ".d.ts") {
+		return false
+	}
+	switch filepath.Ext(path) {
+	case ".js", ".mjs", ".ts", ".mts":
+		return true
+	}
+	return false
+}
+
+// findComponents returns the .svelte files under root, skipping node_modules, dist, and hidden paths.
+func findComponents(root string) ([]string, error) {
+	found, err := walkComponents(os.DirFS(root))
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(found))
+	for i, path := range found {
+		paths[i] = filepath.Join(root, filepath.FromSlash(path))
+	}
+	return paths, nil
+}
+
+// walkComponents returns the slash-separated paths of the .svelte files in fsys,
+// skipping node_modules, dist, and hidden paths.
+func walkComponents(fsys fs.FS) ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if name == "node_modules" || name == "dist" || (path != "." && strings.HasPrefix(name, ".")) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".svelte") {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return paths, nil
+}
+
+// aliasPlugin resolves imports starting with an alias, as in $lib or $lib/forms, to
+// the aliased directory under cwd, then resolves the result as esbuild and the other
+// plugins would.
+func aliasPlugin(aliases map[string]string, cwd string) api.Plugin {
+	dirs := make(map[string]string)
+	var names []string
+	for name, dir := range aliases {
+		name = strings.TrimSuffix(name, "/*")
+		dirs[name] = filepath.Join(cwd, strings.TrimSuffix(dir, "/*"))
+		names = append(names, name)
+	}
+	// Longest first, so $lib/forms wins over $lib.
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+
+	return api.Plugin{
+		Name: "svelte-aliases",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: `^(` + strings.Join(quoted, "|") + `)(/|$)`},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					for _, name := range names {
+						rest, ok := strings.CutPrefix(args.Path, name)
+						if !ok || (rest != "" && rest[0] != '/') {
+							continue
+						}
+						result := build.Resolve(dirs[name]+rest, api.ResolveOptions{
+							Importer:   args.Importer,
+							Kind:       args.Kind,
+							ResolveDir: cwd,
+						})
+						if len(result.Errors) > 0 {
+							return api.OnResolveResult{}, errors.Errorf("resolve %s: %s", args.Path, result.Errors[0].Text)
+						}
+						return api.OnResolveResult{
+							External:  result.External,
+							Namespace: result.Namespace,
+							Path:      result.Path,
+						}, nil
+					}
+					return api.OnResolveResult{}, nil
+				})
+		},
+	}
+}
+
+// componentsPlugin compiles .svelte files as esbuild loads them, so the entry
+// point and components importing other components (import Child from './Child.svelte')
+// share one module graph, along with .svelte.js and .svelte.ts rune modules they
+// import. Relative imports resolve from each component's directory, and bare imports
+// from node_modules, where components shipped by npm packages are compiled too.
+// With Sourcemap set, each component carries its map inline for esbuild to chain,
+// naming its source by path relative to cwd. With ExtractCSS set, each component
+// imports its styles as a virtual .css file, which esbuild bundles into a stylesheet.
+// Loading a component that needs preprocessing sets preprocessed.
+func componentsPlugin(cwd string, opts BuildOptions, preprocessed *atomic.Bool) api.Plugin {
+	fn := "compile"
+	switch {
+	case opts.CustomElements:
+		fn = "compileCustomElement"
+		opts.ExtractCSS = false
+	case opts.ExtractCSS:
+		fn = "compileExternal"
+	}
+	var styles sync.Map
+
+	return api.Plugin{
+		Name: "svelte-components",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: `\.svelte\.css$`},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					return api.OnResolveResult{
+						Path:      args.Path,
+						Namespace: "svelte-styles",
+					}, nil
+				})
+			build.OnLoad(api.OnLoadOptions{Filter: `.*`, Namespace: "svelte-styles"},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					css, _ := styles.Load(strings.TrimSuffix(args.Path, ".css"))
+					contents, _ := css.(string)
+					return api.OnLoadResult{
+						Contents: &contents,
+						Loader:   api.LoaderCSS,
+					}, nil
+				})
+			build.OnResolve(api.OnResolveOptions{Filter: `^\.{0,2}/.*\.svelte(\.js|\.ts)?$`},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					path := args.Path
+					if !filepath.IsAbs(path) {
+						path = filepath.Join(args.ResolveDir, path)
+					}
+					return api.OnResolveResult{
+						Path:      path,
+						Namespace: "svelte-components",
+					}, nil
+				})
+			load := func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				src, err := os.ReadFile(args.Path)
+				if err != nil {
+					return api.OnLoadResult{}, errors.WithStack(err)
+				}
+				name, err := filepath.Rel(cwd, args.Path)
+				if err != nil {
+					name = args.Path
+				}
+				var compiled compileResult
+				if strings.HasSuffix(args.Path, ".svelte") {
+					if needsPreprocess(string(src)) {
+						preprocessed.Store(true)
+					}
+					compiled, err = compile(fn, string(src), filepath.ToSlash(name), opts.Dev)
+				} else {
+					compiled, err = compileModule(string(src), filepath.ToSlash(name), opts.Dev)
+				}
+				if err != nil {
+					return api.OnLoadResult{}, errors.Errorf("compile %s: %v", args.Path, err)
+				}
+				code := compiled.Code
+				if opts.ExtractCSS && compiled.CSS != "" {
+					// Appended so the source map's lines still line up.
+					styles.Store(args.Path, compiled.CSS)
+					code += "\nimport " + strconv.Quote(args.Path+".css") + ";"
+				}
+				if opts.Sourcemap && compiled.Map != "" {
+					code += "\n//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(compiled.Map))
+				}
+				return api.OnLoadResult{
+					Contents:   &code,
+					Loader:     api.LoaderJS,
+					ResolveDir: filepath.Dir(args.Path),
+				}, nil
+			}
+			build.OnLoad(api.OnLoadOptions{Filter: `.*`, Namespace: "svelte-components"}, load)
+			build.OnLoad(api.OnLoadOptions{Filter: `\.svelte(\.js|\.ts)?$`, Namespace: "file"}, load)
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package svelte
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// CacheDir holds compiled output and check diagnostics keyed by a hash of the
+// source and the compiler, so unchanged components are not compiled again. Empty
+// disables the cache.
+var CacheDir string
+
+// cacheKey hashes the compiler with parts, which identify the call and its input.
+func cacheKey(parts ...string) string {
+	h := sha256.New()
+	h.Write([]byte(compilerHash()))
+	for _, p := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cached returns the value stored under key, or calls fetch and stores its result.
+// Failed calls are not cached, and cache read and write failures are ignored.
+func cached[T any](key string, fetch func() (T, error)) (T, error) {
+	if CacheDir == "" {
+		return fetch()
+	}
+	path := filepath.Join(CacheDir, key[:2], key+".json")
+	if data, err := os.ReadFile(path); err == nil {
+		var v T
+		if json.Unmarshal(data, &v) == nil {
+			return v, nil
+		}
+	}
+
+	v, err := fetch()
+	if err != nil {
+		return v, err
+	}
+	writeCache(path, v)
+	return v, nil
+}
+
+// writeCache writes through a temporary file so concurrent readers never see a
+// partial entry.
+func writeCache(path string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0o755) != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
@@ -8,13 +8,68 @@ if (typeof console === "undefined") {
   globalThis.console = { log: function() {}, warn: function() {}, error: function() {} };
 }
 
-// Compile function - expects `source` global to be set
-function compile(source) {
-  const result = svelte.compile(source, {
+// Compile function - returns the client module and its source map. The client
+// compile functions take dev to compile in dev mode, with runtime warnings.
+function compile(source, filename, dev) {
+  return compileClient(source, filename, { css: "injected", dev: !!dev }); // Inject CSS into the JS
+}
+
+// External CSS compile function - returns the component CSS instead of injecting it
+function compileExternal(source, filename, dev) {
+  return compileClient(source, filename, { css: "external", dev: !!dev });
+}
+
+// Custom element compile function - returns a module that registers the component
+// as a custom element, with its styles in the shadow root
+function compileCustomElement(source, filename, dev) {
+  return compileClient(source, filename, { css: "injected", customElement: true, dev: !!dev });
+}
+
+// HMR compile function - returns a dev build that swaps in new versions of itself
+// through import.meta.hot
+function compileHMR(source, filename) {
+  return compileClient(source, filename, { css: "injected", dev: true, hmr: true });
+}
+
+function compileClient(source, filename, options) {
+  const result = svelte.compile(source, Object.assign({
     generate: "client",
     runes: true,
     name: "Component",
-    css: "injected", // Inject CSS into the JS
+    filename: filename || "Component.svelte",
+    outputFilename: "Component.js", // Keeps map sources relative to the working directory
+  }, options));
+  return JSON.stringify({
+    code: result.js.code,
+    css: result.css ? result.css.code : "",
+    map: result.js.map ? result.js.map.toString() : "",
+    error: null,
+  });
+}
+
+// Module compile function - returns a .svelte.js module with its runes compiled
+function compileModule(source, filename, dev) {
+  const result = svelte.compileModule(source, {
+    generate: "client",
+    dev: !!dev,
+    filename: filename || "module.svelte.js",
+  });
+  return JSON.stringify({
+    code: result.js.code,
+    map: result.js.map ? result.js.map.toString() : "",
+    error: null,
+  });
+}
+
+// Server compile function - returns a module whose default export renders the
+// component to HTML with svelte/server, and the component CSS separately
+function compileServer(source, filename) {
+  const result = svelte.compile(source, {
+    generate: "server",
+    runes: true,
+    name: "Component",
+    css: "external",
+    filename: filename || "Component.svelte",
   });
   return JSON.stringify({
     code: result.js.code,
@@ -38,8 +93,8 @@ function check(source, filename) {
       code: w.code || "",
       message: w.message || "",
       filename: w.filename || filename || "",
-      start: w.start ? { line: w.start.line, column: w.start.column } : null,
-      end: w.end ? { line: w.end.line, column: w.end.column } : null,
+      start: w.start ? { line: w.start.line, column: w.start.column, character: w.start.character } : null,
+      end: w.end ? { line: w.end.line, column: w.end.column, character: w.end.character } : null,
     }));
 
     return JSON.stringify({ diagnostics: diagnostics, error: null });
@@ -50,8 +105,8 @@ function check(source, filename) {
       code: e.code || "parse_error",
       message: e.message || String(e),
       filename: e.filename || filename || "",
-      start: e.start ? { line: e.start.line, column: e.start.column } : null,
-      end: e.end ? { line: e.end.line, column: e.end.column } : null,
+      start: e.start ? { line: e.start.line, column: e.start.column, character: e.start.character } : null,
+      end: e.end ? { line: e.end.line, column: e.end.column, character: e.end.character } : null,
     };
     return JSON.stringify({ diagnostics: [diagnostic], error: null });
   }
@@ -0,0 +1,181 @@
+package svelte
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// Fix replaces the bytes from Start to End of a component's source with Text.
+type Fix struct {
+	End   int    `json:"end"`
+	Start int    `json:"start"`
+	Text  string `json:"text"`
+}
+
+// fixers suggest a Fix for a diagnostic in src, given the byte offsets of the range
+// the compiler reported, or return false when the fix would not be trivially safe.
+var fixers = map[string]func(src string, start, end int) (Fix, bool){
+	"a11y_no_redundant_roles": removeAttribute,
+	"css_unused_selector":     removeSelector,
+}
+
+// addFixes sets Fix on the diagnostics in diags that have a fixer.
+func addFixes(src string, diags []Diagnostic) {
+	for i, d := range diags {
+		fixer, ok := fixers[d.Code]
+		if !ok || d.Start == nil || d.End == nil {
+			continue
+		}
+		start, end := byteOffset(src, d.Start.Character), byteOffset(src, d.End.Character)
+		if start < 0 || end < start || end > len(src) {
+			continue
+		}
+		if fix, ok := fixer(src, start, end); ok {
+			diags[i].Fix = &fix
+		}
+	}
+}
+
+// byteOffset converts an offset in UTF-16 code units into src to a byte offset, or
+// -1 if it is past the end.
+func byteOffset(src string, units int) int {
+	n := 0
+	for i, r := range src {
+		if n >= units {
+			return i
+		}
+		n += utf16.RuneLen(r)
+	}
+	if n == units {
+		return len(src)
+	}
+	return -1
+}
+
+// removeAttribute removes an attribute with the whitespace before it.
+func removeAttribute(src string, start, end int) (Fix, bool) {
+	trimmed := strings.TrimRight(src[:start], " \t\r\n")
+	if len(trimmed) == start {
+		return Fix{}, false
+	}
+	return Fix{End: end, Start: len(trimmed)}, true
+}
+
+// removeSelector removes an unused selector from its selector list, or the whole
+// rule when it is the only selector.
+func removeSelector(src string, start, end int) (Fix, bool) {
+	// The selector list runs from the end of the previous rule, declaration block,
+	// or <style> tag to the opening brace of this rule.
+	tag := strings.LastIndex(src[:start], "<style")
+	if tag < 0 {
+		return Fix{}, false
+	}
+	gt := strings.IndexByte(src[tag:start], '>')
+	if gt < 0 {
+		return Fix{}, false
+	}
+	bound := tag + gt + 1
+	preludeStart := bound + strings.LastIndexAny(src[bound:start], "{};") + 1
+	open := strings.IndexByte(src[end:], '{')
+	if open < 0 {
+		return Fix{}, false
+	}
+	preludeEnd := end + open
+	before := strings.TrimSpace(src[preludeStart:start])
+	after := strings.TrimSpace(src[end:preludeEnd])
+
+	switch {
+	case before == "" && after == "":
+		closing := matchingBrace(src, preludeEnd)
+		if closing < 0 {
+			return Fix{}, false
+		}
+		return wholeLines(src, start, closing+1), true
+	case strings.HasPrefix(after, ","):
+		// Remove the selector, its comma, and the space after it.
+		comma := end + strings.IndexByte(src[end:], ',')
+		next := comma + 1 + len(src[comma+1:]) - len(strings.TrimLeft(src[comma+1:], " \t\r\n"))
+		return Fix{End: next, Start: start}, true
+	case strings.HasSuffix(before, ","):
+		comma := strings.LastIndexByte(src[:start], ',')
+		return Fix{End: end, Start: comma}, true
+	}
+	return Fix{}, false
+}
+
+// matchingBrace returns the offset of the } closing the { at open, or -1.
+func matchingBrace(src string, open int) int {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// wholeLines widens a removal from start to end to whole lines when only whitespace
+// surrounds it on its first and last line.
+func wholeLines(src string, start, end int) Fix {
+	lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+	lineEnd := strings.IndexByte(src[end:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(src) - end
+	}
+	if strings.TrimSpace(src[lineStart:start]) != "" || strings.TrimSpace(src[end:end+lineEnd]) != "" {
+		return Fix{End: end, Start: start}
+	}
+	end += lineEnd
+	if end < len(src) {
+		end++
+	}
+	return Fix{End: end, Start: lineStart}
+}
+
+// ApplyFixes returns src with the fixes of diags applied. Fixes overlapping one
+// applied before them are skipped; checking the result again finds what remains.
+func ApplyFixes(src string, diags []Diagnostic) string {
+	var fixes []Fix
+	for _, d := range diags {
+		if d.Fix != nil {
+			fixes = append(fixes, *d.Fix)
+		}
+	}
+	sort.Slice(fixes, func(i, j int) bool { return fixes[i].Start > fixes[j].Start })
+
+	limit := len(src)
+	for _, f := range fixes {
+		if f.End > limit || f.Start < 0 || f.Start > f.End {
+			continue
+		}
+		src = src[:f.Start] + f.Text + src[f.End:]
+		limit = f.Start
+	}
+	return src
+}
+
+// fixFile applies the fixes of diags to the component at path and checks it again.
+func fixFile(path, src string, diags []Diagnostic, opts CheckOptions) ([]Diagnostic, error) {
+	fixed := ApplyFixes(src, diags)
+	if fixed == src {
+		return diags, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := os.WriteFile(path, []byte(fixed), info.Mode().Perm()); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return Check(fixed, path, opts)
+}
@@ -0,0 +1,154 @@
+package svelte
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Page is the data Handler executes its page template with.
+type Page struct {
+	// Head is the import map for the runtime, unless BundleRuntime is set, followed
+	// by the tags from WithHead.
+	Head template.HTML
+	// Script is the compiled component, mounting itself into the element with ID
+	// Target. It belongs in a <script type="module">.
+	Script template.JS
+	Target string
+	Title  string
+}
+
+// Option configures Handler.
+type Option func(*handlerOptions)
+
+type handlerOptions struct {
+	head     []string
+	props    any
+	target   string
+	template *template.Template
+	title    string
+}
+
+// WithHead adds tags, such as stylesheets or meta tags, to the page's <head>. They
+// are written as is, so they must not contain untrusted input.
+func WithHead(tags ...string) Option {
+	return func(o *handlerOptions) { o.head = append(o.head, tags...) }
+}
+
+// WithProps passes props to the component when it mounts. They are marshaled to JSON,
+// so a struct's json tags name the props.
+func WithProps(props any) Option {
+	return func(o *handlerOptions) { o.props = props }
+}
+
+// WithTarget mounts the component into the element with this ID instead of "app".
+func WithTarget(id string) Option {
+	return func(o *handlerOptions) { o.target = id }
+}
+
+// WithTemplate replaces the page template. It is executed with a Page and must
+// render Head, Script, and an element with ID Target, as the default template does.
+func WithTemplate(t *template.Template) Option {
+	return func(o *handlerOptions) { o.template = t }
+}
+
+// WithTitle sets the page's <title>.
+func WithTitle(title string) Option {
+	return func(o *handlerOptions) { o.title = title }
+}
+
+// pageTemplate is the default page for Handler.
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Title}}</title>
+{{.Head}}
+</head>
+<body>
+	<div id="{{.Target}}"></div>
+	<script type="module">
+{{.Script}}
+	</script>
+</body>
+</html>
+`))
+
+// importMap points svelte imports at RuntimeURL for a Svelte release.
+const importMap = `	<script type="importmap">
+	{
+		"imports": {
+			"svelte": "%[1]s/svelte@%[2]s",
+			"svelte/": "%[1]s/svelte@%[2]s/"
+		}
+	}
+	</script>`
+
+// mountScript mounts the compiled Component into the element with a JSON-encoded ID,
+// passing JSON-encoded props.
+const mountScript = `
+
+		import { mount } from 'svelte';
+		mount(Component, { target: document.getElementById(%s), props: %s });
+`
+
+// Handler returns an http.Handler that serves a page rendering a compiled Svelte 5
+// component. The page is built once, so props are fixed when Handler is called.
+func Handler(src string, opts ...Option) (http.Handler, error) {
+	o := handlerOptions{target: "app", template: pageTemplate, title: "Svelte"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	code, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// json.Marshal escapes <, >, and &, so props cannot close the script element.
+	props := []byte("{}")
+	if o.props != nil {
+		props, err = json.Marshal(o.props)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal props")
+		}
+	}
+	target, err := json.Marshal(o.target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	script := code + fmt.Sprintf(mountScript, target, props)
+
+	var head []string
+	if BundleRuntime {
+		script, err = bundleRuntime(script)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		head = append(head, fmt.Sprintf(importMap, strings.TrimSuffix(RuntimeURL, "/"), CurrentVersion()))
+	}
+	for _, tag := range o.head {
+		head = append(head, "\t"+tag)
+	}
+
+	var page bytes.Buffer
+	if err := o.template.Execute(&page, Page{
+		Head:   template.HTML(strings.Join(head, "\n")),
+		Script: template.JS(script),
+		Target: o.target,
+		Title:  o.title,
+	}); err != nil {
+		return nil, errors.Wrap(err, "render page template")
+	}
+	html := page.Bytes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(html)
+	}), nil
+}
@@ -0,0 +1,112 @@
+package svelte
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HMREnv enables hot module replacement in registries created by NewRegistry when
+// set to 1. do dev --hmr sets it for the app.
+const HMREnv = "DO_SVELTE_HMR"
+
+// HMRInterval is how often a Registry with HMR set checks its components for edits
+// while a browser is connected.
+var HMRInterval = 300 * time.Millisecond
+
+// hmrClient implements import.meta.hot for components served with HMR. When the
+// registry reports an edit, it imports the new version of the component and hands it
+// to the accept callbacks of the version on the page, which Svelte uses to swap it
+// into mounted instances. Components not on the page are skipped.
+const hmrClient = `const accepted = new Map();
+
+export function hot(url) {
+  const path = new URL(url).pathname;
+  const callbacks = [];
+  accepted.set(path, callbacks);
+  return { accept(cb) { callbacks.push(cb); }, data: {}, dispose() {} };
+}
+
+function connect() {
+  const url = new URL(%q, location.href);
+  url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+  const ws = new WebSocket(url);
+  ws.onmessage = async (event) => {
+    const { path } = JSON.parse(event.data);
+    const callbacks = accepted.get(path);
+    if (!callbacks) return;
+    try {
+      const module = await import(path + "?t=" + Date.now());
+      callbacks.forEach((cb) => cb(module));
+    } catch (err) {
+      console.error("[hmr] " + path + ": " + err);
+    }
+  };
+  ws.onclose = () => setTimeout(connect, 1000);
+}
+connect();
+`
+
+// hmrPrelude replaces import.meta.hot in components served with HMR.
+const hmrPrelude = "import { hot as __hot } from %q;\nconst __hmr = __hot(import.meta.url);\n"
+
+// withHMR makes compiled HMR code use hmrClient for import.meta.hot.
+func withHMR(code string) string {
+	return fmt.Sprintf(hmrPrelude, RegistryPath+"@hmr.js") + strings.ReplaceAll(code, "import.meta.hot", "__hmr")
+}
+
+// serveHMR upgrades the request to a websocket that receives {"path": ...} for each
+// edited component, until the browser disconnects.
+func (reg *Registry) serveHMR(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebsocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer ws.Close()
+
+	sums := reg.sums()
+	ticker := time.NewTicker(HMRInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.closed:
+			return
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		next := reg.sums()
+		for name, sum := range next {
+			if prev, ok := sums[name]; ok && prev == sum {
+				continue
+			}
+			msg, _ := json.Marshal(map[string]string{"path": RegistryPath + name + ".js"})
+			if ws.Send(msg) != nil {
+				return
+			}
+		}
+		sums = next
+	}
+}
+
+// sums hashes the source of every component, by name.
+func (reg *Registry) sums() map[string][sha256.Size]byte {
+	sums := make(map[string][sha256.Size]byte)
+	paths, err := walkComponents(reg.fsys)
+	if err != nil {
+		return sums
+	}
+	for _, p := range paths {
+		src, err := fs.ReadFile(reg.fsys, p)
+		if err != nil {
+			continue
+		}
+		sums[strings.TrimSuffix(p, ".svelte")] = sha256.Sum256(src)
+	}
+	return sums
+}
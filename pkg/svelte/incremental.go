@@ -0,0 +1,128 @@
+package svelte
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing/fstest"
+
+	"github.com/pkg/errors"
+)
+
+// previousBuild is the output of a Build kept in CacheDir, with a hash of every file
+// it read, so the next Build with the same roots and options can reuse it when none
+// of those files changed.
+type previousBuild struct {
+	Files    map[string][]byte `json:"files"`
+	Inputs   map[string]string `json:"inputs"`
+	Manifest Manifest          `json:"manifest"`
+}
+
+// buildPath returns where the previous build for a generated entry and options is
+// kept, or "" when CacheDir is empty.
+func buildPath(entry, cwd string, opts BuildOptions) string {
+	if CacheDir == "" {
+		return ""
+	}
+	aliases := make([]string, 0, len(opts.Aliases))
+	for name, dir := range opts.Aliases {
+		aliases = append(aliases, name+"="+dir)
+	}
+	sort.Strings(aliases)
+	opts.Aliases = nil
+	key := cacheKey("build", entry, cwd, RuntimeURL, fmt.Sprintf("%+v", opts), strings.Join(aliases, ","))
+	return filepath.Join(CacheDir, "builds", key+".json")
+}
+
+// loadBuild returns the build kept at path if none of its inputs changed.
+func loadBuild(path string) (fs.FS, Manifest, bool) {
+	if path == "" {
+		return nil, Manifest{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, Manifest{}, false
+	}
+	var prev previousBuild
+	if json.Unmarshal(data, &prev) != nil {
+		return nil, Manifest{}, false
+	}
+	for input, sum := range prev.Inputs {
+		if current, err := hashFile(input); err != nil || current != sum {
+			return nil, Manifest{}, false
+		}
+	}
+	out := fstest.MapFS{}
+	for name, contents := range prev.Files {
+		out[name] = &fstest.MapFile{Data: contents, Mode: 0644}
+	}
+	return out, prev.Manifest, true
+}
+
+// saveBuild keeps out and manifest at path, with the files esbuild's metafile lists
+// as inputs. Inputs that are not files, such as the runtime fetched from RuntimeURL
+// and styles extracted from components, are identified by the entry and options.
+func saveBuild(path, metafile, cwd string, out fstest.MapFS, manifest Manifest) error {
+	if path == "" {
+		return nil
+	}
+	var meta struct {
+		Inputs map[string]json.RawMessage `json:"inputs"`
+	}
+	if err := json.Unmarshal([]byte(metafile), &meta); err != nil {
+		return errors.WithStack(err)
+	}
+
+	prev := previousBuild{Files: make(map[string][]byte), Inputs: make(map[string]string), Manifest: manifest}
+	for input := range meta.Inputs {
+		file, ok := inputFile(input, cwd)
+		if !ok {
+			continue
+		}
+		sum, err := hashFile(file)
+		if err != nil {
+			return err
+		}
+		prev.Inputs[file] = sum
+	}
+	for name, f := range out {
+		prev.Files[name] = f.Data
+	}
+	writeCache(path, prev)
+	return nil
+}
+
+// inputFile returns the file behind an input in esbuild's metafile, which has its
+// plugin namespace as a prefix unless it was loaded from disk by esbuild.
+func inputFile(input, cwd string) (string, bool) {
+	if path, ok := strings.CutPrefix(input, "svelte-components:"); ok {
+		return path, true
+	}
+	for _, ns := range []string{"svelte-entry:", "svelte-runtime:", "svelte-styles:"} {
+		if strings.HasPrefix(input, ns) {
+			return "", false
+		}
+	}
+	if input == "<stdin>" {
+		return "", false
+	}
+	if filepath.IsAbs(input) {
+		return input, true
+	}
+	return filepath.Join(cwd, filepath.FromSlash(input)), true
+}
+
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,89 @@
+package svelte
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"strings"
+	"testing/fstest"
+
+	"github.com/pkg/errors"
+)
+
+// setIntegrity hashes every file in out, lists the module outfile and the chunks it
+// imports statically, from esbuild's metafile, as Preload, and writes the tags
+// loading them as manifest.HTML.
+func setIntegrity(manifest *Manifest, out fstest.MapFS, outfile, metafile string) error {
+	manifest.Integrity = make(map[string]string, len(out))
+	for name, f := range out {
+		sum := sha512.Sum384(f.Data)
+		manifest.Integrity[name] = "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	var meta struct {
+		Outputs map[string]struct {
+			Imports []struct {
+				External bool   `json:"external"`
+				Kind     string `json:"kind"`
+				Path     string `json:"path"`
+			} `json:"imports"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(metafile), &meta); err != nil {
+		return errors.WithStack(err)
+	}
+	seen := map[string]bool{outfile: true}
+	manifest.Preload = []string{outfile}
+	for i := 0; i < len(manifest.Preload); i++ {
+		for _, imp := range meta.Outputs[manifest.Preload[i]].Imports {
+			if imp.External || imp.Kind != "import-statement" || seen[imp.Path] {
+				continue
+			}
+			seen[imp.Path] = true
+			manifest.Preload = append(manifest.Preload, imp.Path)
+		}
+	}
+
+	manifest.HTML = manifestHTML(*manifest, outfile)
+	return nil
+}
+
+// manifestHTML returns the tags loading the bundle described by m.
+func manifestHTML(m Manifest, outfile string) string {
+	var b strings.Builder
+	attrs := func(name string) string {
+		a := `="` + html.EscapeString(m.BaseURL+name) + `" integrity="` + m.Integrity[name] + `"`
+		// Integrity checks on other origins need a CORS request.
+		if strings.Contains(m.BaseURL, "//") {
+			a += ` crossorigin="anonymous"`
+		}
+		return a
+	}
+	if m.CSS != "" {
+		b.WriteString(`<link rel="stylesheet" href` + attrs(m.CSS) + ">\n")
+	}
+	for _, name := range m.Preload {
+		if name != outfile {
+			b.WriteString(`<link rel="modulepreload" href` + attrs(name) + ">\n")
+		}
+	}
+	b.WriteString(`<script type="module" src` + attrs(outfile) + "></script>\n")
+	if m.Legacy != "" {
+		b.WriteString(`<script nomodule src` + attrs(m.Legacy) + "></script>\n")
+	}
+	return b.String()
+}
+
+// Link returns a Link header value preloading the stylesheet, the module, and the
+// chunks it imports, so servers can start those downloads before sending the page.
+func (m Manifest) Link() string {
+	var links []string
+	if m.CSS != "" {
+		links = append(links, "<"+m.BaseURL+m.CSS+">; rel=preload; as=style")
+	}
+	for _, name := range m.Preload {
+		links = append(links, "<"+m.BaseURL+name+">; rel=modulepreload")
+	}
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,141 @@
+package svelte
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"modernc.org/quickjs"
+)
+
+// MemoryLimit bounds the memory of each QuickJS VM, in bytes, so a pathological
+// component fails with ErrMemoryLimit instead of exhausting the process.
+var MemoryLimit uintptr = 512 << 20
+
+// Timeout bounds each compile or check of a single component, so one that sends the
+// compiler into a loop fails with ErrTimeout instead of hanging the caller.
+var Timeout = 30 * time.Second
+
+var (
+	ErrMemoryLimit = errors.New("svelte: compiler exceeded its memory limit")
+	ErrTimeout     = errors.New("svelte: compiler timed out")
+)
+
+// vms holds idle VMs with the compiler loaded. Loading the compiler bundle takes
+// much longer than compiling a component, so VMs are reused across calls.
+var vms = make(chan *quickjs.VM, runtime.GOMAXPROCS(0))
+
+// withVM calls fn with a VM that has compiler.min.js and compile.js evaluated. The VM
+// returns to the pool afterwards unless fn failed, in case the failure left it in a
+// bad state, or the pool is full.
+func withVM(fn func(vm *quickjs.VM) error) error {
+	var vm *quickjs.VM
+	select {
+	case vm = <-vms:
+	default:
+		var err error
+		if vm, err = newVM(); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(vm); err != nil {
+		_ = vm.Close()
+		return err
+	}
+	select {
+	case vms <- vm:
+	default:
+		_ = vm.Close()
+	}
+	return nil
+}
+
+func newVM() (*quickjs.VM, error) {
+	vm, err := quickjs.NewVM()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, err = vm.Eval(compilerSource(), 0); err != nil {
+		_ = vm.Close()
+		return nil, errors.WithStack(err)
+	}
+	if _, err = vm.Eval(compileJS, 0); err != nil {
+		_ = vm.Close()
+		return nil, errors.WithStack(err)
+	}
+	return vm, nil
+}
+
+// call runs fn from compile.js with JSON-encoded args in a pooled VM and returns the
+// JSON string it produces.
+func call(fn string, args ...any) (string, error) {
+	encoded := make([]string, len(args))
+	for i, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		encoded[i] = string(data)
+	}
+	script := fn + "(" + strings.Join(encoded, ", ") + ")"
+
+	var out string
+	err := withVM(func(vm *quickjs.VM) error {
+		// Set on each call rather than in newVM so the limits bound each compile
+		// rather than loading the compiler, and changes apply to pooled VMs.
+		if MemoryLimit > 0 {
+			vm.SetMemoryLimit(MemoryLimit)
+		}
+		_ = vm.SetEvalTimeout(Timeout)
+		result, err := vm.Eval(script, 0)
+		if err != nil {
+			return evalError(fn, err)
+		}
+		s, ok := result.(string)
+		if !ok {
+			return errors.Errorf("svelte: %s returned %T, not a string", fn, result)
+		}
+		out = s
+		return nil
+	})
+	return out, err
+}
+
+// evalError maps QuickJS failures from interrupts and allocation failures to
+// ErrTimeout and ErrMemoryLimit. QuickJS reports running out of memory as a null or
+// "out of memory" exception.
+func evalError(fn string, err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "interrupted"):
+		return errors.Wrapf(ErrTimeout, "%s after %s", fn, Timeout)
+	case msg == "null" || strings.Contains(msg, "out of memory"):
+		return errors.Wrapf(ErrMemoryLimit, "%s with a %d MiB limit", fn, MemoryLimit>>20)
+	}
+	return errors.WithStack(err)
+}
+
+// parallel calls fn for each index below n using up to GOMAXPROCS goroutines.
+func parallel(n int, fn func(i int)) {
+	next := make(chan int)
+	done := make(chan struct{})
+	workers := min(n, runtime.GOMAXPROCS(0))
+	for range workers {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	for range workers {
+		<-done
+	}
+}
@@ -0,0 +1,115 @@
+package svelte
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Preprocessor converts a <script> or <style> block whose lang attribute matches
+// Lang into JavaScript or CSS before the Svelte compiler runs.
+type Preprocessor struct {
+	Lang string
+	// Process returns the converted contents. filename is the component's path, or
+	// empty when unknown, for resolving imports relative to the component.
+	Process func(contents, filename string) (string, error)
+	// Tag is "script" or "style".
+	Tag string
+}
+
+// Preprocessors run on the input of Compile, CompileSSR, Check, and Build. The
+// defaults convert scss and sass with Dart Sass and postcss (for Tailwind and other
+// PostCSS plugins) with postcss-cli, which must be on PATH when used. Projects can
+// append their own or replace these. <script lang="ts"> is handled separately.
+var Preprocessors = []Preprocessor{
+	{Lang: "postcss", Process: Command("postcss"), Tag: "style"},
+	{Lang: "sass", Process: Command("sass", "--stdin", "--indented", "--no-source-map"), Tag: "style"},
+	{Lang: "scss", Process: Command("sass", "--stdin", "--no-source-map"), Tag: "style"},
+}
+
+// blockTag matches <script> and <style> elements, capturing the tag name, its
+// attributes, and its contents.
+var blockTag = regexp.MustCompile(`(?s)<(script|style)\b([^>]*)>(.*?)</(?:script|style)>`)
+
+// langAttr matches a lang attribute, capturing its value.
+var langAttr = regexp.MustCompile(`\s*\blang\s*=\s*["']?([\w-]+)["']?`)
+
+// Command returns a Process function that pipes the block through a command, run in
+// the component's directory so relative imports and config files resolve.
+func Command(name string, args ...string) func(contents, filename string) (string, error) {
+	return func(contents, filename string) (string, error) {
+		if _, err := exec.LookPath(name); err != nil {
+			return "", errors.Errorf("%s is required to preprocess %s but is not installed", name, filename)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(name, args...)
+		// Components read from an fs.FS may not exist on disk.
+		if dir := filepath.Dir(filename); filename != "" && isDir(dir) {
+			cmd.Dir = dir
+		}
+		cmd.Stdin = strings.NewReader(contents)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", errors.Errorf("%s: %s", name, strings.TrimSpace(stderr.String()+" "+err.Error()))
+		}
+		return stdout.String(), nil
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// needsPreprocess reports whether preprocess would convert a block of src.
+func needsPreprocess(src string) bool {
+	for _, m := range blockTag.FindAllStringSubmatch(src, -1) {
+		lang := langAttr.FindStringSubmatch(m[2])
+		if lang == nil {
+			continue
+		}
+		for _, p := range Preprocessors {
+			if p.Tag == m[1] && p.Lang == lang[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// preprocess runs the matching Preprocessors on each block of src and drops the
+// lang attribute of converted blocks, since their contents are now plain JavaScript
+// or CSS.
+func preprocess(src, filename string) (string, error) {
+	var failed error
+	out := blockTag.ReplaceAllStringFunc(src, func(block string) string {
+		m := blockTag.FindStringSubmatch(block)
+		tag, attrs, contents := m[1], m[2], m[3]
+		lang := langAttr.FindStringSubmatch(attrs)
+		if failed != nil || lang == nil {
+			return block
+		}
+		for _, p := range Preprocessors {
+			if p.Tag != tag || p.Lang != lang[1] {
+				continue
+			}
+			converted, err := p.Process(contents, filename)
+			if err != nil {
+				failed = errors.Wrapf(err, "preprocess <%s lang=%q>", tag, lang[1])
+				return block
+			}
+			return "<" + tag + langAttr.ReplaceAllString(attrs, "") + ">" + converted + "</" + tag + ">"
+		}
+		return block
+	})
+	if failed != nil {
+		return "", failed
+	}
+	return out, nil
+}
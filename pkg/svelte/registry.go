@@ -0,0 +1,171 @@
+package svelte
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// RegistryPath is the URL path a Registry serves components under.
+const RegistryPath = "/components/"
+
+// svelteImport matches relative imports of .svelte files in compiled code, capturing
+// the path without its extension.
+var svelteImport = regexp.MustCompile(`(\bfrom\s*|\bimport\s*\(?\s*)(['"])(\.{1,2}/[^'"]+)\.svelte(['"])`)
+
+// Registry serves the .svelte files in a filesystem as ES modules, compiling each on
+// first request and again only when its source changes, so Go servers can add Svelte
+// components to server-rendered pages without running do bundle. Mount it with
+//
+//	mux.Handle(svelte.RegistryPath, svelte.NewRegistry(os.DirFS("components")))
+//
+// Components are served at /components/<name>.js, where name is the path without
+// .svelte, and /components/index.js exports a map from each name to a function
+// importing it, so a component cannot be named index. Pages must provide an import
+// map for svelte, as Handler does.
+//
+// With HMR set, components are compiled in dev mode and edits are pushed over a
+// websocket at /components/@hmr, so mounted components update in place without a
+// page reload.
+type Registry struct {
+	// HMR enables hot module replacement. NewRegistry sets it from HMREnv.
+	HMR  bool
+	fsys fs.FS
+	mu   sync.Mutex
+	// modules holds each compiled component by name, with a hash of its source.
+	modules map[string]registryModule
+}
+
+type registryModule struct {
+	code string
+	sum  string
+}
+
+// NewRegistry returns a Registry for the components in fsys.
+func NewRegistry(fsys fs.FS) *Registry {
+	return &Registry{HMR: os.Getenv(HMREnv) == "1", fsys: fsys, modules: make(map[string]registryModule)}
+}
+
+// Names returns the name of every component in the registry, in path order.
+func (reg *Registry) Names() ([]string, error) {
+	paths, err := walkComponents(reg.fsys)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = strings.TrimSuffix(p, ".svelte")
+	}
+	return names, nil
+}
+
+// Module returns the compiled ES module for the component called name, with its
+// .svelte imports pointing at the registry's .js paths, and a hash of its source.
+func (reg *Registry) Module(name string) (code, sum string, err error) {
+	src, err := fs.ReadFile(reg.fsys, name+".svelte")
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	hash := sha256.Sum256(src)
+	sum = hex.EncodeToString(hash[:])
+
+	reg.mu.Lock()
+	m, ok := reg.modules[name]
+	reg.mu.Unlock()
+	if ok && m.sum == sum {
+		return m.code, m.sum, nil
+	}
+
+	fn := "compile"
+	if reg.HMR {
+		fn = "compileHMR"
+	}
+	compiled, err := compile(fn, string(src), name+".svelte", false)
+	if err != nil {
+		return "", "", err
+	}
+	code = svelteImport.ReplaceAllString(compiled.Code, "$1$2$3.js$4")
+	if reg.HMR {
+		code = withHMR(code)
+	}
+
+	reg.mu.Lock()
+	reg.modules[name] = registryModule{code: code, sum: sum}
+	reg.mu.Unlock()
+	return code, sum, nil
+}
+
+// Index returns the ES module whose default export maps each component name to a
+// function importing its module.
+func (reg *Registry) Index() (string, error) {
+	names, err := reg.Names()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("export default {\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s: () => import(%s),\n", strconv.Quote(name), strconv.Quote("./"+name+".js"))
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// ServeHTTP serves /components/index.js and /components/<name>.js, and with HMR
+// set the /components/@hmr websocket and its /components/@hmr.js client.
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(path.Clean(r.URL.Path), RegistryPath)
+	if ok && reg.HMR {
+		switch name {
+		case "@hmr":
+			reg.serveHMR(w, r)
+			return
+		case "@hmr.js":
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			_, _ = fmt.Fprintf(w, hmrClient, RegistryPath+"@hmr")
+			return
+		}
+	}
+	if !ok || !strings.HasSuffix(name, ".js") {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.TrimSuffix(name, ".js")
+
+	var code, sum string
+	var err error
+	if name == "index" {
+		code, err = reg.Index()
+	} else {
+		code, sum, err = reg.Module(name)
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	if sum != "" {
+		w.Header().Set("ETag", strconv.Quote(sum[:16]))
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == w.Header().Get("ETag") {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	_, _ = w.Write([]byte(code))
+}
@@ -0,0 +1,74 @@
+package svelte
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/housecat-inc/do/pkg/sarif"
+	"github.com/pkg/errors"
+)
+
+// Report is the JSON form of a set of diagnostics, as written by WriteJSON.
+type Report struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Errors      int          `json:"errors"`
+	// Files is the number of files with diagnostics.
+	Files    int `json:"files"`
+	Warnings int `json:"warnings"`
+}
+
+// NewReport counts diagnostics by type and file.
+func NewReport(diags []Diagnostic) Report {
+	r := Report{Diagnostics: []Diagnostic{}}
+	files := make(map[string]bool)
+	for _, d := range diags {
+		r.Diagnostics = append(r.Diagnostics, d)
+		files[d.Filename] = true
+		if d.Type == "warning" {
+			r.Warnings++
+		} else {
+			r.Errors++
+		}
+	}
+	r.Files = len(files)
+	return r
+}
+
+// WriteJSON writes diags as an indented Report.
+func WriteJSON(w io.Writer, diags []Diagnostic) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.WithStack(enc.Encode(NewReport(diags)))
+}
+
+// SARIFRun returns diags as the run of a SARIF log, with file paths relative to
+// root, which should be the repository root.
+func SARIFRun(diags []Diagnostic, root string) sarif.Run {
+	run := sarif.NewRun("svelte", "https://svelte.dev", CurrentVersion())
+	for _, d := range diags {
+		run.AddRule(sarif.Rule{HelpURI: "https://svelte.dev/e/" + d.Code, ID: d.Code})
+
+		level := "error"
+		if d.Type == "warning" {
+			level = "warning"
+		}
+		var region *sarif.Region
+		if d.Start != nil {
+			// Svelte columns are 0-based; SARIF columns are 1-based.
+			region = &sarif.Region{StartColumn: d.Start.Column + 1, StartLine: d.Start.Line}
+			if d.End != nil {
+				region.EndColumn = d.End.Column + 1
+				region.EndLine = d.End.Line
+			}
+		}
+		run.Add(level, d.Code, d.Message, d.Filename, root, region)
+	}
+	return run
+}
+
+// WriteSARIF writes diags as a SARIF 2.1.0 log for GitHub code scanning and other
+// tools. File paths are written relative to root, which should be the repository
+// root, with forward slashes.
+func WriteSARIF(w io.Writer, diags []Diagnostic, root string) error {
+	return sarif.Write(w, SARIFRun(diags, root))
+}
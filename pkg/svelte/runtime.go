@@ -0,0 +1,134 @@
+package svelte
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// RuntimeURL serves the Svelte client runtime as ES modules. Browsers import it from
+// here unless the runtime is bundled, in which case Build and Handler fetch it from
+// here at build time instead.
+var RuntimeURL = "https://esm.sh"
+
+// BundleRuntime makes Handler pages include the Svelte runtime rather than import it
+// from RuntimeURL in the browser. See BuildOptions.BundleRuntime.
+var BundleRuntime bool
+
+// runtimeImport matches imports of the Svelte runtime, such as svelte/internal/client.
+const runtimeImport = `^svelte(/.*)?$`
+
+// runtimePlugin resolves svelte and svelte/* imports to modules on RuntimeURL for the
+// selected Svelte release and bundles them, following the imports between them.
+// Modules are kept in CompilerDir, since a release's modules never change. With dev
+// set, the runtime's development build is used, which reports misuse in the console.
+func runtimePlugin(dev bool) api.Plugin {
+	query := "?target=es2022"
+	if dev {
+		query += "&dev"
+	}
+	return api.Plugin{
+		Name: "svelte-runtime",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: runtimeImport},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					subpath := strings.TrimPrefix(args.Path, "svelte")
+					return api.OnResolveResult{
+						Path:      strings.TrimSuffix(RuntimeURL, "/") + "/svelte@" + CurrentVersion() + subpath + query,
+						Namespace: "svelte-runtime",
+					}, nil
+				})
+			build.OnResolve(api.OnResolveOptions{Filter: `.*`, Namespace: "svelte-runtime"},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					base, err := url.Parse(args.Importer)
+					if err != nil {
+						return api.OnResolveResult{}, errors.WithStack(err)
+					}
+					ref, err := url.Parse(args.Path)
+					if err != nil {
+						return api.OnResolveResult{}, errors.WithStack(err)
+					}
+					return api.OnResolveResult{
+						Path:      base.ResolveReference(ref).String(),
+						Namespace: "svelte-runtime",
+					}, nil
+				})
+			build.OnLoad(api.OnLoadOptions{Filter: `.*`, Namespace: "svelte-runtime"},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					contents, err := runtimeModule(args.Path)
+					if err != nil {
+						return api.OnLoadResult{}, err
+					}
+					return api.OnLoadResult{
+						Contents: &contents,
+						Loader:   api.LoaderJS,
+					}, nil
+				})
+		},
+	}
+}
+
+// runtimeModule returns the module at rawURL, from CompilerDir if it was fetched before.
+func runtimeModule(rawURL string) (string, error) {
+	var path string
+	if CompilerDir != "" {
+		sum := sha256.Sum256([]byte(rawURL))
+		path = filepath.Join(CompilerDir, "runtime", hex.EncodeToString(sum[:])+".js")
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data), nil
+		}
+	}
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "download Svelte runtime %s", rawURL)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("download Svelte runtime %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "download Svelte runtime %s", rawURL)
+	}
+
+	if path != "" && os.MkdirAll(filepath.Dir(path), 0o755) == nil {
+		_ = os.WriteFile(path, data, 0o644)
+	}
+	return string(data), nil
+}
+
+// bundleRuntime bundles a module with the Svelte runtime it imports into one script.
+func bundleRuntime(code string) (string, error) {
+	result := api.Build(api.BuildOptions{
+		Stdin: &api.StdinOptions{
+			Contents: code,
+			Loader:   api.LoaderJS,
+		},
+		Bundle:            true,
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		MinifySyntax:      true,
+		Format:            api.FormatESModule,
+		Write:             false,
+		Plugins:           []api.Plugin{runtimePlugin(false)},
+	})
+	if len(result.Errors) > 0 {
+		msgs := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			msgs[i] = e.Text
+		}
+		return "", errors.Errorf("esbuild: %s", strings.Join(msgs, "; "))
+	}
+	return string(result.OutputFiles[0].Contents), nil
+}
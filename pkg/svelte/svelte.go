@@ -5,19 +5,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
-	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/pkg/errors"
-	"modernc.org/quickjs"
 )
 
 // Position represents a location in source code.
 type Position struct {
-	Column int `json:"column"`
-	Line   int `json:"line"`
+	// Character is the offset in UTF-16 code units from the start of the source, as
+	// the Svelte compiler reports it.
+	Character int `json:"character"`
+	Column    int `json:"column"`
+	Line      int `json:"line"`
 }
 
 // Diagnostic represents a compiler warning or error.
@@ -25,9 +29,11 @@ type Diagnostic struct {
 	Code     string    `json:"code"`
 	End      *Position `json:"end"`
 	Filename string    `json:"filename"`
-	Message  string    `json:"message"`
-	Start    *Position `json:"start"`
-	Type     string    `json:"type"`
+	// Fix, if set, is an edit to the component source that resolves the diagnostic.
+	Fix     *Fix      `json:"fix,omitempty"`
+	Message string    `json:"message"`
+	Start   *Position `json:"start"`
+	Type    string    `json:"type"`
 }
 
 //go:generate curl -so compiler.min.js https://esm.sh/svelte@5.46.1/compiler/index.js?raw
@@ -35,112 +41,231 @@ type Diagnostic struct {
 //go:embed compile.js
 var compileJS string
 
+// compilerJS is the embedded compiler for Version. Update both together.
+//
 //go:embed compiler.min.js
 var compilerJS string
 
-// html serves a pre-compiled Svelte component with esm.sh runtime
-const html = `<!DOCTYPE html>
-<html>
-<head>
-	<title>Test</title>
-	<script type="importmap">
-	{
-		"imports": {
-			"svelte": "https://esm.sh/svelte@5.46.1",
-			"svelte/": "https://esm.sh/svelte@5.46.1/"
-		}
-	}
-	</script>
-</head>
-<body>
-	<div id="app"></div>
-	<script type="module">
-%s
-
-		import { mount } from 'svelte';
-		mount(Component, { target: document.getElementById('app') });
-	</script>
-</body>
-</html>
-`
+// ServerModule is a component compiled for server-side rendering.
+type ServerModule struct {
+	// Code is an ES module importing svelte/internal/server whose default export
+	// renders the component when passed to render from svelte/server.
+	Code string
+	// CSS is the component's scoped styles, which the client build injects itself.
+	CSS string
+}
 
 // Compile compiles a Svelte component using QuickJS and returns the JS code.
+// <script lang="ts"> blocks are transpiled to JavaScript first, and blocks matching
+// Preprocessors are converted.
 func Compile(src string) (string, error) {
-	vm, err := quickjs.NewVM()
+	code, _, err := CompileWithSourceMap(src, "")
+	return code, err
+}
+
+// CompileWithSourceMap compiles like Compile and also returns the source map as JSON,
+// with filename as its source. For <script lang="ts"> blocks the map points at the
+// transpiled script.
+func CompileWithSourceMap(src, filename string) (code, sourceMap string, err error) {
+	out, err := compile("compile", src, filename, false)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return "", "", err
 	}
-	defer func() { _ = vm.Close() }()
+	return out.Code, out.Map, nil
+}
 
-	if _, err = vm.Eval(compilerJS, 0); err != nil {
-		return "", errors.WithStack(err)
+// CompileSSR compiles a Svelte component with generate: 'server', for rendering
+// HTML that the Compile output hydrates on the client.
+func CompileSSR(src string) (ServerModule, error) {
+	out, err := compile("compileServer", src, "", false)
+	if err != nil {
+		return ServerModule{}, err
 	}
+	return ServerModule{Code: out.Code, CSS: out.CSS}, nil
+}
 
-	if _, err = vm.Eval(compileJS, 0); err != nil {
-		return "", errors.WithStack(err)
-	}
+// customElementTag matches valid custom element names, which need a hyphen.
+var customElementTag = regexp.MustCompile(`^[a-z][a-z0-9._]*-[a-z0-9._-]*$`)
 
-	sourceJSON, err := json.Marshal(src)
+// CompileCustomElement compiles a Svelte component as a custom element, so pages can
+// use it as <my-widget name="..."> without a mount script once the module loads. Its
+// styles are injected into the element's shadow root. A component declaring
+// <svelte:options customElement="my-widget" /> registers itself under that name;
+// otherwise it is registered as tag, which must then be set.
+func CompileCustomElement(src, tag string) (string, error) {
+	out, err := compile("compileCustomElement", src, "", false)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return "", err
+	}
+	if strings.Contains(out.Code, "customElements.define(") {
+		return out.Code, nil
 	}
+	if !customElementTag.MatchString(tag) {
+		return "", errors.Errorf("invalid custom element name %q: it must be lowercase and contain a hyphen, or the component must set <svelte:options customElement>", tag)
+	}
+	return out.Code + fmt.Sprintf("\ncustomElements.define(%q, Component.element);\n", tag), nil
+}
+
+type compileResult struct {
+	Code  string `json:"code"`
+	CSS   string `json:"css"`
+	Error string `json:"error"`
+	Map   string `json:"map"`
+}
 
-	result, err := vm.Eval(fmt.Sprintf("compile(%s)", sourceJSON), 0)
+// compile runs fn from compile.js on src, or returns its cached result. With dev set,
+// the client functions compile in Svelte's dev mode, which adds runtime warnings.
+func compile(fn, src, filename string, dev bool) (compileResult, error) {
+	src, err := preprocess(src, filename)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return compileResult{}, err
 	}
+	return cached(cacheKey(devKey(fn, dev), filename, src), func() (compileResult, error) {
+		if hasTypeScript(src) {
+			if src, err = stripTypes(src); err != nil {
+				return compileResult{}, err
+			}
+		}
+		return compileVM(fn, src, filename, dev)
+	})
+}
 
-	var out struct {
-		Code  string `json:"code"`
-		Error string `json:"error"`
+// devKey distinguishes dev mode output of fn in the cache.
+func devKey(fn string, dev bool) string {
+	if dev {
+		return fn + ":dev"
 	}
-	if err := json.Unmarshal([]byte(result.(string)), &out); err != nil {
-		return "", errors.WithStack(err)
+	return fn
+}
+
+// CompileModule compiles a .svelte.js or .svelte.ts module, which may use runes such
+// as $state outside a component to share reactive state between components.
+// TypeScript is transpiled first when filename ends in .ts.
+func CompileModule(src, filename string) (string, error) {
+	out, err := compileModule(src, filename, false)
+	return out.Code, err
+}
+
+func compileModule(src, filename string, dev bool) (compileResult, error) {
+	return cached(cacheKey(devKey("compileModule", dev), filename, src), func() (compileResult, error) {
+		if strings.HasSuffix(filename, ".ts") {
+			var err error
+			if src, err = transpileTS(src); err != nil {
+				return compileResult{}, err
+			}
+		}
+		return compileVM("compileModule", src, filename, dev)
+	})
+}
+
+func compileVM(fn, src, filename string, dev bool) (compileResult, error) {
+	var out compileResult
+	result, err := call(fn, src, filename, dev)
+	if err != nil {
+		return out, err
+	}
+
+	if err := json.Unmarshal([]byte(result), &out); err != nil {
+		return out, errors.WithStack(err)
 	}
 	if out.Error != "" {
-		return "", errors.Errorf("svelte: %s", out.Error)
+		return out, errors.Errorf("svelte: %s", out.Error)
 	}
 
-	return out.Code, nil
+	return out, nil
 }
 
 // Check validates a Svelte component and returns diagnostics (warnings/errors).
 // Unlike Compile, it does not generate output code - it only checks for issues.
-func Check(src, filename string) ([]Diagnostic, error) {
-	vm, err := quickjs.NewVM()
+// TypeScript the Svelte compiler can't handle natively, such as enums, is transpiled
+// and checked again, so positions in those components may be approximate.
+func Check(src, filename string, opts CheckOptions) ([]Diagnostic, error) {
+	diags, err := checkSource(src, filename)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
-	defer func() { _ = vm.Close() }()
+	return opts.Apply(diags), nil
+}
 
-	if _, err = vm.Eval(compilerJS, 0); err != nil {
-		return nil, errors.WithStack(err)
+// checkSource checks src, suggesting fixes when the compiler saw src unchanged so
+// its positions are offsets into src.
+func checkSource(src, filename string) ([]Diagnostic, error) {
+	original := src
+	src, err := preprocess(src, filename)
+	if err != nil {
+		return []Diagnostic{{Code: "preprocess_error", Filename: filename, Message: err.Error(), Type: "error"}}, nil
 	}
 
-	if _, err = vm.Eval(compileJS, 0); err != nil {
-		return nil, errors.WithStack(err)
+	diags, err := check(src, filename)
+	if err != nil || !hasTypeScript(src) || !slices.ContainsFunc(diags, isTypeScriptFeature) {
+		if err == nil && src == original {
+			addFixes(src, diags)
+		}
+		return diags, err
 	}
 
-	sourceJSON, err := json.Marshal(src)
+	js, err := stripTypes(src)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return []Diagnostic{{Code: "typescript_error", Filename: filename, Message: err.Error(), Type: "error"}}, nil
 	}
+	return check(js, filename)
+}
 
-	filenameJSON, err := json.Marshal(filename)
-	if err != nil {
-		return nil, errors.WithStack(err)
+// CheckOptions adjusts the diagnostics from Check and CheckDir. Codes may be
+// path.Match patterns, such as a11y_* for every accessibility rule.
+type CheckOptions struct {
+	// Errors lists warning codes to report as errors.
+	Errors []string
+	// Fix makes CheckDir rewrite each file with the fixes of its diagnostics
+	// applied, then report what remains. CheckFS cannot write and ignores it.
+	Fix bool
+	// Ignore lists diagnostic codes to drop.
+	Ignore []string
+}
+
+// Apply drops ignored diagnostics and promotes warnings listed in Errors.
+func (o CheckOptions) Apply(diags []Diagnostic) []Diagnostic {
+	var out []Diagnostic
+	for _, d := range diags {
+		if matchCode(o.Ignore, d.Code) {
+			continue
+		}
+		if d.Type == "warning" && matchCode(o.Errors, d.Code) {
+			d.Type = "error"
+		}
+		out = append(out, d)
 	}
+	return out
+}
+
+func matchCode(patterns []string, code string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, code); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isTypeScriptFeature(d Diagnostic) bool {
+	return d.Code == "typescript_invalid_feature"
+}
 
-	result, err := vm.Eval(fmt.Sprintf("check(%s, %s)", sourceJSON, filenameJSON), 0)
+func check(src, filename string) ([]Diagnostic, error) {
+	return cached(cacheKey("check", filename, src), func() ([]Diagnostic, error) { return checkVM(src, filename) })
+}
+
+func checkVM(src, filename string) ([]Diagnostic, error) {
+	result, err := call("check", src, filename)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
 
 	var out struct {
 		Diagnostics []Diagnostic `json:"diagnostics"`
 		Error       string       `json:"error"`
 	}
-	if err := json.Unmarshal([]byte(result.(string)), &out); err != nil {
+	if err := json.Unmarshal([]byte(result), &out); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	if out.Error != "" {
@@ -150,65 +275,94 @@ func Check(src, filename string) ([]Diagnostic, error) {
 	return out.Diagnostics, nil
 }
 
-// CheckDir walks a directory and checks all .svelte files, returning all diagnostics.
-// It skips node_modules and hidden directories by default.
-func CheckDir(root string) ([]Diagnostic, error) {
-	var all []Diagnostic
-
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+// CheckDir walks a directory and checks all .svelte files concurrently, returning all
+// diagnostics in path order with opts applied. It skips node_modules, dist, and
+// hidden paths.
+func CheckDir(root string, opts CheckOptions) ([]Diagnostic, error) {
+	return checkFS(os.DirFS(root), root, opts)
+}
 
-		if d.IsDir() {
-			name := d.Name()
-			if name == "node_modules" || strings.HasPrefix(name, ".") {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+// CheckFS is CheckDir for the .svelte files in fsys, such as an embed.FS.
+// Diagnostics name files by their slash-separated path in fsys.
+func CheckFS(fsys fs.FS, opts CheckOptions) ([]Diagnostic, error) {
+	return checkFS(fsys, "", opts)
+}
 
-		if !strings.HasSuffix(path, ".svelte") {
-			return nil
-		}
+// checkFS checks the components in fsys, naming them by path joined to root.
+func checkFS(fsys fs.FS, root string, opts CheckOptions) ([]Diagnostic, error) {
+	paths, err := walkComponents(fsys)
+	if err != nil {
+		return nil, err
+	}
 
-		src, err := os.ReadFile(path)
+	results := make([][]Diagnostic, len(paths))
+	errs := make([]error, len(paths))
+	parallel(len(paths), func(i int) {
+		src, err := fs.ReadFile(fsys, paths[i])
 		if err != nil {
-			return errors.WithStack(err)
+			errs[i] = errors.WithStack(err)
+			return
 		}
 
-		diags, err := Check(string(src), path)
+		filename := paths[i]
+		if root != "" {
+			filename = filepath.Join(root, filepath.FromSlash(paths[i]))
+		}
+		diags, err := Check(string(src), filename, opts)
+		if err == nil && opts.Fix && root != "" {
+			diags, err = fixFile(filename, string(src), diags, opts)
+		}
 		if err != nil {
-			all = append(all, Diagnostic{
+			diags = []Diagnostic{{
 				Code:     "check_error",
-				Filename: path,
+				Filename: filename,
 				Message:  err.Error(),
 				Type:     "error",
-			})
-			return nil
+			}}
 		}
-
-		all = append(all, diags...)
-		return nil
+		results[i] = diags
 	})
 
-	if err != nil {
-		return nil, err
+	var all []Diagnostic
+	for i, diags := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		all = append(all, diags...)
 	}
 	return all, nil
 }
 
-// Handler returns an http.Handler that serves a compiled Svelte 5 component.
-func Handler(src string) (http.Handler, error) {
-	code, err := Compile(src)
+// CompileFS compiles every .svelte file in fsys concurrently, such as an embed.FS,
+// and returns each component's JavaScript by its slash-separated path.
+func CompileFS(fsys fs.FS) (map[string]string, error) {
+	paths, err := walkComponents(fsys)
 	if err != nil {
 		return nil, err
 	}
 
-	html := fmt.Sprintf(html, code)
+	codes := make([]string, len(paths))
+	errs := make([]error, len(paths))
+	parallel(len(paths), func(i int) {
+		src, err := fs.ReadFile(fsys, paths[i])
+		if err != nil {
+			errs[i] = errors.WithStack(err)
+			return
+		}
+		compiled, err := compile("compile", string(src), paths[i], false)
+		if err != nil {
+			errs[i] = errors.Wrapf(err, "compile %s", paths[i])
+			return
+		}
+		codes[i] = compiled.Code
+	})
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_, _ = w.Write([]byte(html))
-	}), nil
+	out := make(map[string]string, len(paths))
+	for i, path := range paths {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		out[path] = codes[i]
+	}
+	return out, nil
 }
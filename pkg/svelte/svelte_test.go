@@ -1,9 +1,24 @@
 package svelte_test
 
 import (
+	"bufio"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/housecat-inc/do/pkg/svelte"
 	"github.com/stretchr/testify/assert"
@@ -56,7 +71,7 @@ func TestCheck(t *testing.T) {
 
 	for _, ts := range tests {
 		t.Run(ts.name, func(t *testing.T) {
-			diags, err := svelte.Check(ts.src, ts.name+".svelte")
+			diags, err := svelte.Check(ts.src, ts.name+".svelte", svelte.CheckOptions{})
 
 			if ts.wantErrors {
 				hasError := err != nil
@@ -101,10 +116,1047 @@ func TestCheckDir(t *testing.T) {
 	err = os.WriteFile(filepath.Join(tmpDir, "Bad.svelte"), []byte(`<img src="x.png">`), 0644)
 	r.NoError(err)
 
-	diags, err := svelte.CheckDir(tmpDir)
+	diags, err := svelte.CheckDir(tmpDir, svelte.CheckOptions{})
 	r.NoError(err)
 	a.Len(diags, 1)
 	a.Equal("warning", diags[0].Type)
 	a.Equal("a11y_missing_attribute", diags[0].Code)
 	a.Contains(diags[0].Filename, "Bad.svelte")
+
+	diags, err = svelte.CheckDir(tmpDir, svelte.CheckOptions{Errors: []string{"a11y_*"}})
+	r.NoError(err)
+	r.Len(diags, 1)
+	a.Equal("error", diags[0].Type)
+
+	diags, err = svelte.CheckDir(tmpDir, svelte.CheckOptions{Ignore: []string{"a11y_missing_attribute"}})
+	r.NoError(err)
+	a.Empty(diags)
+}
+
+func TestBuild(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	tmpDir := t.TempDir()
+	t.Chdir(tmpDir)
+	r.NoError(os.MkdirAll(filepath.Join("src", "forms"), 0755))
+	r.NoError(os.WriteFile(filepath.Join("src", "forms", "Button.svelte"), []byte(`<button>Click</button>`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("src", "Hello.svelte"), []byte(`<h1>Hello</h1>`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"src"}, svelte.BuildOptions{})
+	r.NoError(err)
+	a.Equal([]svelte.Component{
+		{Export: "src/Hello", Path: filepath.Join("src", "Hello.svelte")},
+		{Export: "src/forms/Button", Path: filepath.Join("src", "forms", "Button.svelte")},
+	}, manifest.Components)
+	a.Equal([]string{svelte.DefaultOutfile}, manifest.Files)
+
+	data, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.Contains(string(data), "src/forms/Button")
+	a.NoFileExists(filepath.Join("dist", svelte.DefaultOutfile))
+}
+
+func TestBuildImports(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.MkdirAll(filepath.Join("src", "lib"), 0755))
+	r.NoError(os.WriteFile(filepath.Join("src", "lib", "Child.svelte"), []byte(`<script>import { greeting } from './greeting.js';</script><p>{greeting} from child</p>`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("src", "lib", "greeting.js"), []byte(`export const greeting = 'hi';`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("src", "Page.svelte"), []byte(`<script>import Child from './lib/Child.svelte';</script><Child />`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"src"}, svelte.BuildOptions{})
+	r.NoError(err)
+	a.Len(manifest.Components, 2)
+
+	data, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.Contains(string(data), " from child")
+	a.Contains(string(data), `"hi"`)
+	a.NotContains(string(data), "Child.svelte")
+
+	r.NoError(os.WriteFile(filepath.Join("src", "Page.svelte"), []byte(`<script>import Missing from './Missing.svelte';</script><Missing />`), 0644))
+	_, _, err = svelte.Build([]string{"src"}, svelte.BuildOptions{})
+	a.ErrorContains(err, "Missing.svelte")
+}
+
+func TestTypeScript(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	src := `<script lang="ts">
+	import { onMount } from 'svelte';
+	enum Color { Red = 'red', Blue = 'blue' }
+	interface Props { label: string }
+	let { label }: Props = $props();
+	let color: Color = $state(Color.Red);
+	onMount(() => {});
+</script>
+<button style:color={color} onclick={() => (color = Color.Blue)}>{label}</button>`
+
+	code, err := svelte.Compile(src)
+	r.NoError(err)
+	a.Contains(code, `"blue"`)
+	a.Contains(code, "onMount")
+	a.NotContains(code, "interface")
+
+	diags, err := svelte.Check(src, "Button.svelte", svelte.CheckOptions{})
+	r.NoError(err)
+	a.Empty(diags)
+
+	diags, err = svelte.Check(`<script lang="ts">let x: = 1;</script>`, "Bad.svelte", svelte.CheckOptions{})
+	r.NoError(err)
+	r.Len(diags, 1)
+	a.Equal("error", diags[0].Type)
+}
+
+func TestCompileSSR(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	mod, err := svelte.CompileSSR(`<script>let { name } = $props();</script>
+<h1>Hello {name}</h1>
+<style>h1 { color: red; }</style>`)
+	r.NoError(err)
+
+	a.Contains(mod.Code, "svelte/internal/server")
+	a.Contains(mod.Code, "$$renderer")
+	a.Contains(mod.CSS, "h1.svelte-")
+	a.NotContains(mod.Code, "color: red")
+}
+
+func TestCompileConcurrent(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	want, err := svelte.Compile(`<p>same</p>`)
+	r.NoError(err)
+
+	var wg sync.WaitGroup
+	codes := make([]string, 8)
+	for i := range codes {
+		wg.Go(func() {
+			codes[i], _ = svelte.Compile(`<p>same</p>`)
+		})
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		a.Equal(want, code)
+	}
+	_, err = svelte.Compile(`<p>{</p>`)
+	a.Error(err)
+	code, err := svelte.Compile(`<p>same</p>`)
+	r.NoError(err)
+	a.Equal(want, code)
+}
+
+func TestCompileCache(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	svelte.CacheDir = t.TempDir()
+	t.Cleanup(func() { svelte.CacheDir = "" })
+
+	src := `<p>cached</p>`
+	code, err := svelte.Compile(src)
+	r.NoError(err)
+
+	var entries []string
+	r.NoError(filepath.WalkDir(svelte.CacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			entries = append(entries, path)
+		}
+		return err
+	}))
+	r.Len(entries, 1)
+	r.NoError(os.WriteFile(entries[0], []byte(`{"code": "from cache"}`), 0644))
+
+	code2, err := svelte.Compile(src)
+	r.NoError(err)
+	a.Equal("from cache", code2)
+
+	code3, err := svelte.Compile(src + " ")
+	r.NoError(err)
+	a.NotEqual("from cache", code3)
+	a.Contains(code, "cached")
+
+	_, err = svelte.Compile(`<p>{</p>`)
+	a.Error(err)
+}
+
+func TestBuildSourcemap(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.MkdirAll("src", 0755))
+	r.NoError(os.WriteFile(filepath.Join("src", "Hello.svelte"), []byte(`<script>let name = $state('world');</script>
+<h1>Hello {name}</h1>`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"src"}, svelte.BuildOptions{Sourcemap: true})
+	r.NoError(err)
+	a.ElementsMatch([]string{svelte.DefaultOutfile, svelte.DefaultOutfile + ".map"}, manifest.Files)
+
+	data, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.Contains(string(data), "//# sourceMappingURL="+svelte.DefaultOutfile+".map")
+
+	data, err = fs.ReadFile(out, svelte.DefaultOutfile+".map")
+	r.NoError(err)
+	a.Contains(string(data), `"src/Hello.svelte"`)
+	a.Contains(string(data), "$state('world')")
+}
+
+func TestBuildExtractCSS(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.MkdirAll("src", 0755))
+	r.NoError(os.WriteFile(filepath.Join("src", "Child.svelte"), []byte(`<p>child</p><style>p { color: blue; }</style>`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("src", "Page.svelte"), []byte(`<script>import Child from './Child.svelte';</script>
+<h1>Page</h1><Child /><style>h1 { color: red; }</style>`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"src"}, svelte.BuildOptions{ExtractCSS: true})
+	r.NoError(err)
+	a.Equal("app.min.css", manifest.CSS)
+	a.Len(manifest.CSSHash, 12)
+	a.ElementsMatch([]string{svelte.DefaultOutfile, "app.min.css"}, manifest.Files)
+
+	css, err := fs.ReadFile(out, "app.min.css")
+	r.NoError(err)
+	a.Contains(string(css), "color:red")
+	a.Contains(string(css), "color:#00f")
+
+	js, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.NotContains(string(js), "color:red")
+	a.NotContains(string(js), "color: red")
+}
+
+func TestPreprocessors(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	prev := svelte.Preprocessors
+	t.Cleanup(func() { svelte.Preprocessors = prev })
+	svelte.Preprocessors = append(slices.Clone(prev), svelte.Preprocessor{
+		Lang: "vars",
+		Process: func(contents, filename string) (string, error) {
+			return strings.ReplaceAll(contents, "$accent", "rebeccapurple"), nil
+		},
+		Tag: "style",
+	})
+
+	code, err := svelte.Compile(`<p>hi</p><style lang="vars">p { color: $accent; }</style>`)
+	r.NoError(err)
+	a.Contains(code, "rebeccapurple")
+
+	diags, err := svelte.Check(`<p>hi</p><style lang="vars">p { color: $accent; }</style>`, "P.svelte", svelte.CheckOptions{})
+	r.NoError(err)
+	a.Empty(diags)
+
+	t.Setenv("PATH", t.TempDir())
+	_, err = svelte.Compile(`<p>hi</p><style lang="scss">p { color: red; }</style>`)
+	a.ErrorContains(err, "sass is required")
+}
+
+func TestUseVersion(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	prevDir, prevVersion := svelte.CompilerDir, svelte.CurrentVersion()
+	svelte.CompilerDir = t.TempDir()
+	t.Cleanup(func() {
+		svelte.CompilerDir = prevDir
+		r.NoError(svelte.UseVersion(prevVersion))
+	})
+
+	a.Error(svelte.UseVersion("../5.0.0"))
+	a.Equal(svelte.Version, svelte.CurrentVersion())
+
+	// A compiler already in CompilerDir is used without downloading.
+	js, err := os.ReadFile("compiler.min.js")
+	r.NoError(err)
+	r.NoError(os.WriteFile(filepath.Join(svelte.CompilerDir, "compiler-5.0.0.js"), js, 0644))
+	r.NoError(svelte.UseVersion("5.0.0"))
+	a.Equal("5.0.0", svelte.CurrentVersion())
+
+	code, err := svelte.Compile(`<p>pinned</p>`)
+	r.NoError(err)
+	a.Contains(code, "pinned")
+
+	h, err := svelte.Handler(`<p>pinned</p>`)
+	r.NoError(err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	a.Contains(rec.Body.String(), "https://esm.sh/svelte@5.0.0")
+}
+
+func TestBundleRuntime(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	prevDir := svelte.CompilerDir
+	svelte.CompilerDir = t.TempDir()
+	t.Cleanup(func() { svelte.CompilerDir = prevDir })
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.Path)
+		switch req.URL.Path {
+		case "/svelte@" + svelte.Version:
+			_, _ = w.Write([]byte(`export * from "/svelte@` + svelte.Version + `/es2022/svelte.mjs";`))
+		case "/svelte@" + svelte.Version + "/es2022/svelte.mjs":
+			_, _ = w.Write([]byte(`export function mount() { console.log("vendored mount") }`))
+		case "/svelte@" + svelte.Version + "/internal/client", "/svelte@" + svelte.Version + "/internal/disclose-version":
+			_, _ = w.Write([]byte(`export const vendored = "vendored runtime";`))
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	prev := svelte.RuntimeURL
+	svelte.RuntimeURL = srv.URL
+	t.Cleanup(func() { svelte.RuntimeURL = prev })
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "App.svelte"), []byte(`<p>hi</p>`), 0644))
+	t.Chdir(dir)
+
+	out, _, err := svelte.Build([]string{"."}, svelte.BuildOptions{BundleRuntime: true})
+	r.NoError(err)
+	js, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.NotContains(string(js), `from"svelte`)
+	a.NotContains(string(js), srv.URL)
+
+	prevBundle := svelte.BundleRuntime
+	svelte.BundleRuntime = true
+	t.Cleanup(func() { svelte.BundleRuntime = prevBundle })
+	h, err := svelte.Handler(`<p>hi</p>`)
+	r.NoError(err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	a.Contains(rec.Body.String(), "vendored mount")
+	a.NotContains(rec.Body.String(), "importmap")
+
+	// Modules are fetched once and then read from CompilerDir.
+	requests = nil
+	_, _, err = svelte.Build([]string{"."}, svelte.BuildOptions{BundleRuntime: true})
+	r.NoError(err)
+	a.Empty(requests)
+}
+
+func TestHandlerOptions(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	h, err := svelte.Handler(`<script>let { name } = $props();</script><p>{name}</p>`,
+		svelte.WithHead(`<link rel="stylesheet" href="/app.css">`),
+		svelte.WithProps(map[string]string{"name": "</script>"}),
+		svelte.WithTarget("root"),
+		svelte.WithTitle("Hello & welcome"),
+	)
+	r.NoError(err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	body := rec.Body.String()
+	a.Contains(body, "<title>Hello &amp; welcome</title>")
+	a.Contains(body, `<link rel="stylesheet" href="/app.css">`)
+	a.Contains(body, `<div id="root"></div>`)
+	a.Contains(body, `document.getElementById("root"), props: {"name":"\u003c/script\u003e"}`)
+	a.Contains(body, "importmap")
+
+	tmpl := template.Must(template.New("custom").Parse(`<main id="{{.Target}}"></main><script type="module">{{.Script}}</script>`))
+	h, err = svelte.Handler(`<p>custom</p>`, svelte.WithTemplate(tmpl))
+	r.NoError(err)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	a.True(strings.HasPrefix(rec.Body.String(), `<main id="app"></main><script type="module">`))
+	a.Contains(rec.Body.String(), "custom")
+
+	_, err = svelte.Handler(`<p>hi</p>`, svelte.WithProps(func() {}))
+	a.Error(err)
+}
+
+func TestRegistry(t *testing.T) {
+	a := assert.New(t)
+	fsys := fstest.MapFS{
+		"App.svelte":              {Data: []byte(`<script>import Button from './forms/Button.svelte';</script><Button />`)},
+		"forms/Button.svelte":     {Data: []byte(`<button>click</button>`)},
+		"node_modules/x/X.svelte": {Data: []byte(`<p>skipped</p>`)},
+	}
+	reg := svelte.NewRegistry(fsys)
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/components/index.js", "")
+	a.Equal(http.StatusOK, rec.Code)
+	a.Equal(`export default {
+  "App": () => import("./App.js"),
+  "forms/Button": () => import("./forms/Button.js"),
+}
+`, rec.Body.String())
+
+	rec = get("/components/App.js", "")
+	a.Equal(http.StatusOK, rec.Code)
+	a.Equal("text/javascript; charset=utf-8", rec.Header().Get("Content-Type"))
+	a.Contains(rec.Body.String(), `from './forms/Button.js'`)
+	etag := rec.Header().Get("ETag")
+	a.NotEmpty(etag)
+	a.Equal(http.StatusNotModified, get("/components/App.js", etag).Code)
+
+	// Edits are picked up on the next request.
+	fsys["forms/Button.svelte"] = &fstest.MapFile{Data: []byte(`<button>edited</button>`)}
+	a.Contains(get("/components/forms/Button.js", "").Body.String(), "edited")
+
+	a.Equal(http.StatusNotFound, get("/components/Missing.js", "").Code)
+	a.Equal(http.StatusNotFound, get("/other/App.js", "").Code)
+
+	fsys["Broken.svelte"] = &fstest.MapFile{Data: []byte(`<p>{</p>`)}
+	a.Equal(http.StatusInternalServerError, get("/components/Broken.js", "").Code)
+}
+
+func TestReports(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	diags := []svelte.Diagnostic{
+		{Code: "a11y_missing_attribute", Filename: "/repo/src/Bad.svelte", Message: "missing alt", Start: &svelte.Position{Column: 0, Line: 3}, End: &svelte.Position{Column: 12, Line: 3}, Type: "warning"},
+		{Code: "check_error", Filename: "/repo/src/Broken.svelte", Message: "unexpected token", Type: "error"},
+	}
+
+	var buf strings.Builder
+	r.NoError(svelte.WriteJSON(&buf, diags))
+	var report svelte.Report
+	r.NoError(json.Unmarshal([]byte(buf.String()), &report))
+	a.Equal(1, report.Errors)
+	a.Equal(1, report.Warnings)
+	a.Equal(2, report.Files)
+	a.Len(report.Diagnostics, 2)
+
+	buf.Reset()
+	r.NoError(svelte.WriteSARIF(&buf, diags, "/repo"))
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartColumn int `json:"startColumn"`
+							StartLine   int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				RuleID string `json:"ruleId"`
+			} `json:"results"`
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+		} `json:"runs"`
+		Version string `json:"version"`
+	}
+	r.NoError(json.Unmarshal([]byte(buf.String()), &log))
+	a.Equal("2.1.0", log.Version)
+	r.Len(log.Runs, 1)
+	r.Len(log.Runs[0].Results, 2)
+	res := log.Runs[0].Results[0]
+	a.Equal("warning", res.Level)
+	a.Equal("a11y_missing_attribute", res.RuleID)
+	a.Equal("src/Bad.svelte", res.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	a.Equal(1, res.Locations[0].PhysicalLocation.Region.StartColumn)
+	a.Equal(3, res.Locations[0].PhysicalLocation.Region.StartLine)
+	a.Nil(log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region)
+	a.Len(log.Runs[0].Tool.Driver.Rules, 2)
+}
+
+func TestFS(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fsys := fstest.MapFS{
+		"components/Bad.svelte":  {Data: []byte(`<img src="a.png">`)},
+		"components/Good.svelte": {Data: []byte(`<p>good</p>`)},
+		".hidden/Skip.svelte":    {Data: []byte(`<img src="a.png">`)},
+	}
+
+	diags, err := svelte.CheckFS(fsys, svelte.CheckOptions{})
+	r.NoError(err)
+	r.Len(diags, 1)
+	a.Equal("components/Bad.svelte", diags[0].Filename)
+	a.Equal("a11y_missing_attribute", diags[0].Code)
+
+	codes, err := svelte.CompileFS(fsys)
+	r.NoError(err)
+	a.Len(codes, 2)
+	a.Contains(codes["components/Good.svelte"], "good")
+
+	fsys["components/Broken.svelte"] = &fstest.MapFile{Data: []byte(`<p>{</p>`)}
+	_, err = svelte.CompileFS(fsys)
+	a.ErrorContains(err, "components/Broken.svelte")
+}
+
+func TestCustomElement(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	code, err := svelte.CompileCustomElement(`<svelte:options customElement="my-widget" /><p>widget</p><style>p { color: red; }</style>`, "")
+	r.NoError(err)
+	a.Contains(code, "customElements.define('my-widget'")
+	a.Contains(code, "append_styles")
+
+	code, err = svelte.CompileCustomElement(`<p>tagged</p>`, "x-tagged")
+	r.NoError(err)
+	a.Contains(code, `customElements.define("x-tagged", Component.element)`)
+
+	_, err = svelte.CompileCustomElement(`<p>untagged</p>`, "")
+	a.ErrorContains(err, "invalid custom element name")
+	_, err = svelte.CompileCustomElement(`<p>untagged</p>`, "Widget")
+	a.Error(err)
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "Widget.svelte"), []byte(`<svelte:options customElement="my-widget" /><p>widget</p>`), 0644))
+	t.Chdir(dir)
+	out, _, err := svelte.Build([]string{"."}, svelte.BuildOptions{CustomElements: true, ExtractCSS: true})
+	r.NoError(err)
+	js, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.Contains(string(js), `customElements.define("my-widget"`)
+}
+
+func TestFixes(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	src := `<button role="button">é</button><p>x</p>
+<style>
+  .unused { color: red; }
+  p, .gone { color: blue; }
+  div > .child { color: green; }
+</style>
+`
+	diags, err := svelte.Check(src, "Fix.svelte", svelte.CheckOptions{})
+	r.NoError(err)
+	fixed := 0
+	for _, d := range diags {
+		if d.Fix != nil {
+			fixed++
+		}
+	}
+	a.Equal(4, fixed)
+	a.Equal(`<button>é</button><p>x</p>
+<style>
+  p { color: blue; }
+</style>
+`, svelte.ApplyFixes(src, diags))
+
+	// Offsets into preprocessed source would be wrong, so no fixes are suggested.
+	prev := svelte.Preprocessors
+	t.Cleanup(func() { svelte.Preprocessors = prev })
+	svelte.Preprocessors = append(slices.Clone(prev), svelte.Preprocessor{
+		Lang: "vars",
+		Process: func(contents, filename string) (string, error) {
+			return strings.ReplaceAll(contents, "$accent", "red"), nil
+		},
+		Tag: "style",
+	})
+	diags, err = svelte.Check(`<p>x</p><style lang="vars">.unused { color: $accent; }</style>`, "P.svelte", svelte.CheckOptions{})
+	r.NoError(err)
+	r.Len(diags, 1)
+	a.Nil(diags[0].Fix)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Fix.svelte")
+	r.NoError(os.WriteFile(path, []byte(src), 0644))
+	diags, err = svelte.CheckDir(dir, svelte.CheckOptions{Fix: true})
+	r.NoError(err)
+	a.Empty(diags)
+	data, err := os.ReadFile(path)
+	r.NoError(err)
+	a.NotContains(string(data), "unused")
+}
+
+func TestRuneModules(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	code, err := svelte.CompileModule(`export const counter = $state({ count: 0 });`, "counter.svelte.js")
+	r.NoError(err)
+	a.Contains(code, "$.proxy")
+	a.NotContains(code, "$state")
+
+	code, err = svelte.CompileModule(`export const total: { n: number } = $state({ n: 1 });`, "total.svelte.ts")
+	r.NoError(err)
+	a.NotContains(code, "number")
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.WriteFile("counter.svelte.ts", []byte(`export const counter: { count: number } = $state({ count: 0 });`), 0644))
+	r.NoError(os.WriteFile("Counter.svelte", []byte(`<script>import { counter } from './counter.svelte.ts';</script><button onclick={() => counter.count++}>{counter.count}</button>`), 0644))
+	out, manifest, err := svelte.Build([]string{"."}, svelte.BuildOptions{})
+	r.NoError(err)
+	a.Len(manifest.Components, 1)
+	data, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.NotContains(string(data), "$state")
+}
+
+func TestRegistryHMR(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	prev := svelte.HMRInterval
+	svelte.HMRInterval = 10 * time.Millisecond
+	t.Cleanup(func() { svelte.HMRInterval = prev })
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "App.svelte"), []byte(`<p>one</p>`), 0644))
+	reg := svelte.NewRegistry(os.DirFS(dir))
+	reg.HMR = true
+	srv := httptest.NewServer(reg)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/components/App.js")
+	r.NoError(err)
+	body, err := io.ReadAll(resp.Body)
+	r.NoError(err)
+	_ = resp.Body.Close()
+	a.Contains(string(body), `from "/components/@hmr.js"`)
+	a.Contains(string(body), "__hmr.accept")
+	a.NotContains(string(body), "import.meta.hot")
+
+	resp, err = http.Get(srv.URL + "/components/@hmr.js")
+	r.NoError(err)
+	_ = resp.Body.Close()
+	a.Equal(http.StatusOK, resp.StatusCode)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	r.NoError(err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_, err = conn.Write([]byte("GET /components/@hmr HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	r.NoError(err)
+	br := bufio.NewReader(conn)
+	handshake, err := http.ReadResponse(br, nil)
+	r.NoError(err)
+	a.Equal(http.StatusSwitchingProtocols, handshake.StatusCode)
+	a.Equal("s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", handshake.Header.Get("Sec-WebSocket-Accept"))
+
+	time.Sleep(50 * time.Millisecond)
+	r.NoError(os.WriteFile(filepath.Join(dir, "App.svelte"), []byte(`<p>two</p>`), 0644))
+	r.NoError(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	header := make([]byte, 2)
+	_, err = io.ReadFull(br, header)
+	r.NoError(err)
+	a.Equal(byte(0x81), header[0])
+	msg := make([]byte, header[1])
+	_, err = io.ReadFull(br, msg)
+	r.NoError(err)
+	a.JSONEq(`{"path": "/components/App.js"}`, string(msg))
+}
+
+func TestLimits(t *testing.T) {
+	a := assert.New(t)
+	prevLimit, prevTimeout := svelte.MemoryLimit, svelte.Timeout
+	t.Cleanup(func() { svelte.MemoryLimit, svelte.Timeout = prevLimit, prevTimeout })
+
+	svelte.Timeout = time.Nanosecond
+	_, err := svelte.Compile(`<p>timeout</p>`)
+	a.ErrorIs(err, svelte.ErrTimeout)
+	svelte.Timeout = prevTimeout
+
+	svelte.MemoryLimit = 1 << 20
+	_, err = svelte.Check(`<p>memory</p>`, "Memory.svelte", svelte.CheckOptions{})
+	a.ErrorIs(err, svelte.ErrMemoryLimit)
+	svelte.MemoryLimit = prevLimit
+
+	_, err = svelte.Compile(`<p>recovered</p>`)
+	a.NoError(err)
+}
+
+func BenchmarkCompile(b *testing.B) {
+	src := `<script>let { items } = $props(); let count = $state(0);</script>
+{#each items as item}<button onclick={() => count++}>{item} {count}</button>{/each}
+<style>button { color: red; }</style>`
+	for b.Loop() {
+		if _, err := svelte.Compile(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	src := `<img src="a.png"><p>{1 + 1}</p>`
+	for b.Loop() {
+		if _, err := svelte.Check(src, "Bench.svelte", svelte.CheckOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBuildSplitting(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.MkdirAll("lib", 0755))
+	r.NoError(os.WriteFile(filepath.Join("lib", "shared.js"), []byte(`export const shared = () => 'shared helper';`), 0644))
+	r.NoError(os.WriteFile("One.svelte", []byte(`<script>import { shared } from './lib/shared.js';</script><p>{shared()} one</p>`), 0644))
+	r.NoError(os.WriteFile("Two.svelte", []byte(`<script>import { shared } from './lib/shared.js';</script><p>{shared()} two</p>`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"."}, svelte.BuildOptions{Splitting: true})
+	r.NoError(err)
+	r.Len(manifest.Components, 2)
+
+	entry, err := fs.ReadFile(out, svelte.DefaultOutfile)
+	r.NoError(err)
+	a.NotContains(string(entry), " one")
+	for _, c := range manifest.Components {
+		a.True(strings.HasPrefix(c.Chunk, "chunks/"), c.Chunk)
+		a.Contains(string(entry), strings.TrimPrefix(c.Chunk, "chunks/"))
+		chunk, err := fs.ReadFile(out, c.Chunk)
+		r.NoError(err)
+		a.NotContains(string(chunk), "shared helper")
+	}
+
+	var shared int
+	for _, f := range manifest.Files {
+		data, err := fs.ReadFile(out, f)
+		r.NoError(err)
+		if strings.Contains(string(data), "shared helper") {
+			shared++
+		}
+	}
+	a.Equal(1, shared)
+}
+
+func TestBuildEntries(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.MkdirAll(filepath.Join("admin", "users"), 0755))
+	r.NoError(os.MkdirAll("site", 0755))
+	r.NoError(os.WriteFile(filepath.Join("admin", "Dashboard.svelte"), []byte(`<p>dashboard</p>`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("admin", "users", "List.svelte"), []byte(`<p>list</p>`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("site", "Home.svelte"), []byte(`<p>home</p>`), 0644))
+
+	_, manifest, err := svelte.Build([]string{"admin", "admin/*.svelte"}, svelte.BuildOptions{Outfile: "admin.min.js", StripPrefix: "admin/"})
+	r.NoError(err)
+	var exports []string
+	for _, c := range manifest.Components {
+		exports = append(exports, c.Export)
+	}
+	a.Equal([]string{"Dashboard", "users/List"}, exports)
+	a.Equal([]string{"admin.min.js"}, manifest.Files)
+
+	_, manifest, err = svelte.Build([]string{"site/Home.svelte"}, svelte.BuildOptions{})
+	r.NoError(err)
+	r.Len(manifest.Components, 1)
+	a.Equal("site/Home", manifest.Components[0].Export)
+
+	_, _, err = svelte.Build([]string{"missing"}, svelte.BuildOptions{})
+	a.ErrorContains(err, "no components match missing")
+}
+
+func TestBuildNodeModules(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		"node_modules/fmt-date/package.json": `{"name": "fmt-date", "main": "index.js"}`,
+		"node_modules/fmt-date/index.js":     `export function format(d) { return "formatted:" + d }`,
+		"node_modules/@ui/kit/package.json":  `{"name": "@ui/kit", "exports": {".": {"svelte": "./Badge.svelte", "default": "./index.js"}}}`,
+		"node_modules/@ui/kit/Badge.svelte":  `<span class="badge">badge</span><style>.badge { color: red }</style>`,
+		"node_modules/@ui/kit/index.js":      `throw new Error("not the svelte entry")`,
+		"src/App.svelte":                     `<script>import { format } from 'fmt-date'; import Badge from '@ui/kit'</script><p>{format(1)}</p><Badge />`,
+	}
+	for name, src := range files {
+		r.NoError(os.MkdirAll(filepath.Dir(name), 0755))
+		r.NoError(os.WriteFile(name, []byte(src), 0644))
+	}
+
+	out, manifest, err := svelte.Build([]string{"src"}, svelte.BuildOptions{ExtractCSS: true})
+	r.NoError(err)
+	r.Len(manifest.Components, 1)
+	bundle, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Contains(string(bundle), "formatted:")
+	a.Contains(string(bundle), "badge")
+	a.NotContains(string(bundle), "not the svelte entry")
+	css, err := fs.ReadFile(out, "app.min.css")
+	r.NoError(err)
+	a.Contains(string(css), "red")
+
+	r.NoError(os.WriteFile("src/App.svelte", []byte(`<script>import { parse } from '@scope/missing/sub'</script>{parse()}`), 0644))
+	_, _, err = svelte.Build([]string{"src"}, svelte.BuildOptions{})
+	a.ErrorContains(err, "npm install @scope/missing")
+}
+
+func TestBuildScripts(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.MkdirAll("src", 0755))
+	r.NoError(os.WriteFile(filepath.Join("src", "Counter.svelte"), []byte(`<p>counter</p>`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("src", "main.ts"), []byte(`import { mount } from 'svelte'
+import Counter from './Counter.svelte'
+
+const target: HTMLElement | null = document.getElementById('counter')
+if (target) mount(Counter, { target })
+`), 0644))
+	r.NoError(os.WriteFile(filepath.Join("src", "types.d.ts"), []byte(`declare const x: number`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"src/*"}, svelte.BuildOptions{})
+	r.NoError(err)
+	a.Equal([]string{filepath.Join("src", "main.ts")}, manifest.Scripts)
+	r.Len(manifest.Components, 1)
+	bundle, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Contains(string(bundle), `getElementById("counter")`)
+	a.NotContains(string(bundle), "HTMLElement")
+
+	_, manifest, err = svelte.Build([]string{"src/main.ts"}, svelte.BuildOptions{})
+	r.NoError(err)
+	a.Len(manifest.Scripts, 1)
+	a.Empty(manifest.Components)
+}
+
+func TestBuildDev(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.WriteFile("Counter.svelte", []byte(`<script>let count = $state(0);</script><button onclick={() => count++}>{count}</button>`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"."}, svelte.BuildOptions{Dev: true})
+	r.NoError(err)
+	a.Equal([]string{"app.min.js"}, manifest.Files)
+	bundle, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Contains(string(bundle), "function Component($$anchor, $$props)")
+	a.Contains(string(bundle), "sourceMappingURL=data:application/json;base64,")
+	a.Contains(string(bundle), "Counter.svelte")
+	a.Contains(string(bundle), "add_locations")
+
+	out, _, err = svelte.Build([]string{"."}, svelte.BuildOptions{})
+	r.NoError(err)
+	bundle, err = fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.NotContains(string(bundle), "add_locations")
+}
+
+func TestBuildAliases(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		"src/lib/Button.svelte":             `<button>aliased button</button>`,
+		"src/lib/format.ts":                 `export const format = (n: number) => "aliased format " + n`,
+		"src/components/forms/Field.svelte": `<input placeholder="aliased field" />`,
+		"src/routes/Page.svelte": `<script>
+	import Button from '$lib/Button.svelte'
+	import Field from '@components/forms/Field.svelte'
+	import { format } from '$lib/format'
+</script>
+<Button /><Field /><p>{format(1)}</p>`,
+	}
+	for name, src := range files {
+		r.NoError(os.MkdirAll(filepath.Dir(name), 0755))
+		r.NoError(os.WriteFile(name, []byte(src), 0644))
+	}
+
+	aliases := map[string]string{"$lib": "src/lib", "@components/*": "src/components/*"}
+	out, _, err := svelte.Build([]string{"src/routes"}, svelte.BuildOptions{Aliases: aliases})
+	r.NoError(err)
+	bundle, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Contains(string(bundle), "aliased button")
+	a.Contains(string(bundle), "aliased field")
+	a.Contains(string(bundle), "aliased format")
+
+	r.NoError(os.WriteFile("src/routes/Page.svelte", []byte(`<script>import Missing from '$lib/Missing.svelte'</script><Missing />`), 0644))
+	_, _, err = svelte.Build([]string{"src/routes"}, svelte.BuildOptions{Aliases: aliases})
+	a.ErrorContains(err, filepath.Join("src", "lib", "Missing.svelte"))
+}
+
+func TestBuildIncremental(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+	svelte.CacheDir = t.TempDir()
+	t.Cleanup(func() { svelte.CacheDir = "" })
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.WriteFile("Page.svelte", []byte(`<script>import { title } from './title.js'</script><h1>{title}</h1>`), 0644))
+	r.NoError(os.WriteFile("title.js", []byte(`export const title = 'first title'`), 0644))
+
+	_, manifest, err := svelte.Build([]string{"."}, svelte.BuildOptions{})
+	r.NoError(err)
+
+	builds, err := filepath.Glob(filepath.Join(svelte.CacheDir, "builds", "*.json"))
+	r.NoError(err)
+	r.Len(builds, 1)
+	data, err := os.ReadFile(builds[0])
+	r.NoError(err)
+	var prev map[string]any
+	r.NoError(json.Unmarshal(data, &prev))
+	prev["files"] = map[string][]byte{"app.min.js": []byte("from previous build")}
+	data, err = json.Marshal(prev)
+	r.NoError(err)
+	r.NoError(os.WriteFile(builds[0], data, 0644))
+
+	out, reused, err := svelte.Build([]string{"."}, svelte.BuildOptions{})
+	r.NoError(err)
+	a.Equal(manifest, reused)
+	bundle, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Equal("from previous build", string(bundle))
+
+	r.NoError(os.WriteFile("title.js", []byte(`export const title = 'second title'`), 0644))
+	out, _, err = svelte.Build([]string{"."}, svelte.BuildOptions{})
+	r.NoError(err)
+	bundle, err = fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Contains(string(bundle), "second title")
+
+	out, _, err = svelte.Build([]string{"."}, svelte.BuildOptions{Sourcemap: true})
+	r.NoError(err)
+	_, err = fs.ReadFile(out, "app.min.js.map")
+	a.NoError(err)
+}
+
+func TestBuildLegacy(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`export const vendored = "vendored runtime";`))
+	}))
+	t.Cleanup(srv.Close)
+	prevURL, prevDir := svelte.RuntimeURL, svelte.CompilerDir
+	svelte.RuntimeURL, svelte.CompilerDir = srv.URL, t.TempDir()
+	t.Cleanup(func() { svelte.RuntimeURL, svelte.CompilerDir = prevURL, prevDir })
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.WriteFile("Widget.svelte", []byte(`<p class="widget">widget</p><style>.widget { color: red }</style>`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"."}, svelte.BuildOptions{ExtractCSS: true, Legacy: true, Splitting: true, GlobalName: "Widgets"})
+	r.NoError(err)
+	a.Equal("app.legacy.min.js", manifest.Legacy)
+	a.Contains(manifest.HTML, `<script type="module" src="app.min.js" integrity="`+manifest.Integrity["app.min.js"]+`"></script>`)
+	a.Contains(manifest.HTML, `<script nomodule src="app.legacy.min.js" integrity="`+manifest.Integrity["app.legacy.min.js"]+`"></script>`)
+	a.Equal("app.min.css", manifest.CSS)
+	a.NotContains(manifest.Files, "app.legacy.min.css")
+
+	legacy, err := fs.ReadFile(out, "app.legacy.min.js")
+	r.NoError(err)
+	a.True(strings.HasPrefix(string(legacy), "var Widgets=(()=>{"), string(legacy))
+	a.Contains(string(legacy), "Widgets=Widgets.default;")
+	a.NotContains(string(legacy), "svelte/internal")
+	a.NotContains(string(legacy), "import(")
+	a.NotContains(string(legacy), "export")
+
+	module, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Contains(string(module), "import(")
+
+	a.Equal("site.legacy.js", svelte.LegacyOutfile("site.js"))
+}
+
+func TestBuildDefine(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.WriteFile("Api.svelte", []byte(`<script>const url = import.meta.env.PUBLIC_API_URL</script><a href={url}>api</a>`), 0644))
+	r.NoError(os.WriteFile("main.js", []byte(`console.log(import.meta.env.PUBLIC_API_URL, import.meta.env.PUBLIC_QUOTE)`), 0644))
+
+	out, _, err := svelte.Build([]string{".", "main.js"}, svelte.BuildOptions{Define: map[string]string{
+		"PUBLIC_API_URL": "https://api.example.com",
+		"PUBLIC_QUOTE":   `say "hi"`,
+	}})
+	r.NoError(err)
+	bundle, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	a.Contains(string(bundle), `"https://api.example.com"`)
+	a.Contains(string(bundle), `'say "hi"'`)
+	a.NotContains(string(bundle), "import.meta.env")
+
+	_, _, err = svelte.Build([]string{"."}, svelte.BuildOptions{Define: map[string]string{"API-URL": "x"}})
+	a.ErrorContains(err, `invalid define "API-URL"`)
+}
+
+func TestBuildExclude(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	for _, name := range []string{"src/Button.svelte", "src/Button.stories.svelte", "src/forms/Field.svelte", "src/testdata/Fixture.svelte"} {
+		r.NoError(os.MkdirAll(filepath.Dir(name), 0755))
+		r.NoError(os.WriteFile(name, []byte(`<p>component</p>`), 0644))
+	}
+	exports := func(opts svelte.BuildOptions) []string {
+		_, manifest, err := svelte.Build([]string{"src"}, opts)
+		r.NoError(err)
+		var names []string
+		for _, c := range manifest.Components {
+			names = append(names, c.Export)
+		}
+		return names
+	}
+
+	a.Equal([]string{"src/Button", "src/forms/Field"}, exports(svelte.BuildOptions{Exclude: []string{"*.stories.svelte", "testdata/"}}))
+	a.Equal([]string{"src/forms/Field"}, exports(svelte.BuildOptions{Include: []string{"forms/"}}))
+	a.Equal([]string{"src/Button.stories"}, exports(svelte.BuildOptions{Include: []string{"src/*.svelte"}, Exclude: []string{"src/Button.svelte"}}))
+
+	_, _, err := svelte.Build([]string{"src"}, svelte.BuildOptions{Exclude: []string{"["}})
+	a.ErrorContains(err, `bad pattern "["`)
+
+	ok, err := svelte.MatchPath("testdata/", "testdata/Fixture.svelte")
+	r.NoError(err)
+	a.True(ok)
+	ok, err = svelte.MatchPath("testdata/", "src/testdata.svelte")
+	r.NoError(err)
+	a.False(ok)
+}
+
+func TestBuildIntegrity(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	t.Chdir(t.TempDir())
+	r.NoError(os.WriteFile("Widget.svelte", []byte(`<p class="widget">widget</p><style>.widget { color: red }</style>`), 0644))
+
+	out, manifest, err := svelte.Build([]string{"."}, svelte.BuildOptions{ExtractCSS: true, BaseURL: "https://cdn.example.com/assets/"})
+	r.NoError(err)
+
+	bundle, err := fs.ReadFile(out, "app.min.js")
+	r.NoError(err)
+	sum := sha512.Sum384(bundle)
+	integrity := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	a.Equal(integrity, manifest.Integrity["app.min.js"])
+	a.Contains(manifest.Integrity, "app.min.css")
+	a.Equal([]string{"app.min.js"}, manifest.Preload)
+
+	a.Equal(`<link rel="stylesheet" href="https://cdn.example.com/assets/app.min.css" integrity="`+manifest.Integrity["app.min.css"]+`" crossorigin="anonymous">
+<script type="module" src="https://cdn.example.com/assets/app.min.js" integrity="`+integrity+`" crossorigin="anonymous"></script>
+`, manifest.HTML)
+	a.Equal("<https://cdn.example.com/assets/app.min.css>; rel=preload; as=style, <https://cdn.example.com/assets/app.min.js>; rel=modulepreload", manifest.Link())
+
+	_, manifest, err = svelte.Build([]string{"."}, svelte.BuildOptions{})
+	r.NoError(err)
+	a.NotContains(manifest.HTML, "crossorigin")
 }
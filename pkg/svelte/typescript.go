@@ -0,0 +1,72 @@
+package svelte
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// scriptTag matches a <script> element, capturing its attributes and contents.
+var scriptTag = regexp.MustCompile(`(?s)(<script\b)([^>]*)(>)(.*?)(</script>)`)
+
+// langTS matches a lang="ts" attribute in any quoting style.
+var langTS = regexp.MustCompile(`\blang\s*=\s*["']?(ts|typescript)\b["']?`)
+
+// tsconfig keeps imports that are only used in the markup, which esbuild would
+// otherwise drop as unused. Type-only imports must use `import type`.
+const tsconfig = `{"compilerOptions":{"verbatimModuleSyntax":true}}`
+
+// stripTypes transpiles <script lang="ts"> blocks to JavaScript with esbuild. The
+// Svelte compiler strips plain type annotations itself but rejects TypeScript that
+// emits code, such as enums and constructor parameter properties.
+func stripTypes(src string) (string, error) {
+	var tsErr error
+	out := scriptTag.ReplaceAllStringFunc(src, func(tag string) string {
+		m := scriptTag.FindStringSubmatch(tag)
+		if tsErr != nil || !langTS.MatchString(m[2]) {
+			return tag
+		}
+		js, err := transpileTS(m[4])
+		if err != nil {
+			tsErr = err
+			return tag
+		}
+		return m[1] + m[2] + m[3] + "\n" + js + m[5]
+	})
+	if tsErr != nil {
+		return "", tsErr
+	}
+	return out, nil
+}
+
+// transpileTS converts TypeScript to JavaScript with esbuild.
+func transpileTS(src string) (string, error) {
+	result := api.Transform(src, api.TransformOptions{
+		Loader:      api.LoaderTS,
+		Target:      api.ESNext,
+		TsconfigRaw: tsconfig,
+	})
+	if len(result.Errors) > 0 {
+		msgs := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			msgs[i] = e.Text
+			if e.Location != nil {
+				msgs[i] = e.Location.LineText + ": " + e.Text
+			}
+		}
+		return "", errors.Errorf("typescript: %s", strings.Join(msgs, "; "))
+	}
+	return string(result.Code), nil
+}
+
+// hasTypeScript reports whether src has a <script lang="ts"> block.
+func hasTypeScript(src string) bool {
+	for _, m := range scriptTag.FindAllStringSubmatch(src, -1) {
+		if langTS.MatchString(m[2]) {
+			return true
+		}
+	}
+	return false
+}
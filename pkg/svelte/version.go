@@ -0,0 +1,153 @@
+package svelte
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"modernc.org/quickjs"
+)
+
+// Version is the Svelte release of the embedded compiler.
+const Version = "5.46.1"
+
+// compilerURL serves the compiler bundle for a Svelte release, as fetched by go:generate.
+const compilerURL = "https://esm.sh/svelte@%s/compiler/index.js?raw"
+
+// CompilerDir holds compilers downloaded by UseVersion. Empty downloads them again
+// on every use.
+var CompilerDir = defaultCompilerDir()
+
+// versionPattern accepts release versions such as 5.46.1 or 5.0.0-next.1.
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`)
+
+// compiler is the active compiler bundle, its release, and a hash of it with
+// compile.js for cache keys.
+var compiler = struct {
+	sync.RWMutex
+	hash    string
+	js      string
+	version string
+}{js: compilerJS, version: Version}
+
+func defaultCompilerDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "do", "svelte")
+}
+
+// UseVersion switches Compile, Check, Build, and the Handler runtime to a Svelte
+// release, so projects can pin the version their components were written for. The
+// compiler is downloaded from esm.sh on first use and kept in CompilerDir. An empty
+// version selects the embedded compiler. Call it before compiling anything.
+func UseVersion(version string) error {
+	if version == "" || version == Version {
+		setCompiler(Version, compilerJS)
+		return nil
+	}
+	if !versionPattern.MatchString(version) {
+		return errors.Errorf("invalid Svelte version %q", version)
+	}
+
+	var path string
+	if CompilerDir != "" {
+		path = filepath.Join(CompilerDir, "compiler-"+version+".js")
+		if data, err := os.ReadFile(path); err == nil {
+			setCompiler(version, string(data))
+			return nil
+		}
+	}
+
+	js, err := downloadCompiler(version)
+	if err != nil {
+		return err
+	}
+	// Evaluate it once so a bad download fails here rather than on every compile.
+	prev := CurrentVersion()
+	setCompiler(version, js)
+	if err := withVM(func(*quickjs.VM) error { return nil }); err != nil {
+		_ = UseVersion(prev)
+		return errors.Wrapf(err, "load Svelte %s compiler", version)
+	}
+
+	if path != "" && os.MkdirAll(CompilerDir, 0o755) == nil {
+		_ = os.WriteFile(path, []byte(js), 0o644)
+	}
+	return nil
+}
+
+// CurrentVersion returns the Svelte release selected by UseVersion.
+func CurrentVersion() string {
+	compiler.RLock()
+	defer compiler.RUnlock()
+	return compiler.version
+}
+
+func downloadCompiler(version string) (string, error) {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(fmt.Sprintf(compilerURL, version))
+	if err != nil {
+		return "", errors.Wrapf(err, "download Svelte %s compiler", version)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("download Svelte %s compiler: %s", version, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "download Svelte %s compiler", version)
+	}
+	return string(data), nil
+}
+
+// setCompiler makes js the active compiler and closes pooled VMs that loaded the
+// previous one.
+func setCompiler(version, js string) {
+	compiler.Lock()
+	defer compiler.Unlock()
+	if compiler.version == version && compiler.js == js {
+		return
+	}
+	compiler.hash = ""
+	compiler.js = js
+	compiler.version = version
+	for {
+		select {
+		case vm := <-vms:
+			_ = vm.Close()
+		default:
+			return
+		}
+	}
+}
+
+// compilerSource returns the active compiler bundle.
+func compilerSource() string {
+	compiler.RLock()
+	defer compiler.RUnlock()
+	return compiler.js
+}
+
+// compilerHash identifies the active compiler, so switching or upgrading it
+// invalidates the cache.
+func compilerHash() string {
+	compiler.Lock()
+	defer compiler.Unlock()
+	if compiler.hash == "" {
+		h := sha256.New()
+		h.Write([]byte(compiler.js))
+		h.Write([]byte(compileJS))
+		compiler.hash = hex.EncodeToString(h.Sum(nil))
+	}
+	return compiler.hash
+}
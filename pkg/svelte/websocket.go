@@ -0,0 +1,123 @@
+package svelte
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// websocketGUID is appended to the client's key to prove the server speaks the
+// WebSocket protocol (RFC 6455).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocketConn is the server side of a WebSocket that only sends text messages.
+// Messages from the client are read and discarded so closes are noticed.
+type websocketConn struct {
+	closed chan struct{}
+	conn   net.Conn
+	mu     sync.Mutex
+	once   sync.Once
+}
+
+// upgradeWebsocket completes a WebSocket handshake on w.
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		return nil, errors.New("not a websocket request")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection does not support websockets")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	ws := &websocketConn{closed: make(chan struct{}), conn: conn}
+	go ws.discard(rw.Reader)
+	return ws, nil
+}
+
+// discard reads client frames until the connection fails or the client closes it.
+func (ws *websocketConn) discard(r *bufio.Reader) {
+	defer ws.Close()
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		if header[0]&0x0f == 0x8 {
+			return
+		}
+		n := uint64(header[1] & 0x7f)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		if header[1]&0x80 != 0 {
+			n += 4 // masking key
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+			return
+		}
+	}
+}
+
+// Send writes msg as a text frame.
+func (ws *websocketConn) Send(msg []byte) error {
+	frame := []byte{0x81}
+	switch n := len(msg); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	_, err := ws.conn.Write(append(frame, msg...))
+	return errors.WithStack(err)
+}
+
+// Close closes the connection. It is safe to call more than once.
+func (ws *websocketConn) Close() {
+	ws.once.Do(func() {
+		close(ws.closed)
+		_ = ws.conn.Close()
+	})
+}
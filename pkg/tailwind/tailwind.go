@@ -0,0 +1,211 @@
+// Package tailwind runs the standalone Tailwind CSS CLI, downloading the release a
+// project pins on first use, so apps get Tailwind without Node or a Makefile.
+package tailwind
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the Tailwind release used when Options.Version is empty.
+const Version = "4.1.13"
+
+// DefaultOutput is where Build writes the stylesheet when Options.Output is empty.
+const DefaultOutput = "dist/app.min.css"
+
+// ReleaseURL serves the standalone CLI binaries, as
+// ReleaseURL/v<version>/tailwindcss-<os>-<arch>.
+var ReleaseURL = "https://github.com/tailwindlabs/tailwindcss/releases/download"
+
+// Dir holds downloaded binaries and generated input stylesheets. Empty downloads
+// binaries again on every use.
+var Dir = defaultDir()
+
+// versionPattern accepts release versions such as 4.1.13 or 4.0.0-beta.1.
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`)
+
+// defaultInput imports Tailwind and scans the templates under a root for classes,
+// instead of everything Tailwind detects on its own, such as the output itself.
+const defaultInput = `@import "tailwindcss" source(none);
+@source %q;
+`
+
+// Options configures Build and Command.
+type Options struct {
+	// Input is the stylesheet to compile. Empty compiles Tailwind with the classes
+	// used in .html, .svelte, and .templ files under Root.
+	Input string
+	// Minify minifies Output. Build always minifies.
+	Minify bool
+	// Output defaults to DefaultOutput.
+	Output string
+	// Root is the directory scanned by the default input. Empty is the working
+	// directory.
+	Root    string
+	Version string
+	// Watch keeps Tailwind running, rebuilding Output whenever a template changes.
+	Watch bool
+}
+
+func defaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "do", "tailwind")
+}
+
+// Build compiles opts.Input into a minified opts.Output.
+func Build(ctx context.Context, opts Options) error {
+	opts.Minify = true
+	opts.Watch = false
+	cmd, err := Command(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("tailwindcss: %v\n%s", err, out)
+	}
+	return nil
+}
+
+// Command returns the tailwindcss command for opts, downloading the CLI if needed.
+func Command(ctx context.Context, opts Options) (*exec.Cmd, error) {
+	bin, err := Binary(opts.Version)
+	if err != nil {
+		return nil, err
+	}
+	input := opts.Input
+	if input == "" {
+		if input, err = writeDefaultInput(opts.Root); err != nil {
+			return nil, err
+		}
+	}
+	output := opts.Output
+	if output == "" {
+		output = DefaultOutput
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	args := []string{"--input", input, "--output", output}
+	if opts.Minify {
+		args = append(args, "--minify")
+	}
+	if opts.Watch {
+		// Without always, the CLI stops watching when stdin closes.
+		args = append(args, "--watch=always")
+	}
+	return exec.CommandContext(ctx, bin, args...), nil
+}
+
+// writeDefaultInput writes the default input stylesheet for root to Dir, or a
+// temporary directory when Dir is empty, and returns its path.
+func writeDefaultInput(root string) (string, error) {
+	if root == "" {
+		root = "."
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	dir := Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(abs))
+	path := filepath.Join(dir, "input-"+hex.EncodeToString(sum[:8])+".css")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	css := fmt.Sprintf(defaultInput, filepath.ToSlash(abs)+"/**/*.{html,svelte,templ}")
+	if err := os.WriteFile(path, []byte(css), 0o644); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return path, nil
+}
+
+// Binary returns the path of the standalone CLI for version, or Version when empty,
+// downloading it to Dir on first use.
+func Binary(version string) (string, error) {
+	if version == "" {
+		version = Version
+	}
+	if !versionPattern.MatchString(version) {
+		return "", errors.Errorf("invalid Tailwind version %q", version)
+	}
+	asset, err := assetName(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+
+	dir := Dir
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "tailwind"); err != nil {
+			return "", errors.WithStack(err)
+		}
+	}
+	path := filepath.Join(dir, version, asset)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	data, err := download(fmt.Sprintf("%s/v%s/%s", ReleaseURL, version, asset))
+	if err != nil {
+		return "", errors.Wrapf(err, "download Tailwind %s", version)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	// Written under another name first so an interrupted download is not used.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return path, nil
+}
+
+// assetName returns the release asset of the standalone CLI for a platform.
+func assetName(goos, goarch string) (string, error) {
+	oses := map[string]string{"darwin": "macos", "linux": "linux", "windows": "windows"}
+	arches := map[string]string{"amd64": "x64", "arm64": "arm64"}
+	o, a := oses[goos], arches[goarch]
+	if o == "" || a == "" {
+		return "", errors.Errorf("no standalone Tailwind CLI for %s/%s", goos, goarch)
+	}
+	name := "tailwindcss-" + o + "-" + a
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name, nil
+}
+
+func download(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, errors.WithStack(err)
+}
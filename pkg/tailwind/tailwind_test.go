@@ -0,0 +1,79 @@
+package tailwind_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/tailwind"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCLI writes its arguments and input stylesheet to the file after --output.
+const fakeCLI = `#!/bin/sh
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --input) input="$2"; shift ;;
+    --output) output="$2"; shift ;;
+    *) flags="$flags $1" ;;
+  esac
+  shift
+done
+{ echo "flags:$flags"; cat "$input"; } > "$output"
+`
+
+func TestBuild(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake CLI is a shell script")
+	}
+	r := require.New(t)
+	a := assert.New(t)
+
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, "/v4.1.13/tailwindcss-") {
+			http.NotFound(w, req)
+			return
+		}
+		downloads.Add(1)
+		_, _ = w.Write([]byte(fakeCLI))
+	}))
+	defer srv.Close()
+
+	prevURL, prevDir := tailwind.ReleaseURL, tailwind.Dir
+	t.Cleanup(func() { tailwind.ReleaseURL, tailwind.Dir = prevURL, prevDir })
+	tailwind.ReleaseURL = srv.URL
+	tailwind.Dir = t.TempDir()
+	t.Chdir(t.TempDir())
+
+	r.NoError(tailwind.Build(t.Context(), tailwind.Options{}))
+	out, err := os.ReadFile(tailwind.DefaultOutput)
+	r.NoError(err)
+	cwd, err := os.Getwd()
+	r.NoError(err)
+	a.Contains(string(out), "flags: --minify")
+	a.Contains(string(out), `@import "tailwindcss" source(none);`)
+	a.Contains(string(out), filepath.ToSlash(cwd)+"/**/*.{html,svelte,templ}")
+
+	r.NoError(os.WriteFile("app.css", []byte(`@import "tailwindcss";`), 0644))
+	r.NoError(tailwind.Build(t.Context(), tailwind.Options{Input: "app.css", Output: "public/site.css"}))
+	out, err = os.ReadFile(filepath.Join("public", "site.css"))
+	r.NoError(err)
+	a.Contains(string(out), `@import "tailwindcss";`)
+	a.Equal(int32(1), downloads.Load())
+
+	cmd, err := tailwind.Command(t.Context(), tailwind.Options{Watch: true})
+	r.NoError(err)
+	a.Contains(cmd.Args, "--watch=always")
+
+	_, err = tailwind.Binary("4.0.0")
+	a.ErrorContains(err, "404")
+	_, err = tailwind.Binary("latest")
+	a.ErrorContains(err, `invalid Tailwind version "latest"`)
+}
@@ -0,0 +1,186 @@
+// Package terraform renders deployed Cloud Run services as Terraform configuration,
+// for teams moving from go do deploys to infrastructure as code.
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/pkg/errors"
+)
+
+// Service is a deployed service and the resources attached to it.
+type Service struct {
+	Config  gcloud.ServiceConfig
+	Domains []string
+	IAM     []gcloud.IAMBinding
+	Project string
+	Region  string
+}
+
+// nameInvalid matches runs of characters not allowed in Terraform resource names.
+var nameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// Write renders s as google provider resources: a google_cloud_run_v2_service,
+// an IAM member per binding, and a domain mapping per custom domain.
+func Write(w io.Writer, s Service) error {
+	var b strings.Builder
+	name := resourceName(s.Config.Name)
+	ref := "google_cloud_run_v2_service." + name
+
+	fmt.Fprintf(&b, "resource \"google_cloud_run_v2_service\" %q {\n", name)
+	writeAttrs(&b, "  ", [][2]string{
+		{"name", quote(s.Config.Name)},
+		{"project", quote(s.Project)},
+		{"location", quote(s.Region)},
+	})
+	if len(s.Config.Labels) > 0 {
+		b.WriteString("\n")
+		writeMap(&b, "  ", "labels", s.Config.Labels)
+	}
+
+	b.WriteString("\n  template {\n")
+	var attrs [][2]string
+	if s.Config.ServiceAccount != "" {
+		attrs = append(attrs, [2]string{"service_account", quote(s.Config.ServiceAccount)})
+	}
+	if s.Config.Concurrency > 0 {
+		attrs = append(attrs, [2]string{"max_instance_request_concurrency", strconv.Itoa(s.Config.Concurrency)})
+	}
+	writeAttrs(&b, "    ", attrs)
+	if len(attrs) > 0 {
+		b.WriteString("\n")
+	}
+	if s.Config.MinInstances > 0 || s.Config.MaxInstances > 0 {
+		var scaling [][2]string
+		if s.Config.MinInstances > 0 {
+			scaling = append(scaling, [2]string{"min_instance_count", strconv.Itoa(s.Config.MinInstances)})
+		}
+		if s.Config.MaxInstances > 0 {
+			scaling = append(scaling, [2]string{"max_instance_count", strconv.Itoa(s.Config.MaxInstances)})
+		}
+		b.WriteString("    scaling {\n")
+		writeAttrs(&b, "      ", scaling)
+		b.WriteString("    }\n\n")
+	}
+
+	b.WriteString("    containers {\n")
+	fmt.Fprintf(&b, "      image = %s\n", quote(s.Config.Image))
+	limits := make(map[string]string)
+	if s.Config.CPU != "" {
+		limits["cpu"] = s.Config.CPU
+	}
+	if s.Config.Memory != "" {
+		limits["memory"] = s.Config.Memory
+	}
+	if len(limits) > 0 {
+		b.WriteString("\n      resources {\n")
+		writeMap(&b, "        ", "limits", limits)
+		b.WriteString("      }\n")
+	}
+	for _, e := range s.Config.Env {
+		b.WriteString("\n      env {\n")
+		if e.Secret != "" {
+			writeAttrs(&b, "        ", [][2]string{{"name", quote(e.Name)}})
+			b.WriteString("\n        value_source {\n          secret_key_ref {\n")
+			writeAttrs(&b, "            ", [][2]string{{"secret", quote(e.Secret)}, {"version", quote(e.SecretVersion)}})
+			b.WriteString("          }\n        }\n")
+		} else {
+			writeAttrs(&b, "        ", [][2]string{{"name", quote(e.Name)}, {"value", quote(e.Value)}})
+		}
+		b.WriteString("      }\n")
+	}
+	if len(s.Config.CloudSQL) > 0 {
+		b.WriteString("\n      volume_mounts {\n        name       = \"cloudsql\"\n        mount_path = \"/cloudsql\"\n      }\n")
+	}
+	b.WriteString("    }\n")
+
+	if len(s.Config.CloudSQL) > 0 {
+		quoted := make([]string, len(s.Config.CloudSQL))
+		for i, c := range s.Config.CloudSQL {
+			quoted[i] = quote(c)
+		}
+		b.WriteString("\n    volumes {\n      name = \"cloudsql\"\n      cloud_sql_instance {\n")
+		fmt.Fprintf(&b, "        instances = [%s]\n", strings.Join(quoted, ", "))
+		b.WriteString("      }\n    }\n")
+	}
+	b.WriteString("  }\n}\n")
+
+	for _, binding := range s.IAM {
+		role := strings.TrimPrefix(binding.Role, "roles/")
+		fmt.Fprintf(&b, "\nresource \"google_cloud_run_v2_service_iam_member\" %q {\n", resourceName(name+"_"+role+"_"+binding.Member))
+		writeAttrs(&b, "  ", [][2]string{
+			{"project", ref + ".project"},
+			{"location", ref + ".location"},
+			{"name", ref + ".name"},
+			{"role", quote(binding.Role)},
+			{"member", quote(binding.Member)},
+		})
+		b.WriteString("}\n")
+	}
+
+	for _, domain := range s.Domains {
+		fmt.Fprintf(&b, "\nresource \"google_cloud_run_domain_mapping\" %q {\n", resourceName(domain))
+		writeAttrs(&b, "  ", [][2]string{
+			{"name", quote(domain)},
+			{"location", ref + ".location"},
+		})
+		b.WriteString("\n  metadata {\n")
+		fmt.Fprintf(&b, "    namespace = %s.project\n", ref)
+		b.WriteString("  }\n\n  spec {\n")
+		fmt.Fprintf(&b, "    route_name = %s.name\n", ref)
+		b.WriteString("  }\n}\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return errors.WithStack(err)
+}
+
+// writeAttrs writes attribute assignments with aligned equals signs, as terraform fmt does.
+func writeAttrs(b *strings.Builder, indent string, attrs [][2]string) {
+	width := 0
+	for _, a := range attrs {
+		width = max(width, len(a[0]))
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(b, "%s%-*s = %s\n", indent, width, a[0], a[1])
+	}
+}
+
+// writeMap writes an attribute holding a map of strings, sorted by key with aligned values.
+func writeMap(b *strings.Builder, indent, attr string, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	width := 0
+	for k := range m {
+		keys = append(keys, k)
+		width = max(width, len(quote(k)))
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "%s%s = {\n", indent, attr)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s  %-*s = %s\n", indent, width, quote(k), quote(m[k]))
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// resourceName converts s to a valid Terraform resource name.
+func resourceName(s string) string {
+	name := strings.Trim(nameInvalid.ReplaceAllString(s, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "r_" + name
+	}
+	return name
+}
+
+// quote returns s as an HCL string literal, escaping template sequences.
+func quote(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
@@ -0,0 +1,83 @@
+package terraform_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/housecat-inc/do/pkg/gcloud"
+	"github.com/housecat-inc/do/pkg/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	var b strings.Builder
+	r.NoError(terraform.Write(&b, terraform.Service{
+		Config: gcloud.ServiceConfig{
+			Env:          []gcloud.EnvVar{{Name: "GREETING", Value: "hi ${name}"}},
+			Image:        "gcr.io/my-project/app@sha256:abc",
+			MaxInstances: 10,
+			Memory:       "512Mi",
+			Name:         "app",
+		},
+		IAM:     []gcloud.IAMBinding{{Member: "allUsers", Role: "roles/run.invoker"}},
+		Project: "my-project",
+		Region:  "us-central1",
+	}))
+
+	a.Equal(`resource "google_cloud_run_v2_service" "app" {
+  name     = "app"
+  project  = "my-project"
+  location = "us-central1"
+
+  template {
+    scaling {
+      max_instance_count = 10
+    }
+
+    containers {
+      image = "gcr.io/my-project/app@sha256:abc"
+
+      resources {
+        limits = {
+          "memory" = "512Mi"
+        }
+      }
+
+      env {
+        name  = "GREETING"
+        value = "hi $${name}"
+      }
+    }
+  }
+}
+
+resource "google_cloud_run_v2_service_iam_member" "app_run_invoker_allUsers" {
+  project  = google_cloud_run_v2_service.app.project
+  location = google_cloud_run_v2_service.app.location
+  name     = google_cloud_run_v2_service.app.name
+  role     = "roles/run.invoker"
+  member   = "allUsers"
+}
+`, b.String())
+}
+
+func TestWriteDomains(t *testing.T) {
+	r := require.New(t)
+	a := assert.New(t)
+
+	var b strings.Builder
+	r.NoError(terraform.Write(&b, terraform.Service{
+		Config:  gcloud.ServiceConfig{CloudSQL: []string{"p:r:db"}, Image: "img", Name: "app"},
+		Domains: []string{"app.example.com"},
+		Project: "p",
+		Region:  "r",
+	}))
+
+	a.Contains(b.String(), `instances = ["p:r:db"]`)
+	a.Contains(b.String(), `resource "google_cloud_run_domain_mapping" "app_example_com" {`)
+	a.Contains(b.String(), `route_name = google_cloud_run_v2_service.app.name`)
+}